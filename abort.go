@@ -0,0 +1,88 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+)
+
+// Abort describes a connection that's closed before the request/response
+// exchange completes, and the follow-up request that's used to check how
+// the server dealt with it.
+type Abort struct {
+	// AfterBytes, if greater than 0, closes the connection after the
+	// specified number of request body bytes has been sent.
+	AfterBytes int64
+	// AfterHeaders, if set, closes the connection as soon as the response
+	// headers are received, without reading the response body.
+	AfterHeaders bool
+
+	// Method and Path of the follow-up request, they default to those of
+	// the aborted request.
+	Method string
+	Path   string
+	// Then is the follow-up request that's executed after the abort,
+	// e.g. to check that a half-finished upload was cleaned up.
+	Then Request
+}
+
+var errAborted = errors.New("hit: connection aborted")
+
+// abort sends the request, closes its connection as specified by the
+// receiver's Abort and then executes the follow-up request.
+func (r Request) abort(method, path string) error {
+	req, err := r.newRequest(method, path)
+	if err != nil {
+		return err
+	}
+	if r.Abort.AfterBytes > 0 && req.Body != nil {
+		req.Body = &abortBody{rc: req.Body, left: r.Abort.AfterBytes}
+		req.GetBody = nil
+	}
+
+	// The error is expected, the transport fails the request once the body
+	// is aborted, it's the server's reaction that's being tested.
+	if res, err := r.runner().httpClient().Do(req); err == nil {
+		if !r.Abort.AfterHeaders {
+			io.Copy(ioutil.Discard, res.Body)
+		}
+		res.Body.Close()
+	}
+
+	m, p := r.Abort.Method, r.Abort.Path
+	if m == "" {
+		m = method
+	}
+	if p == "" {
+		p = path
+	}
+	then := r.Abort.Then
+	then.rn = r.rn
+	then.Want.Body = r.runner().withMode(then.Want.Body)
+	return then.execute(m, p)
+}
+
+// abortBody is an io.ReadCloser that fails with errAborted after left bytes
+// have been read from the underlying body, causing the transport to close
+// the connection.
+type abortBody struct {
+	rc   io.ReadCloser
+	left int64
+}
+
+func (b *abortBody) Read(p []byte) (int, error) {
+	if b.left <= 0 {
+		return 0, errAborted
+	}
+	if int64(len(p)) > b.left {
+		p = p[:b.left]
+	}
+	n, err := b.rc.Read(p)
+	b.left -= int64(n)
+	return n, err
+}
+
+func (b *abortBody) Close() error { return b.rc.Close() }
@@ -0,0 +1,61 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestRequestAbort(t *testing.T) {
+	var mu sync.Mutex
+	uploads := map[string]bool{}
+	done := make(chan struct{}, 1) // lets the follow-up wait for the upload
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "PUT":
+			mu.Lock()
+			uploads[r.URL.Path] = true
+			mu.Unlock()
+			if _, err := io.Copy(ioutil.Discard, r.Body); err != nil {
+				// clean up the half-finished upload
+				mu.Lock()
+				delete(uploads, r.URL.Path)
+				mu.Unlock()
+			}
+			done <- struct{}{}
+		case "GET":
+			<-done
+			mu.Lock()
+			ok := uploads[r.URL.Path]
+			mu.Unlock()
+			if !ok {
+				w.WriteHeader(404)
+			}
+		}
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	tests := []struct {
+		abort Abort
+		want  int
+	}{
+		{Abort{AfterBytes: 1 << 10}, 404},
+		{Abort{AfterHeaders: true}, 200},
+	}
+	for i, tt := range tests {
+		tt.abort.Method = "GET"
+		tt.abort.Then = Request{Want: Response{Status: tt.want}}
+		r := Request{Body: BytesOfSize(1 << 20), Abort: &tt.abort}
+		if err := rn.Execute(r, "PUT", "/files/"+string(rune('a'+i))); err != nil {
+			t.Errorf("#%d: %v", i, err)
+		}
+	}
+}
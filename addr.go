@@ -0,0 +1,56 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+)
+
+// normAddr returns the specified address with IPv6 literals enclosed in
+// brackets, as is required in URLs, e.g. "::1" becomes "[::1]" and
+// "[::1]:80" is left as is.
+func normAddr(addr string) string {
+	if host, port, err := net.SplitHostPort(addr); err == nil {
+		return net.JoinHostPort(host, port)
+	}
+	if ip := net.ParseIP(strings.Trim(addr, "[]")); ip != nil && ip.To4() == nil {
+		return "[" + ip.String() + "]"
+	}
+	return addr
+}
+
+// DualStack resolves the host of the specified "host:port" address and
+// returns two addresses, the first with one of the host's IPv4 addresses
+// and the second with one of its IPv6 addresses. It fails if the host lacks
+// either, use the result with TestAddrs to check that the endpoints behave
+// the same over both protocols.
+func DualStack(addr string) ([]string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("hit: bad address %q. %v", addr, err)
+	}
+	var addrs []string
+	for _, network := range []string{"ip4", "ip6"} {
+		ips, err := net.DefaultResolver.LookupIP(context.Background(), network, host)
+		if err != nil || len(ips) == 0 {
+			return nil, fmt.Errorf("hit: no %s address for %q. %v", network, host, err)
+		}
+		addrs = append(addrs, net.JoinHostPort(ips[0].String(), port))
+	}
+	return addrs, nil
+}
+
+// TestAddrs executes all of the Hit's Requests against each of the specified
+// addresses in turn, in subtests named after the addresses.
+func (h Hit) TestAddrs(t *testing.T, addrs ...string) {
+	for _, a := range addrs {
+		rn := NewRunner()
+		rn.Addr = a
+		t.Run(a, func(t *testing.T) { rn.Test(t, h) })
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNormAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want string
+	}{
+		{"localhost:3456", "localhost:3456"},
+		{"127.0.0.1:80", "127.0.0.1:80"},
+		{"[::1]:3456", "[::1]:3456"},
+		{"::1", "[::1]"},
+		{"[::1]", "[::1]"},
+		{"2001:db8::1", "[2001:db8::1]"},
+		{"example.com", "example.com"},
+	}
+	for _, tt := range tests {
+		if got := normAddr(tt.addr); got != tt.want {
+			t.Errorf("normAddr(%q) got %q, want %q", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestHitTestAddrs(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ok":true}`))
+	})
+	ts4 := httptest.NewServer(handler)
+	defer ts4.Close()
+	addrs := []string{ts4.Listener.Addr().String()}
+	if ln, err := net.Listen("tcp6", "[::1]:0"); err == nil {
+		ts6 := httptest.NewUnstartedServer(handler)
+		ts6.Listener.Close()
+		ts6.Listener = ln
+		ts6.Start()
+		defer ts6.Close()
+		addrs = append(addrs, ln.Addr().String())
+	} else {
+		t.Logf("no IPv6 loopback, testing IPv4 only. %v", err)
+	}
+
+	h := Hit{Path: "/", Requests: Requests{
+		"GET": {{Want: Response{Status: 200, Body: JSONBody{"ok": true}}}},
+	}}
+	h.TestAddrs(t, addrs...)
+}
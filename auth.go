@@ -0,0 +1,396 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ClientCredentials is a Signer that obtains an access token from an OAuth2
+// token endpoint using the client credentials grant and sets it as a Bearer
+// token in the request's Authorization header. The token is fetched once and
+// cached, it's refreshed only after it has expired.
+//
+// ClientCredentials must not be copied after first use, use a pointer.
+type ClientCredentials struct {
+	// The token endpoint. If it's only a path, e.g. "/oauth/token", it's
+	// resolved against the target of the Runner executing the request.
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// Sign implements the Signer interface.
+func (c *ClientCredentials) Sign(r *http.Request, body []byte) error {
+	return c.signWith(globalRunner(), r, body)
+}
+
+func (c *ClientCredentials) signWith(rn *Runner, r *http.Request, body []byte) error {
+	tok, err := c.fetch(rn)
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Authorization", "Bearer "+tok)
+	return nil
+}
+
+// Token returns the cached access token, fetching a new one from the token
+// endpoint if there's none or if it has expired.
+func (c *ClientCredentials) Token() (string, error) {
+	return c.fetch(globalRunner())
+}
+
+// fetch returns the cached access token, fetching a new one with the
+// specified Runner if needed.
+func (c *ClientCredentials) fetch(rn *Runner) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && (c.expiry.IsZero() || time.Now().Before(c.expiry)) {
+		return c.token, nil
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(c.Scopes) > 0 {
+		form.Set("scope", strings.Join(c.Scopes, " "))
+	}
+	req, err := http.NewRequest("POST", rn.absURL(c.TokenURL), strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", urlencoded)
+	req.SetBasicAuth(url.QueryEscape(c.ClientID), url.QueryEscape(c.ClientSecret))
+
+	res, err := rn.httpClient().Do(req)
+	if err != nil {
+		return "", fmt.Errorf("token request failed. %v", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request got status %d, want 200", res.StatusCode)
+	}
+
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&tok); err != nil {
+		return "", fmt.Errorf("error decoding token response. %v", err)
+	}
+	if tok.AccessToken == "" {
+		return "", fmt.Errorf("token response has no access_token")
+	}
+
+	c.token, c.expiry = tok.AccessToken, time.Time{}
+	if tok.ExpiresIn > 0 {
+		// refresh a bit early so that the token doesn't expire mid-request
+		c.expiry = time.Now().Add(time.Duration(tok.ExpiresIn)*time.Second - 10*time.Second)
+	}
+	return c.token, nil
+}
+
+// absURL returns the specified URL as is, unless it's only a path in which
+// case it's resolved against the Runner's target.
+func (rn *Runner) absURL(u string) string {
+	if strings.HasPrefix(u, "/") {
+		return rn.baseURL() + u
+	}
+	return u
+}
+
+// DigestAuth holds the credentials for the HTTP Digest authentication scheme.
+// The MD5 and SHA-256 algorithms, and their -sess variants, are supported.
+type DigestAuth struct {
+	User string
+	Pass string
+}
+
+// retry answers the Digest challenge of the specified response by resending
+// the specified request with the computed Authorization header. If the
+// response doesn't carry a Digest challenge it's returned as is.
+func (d DigestAuth) retry(rn *Runner, req *http.Request, res *http.Response) (*http.Response, error) {
+	var chal string
+	for _, v := range res.Header["Www-Authenticate"] {
+		if strings.HasPrefix(strings.ToLower(v), "digest ") {
+			chal = v[len("digest "):]
+			break
+		}
+	}
+	if chal == "" {
+		return res, nil
+	}
+	auth, err := d.authorization(req.Method, req.URL.RequestURI(), parseAuthParams(chal))
+	if err != nil {
+		return nil, err
+	}
+
+	// drain the challenge so that the connection can be reused
+	ioutil.ReadAll(res.Body)
+	res.Body.Close()
+
+	req.Header.Set("Authorization", auth)
+	return rn.resend(req)
+}
+
+// authorization computes the value of the Authorization header for the
+// specified method, uri and challenge parameters.
+func (d DigestAuth) authorization(method, uri string, chal map[string]string) (string, error) {
+	var h func() hash.Hash
+	algo := chal["algorithm"]
+	switch strings.ToUpper(strings.TrimSuffix(strings.ToLower(algo), "-sess")) {
+	case "", "MD5":
+		h = md5.New
+	case "SHA-256":
+		h = sha256.New
+	default:
+		return "", fmt.Errorf("unsupported digest algorithm %q", algo)
+	}
+	hexsum := func(s string) string {
+		sum := h()
+		sum.Write([]byte(s))
+		return hex.EncodeToString(sum.Sum(nil))
+	}
+
+	qop := ""
+	if chal["qop"] != "" {
+		for _, v := range strings.Split(chal["qop"], ",") {
+			if strings.TrimSpace(v) == "auth" {
+				qop = "auth"
+			}
+		}
+		if qop == "" {
+			return "", fmt.Errorf("unsupported digest qop %q", chal["qop"])
+		}
+	}
+
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	cnonce, nc, nonce := hex.EncodeToString(b), "00000001", chal["nonce"]
+
+	ha1 := hexsum(d.User + ":" + chal["realm"] + ":" + d.Pass)
+	if strings.HasSuffix(strings.ToLower(algo), "-sess") {
+		ha1 = hexsum(ha1 + ":" + nonce + ":" + cnonce)
+	}
+	ha2 := hexsum(method + ":" + uri)
+
+	var resp string
+	if qop != "" {
+		resp = hexsum(ha1 + ":" + nonce + ":" + nc + ":" + cnonce + ":" + qop + ":" + ha2)
+	} else {
+		resp = hexsum(ha1 + ":" + nonce + ":" + ha2)
+	}
+
+	s := fmt.Sprintf(`Digest username="%s", realm="%s", nonce="%s", uri="%s", response="%s"`,
+		escapeQuotes(d.User), escapeQuotes(chal["realm"]), escapeQuotes(nonce), uri, resp)
+	if algo != "" {
+		s += ", algorithm=" + algo
+	}
+	if qop != "" {
+		s += fmt.Sprintf(`, qop=%s, nc=%s, cnonce="%s"`, qop, nc, cnonce)
+	}
+	if opaque, ok := chal["opaque"]; ok {
+		s += fmt.Sprintf(`, opaque="%s"`, escapeQuotes(opaque))
+	}
+	return s, nil
+}
+
+// parseAuthParams parses a comma separated list of key=value pairs, as used
+// in the WWW-Authenticate and Authorization headers, into a map. Values may
+// be quoted strings.
+func parseAuthParams(s string) map[string]string {
+	m := make(map[string]string)
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimLeft(s, ", ") {
+		i := strings.IndexByte(s, '=')
+		if i < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(s[:i]))
+		s = strings.TrimSpace(s[i+1:])
+
+		var val string
+		if strings.HasPrefix(s, `"`) {
+			var b strings.Builder
+			j := 1
+			for ; j < len(s) && s[j] != '"'; j++ {
+				if s[j] == '\\' && j+1 < len(s) {
+					j++
+				}
+				b.WriteByte(s[j])
+			}
+			if j < len(s) {
+				j++
+			}
+			val, s = b.String(), s[j:]
+		} else {
+			j := strings.IndexByte(s, ',')
+			if j < 0 {
+				j = len(s)
+			}
+			val, s = strings.TrimSpace(s[:j]), s[j:]
+		}
+		m[key] = val
+	}
+	return m
+}
+
+// Login is a Signer that performs an authentication request once, before the
+// first request it signs, and then attaches the captured token and cookies
+// to every request it signs. Set it as the Signer of all Hits that require
+// an authenticated user.
+//
+// Login must not be copied after first use, use a pointer.
+type Login struct {
+	// The method of the authentication request, defaults to "POST".
+	Method string
+	// The path of the authentication request.
+	Path   string
+	Header Header
+	Body   Bodyer
+	// TokenFrom, if set, extracts the token from the authentication
+	// response, the token is then sent as a Bearer token. Any cookies set
+	// by the authentication response are captured regardless of TokenFrom.
+	TokenFrom func(res *http.Response, body []byte) (string, error)
+
+	once    sync.Once
+	err     error
+	token   string
+	cookies []*http.Cookie
+}
+
+// Sign implements the Signer interface.
+func (l *Login) Sign(r *http.Request, body []byte) error {
+	return l.signWith(globalRunner(), r, body)
+}
+
+func (l *Login) signWith(rn *Runner, r *http.Request, body []byte) error {
+	if err := l.login(rn); err != nil {
+		return err
+	}
+	if l.token != "" {
+		r.Header.Set("Authorization", "Bearer "+l.token)
+	}
+	for _, c := range l.cookies {
+		r.AddCookie(c)
+	}
+	return nil
+}
+
+// Token returns the captured token, performing the authentication request
+// if it hasn't been done yet.
+func (l *Login) Token() (string, error) {
+	err := l.Do()
+	return l.token, err
+}
+
+// Cookies returns the captured cookies, performing the authentication
+// request if it hasn't been done yet.
+func (l *Login) Cookies() ([]*http.Cookie, error) {
+	err := l.Do()
+	return l.cookies, err
+}
+
+// Do performs the authentication request. It does so only once, subsequent
+// calls return the result of the first one.
+func (l *Login) Do() error {
+	return l.login(globalRunner())
+}
+
+// login performs the authentication request once, with the specified Runner.
+func (l *Login) login(rn *Runner) error {
+	l.once.Do(func() { l.err = l.do(rn) })
+	return l.err
+}
+
+func (l *Login) do(rn *Runner) error {
+	method := l.Method
+	if method == "" {
+		method = "POST"
+	}
+	var body io.Reader
+	if l.Body != nil {
+		var err error
+		if body, err = l.Body.Body(); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, rn.absURL(l.Path), body)
+	if err != nil {
+		return err
+	}
+	if l.Body != nil {
+		req.Header.Set("Content-Type", l.Body.Type())
+	}
+	if l.Header != nil {
+		l.Header.AddTo(req)
+	}
+
+	res, err := rn.httpClient().Do(req)
+	if err != nil && !isRedirectError(err) {
+		return fmt.Errorf("hit: login %s %s failed. %v", method, l.Path, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 400 {
+		return fmt.Errorf("hit: login %s %s got status %d", method, l.Path, res.StatusCode)
+	}
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("hit: login %s %s failed reading body. %v", method, l.Path, err)
+	}
+
+	l.cookies = res.Cookies()
+	if l.TokenFrom != nil {
+		if l.token, err = l.TokenFrom(res, b); err != nil {
+			return fmt.Errorf("hit: login %s %s failed capturing token. %v", method, l.Path, err)
+		}
+	}
+	return nil
+}
+
+// TokenFromJSON returns a function, usable as Login's TokenFrom, that
+// extracts the token from the named field of a JSON response body.
+func TokenFromJSON(field string) func(*http.Response, []byte) (string, error) {
+	return func(res *http.Response, body []byte) (string, error) {
+		var m map[string]interface{}
+		if err := json.Unmarshal(body, &m); err != nil {
+			return "", err
+		}
+		s, ok := m[field].(string)
+		if !ok || s == "" {
+			return "", fmt.Errorf("response body has no %q string field", field)
+		}
+		return s, nil
+	}
+}
+
+// TokenFromHeader returns a function, usable as Login's TokenFrom, that
+// extracts the token from the named response header. A "Bearer " prefix
+// is removed from the header's value.
+func TokenFromHeader(name string) func(*http.Response, []byte) (string, error) {
+	return func(res *http.Response, body []byte) (string, error) {
+		s := strings.TrimPrefix(res.Header.Get(name), "Bearer ")
+		if s == "" {
+			return "", fmt.Errorf("response has no %q header", name)
+		}
+		return s, nil
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestClientCredentials(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id, secret, _ := r.BasicAuth(); id != "app" || secret != "s3cr3t" {
+			w.WriteHeader(401)
+			return
+		}
+		if r.FormValue("grant_type") != "client_credentials" || r.FormValue("scope") != "a b" {
+			w.WriteHeader(400)
+			return
+		}
+		calls++
+		fmt.Fprintf(w, `{"access_token":"tok%d","token_type":"bearer","expires_in":3600}`, calls)
+	}))
+	defer ts.Close()
+
+	c := &ClientCredentials{TokenURL: ts.URL, ClientID: "app", ClientSecret: "s3cr3t", Scopes: []string{"a", "b"}}
+	for i := 0; i < 2; i++ {
+		r, _ := http.NewRequest("GET", "http://localhost/", nil)
+		if err := c.Sign(r, nil); err != nil {
+			t.Fatalf("#%d: got err %v, want <nil>", i, err)
+		}
+		if got, want := r.Header.Get("Authorization"), "Bearer tok1"; got != want {
+			t.Errorf("#%d: got %q, want %q", i, got, want)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("token endpoint called %d times, want 1", calls)
+	}
+}
+
+func TestRequestAuthorization(t *testing.T) {
+	var got string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("Authorization")
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	tests := []struct {
+		r    Request
+		want string
+	}{
+		{Request{BasicAuth: &BasicAuth{"jdoe", "pass"}, Want: Response{Status: 200}}, "Basic amRvZTpwYXNz"},
+		{Request{Bearer: "abc123", Want: Response{Status: 200}}, "Bearer abc123"},
+	}
+	for i, tt := range tests {
+		if err := rn.Execute(tt.r, "GET", "/"); err != nil {
+			t.Errorf("#%d: got err %v, want <nil>", i, err)
+		}
+		if got != tt.want {
+			t.Errorf("#%d: got %q, want %q", i, got, tt.want)
+		}
+	}
+}
+
+func TestDigestAuth(t *testing.T) {
+	hexmd5 := func(s string) string {
+		sum := md5.Sum([]byte(s))
+		return hex.EncodeToString(sum[:])
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "Digest ") {
+			w.Header().Set("Www-Authenticate", `Digest realm="test", qop="auth,auth-int", nonce="abc", opaque="xyz"`)
+			w.WriteHeader(401)
+			return
+		}
+		p := parseAuthParams(auth[len("Digest "):])
+		ha1 := hexmd5("jdoe:test:pass")
+		ha2 := hexmd5(r.Method + ":" + p["uri"])
+		want := hexmd5(ha1 + ":abc:" + p["nc"] + ":" + p["cnonce"] + ":auth:" + ha2)
+		if p["response"] != want || p["opaque"] != "xyz" || p["uri"] != r.URL.RequestURI() {
+			w.WriteHeader(403)
+			return
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		fmt.Fprintf(w, `{"body":%q}`, b)
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	r := Request{
+		DigestAuth: &DigestAuth{"jdoe", "pass"},
+		Body:       FormBody{"a": {"b"}},
+		Want:       Response{Status: 200, Body: JSONBody{"body": "a=b"}},
+	}
+	if err := rn.Execute(r, "POST", "/secret?x=1"); err != nil {
+		t.Error(err)
+	}
+
+	r = Request{DigestAuth: &DigestAuth{"jdoe", "wrong"}, Want: Response{Status: 403}}
+	if err := rn.Execute(r, "GET", "/secret"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParseAuthParams(t *testing.T) {
+	got := parseAuthParams(`realm="a \"b\", c", qop=auth, nonce="n"`)
+	want := map[string]string{"realm": `a "b", c`, "qop": "auth", "nonce": "n"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestLogin(t *testing.T) {
+	logins := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("pass") != "secret" {
+			w.WriteHeader(401)
+			return
+		}
+		logins++
+		http.SetCookie(w, &http.Cookie{Name: "sid", Value: "s1"})
+		fmt.Fprint(w, `{"token":"t1"}`)
+	})
+	mux.HandleFunc("/me", func(w http.ResponseWriter, r *http.Request) {
+		c, err := r.Cookie("sid")
+		if err != nil || c.Value != "s1" || r.Header.Get("Authorization") != "Bearer t1" {
+			w.WriteHeader(401)
+			return
+		}
+		w.WriteHeader(200)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	login := &Login{Path: "/login", Body: FormBody{"pass": {"secret"}}, TokenFrom: TokenFromJSON("token")}
+	h := Hit{Path: "/me", Requests: Requests{"GET": {{Want: Response{Status: 200}}, {Want: Response{Status: 200}}}}, Signer: login}
+	rn.Test(t, h)
+	if logins != 1 {
+		t.Errorf("got %d logins, want 1", logins)
+	}
+
+	bad := &Login{Path: "/login", Body: FormBody{"pass": {"wrong"}}}
+	if err := rn.Execute(Request{Signer: bad}, "GET", "/me"); err == nil || !strings.Contains(err.Error(), "got status 401") {
+		t.Errorf("got err %v, want login error", err)
+	}
+}
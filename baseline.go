@@ -0,0 +1,168 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// BaselineUpdateEnv is the environment variable that, if set to a non-empty
+// value, initializes UpdateBaseline.
+const BaselineUpdateEnv = "HIT_UPDATE_BASELINE"
+
+var (
+	// BaselineFile is the file in which the baseline is kept.
+	BaselineFile = filepath.Join("testdata", "baseline.json")
+
+	// UpdateBaseline, if set, makes CheckBaseline save the current run as
+	// the baseline instead of comparing the run to it.
+	UpdateBaseline = os.Getenv(BaselineUpdateEnv) != ""
+
+	// RecordBaseline, if set, records the outcomes of the executed requests
+	// for CurrentBaseline, SaveBaseline and CheckBaseline. It's meant to be
+	// set in TestMain before m.Run, nothing is recorded otherwise.
+	RecordBaseline bool
+)
+
+// Baseline summarizes a run by endpoint, the keys are of the form
+// "METHOD path".
+type Baseline map[string]BaselineEntry
+
+// BaselineEntry summarizes the requests of a run to one endpoint.
+type BaselineEntry struct {
+	Requests int           `json:"requests"`
+	Failures int           `json:"failures"`
+	P50      time.Duration `json:"p50"`
+	P95      time.Duration `json:"p95"`
+}
+
+// Regression holds the allowed growth, as fractions, of an endpoint's
+// numbers over the baseline, e.g. a Latency of 0.2 allows its 95th
+// percentile latency to grow by 20%.
+type Regression struct {
+	Latency  float64
+	Failures float64
+}
+
+// outcome holds the outcomes of the executed requests to one endpoint.
+type outcome struct {
+	dd       []time.Duration
+	failures int
+}
+
+// outcomes records the outcomes of the executed requests by endpoint.
+var outcomes = struct {
+	sync.Mutex
+	m map[string]*outcome
+}{m: make(map[string]*outcome)}
+
+// recordOutcome records the outcome of an executed request.
+func recordOutcome(method, path string, d time.Duration, failed bool) {
+	if !RecordBaseline {
+		return
+	}
+	outcomes.Lock()
+	defer outcomes.Unlock()
+	k := method + " " + path
+	o := outcomes.m[k]
+	if o == nil {
+		o = &outcome{}
+		outcomes.m[k] = o
+	}
+	o.dd = append(o.dd, d)
+	if failed {
+		o.failures++
+	}
+}
+
+// ResetBaseline discards the outcomes recorded so far, e.g. those of a warm-up
+// run or of a previous Load.
+func ResetBaseline() {
+	outcomes.Lock()
+	defer outcomes.Unlock()
+	outcomes.m = make(map[string]*outcome)
+}
+
+// CurrentBaseline returns the summary of the requests executed so far.
+func CurrentBaseline() Baseline {
+	outcomes.Lock()
+	defer outcomes.Unlock()
+	b := make(Baseline)
+	for k, o := range outcomes.m {
+		l := latencyOf(o.dd)
+		b[k] = BaselineEntry{Requests: len(o.dd), Failures: o.failures, P50: l.P50, P95: l.P95}
+	}
+	return b
+}
+
+// SaveBaseline writes the summary of the requests executed so far to
+// BaselineFile.
+func SaveBaseline() error {
+	b, err := json.MarshalIndent(CurrentBaseline(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("hit: failed encoding baseline. %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(BaselineFile), 0755); err != nil {
+		return fmt.Errorf("hit: failed creating baseline directory. %v", err)
+	}
+	if err := ioutil.WriteFile(BaselineFile, append(b, '\n'), 0644); err != nil {
+		return fmt.Errorf("hit: failed writing baseline. %v", err)
+	}
+	return nil
+}
+
+// CheckBaseline compares the requests executed so far to the baseline in
+// BaselineFile and returns an error listing the endpoints whose latency or
+// number of failures grew by more than allowed. Endpoints missing from
+// either run are not compared. If the file doesn't exist yet, or if
+// UpdateBaseline is set, the current run is saved as the baseline instead.
+// It's meant to be called from TestMain after m.Run, with RecordBaseline set.
+func CheckBaseline(max Regression) error {
+	if !RecordBaseline {
+		return fmt.Errorf("hit: CheckBaseline without RecordBaseline set, there's no run to check")
+	}
+	raw, err := ioutil.ReadFile(BaselineFile)
+	if os.IsNotExist(err) || UpdateBaseline {
+		return SaveBaseline()
+	} else if err != nil {
+		return fmt.Errorf("hit: failed reading baseline. %v", err)
+	}
+	var base Baseline
+	if err := json.Unmarshal(raw, &base); err != nil {
+		return fmt.Errorf("hit: failed decoding baseline %q. %v", BaselineFile, err)
+	}
+
+	cur := CurrentBaseline()
+	var keys []string
+	for k := range cur {
+		if _, ok := base[k]; ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var msg string
+	for _, k := range keys {
+		got, want := cur[k], base[k]
+		if limit := time.Duration(float64(want.P95) * (1 + max.Latency)); got.P95 > limit {
+			msg += fmt.Sprintf("%s p95 got = %s%s%s, want at most %s%s%s, baseline %s\n",
+				k, RedColor, got.P95, StopColor, RedColor, limit, StopColor, want.P95)
+		}
+		if limit := int(float64(want.Failures) * (1 + max.Failures)); got.Failures > limit {
+			msg += fmt.Sprintf("%s failures got = %s%d%s, want at most %s%d%s, baseline %d\n",
+				k, RedColor, got.Failures, StopColor, RedColor, limit, StopColor, want.Failures)
+		}
+	}
+	if msg != "" {
+		return fmt.Errorf("Baseline %q regressed, set %s to update it:\n%s", BaselineFile, BaselineUpdateEnv, msg)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckBaseline(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(f string, u bool) { BaselineFile, UpdateBaseline = f, u }(BaselineFile, UpdateBaseline)
+	BaselineFile = filepath.Join(dir, "testdata", "baseline.json")
+	defer func(m map[string]*outcome, r bool) { outcomes.m, RecordBaseline = m, r }(outcomes.m, RecordBaseline)
+
+	// nothing is recorded unless asked for
+	RecordBaseline = false
+	ResetBaseline()
+	recordOutcome("GET", "/a", time.Millisecond, false)
+	if got := CurrentBaseline(); len(got) != 0 {
+		t.Errorf("got %v recorded without RecordBaseline, want none", got)
+	}
+	if err := CheckBaseline(Regression{}); err == nil {
+		t.Errorf("got no error checking without RecordBaseline")
+	}
+
+	RecordBaseline = true
+	run := func(a, b time.Duration, failures int) {
+		ResetBaseline()
+		for i := 0; i < 10; i++ {
+			recordOutcome("GET", "/a", a, i < failures)
+			recordOutcome("GET", "/b", b, false)
+		}
+	}
+
+	// the first run is saved as the baseline
+	run(10*time.Millisecond, 10*time.Millisecond, 2)
+	if err := CheckBaseline(Regression{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(BaselineFile); err != nil {
+		t.Fatalf("baseline not saved: %v", err)
+	}
+
+	tests := []struct {
+		a, b     time.Duration
+		failures int
+		max      Regression
+		want     []string
+	}{
+		{10 * time.Millisecond, 10 * time.Millisecond, 2, Regression{}, nil},
+		{12 * time.Millisecond, 10 * time.Millisecond, 3, Regression{Latency: 0.2, Failures: 0.5}, nil},
+		{13 * time.Millisecond, 10 * time.Millisecond, 2, Regression{Latency: 0.2}, []string{"GET /a p95"}},
+		{10 * time.Millisecond, 20 * time.Millisecond, 4, Regression{Latency: 0.2, Failures: 0.5}, []string{"GET /a failures", "GET /b p95"}},
+	}
+	for i, tt := range tests {
+		run(tt.a, tt.b, tt.failures)
+		err := CheckBaseline(tt.max)
+		if tt.want == nil {
+			if err != nil {
+				t.Errorf("#%d: unexpected error %v", i, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("#%d: want error, got nil", i)
+			continue
+		}
+		for _, w := range tt.want {
+			if !strings.Contains(err.Error(), w) {
+				t.Errorf("#%d: error %q does not contain %q", i, err, w)
+			}
+		}
+	}
+
+	// updating overwrites the baseline with the regressed run
+	UpdateBaseline = true
+	if err := CheckBaseline(Regression{}); err != nil {
+		t.Fatal(err)
+	}
+	UpdateBaseline = false
+	if err := CheckBaseline(Regression{}); err != nil {
+		t.Errorf("after update: unexpected error %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Budget is the latency budget of the requests with a tag, e.g. a suite-wide
+// performance gate checked by CheckBudgets once all of the tests have run.
+type Budget struct {
+	Tag string
+	// P95, if greater than 0, is the maximum 95th percentile of the
+	// durations of the tagged requests.
+	P95 time.Duration
+	// Total, if greater than 0, is the maximum sum of the durations of
+	// the tagged requests.
+	Total time.Duration
+}
+
+// timing is the duration of an executed request, from the moment it's sent
+// until its response is read and compared.
+type timing struct {
+	method, path string
+	d            time.Duration
+}
+
+// timings records the durations of the executed requests by tag.
+var timings = struct {
+	sync.Mutex
+	m map[string][]timing
+}{m: make(map[string][]timing)}
+
+// recordTiming records the duration of a request with the specified tags.
+func recordTiming(tags []string, method, path string, d time.Duration) {
+	if len(tags) == 0 {
+		return
+	}
+	timings.Lock()
+	defer timings.Unlock()
+	for _, tag := range tags {
+		timings.m[tag] = append(timings.m[tag], timing{method, path, d})
+	}
+}
+
+// ResetTimings discards the durations recorded so far.
+func ResetTimings() {
+	timings.Lock()
+	defer timings.Unlock()
+	timings.m = make(map[string][]timing)
+}
+
+// CheckBudgets checks the durations of the requests executed so far against
+// the specified budgets. It returns an error with a breakdown of every
+// breached budget, listing the slowest of its requests. It's meant to be
+// called from TestMain after m.Run, failing the run if the error is not nil.
+func CheckBudgets(budgets ...Budget) error {
+	timings.Lock()
+	defer timings.Unlock()
+
+	var msg string
+	for _, b := range budgets {
+		tt := timings.m[b.Tag]
+		dd := make([]time.Duration, len(tt))
+		var total time.Duration
+		for i, t := range tt {
+			dd[i] = t.d
+			total += t.d
+		}
+		l := latencyOf(dd)
+
+		var breach string
+		if b.P95 > 0 && l.P95 > b.P95 {
+			breach += fmt.Sprintf("  p95 got = %s%s%s, want at most %s%s%s\n",
+				RedColor, l.P95, StopColor, RedColor, b.P95, StopColor)
+		}
+		if b.Total > 0 && total > b.Total {
+			breach += fmt.Sprintf("  total got = %s%s%s, want at most %s%s%s\n",
+				RedColor, total, StopColor, RedColor, b.Total, StopColor)
+		}
+		if breach == "" {
+			continue
+		}
+		msg += fmt.Sprintf("%sBudget %q%s breached by %d requests, %s, total %s\n%s",
+			PurpleColor, b.Tag, StopColor, len(tt), l, total, breach)
+
+		slowest := append([]timing(nil), tt...)
+		sort.Slice(slowest, func(i, j int) bool { return slowest[i].d > slowest[j].d })
+		if len(slowest) > 5 {
+			slowest = slowest[:5]
+		}
+		for _, t := range slowest {
+			msg += fmt.Sprintf("  %s %s %s\n", t.method, t.path, t.d)
+		}
+	}
+	if msg != "" {
+		return fmt.Errorf("%s", strings.TrimSuffix(msg, "\n"))
+	}
+	return nil
+}
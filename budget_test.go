@@ -0,0 +1,52 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckBudgets(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(50 * time.Millisecond)
+		}
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+	defer ResetTimings()
+	ResetTimings()
+
+	rn.Test(t, Hit{Path: "/fast", Requests: Requests{"GET": {
+		{Tags: []string{"fast", "all"}, Want: Response{Status: 200}},
+		{Tags: []string{"fast", "all"}, Want: Response{Status: 200}},
+	}}})
+	rn.Test(t, Hit{Path: "/slow", Requests: Requests{"GET": {
+		{Tags: []string{"all"}, Want: Response{Status: 200}},
+	}}})
+
+	if err := CheckBudgets(Budget{Tag: "fast", P95: 40 * time.Millisecond}); err != nil {
+		t.Errorf("fast: unexpected error %v", err)
+	}
+	err := CheckBudgets(
+		Budget{Tag: "fast", Total: time.Second},
+		Budget{Tag: "all", P95: 40 * time.Millisecond, Total: 40 * time.Millisecond},
+	)
+	if err == nil {
+		t.Fatal("all: want error, got nil")
+	}
+	for _, want := range []string{`Budget "all"`, "by 3 requests", "p95 got", "total got", "GET /slow"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), `Budget "fast"`) {
+		t.Errorf("error %q reports a budget that wasn't breached", err)
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Inbound represents an expected request made by the system under test to
+// a Callback or a Stub. Its zero fields match any value.
+type Inbound struct {
+	Method string
+	Path   string
+	Header Header
+	Body   Comparer
+}
+
+// Called represents a request received by a Callback or a Stub.
+type Called struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   []byte
+}
+
+// match compares the call to the expectation and returns the failure message,
+// if any.
+func (in Inbound) match(c Called) string {
+	var fail string
+	if in.Method != "" && in.Method != c.Method {
+		fail += fmt.Sprintf("Method got = %s%s%s, want = %s%s%s\n", RedColor, c.Method, StopColor, RedColor, in.Method, StopColor)
+	}
+	if in.Path != "" && in.Path != c.Path {
+		fail += fmt.Sprintf("Path got = %s%s%s, want = %s%s%s\n", RedColor, c.Path, StopColor, RedColor, in.Path, StopColor)
+	}
+	if in.Header != nil {
+		if err := in.Header.Compare(c.Header); err != nil {
+			fail += err.Error()
+		}
+	}
+	if in.Body != nil {
+		if err := in.Body.Compare(bytes.NewReader(c.Body)); err != nil {
+			fail += err.Error()
+		}
+	}
+	return fail
+}
+
+// String returns a short description of the expectation.
+func (in Inbound) String() string {
+	m, p := in.Method, in.Path
+	if m == "" {
+		m = "*"
+	}
+	if p == "" {
+		p = "*"
+	}
+	return m + " " + p
+}
+
+// Callback is a local HTTP listener that records the requests it receives,
+// e.g. a webhook registered with the system under test, and responds to
+// them with Status.
+type Callback struct {
+	// Status is the status of the responses, it defaults to 200.
+	Status int
+
+	ln    net.Listener
+	srv   *http.Server
+	mu    sync.Mutex
+	calls []Called
+	recv  chan struct{} // closed and replaced on every received call
+}
+
+// NewCallback starts a Callback listening on a random port of the loopback
+// interface. It should be closed when it's no longer needed.
+func NewCallback() (*Callback, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("hit: failed starting callback listener. %v", err)
+	}
+	c := &Callback{ln: ln, recv: make(chan struct{})}
+	c.srv = &http.Server{Handler: http.HandlerFunc(c.serveHTTP)}
+	go c.srv.Serve(ln)
+	return c, nil
+}
+
+// URL returns the base URL of the Callback, e.g. "http://127.0.0.1:34567".
+func (c *Callback) URL() string { return "http://" + c.ln.Addr().String() }
+
+// Close stops the Callback.
+func (c *Callback) Close() error { return c.srv.Close() }
+
+// Calls returns the requests received so far.
+func (c *Callback) Calls() []Called {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Called(nil), c.calls...)
+}
+
+func (c *Callback) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	b, _ := ioutil.ReadAll(r.Body)
+	c.mu.Lock()
+	c.calls = append(c.calls, Called{Method: r.Method, Path: r.URL.RequestURI(), Header: r.Header, Body: b})
+	close(c.recv)
+	c.recv = make(chan struct{})
+	c.mu.Unlock()
+
+	status := c.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+}
+
+// Wait waits until the Callback receives a request that matches the specified
+// expectation, it fails if none arrives within the specified duration.
+// Requests received before Wait was called count too.
+func (c *Callback) Wait(want Inbound, within time.Duration) error {
+	deadline := time.After(within)
+	seen := 0
+	var last string
+	for {
+		c.mu.Lock()
+		calls, recv := c.calls[seen:], c.recv
+		seen = len(c.calls)
+		c.mu.Unlock()
+
+		for _, call := range calls {
+			fail := want.match(call)
+			if fail == "" {
+				return nil
+			}
+			last = fmt.Sprintf(" %s%s %s%s\n%s", YellowColor, call.Method, call.Path, StopColor, fail)
+		}
+		select {
+		case <-recv:
+		case <-deadline:
+			msg := fmt.Sprintf("Callback got %s<no matching call>%s within %s, want %s%s%s\n",
+				RedColor, StopColor, within, RedColor, want, StopColor)
+			if last != "" {
+				msg += "The last call differs:\n" + last
+			}
+			return fmt.Errorf("%s", msg)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCallback(t *testing.T) {
+	cb, err := NewCallback()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cb.Close()
+
+	// the system under test calls the webhook some time later
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		http.Post(cb.URL()+"/hooks/order", "application/json", strings.NewReader(`{"event":"created","id":1}`))
+	}()
+
+	want := Inbound{
+		Method: "POST",
+		Path:   "/hooks/order",
+		Header: Header{"Content-Type": {"application/json"}},
+		Body:   JSONBody{"event": "created", "id": 1},
+	}
+	if err := cb.Wait(want, time.Second); err != nil {
+		t.Error(err)
+	}
+	if n := len(cb.Calls()); n != 1 {
+		t.Errorf("got %d calls, want 1", n)
+	}
+
+	want.Body = JSONBody{"event": "deleted", "id": 1}
+	err = cb.Wait(want, 50*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "The last call differs") {
+		t.Errorf("got err %v, want mismatch failure", err)
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Chaos is an http.RoundTripper that injects network faults into the requests
+// it sends. Set the package's Transport to a *Chaos to run a suite under bad
+// network conditions.
+type Chaos struct {
+	// Transport is used to send the requests, it defaults to
+	// http.DefaultTransport.
+	Transport http.RoundTripper
+
+	// Latency is added before each request is sent, Jitter, if greater
+	// than 0, adds a random duration in [0, Jitter) to it.
+	Latency time.Duration
+	Jitter  time.Duration
+
+	// PacketDelay is added before every read of the request and of the
+	// response body, simulating a slow network.
+	PacketDelay time.Duration
+
+	// FailureRate is the fraction, from 0 to 1, of requests that fail
+	// without being sent. A failed request returns an error, or, if
+	// FailStatus is set, a response with that status.
+	FailureRate float64
+	FailStatus  int
+
+	// Seed, if not 0, seeds the random source, making the injected faults
+	// reproducible.
+	Seed int64
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// ErrChaos is the error returned by a Chaos transport for failed requests.
+var ErrChaos = errors.New("hit: chaos: injected failure")
+
+// RoundTrip implements the http.RoundTripper interface.
+func (c *Chaos) RoundTrip(r *http.Request) (*http.Response, error) {
+	c.mu.Lock()
+	if c.rand == nil {
+		seed := c.Seed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		c.rand = rand.New(rand.NewSource(seed))
+	}
+	fail := c.FailureRate > 0 && c.rand.Float64() < c.FailureRate
+	delay := c.Latency
+	if c.Jitter > 0 {
+		delay += time.Duration(c.rand.Int63n(int64(c.Jitter)))
+	}
+	c.mu.Unlock()
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return nil, r.Context().Err()
+		}
+	}
+	if fail {
+		if r.Body != nil {
+			r.Body.Close()
+		}
+		if c.FailStatus == 0 {
+			return nil, ErrChaos
+		}
+		return &http.Response{
+			Status:     fmt.Sprintf("%d %s", c.FailStatus, http.StatusText(c.FailStatus)),
+			StatusCode: c.FailStatus,
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     http.Header{},
+			Body:       ioutil.NopCloser(strings.NewReader("")),
+			Request:    r,
+		}, nil
+	}
+
+	if c.PacketDelay > 0 && r.Body != nil {
+		r = r.Clone(r.Context())
+		r.Body = &delayedBody{r.Body, c.PacketDelay}
+	}
+	tr := c.Transport
+	if tr == nil {
+		tr = http.DefaultTransport
+	}
+	res, err := tr.RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+	if c.PacketDelay > 0 {
+		res.Body = &delayedBody{res.Body, c.PacketDelay}
+	}
+	return res, nil
+}
+
+// delayedBody is an io.ReadCloser that sleeps before every read.
+type delayedBody struct {
+	rc io.ReadCloser
+	d  time.Duration
+}
+
+func (b *delayedBody) Read(p []byte) (int, error) {
+	time.Sleep(b.d)
+	return b.rc.Read(p)
+}
+
+func (b *delayedBody) Close() error { return b.rc.Close() }
@@ -0,0 +1,52 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestChaos(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	rn.Transport = &Chaos{Latency: 50 * time.Millisecond, Jitter: 10 * time.Millisecond, PacketDelay: 10 * time.Millisecond}
+	start := time.Now()
+	r := Request{Body: FormBody{"a": {"b"}}, Want: Response{Status: 200, Body: RawBody("hello")}}
+	if err := rn.Execute(r, "POST", "/"); err != nil {
+		t.Error(err)
+	}
+	if d := time.Since(start); d < 60*time.Millisecond {
+		t.Errorf("got duration %s, want at least 60ms", d)
+	}
+
+	rn.Transport = &Chaos{FailureRate: 1, FailStatus: 503}
+	if err := rn.Execute(Request{Want: Response{Status: 503}}, "GET", "/"); err != nil {
+		t.Error(err)
+	}
+
+	c := &Chaos{FailureRate: 0.5, Seed: 1}
+	failed := 0
+	for i := 0; i < 100; i++ {
+		req, _ := http.NewRequest("GET", ts.URL, nil)
+		res, err := c.RoundTrip(req)
+		if err == ErrChaos {
+			failed++
+			continue
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+	}
+	if failed < 30 || failed > 70 {
+		t.Errorf("got %d failures of 100, want about 50", failed)
+	}
+}
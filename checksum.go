@@ -0,0 +1,68 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// bodyDigest is a digest of the response body expected by a Response.
+type bodyDigest struct {
+	name string
+	want string
+	h    hash.Hash
+}
+
+// digests returns the digests of the response body expected by the receiver.
+func (r Response) digests() []bodyDigest {
+	var dd []bodyDigest
+	if r.BodySHA256 != "" {
+		dd = append(dd, bodyDigest{"BodySHA256", r.BodySHA256, sha256.New()})
+	}
+	if r.BodyMD5 != "" {
+		dd = append(dd, bodyDigest{"BodyMD5", r.BodyMD5, md5.New()})
+	}
+	if r.BodyCRC32 != "" {
+		dd = append(dd, bodyDigest{"BodyCRC32", r.BodyCRC32, crc32.NewIEEE()})
+	}
+	return dd
+}
+
+// teeDigests makes the body of the specified response feed the digests as
+// it's read and returns a function that reads the rest of the body and
+// compares the digests.
+func teeDigests(res *http.Response, dd []bodyDigest) func() error {
+	ws := make([]io.Writer, len(dd))
+	for i, d := range dd {
+		ws[i] = d.h
+	}
+	tee := io.TeeReader(res.Body, io.MultiWriter(ws...))
+	res.Body = peekedBody{tee, res.Body}
+
+	return func() error {
+		if _, err := io.Copy(ioutil.Discard, tee); err != nil {
+			return fmt.Errorf("hit: error reading http.Response.Body. %v\n", err)
+		}
+		var msg string
+		for _, d := range dd {
+			if got := hex.EncodeToString(d.h.Sum(nil)); !strings.EqualFold(got, d.want) {
+				msg += fmt.Sprintf("%s got = %s%s%s, want = %s%s%s\n",
+					d.name, RedColor, got, StopColor, RedColor, d.want, StopColor)
+			}
+		}
+		if msg != "" {
+			return fmt.Errorf("%s", msg)
+		}
+		return nil
+	}
+}
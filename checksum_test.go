@@ -0,0 +1,59 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestResponseBodyDigests(t *testing.T) {
+	const (
+		body   = "hello world"
+		sha    = "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"
+		md     = "5eb63bbbe01eeed093cb22bb8f5acdc3"
+		crc    = "0d4a1185"
+		zeroes = "0000000000000000000000000000000000000000000000000000000000000000"
+	)
+	tests := []struct {
+		want Response
+		err  []string
+	}{
+		{want: Response{Status: 200, BodySHA256: sha, BodyMD5: md, BodyCRC32: crc}},
+		{want: Response{Status: 200, BodySHA256: strings.ToUpper(sha)}},
+		// the digest covers the whole body even if the Comparer stops early
+		{want: Response{Status: 200, Body: RawBody("hello"), BodySHA256: sha}, err: []string{"Body length"}},
+		{want: Response{Status: 200, BodySHA256: zeroes, BodyCRC32: "ffffffff"}, err: []string{"BodySHA256 got", "BodyCRC32 got"}},
+		{want: Response{Status: 200, SniffContentType: true, BodyMD5: md}},
+	}
+	for i, tt := range tests {
+		res := &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{"Content-Type": {"text/plain"}},
+			Body:       ioutil.NopCloser(bytes.NewReader([]byte(body))),
+		}
+		err := tt.want.Compare(res)
+		if len(tt.err) == 0 {
+			if err != nil {
+				t.Errorf("#%d: unexpected error %v", i, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("#%d: want error, got nil", i)
+			continue
+		}
+		for _, w := range tt.err {
+			if !strings.Contains(err.Error(), w) {
+				t.Errorf("#%d: error %q does not contain %q", i, err, w)
+			}
+		}
+		if strings.Contains(err.Error(), "BodySHA256") && tt.want.BodySHA256 == sha {
+			t.Errorf("#%d: digest of the whole body failed %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,304 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Encoder encodes a value into a request body.
+type Encoder func(v interface{}) ([]byte, error)
+
+// encoders maps media types to their Encoders.
+var encoders = struct {
+	sync.RWMutex
+	m map[string]Encoder
+}{m: map[string]Encoder{
+	appjson:           json.Marshal,
+	"application/xml": xml.Marshal,
+	"text/xml":        xml.Marshal,
+}}
+
+// RegisterEncoder registers the Encoder of the specified media type, e.g.
+// "application/cbor", replacing the one registered before, if any. The
+// parameters of the media type, e.g. charset, are ignored.
+func RegisterEncoder(mediaType string, enc Encoder) {
+	encoders.Lock()
+	defer encoders.Unlock()
+	encoders.m[baseMediaType(mediaType)] = enc
+}
+
+// baseMediaType returns the lower-cased media type without its parameters.
+func baseMediaType(v string) string {
+	if mt, _, err := mime.ParseMediaType(v); err == nil {
+		return mt
+	}
+	return strings.ToLower(strings.TrimSpace(v))
+}
+
+// Encoded returns a Bodyer of the specified content type whose body is v
+// encoded by the Encoder registered for the content type's media type.
+func Encoded(contentType string, v interface{}) Bodyer {
+	return encodedBody{typ: contentType, v: v}
+}
+
+type encodedBody struct {
+	typ string
+	v   interface{}
+}
+
+func (b encodedBody) Type() string { return b.typ }
+
+func (b encodedBody) Body() (io.Reader, error) {
+	encoders.RLock()
+	enc, ok := encoders.m[baseMediaType(b.typ)]
+	encoders.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("hit: no encoder registered for %q", b.typ)
+	}
+	p, err := enc(b.v)
+	if err != nil {
+		return nil, fmt.Errorf("hit: encoding %q body (%+v) failed. %v", b.typ, b.v, err)
+	}
+	return bytes.NewReader(p), nil
+}
+
+func (b encodedBody) String() string { return fmt.Sprintf("%s %+v", b.typ, b.v) }
+
+// Decoder decodes a response body into a value of the form produced by
+// decoding JSON with UseNumber set, i.e. objects are map[string]interface{},
+// arrays are []interface{} and numbers are json.Number, so that it can be
+// compared to the same expected values as a JSON body.
+type Decoder func(r io.Reader) (interface{}, error)
+
+// decoders maps media types to their Decoders.
+var decoders = struct {
+	sync.RWMutex
+	m map[string]Decoder
+}{m: map[string]Decoder{
+	appjson:           decodeJSON,
+	"application/xml": decodeXML,
+	"text/xml":        decodeXML,
+}}
+
+// RegisterDecoder registers the Decoder of the specified media type, e.g.
+// "application/yaml", replacing the one registered before, if any. The
+// parameters of the media type, e.g. charset, are ignored.
+func RegisterDecoder(mediaType string, dec Decoder) {
+	decoders.Lock()
+	defer decoders.Unlock()
+	decoders.m[baseMediaType(mediaType)] = dec
+}
+
+// decoderOf returns the Decoder registered for the media type of the
+// specified content type. A type without a Decoder of its own that has the
+// structured syntax suffix +json or +xml, e.g. application/problem+json, is
+// decoded as JSON or XML.
+func decoderOf(contentType string) (Decoder, bool) {
+	mt := baseMediaType(contentType)
+	decoders.RLock()
+	defer decoders.RUnlock()
+	if dec, ok := decoders.m[mt]; ok {
+		return dec, true
+	}
+	switch {
+	case strings.HasSuffix(mt, "+json"):
+		return decoders.m[appjson], decoders.m[appjson] != nil
+	case strings.HasSuffix(mt, "+xml"):
+		return decoders.m["application/xml"], decoders.m["application/xml"] != nil
+	}
+	return nil, false
+}
+
+func decodeJSON(r io.Reader) (interface{}, error) {
+	var v interface{}
+	d := json.NewDecoder(r)
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return v, nil
+}
+
+// decodeXML decodes an XML document into a generic value. The root element
+// stands for the value itself, its name is dropped. An element with neither
+// attributes nor child elements is decoded into a scalar, any other element
+// into an object whose members are its child elements, its attributes,
+// prefixed with "@", and its text, if any, under "#text". Repeated child
+// elements are collected into an array, so an array of a single element
+// can't be told apart from the element. Scalars that look like JSON numbers
+// or booleans are decoded as such.
+func decodeXML(r io.Reader) (interface{}, error) {
+	d := xml.NewDecoder(r)
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil, errors.New("no root element")
+		} else if err != nil {
+			return nil, err
+		}
+		if se, ok := tok.(xml.StartElement); ok {
+			return xmlElement(d, se)
+		}
+	}
+}
+
+func xmlElement(d *xml.Decoder, se xml.StartElement) (interface{}, error) {
+	m := make(map[string]interface{})
+	for _, a := range se.Attr {
+		m["@"+a.Name.Local] = xmlScalar(a.Value)
+	}
+	var text strings.Builder
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			v, err := xmlElement(d, t)
+			if err != nil {
+				return nil, err
+			}
+			k := t.Name.Local
+			switch prev := m[k].(type) {
+			case nil:
+				m[k] = v
+			case []interface{}:
+				m[k] = append(prev, v)
+			default:
+				m[k] = []interface{}{prev, v}
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			s := strings.TrimSpace(text.String())
+			if len(m) == 0 {
+				return xmlScalar(s), nil
+			}
+			if s != "" {
+				m["#text"] = xmlScalar(s)
+			}
+			return m, nil
+		}
+	}
+}
+
+var jsonNumber = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+func xmlScalar(s string) interface{} {
+	switch {
+	case s == "true":
+		return true
+	case s == "false":
+		return false
+	case jsonNumber.MatchString(s):
+		return json.Number(s)
+	}
+	return s
+}
+
+// typedComparer is implemented by the Comparers that need the Content-Type of
+// the response, their failures are printed with the values redacted by rd.
+type typedComparer interface {
+	compareTyped(contentType string, r io.Reader, rd Redaction) error
+}
+
+// Decoded returns a Comparer that decodes the response body with the Decoder
+// registered for the response's Content-Type and compares the result to want,
+// an expected value of the same form as a JSONBody's, e.g. a map that may
+// contain Matchers. The same expected value so works for a resource served
+// as JSON and as XML. A body that jsonLike is decoded as JSON.
+func Decoded(want interface{}) Comparer { return decoded{want} }
+
+type decoded struct {
+	v interface{}
+}
+
+// Compare implements the Comparer interface, the body is decoded as JSON.
+func (d decoded) Compare(r io.Reader) error {
+	return d.compareTyped(appjson, r, redactionOf(nil))
+}
+
+func (d decoded) compareTyped(contentType string, r io.Reader, rd Redaction) error {
+	if jsonLike(contentType) {
+		contentType = appjson
+	}
+	dec, ok := decoderOf(contentType)
+	if !ok {
+		return fmt.Errorf("Body has %sno decoder%s for Content-Type %s%q%s\n", RedColor, StopColor, RedColor, contentType, StopColor)
+	}
+	want, err := normalize(d.v)
+	if err != nil {
+		return fmt.Errorf("hit: Comparer %#v, error %v", d, err)
+	}
+	got, err := dec(r)
+	if err != nil {
+		return fmt.Errorf("hit: error decoding %q http.Response.Body. %v", contentType, err)
+	}
+	if err := matchJSON("", got, want, DefaultMode); err != nil {
+		return bodyError(got, want, DefaultMode, rd, err)
+	}
+	return nil
+}
+
+// GoString implements the fmt.GoStringer interface.
+func (d decoded) GoString() string { return fmt.Sprintf("hit.Decoded(%#v)", d.v) }
+
+// decoded reports whether the receiver wraps, possibly through other Modals,
+// a Decoded Comparer and if so it returns the Comparer with its expected
+// value wrapped in the Modals, so that the decoded body is compared in their
+// mode like a JSON body is.
+func (m Modal) decoded() (decoded, bool) {
+	switch v := m.v.(type) {
+	case decoded:
+		m.v = v.v
+		return decoded{m}, true
+	case Modal:
+		if d, ok := v.decoded(); ok {
+			m.v = d.v
+			return decoded{m}, true
+		}
+	}
+	return decoded{}, false
+}
+
+// jsonLike reports whether a body of the specified content type is compared
+// as JSON. Besides the JSON types that's a missing Content-Type and
+// text/plain, which is what net/http sniffs for JSON written without one.
+func jsonLike(contentType string) bool {
+	mt := baseMediaType(contentType)
+	return mt == "" || mt == appjson || strings.HasSuffix(mt, "+json") || mt == "text/plain"
+}
+
+// compareStructured compares a body to the expected structural value of a
+// JSONBody or a Modal by the body's content type. A JSON body is compared by
+// compareJSON as it's streamed, a body of another type is decoded by its
+// Decoder, and a body of a type without one fails the comparison.
+func compareStructured(contentType string, r io.Reader, want interface{}, rd Redaction, compareJSON func(io.Reader, Redaction) error) error {
+	if jsonLike(contentType) {
+		return compareJSON(r, rd)
+	}
+	return decoded{want}.compareTyped(contentType, r, rd)
+}
+
+func (b JSONBody) compareTyped(contentType string, r io.Reader, rd Redaction) error {
+	return compareStructured(contentType, r, map[string]interface{}(b), rd, b.compare)
+}
+
+func (m Modal) compareTyped(contentType string, r io.Reader, rd Redaction) error {
+	if d, ok := m.decoded(); ok {
+		return d.compareTyped(contentType, r, rd)
+	}
+	return compareStructured(contentType, r, m, rd, m.compare)
+}
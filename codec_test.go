@@ -0,0 +1,185 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEncoded(t *testing.T) {
+	type item struct {
+		XMLName struct{} `xml:"item" json:"-"`
+		ID      int      `xml:"id" json:"id"`
+	}
+	// a made up format
+	RegisterEncoder("application/x-upper", func(v interface{}) ([]byte, error) {
+		s, ok := v.(string)
+		if !ok {
+			return nil, errors.New("not a string")
+		}
+		return []byte(strings.ToUpper(s)), nil
+	})
+	defer func() {
+		encoders.Lock()
+		delete(encoders.m, "application/x-upper")
+		encoders.Unlock()
+	}()
+
+	tests := []struct {
+		typ  string
+		v    interface{}
+		want string
+		err  string
+	}{
+		{typ: "application/json", v: item{ID: 1}, want: `{"id":1}`},
+		{typ: "application/json; charset=utf-8", v: []int{1}, want: `[1]`},
+		{typ: "application/xml", v: item{ID: 1}, want: `<item><id>1</id></item>`},
+		{typ: "Application/X-Upper", v: "abc", want: "ABC"},
+		{typ: "application/x-upper", v: 1, err: "not a string"},
+		{typ: "application/cbor", v: 1, err: `no encoder registered for "application/cbor"`},
+	}
+	for i, tt := range tests {
+		b := Encoded(tt.typ, tt.v)
+		if b.Type() != tt.typ {
+			t.Errorf("#%d: got type %q, want %q", i, b.Type(), tt.typ)
+		}
+		r, err := b.Body()
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("#%d: got error %v, want it to contain %q", i, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("#%d: unexpected error %v", i, err)
+			continue
+		}
+		got, _ := ioutil.ReadAll(r)
+		if string(got) != tt.want {
+			t.Errorf("#%d: got body %q, want %q", i, got, tt.want)
+		}
+	}
+}
+
+func TestDecoded(t *testing.T) {
+	// a made up format of key=value lines
+	RegisterDecoder("text/x-kv", func(r io.Reader) (interface{}, error) {
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		m := make(map[string]interface{})
+		for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+			kv := strings.SplitN(line, "=", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("invalid line %q", line)
+			}
+			m[kv[0]] = xmlScalar(kv[1])
+		}
+		return m, nil
+	})
+	defer func() {
+		decoders.Lock()
+		delete(decoders.m, "text/x-kv")
+		decoders.Unlock()
+	}()
+
+	want := Decoded(map[string]interface{}{"id": 1, "name": "a", "tags": []string{"x", "y"}})
+	tests := []struct {
+		typ  string
+		body string
+		err  string
+	}{
+		{typ: "application/json", body: `{"id":1,"name":"a","tags":["x","y"]}`},
+		{typ: "", body: `{"id":1,"name":"a","tags":["x","y"]}`},
+		{typ: "application/xml", body: `<item><id>1</id><name>a</name><tags>x</tags><tags>y</tags></item>`},
+		{typ: "application/vnd.item+xml; charset=utf-8", body: `<?xml version="1.0"?><item><id>1</id><name>a</name><tags>x</tags><tags>y</tags></item>`},
+		{typ: "application/xml", body: `<item><id>2</id><name>a</name><tags>x</tags><tags>y</tags></item>`, err: "Body /id"},
+		{typ: "application/xml", body: `<item><id>1</id>`, err: "error decoding"},
+		{typ: "text/x-kv", body: "id=1\nname=a\n", err: "Body /tags"},
+		{typ: "text/x-kv", body: "id", err: "invalid line"},
+		{typ: "application/cbor", body: "", err: "no decoder"},
+	}
+	for i, tt := range tests {
+		res := &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{},
+			Body:       ioutil.NopCloser(strings.NewReader(tt.body)),
+		}
+		if tt.typ != "" {
+			res.Header.Set("Content-Type", tt.typ)
+		}
+		err := Response{Status: 200, Body: want}.Compare(res)
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("#%d: unexpected error %v", i, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("#%d: got error %v, want it to contain %q", i, err, tt.err)
+		}
+	}
+}
+
+func TestDecodeXML(t *testing.T) {
+	got, err := decodeXML(strings.NewReader(`<user id="7" admin="false">Ann<email/><n>-1.5e3</n></user>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"@id": json.Number("7"), "@admin": false, "#text": "Ann",
+		"email": "", "n": json.Number("-1.5e3"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+}
+
+func TestStructuredDispatch(t *testing.T) {
+	tests := []struct {
+		want Comparer
+		typ  string
+		body string
+		err  string
+	}{
+		{JSONBody{"id": 1}, "application/json", `{"id":1}`, ""},
+		{JSONBody{"id": 1}, "application/problem+json", `{"id":1}`, ""},
+		{JSONBody{"id": 1}, "text/plain; charset=utf-8", `{"id":1}`, ""},
+		{JSONBody{"id": 1}, "application/xml", `<item><id>1</id></item>`, ""},
+		{JSONBody{"id": 1}, "application/xml", `<item><id>2</id></item>`, "Body /id: " + RedColor + "got 2, want 1"},
+		{Partial(JSONBody{"id": 1}), "text/xml", `<item><id>1</id><name>a</name></item>`, ""},
+		{Decoded(map[string]interface{}{"id": 1}), "text/xml", `<item><id>1</id><name>a</name></item>`, "Body"},
+		{Partial(Decoded(map[string]interface{}{"id": 1})), "text/xml", `<item><id>1</id><name>a</name></item>`, ""},
+		{Partial(Decoded(map[string]interface{}{"id": 1})), "application/json", `{"id":1,"name":"a"}`, ""},
+		{Partial(Decoded(map[string]interface{}{"id": 2})), "text/xml", `<item><id>1</id><name>a</name></item>`, "Body /id: " + RedColor + "got 1, want 2"},
+		{JSONBody{"id": 1}, "text/html", `<html></html>`, `no decoder`},
+		{Partial(JSONBody{"id": 1}), "text/html", `<html></html>`, `no decoder`},
+	}
+	for i, tt := range tests {
+		res := &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{"Content-Type": {tt.typ}},
+			Body:       ioutil.NopCloser(strings.NewReader(tt.body)),
+		}
+		err := Response{Status: 200, Body: tt.want}.Compare(res)
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("#%d: unexpected error %v", i, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("#%d: got error %v, want it to contain %q", i, err, tt.err)
+		}
+	}
+}
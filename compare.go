@@ -0,0 +1,551 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Mode is a set of options that control how expected JSON values are compared
+// to the actual ones.
+type Mode uint
+
+const (
+	// UnorderedArrays compares arrays as multisets rather than as ordered
+	// sequences.
+	UnorderedArrays Mode = 1 << iota
+	// PartialObjects compares only those fields of an object that are
+	// present in the expected value, any other fields are ignored.
+	PartialObjects
+	// StrictObjects fails the comparison if an object has fields that
+	// aren't present in the expected value and lists those fields in the
+	// failure message. It takes precedence over PartialObjects.
+	StrictObjects
+)
+
+// dryCapture is set while the elements that match are being searched for, the
+// CaptureAs matchers then match without storing the values, which are stored
+// only once the matching elements are settled on.
+const dryCapture Mode = 1 << 31
+
+// DefaultMode is the Mode used to compare JSON values unless overridden for
+// a specific value with one of the mode wrappers, e.g. Unordered.
+var DefaultMode Mode
+
+// Unordered wraps the specified expected value so that the arrays in it, at any
+// depth, are compared as multisets. The result can be used as a Response's Body
+// or in place of a value nested inside a JSONBody.
+func Unordered(v interface{}) Modal {
+	return Modal{name: "Unordered", set: UnorderedArrays, v: v}
+}
+
+// Ordered wraps the specified expected value so that the arrays in it, at any
+// depth, are compared as ordered sequences, overriding an enclosing Unordered
+// or the DefaultMode.
+func Ordered(v interface{}) Modal {
+	return Modal{name: "Ordered", clear: UnorderedArrays, v: v}
+}
+
+// Partial wraps the specified expected value so that the objects in it, at any
+// depth, are compared partially, i.e. fields not present in the expected
+// objects are ignored.
+func Partial(v interface{}) Modal {
+	return Modal{name: "Partial", set: PartialObjects, clear: StrictObjects, v: v}
+}
+
+// Exact wraps the specified expected value so that the objects in it, at any
+// depth, must have exactly the expected fields, overriding an enclosing Partial
+// or the DefaultMode.
+func Exact(v interface{}) Modal {
+	return Modal{name: "Exact", clear: PartialObjects | StrictObjects, v: v}
+}
+
+// Strict wraps the specified expected value so that the objects in it, at any
+// depth, must not have any fields other than the expected ones, any unexpected
+// fields are listed in the failure message. It's useful for contract tests
+// that must catch accidental field leaks.
+func Strict(v interface{}) Modal {
+	return Modal{name: "Strict", set: StrictObjects, clear: PartialObjects, v: v}
+}
+
+// Modal is an expected JSON value, wrapped by one of the mode wrappers, that's
+// compared using an adjusted Mode. Modal implements both the Matcher and the
+// Comparer interface.
+type Modal struct {
+	name       string
+	set, clear Mode
+	v          interface{}
+}
+
+// Match implements the Matcher interface.
+func (m Modal) Match(got interface{}) error {
+	want, err := normalize(m)
+	if err != nil {
+		return err
+	}
+	return matchJSON("", got, want, DefaultMode)
+}
+
+// Compare implements the Comparer interface.
+func (m Modal) Compare(r io.Reader) error {
+	return m.compare(r, redactionOf(nil))
+}
+
+// compare is like Compare but the failure is printed with the values
+// redacted by rd.
+func (m Modal) compare(r io.Reader, rd Redaction) error {
+	if d, ok := m.decoded(); ok {
+		return d.compareTyped(appjson, r, rd)
+	}
+	want, err := normalize(m)
+	if err != nil {
+		return fmt.Errorf("hit: Comparer %#v, error %v", m, err)
+	}
+	return compareJSONStream(r, want, DefaultMode, rd)
+}
+
+// GoString implements the fmt.GoStringer interface.
+func (m Modal) GoString() string {
+	return fmt.Sprintf("hit.%s(%#v)", m.name, m.v)
+}
+
+func (m Modal) apply(mode Mode) Mode {
+	return mode&^m.clear | m.set
+}
+
+// normalize converts the specified expected value into the form produced by
+// decoding JSON with UseNumber set, leaving any Matchers in place so that they
+// can be evaluated during comparison.
+func normalize(v interface{}) (interface{}, error) {
+	switch m := v.(type) {
+	case nil:
+		return nil, nil
+	case Modal:
+		nv, err := normalize(m.v)
+		if err != nil {
+			return nil, err
+		}
+		m.v = nv
+		return m, nil
+	case Matcher:
+		return v, nil
+	case json.Marshaler:
+		return roundtrip(v)
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			break
+		}
+		m := make(map[string]interface{}, rv.Len())
+		for _, k := range rv.MapKeys() {
+			nv, err := normalize(rv.MapIndex(k).Interface())
+			if err != nil {
+				return nil, err
+			}
+			m[k.String()] = nv
+		}
+		return m, nil
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 {
+			break // []byte is encoded as a base64 string
+		}
+		s := make([]interface{}, rv.Len())
+		for i := range s {
+			nv, err := normalize(rv.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			s[i] = nv
+		}
+		return s, nil
+	}
+	return roundtrip(v)
+}
+
+// roundtrip marshals v into JSON and decodes it back.
+func roundtrip(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err := d.Decode(&out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// modeMatcher is implemented by the Matchers that depend on the Mode of the
+// comparison they're a part of.
+type modeMatcher interface {
+	matchMode(got interface{}, mode Mode) error
+}
+
+// matchMode calls the Matcher's matchMode, if it has one, or its Match.
+func matchMode(m Matcher, got interface{}, mode Mode) error {
+	if mm, ok := m.(modeMatcher); ok {
+		return mm.matchMode(got, mode)
+	}
+	return m.Match(got)
+}
+
+// matchJSON compares the decoded got value to the normalized want value, using
+// the specified mode, and returns an error describing the first difference.
+// The path argument is the JSON pointer to the values being compared.
+func matchJSON(path string, got, want interface{}, mode Mode) error {
+	switch w := want.(type) {
+	case Modal:
+		return matchJSON(path, got, w.v, w.apply(mode))
+	case Matcher:
+		if err := matchMode(w, got, mode); err != nil {
+			return fmt.Errorf("%s: %v", pathOrRoot(path), err)
+		}
+		return nil
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: got %T, want object", pathOrRoot(path), got)
+		}
+		for k, wv := range w {
+			gv, ok := g[k]
+			if _, miss := wv.(missing); miss && !ok {
+				continue
+			}
+			if !ok {
+				return fmt.Errorf("%s: missing", pointer(path, k))
+			}
+			if err := matchJSON(pointer(path, k), gv, wv, mode); err != nil {
+				return err
+			}
+		}
+		if mode&StrictObjects != 0 {
+			var extra []string
+			for k := range g {
+				if _, ok := w[k]; !ok {
+					extra = append(extra, pointer(path, k))
+				}
+			}
+			if len(extra) > 0 {
+				sort.Strings(extra)
+				return &extraFieldsError{extra}
+			}
+			return nil
+		}
+		if mode&PartialObjects != 0 {
+			return nil
+		}
+		for k := range g {
+			if _, ok := w[k]; !ok {
+				return fmt.Errorf("%s: unexpected", pointer(path, k))
+			}
+		}
+		return nil
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: got %T, want array", pathOrRoot(path), got)
+		}
+		if len(g) != len(w) {
+			return fmt.Errorf("%s: got %d elements, want %d", pathOrRoot(path), len(g), len(w))
+		}
+		if mode&UnorderedArrays != 0 {
+			return matchUnordered(path, g, w, mode)
+		}
+		for i := range w {
+			if err := matchJSON(pointer(path, strconv.Itoa(i)), g[i], w[i], mode); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if !reflect.DeepEqual(got, want) {
+		return fmt.Errorf("%s: got %#v, want %#v", pathOrRoot(path), got, want)
+	}
+	return nil
+}
+
+// matchUnordered compares two arrays of equal length as multisets. Since the
+// expected elements may be Matchers an element of got may match more than one
+// element of want, so a maximum bipartite matching is computed. Values are
+// captured only from the pairs of elements of the final matching.
+func matchUnordered(path string, got, want []interface{}, mode Mode) error {
+	ok := make([][]bool, len(want))
+	for i := range want {
+		ok[i] = make([]bool, len(got))
+		for j := range got {
+			ok[i][j] = matchJSON("", got[j], want[i], mode|dryCapture) == nil
+		}
+	}
+
+	owner := make([]int, len(got)) // the want element matched by got[j]
+	for j := range owner {
+		owner[j] = -1
+	}
+	var augment func(i int, seen []bool) bool
+	augment = func(i int, seen []bool) bool {
+		for j := range got {
+			if ok[i][j] && !seen[j] {
+				seen[j] = true
+				if owner[j] < 0 || augment(owner[j], seen) {
+					owner[j] = i
+					return true
+				}
+			}
+		}
+		return false
+	}
+	for i := range want {
+		if !augment(i, make([]bool, len(got))) {
+			return fmt.Errorf("%s: no element matches %#v", pathOrRoot(path), want[i])
+		}
+	}
+	if mode&dryCapture == 0 {
+		for j, i := range owner {
+			matchJSON(pointer(path, strconv.Itoa(j)), got[j], want[i], mode)
+		}
+	}
+	return nil
+}
+
+// diffJSON is the exhaustive counterpart of matchJSON, it returns every
+// difference between the got and the want value, each one at the JSON
+// pointer of the differing leaf. In strict mode the unexpected fields are
+// returned separately. Since it describes a failed comparison no values are
+// captured. The got values are redacted by rd.
+func diffJSON(path string, got, want interface{}, mode Mode, rd Redaction) (diffs []BodyDiff, extra []string) {
+	add := func(path, format string, args ...interface{}) {
+		diffs = append(diffs, BodyDiff{pathOrRoot(path), fmt.Sprintf(format, args...)})
+	}
+	// text returns the got value at path as shown in a difference, with
+	// the values at the redacted paths redacted
+	text := func(path string, v interface{}) string {
+		return jsonText(rd.redactJSON(path, v))
+	}
+	var walk func(path string, got, want interface{}, mode Mode)
+	walk = func(path string, got, want interface{}, mode Mode) {
+		switch w := want.(type) {
+		case Modal:
+			walk(path, got, w.v, w.apply(mode))
+			return
+		case Matcher:
+			if err := matchMode(w, got, mode); err != nil {
+				var msg interface{} = err
+				if rd.redactedPath(path) {
+					msg = Redacted
+				}
+				add(path, "got %s, want %s, %v", text(path, got), jsonText(w), msg)
+			}
+			return
+		case map[string]interface{}:
+			g, ok := got.(map[string]interface{})
+			if !ok {
+				add(path, "got %s, want object", text(path, got))
+				return
+			}
+			for _, k := range objectKeys(w) {
+				gv, ok := g[k]
+				if _, miss := w[k].(missing); miss && !ok {
+					continue
+				}
+				if !ok {
+					add(pointer(path, k), "got <missing>, want %s", jsonText(w[k]))
+					continue
+				}
+				walk(pointer(path, k), gv, w[k], mode)
+			}
+			if mode&PartialObjects != 0 && mode&StrictObjects == 0 {
+				return
+			}
+			for _, k := range objectKeys(g) {
+				if _, ok := w[k]; ok {
+					continue
+				}
+				if mode&StrictObjects != 0 {
+					extra = append(extra, pointer(path, k))
+				} else {
+					add(pointer(path, k), "got %s, want <missing>", text(pointer(path, k), g[k]))
+				}
+			}
+			return
+		case []interface{}:
+			g, ok := got.([]interface{})
+			if !ok {
+				add(path, "got %s, want array", text(path, got))
+				return
+			}
+			if mode&UnorderedArrays != 0 {
+				if len(g) != len(w) {
+					add(path, "got %d elements, want %d", len(g), len(w))
+				} else if err := matchUnordered(path, g, w, mode); err != nil {
+					msg := strings.TrimPrefix(err.Error(), pathOrRoot(path)+": ")
+					if rd.redactedWithin(path) {
+						msg = "no matching order of the elements, " + Redacted
+					}
+					diffs = append(diffs, BodyDiff{pathOrRoot(path), msg})
+				}
+				return
+			}
+			for i := range w {
+				if i < len(g) {
+					walk(pointer(path, strconv.Itoa(i)), g[i], w[i], mode)
+				} else {
+					add(pointer(path, strconv.Itoa(i)), "got <missing>, want %s", jsonText(w[i]))
+				}
+			}
+			for i := len(w); i < len(g); i++ {
+				add(pointer(path, strconv.Itoa(i)), "got %s, want <missing>", text(pointer(path, strconv.Itoa(i)), g[i]))
+			}
+			return
+		}
+		if !reflect.DeepEqual(got, want) {
+			add(path, "got %s, want %s", text(path, got), jsonText(want))
+		}
+	}
+	walk(path, got, want, mode|dryCapture)
+	sort.Strings(extra)
+	return diffs, extra
+}
+
+func objectKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// maxJSONText is the length to which the values shown in a body difference
+// are truncated.
+const maxJSONText = 80
+
+// jsonText returns the specified decoded value in the JSON notation, e.g. 10
+// rather than json.Number("10"), truncated to at most maxJSONText bytes
+// without splitting a rune. Matchers are shown in the Go notation.
+func jsonText(v interface{}) string {
+	var s string
+	switch v.(type) {
+	case Matcher, Modal:
+		s = fmt.Sprintf("%#v", v)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			s = fmt.Sprintf("%#v", v)
+		} else {
+			s = string(b)
+		}
+	}
+	if len(s) > maxJSONText {
+		// cut at the start of a rune so as not to split a multi-byte one
+		i := maxJSONText - 3
+		for i > 0 && !utf8.RuneStart(s[i]) {
+			i--
+		}
+		s = s[:i] + "..."
+	}
+	return s
+}
+
+// duplicateKeys scans the specified JSON document and returns the JSON pointers
+// of the object keys that occur more than once in the same object.
+func duplicateKeys(b []byte) ([]string, error) {
+	if len(bytes.TrimSpace(b)) == 0 {
+		return nil, nil
+	}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+
+	var dups []string
+	var walk func(path string) error
+	walk = func(path string) error {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'):
+			seen := make(map[string]bool)
+			for d.More() {
+				tok, err := d.Token()
+				if err != nil {
+					return err
+				}
+				k := tok.(string)
+				if seen[k] {
+					dups = append(dups, pointer(path, k))
+				}
+				seen[k] = true
+				if err := walk(pointer(path, k)); err != nil {
+					return err
+				}
+			}
+			_, err = d.Token()
+		case json.Delim('['):
+			for i := 0; d.More(); i++ {
+				if err := walk(pointer(path, strconv.Itoa(i))); err != nil {
+					return err
+				}
+			}
+			_, err = d.Token()
+		}
+		return err
+	}
+	if err := walk(""); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return dups, nil
+}
+
+// extraFieldsError is returned by matchJSON in strict mode when an object has
+// unexpected fields.
+type extraFieldsError struct {
+	paths []string
+}
+
+func (e *extraFieldsError) Error() string {
+	return "unexpected fields " + strings.Join(e.paths, ", ")
+}
+
+// bodyError returns the failure of a JSON body that didn't match, err is
+// the error returned by matchJSON. The failure lists every difference
+// between the bodies, compared using the specified mode, with the values
+// redacted by rd.
+func bodyError(got, want interface{}, mode Mode, rd Redaction, err error) error {
+	e := &BodyDiffError{Got: got, Want: want, rd: &rd}
+	e.Diffs, e.Extra = diffJSON("", got, want, mode, rd)
+	if x, ok := err.(*extraFieldsError); ok && len(e.Extra) == 0 {
+		e.Extra = x.paths
+	}
+	return e
+}
+
+// pointer appends the specified reference token to the JSON pointer path.
+func pointer(path, tok string) string {
+	tok = strings.Replace(tok, "~", "~0", -1)
+	tok = strings.Replace(tok, "/", "~1", -1)
+	return path + "/" + tok
+}
+
+func pathOrRoot(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path
+}
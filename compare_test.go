@@ -0,0 +1,161 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+var compareTests = []struct {
+	want Comparer
+	body string
+	ok   bool
+}{
+	{JSONBody{"a": []int{1, 2, 3}}, `{"a":[3,1,2]}`, false},
+	{Unordered(JSONBody{"a": []int{1, 2, 3}}), `{"a":[3,1,2]}`, true},
+	{Unordered(JSONBody{"a": []int{1, 2, 2}}), `{"a":[2,1,1]}`, false},
+	{Unordered([]interface{}{1, Any()}), `[1,1]`, true},
+	{Unordered([]interface{}{Any(), 1}), `[1,2]`, true},
+	{Unordered([]interface{}{OneOf(1, 2), 1}), `[2,3]`, false},
+	{JSONBody{"a": Unordered([]string{"x", "y"}), "b": []string{"x", "y"}}, `{"a":["y","x"],"b":["x","y"]}`, true},
+	{JSONBody{"a": Unordered([]string{"x", "y"}), "b": []string{"x", "y"}}, `{"a":["y","x"],"b":["y","x"]}`, false},
+	{Unordered(JSONBody{"a": []interface{}{[]int{1, 2}, Ordered([]int{3, 4})}}), `{"a":[[4,3],[2,1]]}`, false},
+	{Unordered(JSONBody{"a": []interface{}{[]int{1, 2}, Ordered([]int{3, 4})}}), `{"a":[[3,4],[2,1]]}`, true},
+	{JSONBody{"a": 1}, `{"a":1,"b":2}`, false},
+	{Partial(JSONBody{"a": 1}), `{"a":1,"b":2}`, true},
+	{Partial(JSONBody{"a": 1, "c": 3}), `{"a":1,"b":2}`, false},
+	{Partial(JSONBody{"o": JSONBody{"x": 1}}), `{"a":1,"o":{"x":1,"y":2}}`, true},
+	{Partial(JSONBody{"o": Exact(JSONBody{"x": 1})}), `{"a":1,"o":{"x":1,"y":2}}`, false},
+	{Partial(JSONBody{"o": Exact(JSONBody{"x": 1})}), `{"a":1,"o":{"x":1}}`, true},
+	{JSONBody{"a": 1, "o": Partial(JSONBody{"x": 1})}, `{"a":1,"o":{"x":1,"y":2}}`, true},
+	{JSONBody{"o": Partial(JSONBody{"x": 1})}, `{"a":1,"o":{"x":1,"y":2}}`, false},
+	{Partial([]interface{}{JSONBody{"id": 1}}), `[{"id":1,"name":"foo"}]`, true},
+	{JSONBody{"a": nil}, `{"a":null}`, true},
+	{JSONBody{"a": nil}, `{}`, false},
+	{Partial(JSONBody{"a": nil}), `{"b":1}`, false},
+	{JSONBody{"a": Null()}, `{"a":null}`, true},
+	{JSONBody{"a": Null()}, `{"a":0}`, false},
+	{Partial(JSONBody{"a": Null()}), `{}`, false},
+	{JSONBody{"a": Missing()}, `{}`, true},
+	{JSONBody{"a": Missing()}, `{"a":null}`, false},
+	{Partial(JSONBody{"a": Missing()}), `{"b":1}`, true},
+	{Partial(JSONBody{"a": Missing()}), `{"a":1}`, false},
+	{JSONBody{"a": Any()}, `{}`, false},
+	{Strict(JSONBody{"a": 1}), `{"a":1}`, true},
+	{Strict(JSONBody{"a": 1}), `{"a":1,"b":2}`, false},
+	{Partial(JSONBody{"o": Strict(JSONBody{"x": 1})}), `{"a":1,"o":{"x":1}}`, true},
+	{Strict(JSONBody{"o": Partial(JSONBody{"x": 1})}), `{"o":{"x":1,"y":2}}`, true},
+}
+
+func TestCompare(t *testing.T) {
+	for i, tt := range compareTests {
+		err := tt.want.Compare(strings.NewReader(tt.body))
+		if (err == nil) != tt.ok {
+			t.Errorf("#%d: %#v got err %v, want ok %t", i, tt.want, err, tt.ok)
+		}
+		if e, ok := err.(*BodyDiffError); ok && len(e.Diffs) == 0 && len(e.Extra) == 0 {
+			t.Errorf("#%d: %#v got err %v with no differences", i, tt.want, err)
+		}
+	}
+}
+
+var bodyDiffTests = []struct {
+	want  Comparer
+	body  string
+	diffs []string
+}{
+	{
+		JSONBody{"items": []JSONBody{{"price": 10}, {"price": 12}}, "total": 22},
+		`{"items":[{"price":10},{"price":10}],"total":20}`,
+		[]string{"/items/1/price: got 10, want 12", "/total: got 20, want 22"},
+	}, {
+		JSONBody{"a": "x", "b": []int{1}},
+		`{"b":[1,2],"c":true}`,
+		[]string{"/a: got <missing>, want \"x\"", "/b/1: got 2, want <missing>", "/c: got true, want <missing>"},
+	}, {
+		Partial(JSONBody{"o": JSONBody{"id": Any()}}),
+		`{"o":[],"x":1}`,
+		[]string{"/o: got [], want object"},
+	}, {
+		JSONBody{"s": Len(1)},
+		`{"s":"ab"}`,
+		[]string{"/s: got \"ab\", want hit.Len(1), got length 2, want 1"},
+	}, {
+		Unordered([]int{1, 2}),
+		`[1,3]`,
+		[]string{"/: no element matches \"2\""},
+	},
+}
+
+func TestBodyDiffs(t *testing.T) {
+	for i, tt := range bodyDiffTests {
+		err := tt.want.Compare(strings.NewReader(tt.body))
+		e, ok := err.(*BodyDiffError)
+		if !ok {
+			t.Errorf("#%d: got err %v, want *BodyDiffError", i, err)
+			continue
+		}
+		var got []string
+		for _, d := range e.Diffs {
+			got = append(got, d.String())
+		}
+		if !reflect.DeepEqual(got, tt.diffs) {
+			t.Errorf("#%d: got %q, want %q", i, got, tt.diffs)
+		}
+	}
+}
+
+func TestDefaultMode(t *testing.T) {
+	defer func(m Mode) { DefaultMode = m }(DefaultMode)
+	DefaultMode = UnorderedArrays
+	if err := (JSONBody{"a": []int{1, 2}}).Compare(strings.NewReader(`{"a":[2,1]}`)); err != nil {
+		t.Errorf("got err %v, want <nil>", err)
+	}
+	if err := (JSONBody{"a": Ordered([]int{1, 2})}).Compare(strings.NewReader(`{"a":[2,1]}`)); err == nil {
+		t.Error("got err <nil>, want err")
+	}
+}
+
+func TestStrictListsFields(t *testing.T) {
+	err := Strict(JSONBody{"o": JSONBody{"a": 1}}).Compare(strings.NewReader(`{"o":{"a":1,"c":3,"b/x":2}}`))
+	want := "Body has unexpected fields " + RedColor + "/o/b~1x, /o/c" + StopColor + "\n"
+	if err == nil || !strings.HasSuffix(err.Error(), want) {
+		t.Errorf("got err %v, want suffix %q", err, want)
+	}
+}
+
+func TestJSONTextTruncation(t *testing.T) {
+	s := jsonText("a" + strings.Repeat("é", 60))
+	if !utf8.ValidString(s) || len(s) > maxJSONText || !strings.HasSuffix(s, "...") {
+		t.Errorf("got %q (%d bytes), want valid UTF-8 of at most %d bytes ending in ...", s, len(s), maxJSONText)
+	}
+}
+
+func TestDuplicateKeys(t *testing.T) {
+	tests := []struct {
+		body string
+		want []string
+	}{
+		{`{"a":1,"b":2}`, nil},
+		{`{"a":1,"a":2}`, []string{"/a"}},
+		{`{"a":{"x":1,"x":{"y":1,"y":2}},"b":[{"z":1,"z":1}]}`, []string{"/a/x", "/a/x/y", "/b/0/z"}},
+		{`[{"a":1},{"a":1}]`, nil},
+		{``, nil},
+	}
+	for i, tt := range tests {
+		got, err := duplicateKeys([]byte(tt.body))
+		if err != nil {
+			t.Errorf("#%d: got err %v, want <nil>", i, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("#%d: got %v, want %v", i, got, tt.want)
+		}
+	}
+	if _, err := duplicateKeys([]byte(`{"a":`)); err == nil {
+		t.Error("got err <nil>, want err for malformed JSON")
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"net/http"
+)
+
+// Change modifies a Request, it's applied by With to a clone of the Request
+// it's deriving a variation from.
+type Change func(r *Request)
+
+// Clone returns a copy of the Request that shares none of its headers, tags,
+// variants or expected headers, nor its BasicAuth, DigestAuth or Range, so
+// that the copy can be modified independently. The Body, the Want.Body and
+// the other pointer fields are shared.
+func (r Request) Clone() Request {
+	r.Header = r.Header.clone()
+	r.Trailer = r.Trailer.clone()
+	r.Want = r.Want.clone()
+	if r.Tags != nil {
+		r.Tags = append([]string(nil), r.Tags...)
+	}
+	if r.Variants != nil {
+		vv := make([]Variant, len(r.Variants))
+		for i, v := range r.Variants {
+			vv[i] = Variant{Header: v.Header.clone(), Want: v.Want.clone()}
+		}
+		r.Variants = vv
+	}
+	if r.BasicAuth != nil {
+		a := *r.BasicAuth
+		r.BasicAuth = &a
+	}
+	if r.DigestAuth != nil {
+		a := *r.DigestAuth
+		r.DigestAuth = &a
+	}
+	if r.Range != nil {
+		br := *r.Range
+		r.Range = &br
+	}
+	return r
+}
+
+// clone returns a copy of the Response that shares none of its headers.
+func (res Response) clone() Response {
+	res.Header = res.Header.clone()
+	res.OrderedHeader = res.OrderedHeader.clone()
+	if res.HeaderMatch != nil {
+		hm := make(map[string]Matcher, len(res.HeaderMatch))
+		for k, m := range res.HeaderMatch {
+			hm[k] = m
+		}
+		res.HeaderMatch = hm
+	}
+	if res.AllowHeader != nil {
+		res.AllowHeader = append([]string(nil), res.AllowHeader...)
+	}
+	return res
+}
+
+// clone returns a deep copy of the Header, nil if it's nil.
+func (h Header) clone() Header {
+	if h == nil {
+		return nil
+	}
+	c := make(Header, len(h))
+	for k, vv := range h {
+		c[k] = append([]string(nil), vv...)
+	}
+	return c
+}
+
+// With returns a Clone of the Request with the specified Changes applied in
+// order, e.g. the same request without its credentials
+//
+//	anon := base.With(WithoutHeader("Authorization"), WithStatus(401))
+func (r Request) With(changes ...Change) Request {
+	r = r.Clone()
+	for _, c := range changes {
+		c(&r)
+	}
+	return r
+}
+
+// WithHeader sets the request header's key to the specified values.
+func WithHeader(key string, values ...string) Change {
+	return func(r *Request) {
+		if r.Header == nil {
+			r.Header = Header{}
+		}
+		r.Header[http.CanonicalHeaderKey(key)] = values
+	}
+}
+
+// WithoutHeader removes the specified key from the request header.
+func WithoutHeader(key string) Change {
+	return func(r *Request) {
+		delete(r.Header, http.CanonicalHeaderKey(key))
+	}
+}
+
+// WithBody sets the request body.
+func WithBody(b Bodyer) Change {
+	return func(r *Request) { r.Body = b }
+}
+
+// WithStatus sets the expected response status.
+func WithStatus(status int) Change {
+	return func(r *Request) { r.Want.Status = status }
+}
+
+// WithWant sets the expected response.
+func WithWant(res Response) Change {
+	return func(r *Request) { r.Want = res }
+}
+
+// WithWantBody sets the expected response body.
+func WithWantBody(c Comparer) Change {
+	return func(r *Request) { r.Want.Body = c }
+}
+
+// WithName sets the request's Name.
+func WithName(name string) Change {
+	return func(r *Request) { r.Name = name }
+}
+
+// Merge returns new Requests holding, per method, the Requests of rs followed
+// by those of each of the others in order, e.g. a table of common cases
+// merged with the cases of a single endpoint. The receiver is not modified.
+func (rs Requests) Merge(others ...Requests) Requests {
+	out := make(Requests, len(rs))
+	for _, m := range append([]Requests{rs}, others...) {
+		for method, rr := range m {
+			out[method] = append(out[method], rr...)
+		}
+	}
+	return out
+}
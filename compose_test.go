@@ -0,0 +1,91 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRequestWith(t *testing.T) {
+	base := Request{
+		Header:    Header{"Authorization": {"Bearer t0ken"}, "Accept": {"application/json"}},
+		Body:      JSONBody{"name": "foo"},
+		BasicAuth: &BasicAuth{User: "u", Pass: "p"},
+		Tags:      []string{"users"},
+		Want:      Response{Status: 201, Header: Header{"Location": {"/users/1"}}},
+	}
+
+	tests := []struct {
+		changes []Change
+		want    Request
+	}{{
+		changes: nil,
+		want:    base,
+	}, {
+		changes: []Change{WithoutHeader("authorization"), WithStatus(401)},
+		want: Request{
+			Header:    Header{"Accept": {"application/json"}},
+			Body:      JSONBody{"name": "foo"},
+			BasicAuth: &BasicAuth{User: "u", Pass: "p"},
+			Tags:      []string{"users"},
+			Want:      Response{Status: 401, Header: Header{"Location": {"/users/1"}}},
+		},
+	}, {
+		changes: []Change{
+			WithHeader("accept", "text/csv"),
+			WithBody(JSONBody{}),
+			WithWant(Response{Status: 422}),
+			WithName("empty body"),
+		},
+		want: Request{
+			Header:    Header{"Authorization": {"Bearer t0ken"}, "Accept": {"text/csv"}},
+			Body:      JSONBody{},
+			BasicAuth: &BasicAuth{User: "u", Pass: "p"},
+			Tags:      []string{"users"},
+			Want:      Response{Status: 422},
+			Name:      "empty body",
+		},
+	}}
+
+	for i, tt := range tests {
+		got := base.With(tt.changes...)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("#%d: got %+v, want %+v", i, got, tt.want)
+		}
+	}
+
+	// modifying a clone leaves the original intact
+	cp := base.Clone()
+	cp.Header["Accept"][0] = "text/plain"
+	delete(cp.Want.Header, "Location")
+	cp.BasicAuth.User = "x"
+	cp.Tags[0] = "x"
+	if base.Header["Accept"][0] != "application/json" || base.Want.Header["Location"] == nil ||
+		base.BasicAuth.User != "u" || base.Tags[0] != "users" {
+		t.Errorf("got base modified through its clone: %+v", base)
+	}
+	if got := (Request{}).Clone(); !reflect.DeepEqual(got, Request{}) {
+		t.Errorf("got %+v, want a zero Request", got)
+	}
+}
+
+func TestRequestsMerge(t *testing.T) {
+	a := Requests{"GET": {{Name: "a1"}}, "POST": {{Name: "a2"}}}
+	b := Requests{"GET": {{Name: "b1"}, {Name: "b2"}}}
+	c := Requests{"DELETE": {{Name: "c1"}}}
+
+	got := a.Merge(b, c)
+	want := Requests{
+		"GET":    {{Name: "a1"}, {Name: "b1"}, {Name: "b2"}},
+		"POST":   {{Name: "a2"}},
+		"DELETE": {{Name: "c1"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if len(a["GET"]) != 1 || len(a) != 2 {
+		t.Errorf("got receiver modified: %+v", a)
+	}
+}
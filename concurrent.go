@@ -0,0 +1,122 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// Received represents a response received by a concurrently executed request.
+type Received struct {
+	Status int
+	Header http.Header
+	Body   []byte
+
+	// Reused reports whether the response was received on a connection
+	// that was used by an earlier request.
+	Reused bool
+}
+
+// Invariant is a function that checks a set of responses to concurrently
+// executed copies of the same request, e.g. that only one of them succeeded.
+type Invariant func(rr []Received) error
+
+// StatusCount returns an Invariant that checks that the number of responses
+// with each status is equal to the specified count, e.g. exactly one 201 and
+// four 409s.
+func StatusCount(want map[int]int) Invariant {
+	return func(rr []Received) error {
+		got := make(map[int]int)
+		for _, r := range rr {
+			got[r.Status]++
+		}
+		var codes []int
+		for c := range want {
+			codes = append(codes, c)
+		}
+		for c := range got {
+			if _, ok := want[c]; !ok {
+				codes = append(codes, c)
+			}
+		}
+		sort.Ints(codes)
+
+		var msg string
+		for _, c := range codes {
+			if got[c] != want[c] {
+				msg += fmt.Sprintf("StatusCount[%d] got = %s%d%s, want %s%d%s\n",
+					c, RedColor, got[c], StopColor, RedColor, want[c], StopColor)
+			}
+		}
+		if msg != "" {
+			return fmt.Errorf("%s", msg)
+		}
+		return nil
+	}
+}
+
+// concurrent sends r.Concurrent copies of the specified request at once and
+// checks the responses. It returns the failure message, if any. A templated
+// request is built anew for every copy.
+func (r Request) concurrent(method, path string, req *http.Request) string {
+	var (
+		wg    sync.WaitGroup
+		start = make(chan struct{})
+		rr    = make([]Received, r.Concurrent)
+		fails = make([]string, r.Concurrent)
+	)
+	for i := 0; i < r.Concurrent; i++ {
+		req := req
+		if r.Template {
+			cr := r
+			cr.iter = i
+			var err error
+			if req, err = cr.newRequest(method, path); err != nil {
+				fails[i] = err.Error() + "\n"
+				continue
+			}
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			res, err := r.do(req)
+			if err != nil {
+				fails[i] = err.Error() + "\n"
+				return
+			}
+			b, err := ioutil.ReadAll(res.Body)
+			res.Body.Close()
+			if err != nil {
+				fails[i] = fmt.Sprintf("hit: error reading http.Response.Body. %v\n", err)
+				return
+			}
+			rr[i] = Received{Status: res.StatusCode, Header: res.Header, Body: b, Reused: connOf(res).reused}
+			if r.Invariant == nil {
+				res.Body = ioutil.NopCloser(bytes.NewReader(b))
+				fails[i] = r.check(res).Error()
+			}
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	var fail string
+	for i, f := range fails {
+		if f != "" {
+			fail += fmt.Sprintf("Response #%d of %d:\n%s", i+1, r.Concurrent, f)
+		}
+	}
+	if fail == "" && r.Invariant != nil {
+		if err := r.Invariant(rr); err != nil {
+			fail = err.Error()
+		}
+	}
+	return fail
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRequestConcurrent(t *testing.T) {
+	var mu sync.Mutex
+	created := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if created {
+			w.WriteHeader(409)
+			return
+		}
+		created = true
+		w.WriteHeader(201)
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	tests := []struct {
+		r    Request
+		want string
+	}{
+		{Request{Concurrent: 5, Body: JSONBody{"email": "a@b.c"}, Invariant: StatusCount(map[int]int{201: 1, 409: 4})}, ""},
+		{Request{Concurrent: 3, Invariant: StatusCount(map[int]int{201: 3})}, "StatusCount[201] got = " + RedColor + "1"},
+		{Request{Concurrent: 2, Want: Response{Status: 201}}, "want " + RedColor + "201"},
+	}
+	for i, tt := range tests {
+		created = false
+		err := rn.Execute(tt.r, "POST", "/users")
+		if tt.want == "" && err != nil {
+			t.Errorf("#%d: got err %v, want <nil>", i, err)
+		} else if tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+			t.Errorf("#%d: got err %v, want err containing %q", i, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Config holds the configuration of a Runner. Start from DefaultConfig, which
+// holds the current values of the package's variables, and override what's
+// needed; the zero Config is not valid since it has no target.
+type Config struct {
+	// Addr is the TCP network address used to construct requests.
+	Addr string
+	// BaseURL, if set, is used in place of "http://"+Addr and of the
+	// BaseURL of the Profile, e.g. "https://staging.example.com".
+	BaseURL string
+
+	// Profile, if set, is the target environment.
+	Profile *Profile
+
+	// TLS, if set, configures the TLS client of the Runner's transport,
+	// the Profile's TLS takes precedence.
+	TLS *tls.Config
+	// Transport, if set, is used to send the requests, otherwise they're
+	// sent by a transport of the Runner's own.
+	Transport http.RoundTripper
+
+	// Timeout, if greater than 0, limits the time of a single exchange,
+	// including the reading of the response body.
+	Timeout time.Duration
+
+	// See the package variables of the same names.
+	RateLimitRetries int
+	RateLimitMaxWait time.Duration
+	MaxBodySize      int64
+	BodyReadTimeout  time.Duration
+	Reruns           int
+	Shuffle          int64
+
+	// Quarantine lists the known-flaky Requests whose failures don't
+	// fail the tests.
+	Quarantine []Quarantined
+
+	// Results, if set, records the status and the duration of every
+	// request.
+	Results *ResultStore
+	// Events, if set, receives every failure of a Request as a JSON
+	// encoded Event, one per line.
+	Events io.Writer
+	// Redactions holds the rules by which the sensitive values are
+	// redacted from the failure messages, the Events and the Docs.
+	Redactions Redaction
+	// Secrets is the provider of the values returned by the Runner's
+	// Secret and by the template function secret.
+	Secrets SecretProvider
+
+	// RequestID, if set, injects a unique id into every request and
+	// checks that the response echoes it.
+	RequestID *RequestID
+
+	// Mode is used in place of DefaultMode to compare the JSONBody and
+	// Modal bodies of the Responses.
+	Mode Mode
+
+	// NoColor, if set, strips the ANSI colors from the failure messages,
+	// e.g. for CI logs.
+	NoColor bool
+}
+
+// DefaultConfig returns a Config with the current values of the package's
+// variables and with the active profile.
+func DefaultConfig() Config {
+	return Config{
+		Addr:             Addr,
+		Profile:          profile,
+		Transport:        Transport,
+		RateLimitRetries: RateLimitRetries,
+		RateLimitMaxWait: RateLimitMaxWait,
+		MaxBodySize:      MaxBodySize,
+		BodyReadTimeout:  BodyReadTimeout,
+		Reruns:           Reruns,
+		Shuffle:          Shuffle,
+		RequestID:        RequestIDs,
+		Quarantine:       Quarantine,
+		Results:          Results,
+		Events:           Events,
+		Redactions:       Redactions,
+		Secrets:          Secrets,
+		Mode:             DefaultMode,
+	}
+}
+
+// Clone returns a copy of the Config that shares none of its Profile and TLS
+// configuration, so that the copy can be modified independently.
+func (c Config) Clone() Config {
+	if c.Profile != nil {
+		p := *c.Profile
+		if p.TLS != nil {
+			p.TLS = p.TLS.Clone()
+		}
+		c.Profile = &p
+	}
+	if c.TLS != nil {
+		c.TLS = c.TLS.Clone()
+	}
+	return c
+}
+
+// Validate checks the Config and returns an error describing all of its
+// problems, if any.
+func (c Config) Validate() error {
+	var bad []string
+	if c.BaseURL != "" {
+		if u, err := url.Parse(c.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			bad = append(bad, fmt.Sprintf("BaseURL %q is not an absolute http or https URL", c.BaseURL))
+		}
+	} else if c.Profile == nil || c.Profile.BaseURL == "" {
+		if c.Addr == "" {
+			bad = append(bad, "neither Addr nor BaseURL is set")
+		} else if _, _, err := net.SplitHostPort(normAddr(c.Addr)); err != nil {
+			bad = append(bad, fmt.Sprintf("Addr %q is not a host:port address", c.Addr))
+		}
+	}
+	if c.TLS != nil && c.Transport != nil {
+		bad = append(bad, "TLS is set along with Transport, which ignores it")
+	}
+	for _, d := range []struct {
+		name string
+		v    time.Duration
+	}{
+		{"Timeout", c.Timeout},
+		{"RateLimitMaxWait", c.RateLimitMaxWait},
+		{"BodyReadTimeout", c.BodyReadTimeout},
+	} {
+		if d.v < 0 {
+			bad = append(bad, fmt.Sprintf("%s %s is negative", d.name, d.v))
+		}
+	}
+	if c.RateLimitRetries < 0 {
+		bad = append(bad, fmt.Sprintf("RateLimitRetries %d is negative", c.RateLimitRetries))
+	} else if c.RateLimitRetries > 0 && c.RateLimitMaxWait == 0 {
+		bad = append(bad, "RateLimitRetries is set without a RateLimitMaxWait")
+	}
+	if c.Reruns < 0 {
+		bad = append(bad, fmt.Sprintf("Reruns %d is negative", c.Reruns))
+	}
+	if c.MaxBodySize < 0 {
+		bad = append(bad, fmt.Sprintf("MaxBodySize %d is negative", c.MaxBodySize))
+	}
+	if c.Mode&^(UnorderedArrays|PartialObjects|StrictObjects) != 0 {
+		bad = append(bad, fmt.Sprintf("Mode %#x has unknown bits", uint(c.Mode)))
+	}
+	if len(bad) > 0 {
+		return fmt.Errorf("hit: invalid Config, %s", strings.Join(bad, "; "))
+	}
+	return nil
+}
+
+// NewRunner validates the Config and returns a Runner configured by it.
+func (c Config) NewRunner() (*Runner, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return &Runner{Config: c}, nil
+}
+
+// ansiColor matches the ANSI color sequences of the failure messages.
+var ansiColor = regexp.MustCompile("\033\\[[0-9;]*m")
+
+// plain returns err with the ANSI colors stripped from its message if the
+// Config's NoColor is set.
+func (c Config) plain(err error) error {
+	if err == nil || !c.NoColor {
+		return err
+	}
+	return &messageError{ansiColor.ReplaceAllString(err.Error(), ""), err}
+}
+
+// withMode wraps the specified Response body so that it's compared using the
+// Config's Mode, it returns the body as is if the Mode is DefaultMode or if
+// the body is nil or neither a JSON value nor Decoded.
+func (c Config) withMode(b Comparer) Comparer {
+	if c.Mode == DefaultMode || nilBody(b) {
+		return b
+	}
+	switch b.(type) {
+	case JSONBody, Modal, decoded:
+		return Modal{name: "WithMode", clear: ^Mode(0), set: c.Mode, v: b}
+	}
+	return b
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfigValidate(t *testing.T) {
+	valid := Config{Addr: "localhost:3456"}
+	tests := []struct {
+		c    Config
+		want []string
+	}{
+		{c: valid},
+		{c: Config{BaseURL: "https://example.com"}},
+		{c: Config{Profile: &Profile{BaseURL: "https://example.com"}}},
+		{c: Config{Addr: "::1"}, want: []string{`Addr "::1"`}},
+		{c: Config{}, want: []string{"neither Addr nor BaseURL"}},
+		{c: Config{BaseURL: "example.com"}, want: []string{`BaseURL "example.com"`}},
+		{c: Config{BaseURL: "ftp://example.com"}, want: []string{`BaseURL "ftp://example.com"`}},
+		{
+			c: Config{
+				Addr:            "localhost:3456",
+				Timeout:         -time.Second,
+				BodyReadTimeout: -time.Second,
+				MaxBodySize:     -1,
+			},
+			want: []string{"Timeout -1s is negative", "BodyReadTimeout -1s is negative", "MaxBodySize -1 is negative"},
+		},
+		{
+			c:    Config{Addr: "localhost:3456", RateLimitRetries: 2},
+			want: []string{"without a RateLimitMaxWait"},
+		},
+		{
+			c:    Config{Addr: "localhost:3456", TLS: &tls.Config{}, Transport: &Chaos{}},
+			want: []string{"TLS is set along with Transport"},
+		},
+		{
+			c:    Config{Addr: "localhost:3456", Mode: 1 << 10},
+			want: []string{"unknown bits"},
+		},
+	}
+	for i, tt := range tests {
+		err := tt.c.Validate()
+		if len(tt.want) == 0 {
+			if err != nil {
+				t.Errorf("#%d: unexpected error %v", i, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("#%d: want error, got nil", i)
+			continue
+		}
+		for _, w := range tt.want {
+			if !strings.Contains(err.Error(), w) {
+				t.Errorf("#%d: error %q does not contain %q", i, err, w)
+			}
+		}
+	}
+
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Errorf("DefaultConfig: unexpected error %v", err)
+	}
+}
+
+func TestConfigClone(t *testing.T) {
+	c := Config{Addr: "localhost:3456", Profile: &Profile{Name: "a"}, TLS: &tls.Config{ServerName: "a"}}
+	cc := c.Clone()
+	cc.Profile.Name, cc.TLS.ServerName = "b", "b"
+	if c.Profile.Name != "a" || c.TLS.ServerName != "a" {
+		t.Errorf("modifying the clone modified the original %+v", c)
+	}
+}
+
+func TestHitConfig(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"a":1,"b":2}`))
+	}))
+	defer ts.Close()
+
+	c := DefaultConfig()
+	c.Addr = ts.URL[len("http://"):]
+	c.Mode = PartialObjects
+	h := Hit{Path: "/", Config: &c, Requests: Requests{"GET": {
+		{Want: Response{Status: 200, Body: JSONBody{"a": 1}}},
+	}}}
+	h.Test(t)
+
+	// the Config's Mode applies to decoded bodies as well
+	xs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write([]byte(`<item><a>1</a><b>2</b></item>`))
+	}))
+	defer xs.Close()
+	xc := c.Clone()
+	xc.Addr = xs.URL[len("http://"):]
+	Hit{Path: "/", Config: &xc, Requests: Requests{"GET": {
+		{Want: Response{Status: 200, Body: Decoded(map[string]interface{}{"a": 1})}},
+	}}}.Test(t)
+
+	// the Runner's Mode and NoColor apply to its failures
+	nc := c.Clone()
+	nc.Mode, nc.NoColor = 0, true
+	rn, err := nc.NewRunner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rn.Execute(Request{Want: Response{Status: 200, Body: JSONBody{"a": 1}}}, "GET", "/")
+	if err == nil {
+		t.Fatal("want error in exact mode, got nil")
+	}
+	if strings.Contains(err.Error(), "\033[") {
+		t.Errorf("error %q contains colors", err)
+	}
+
+	if _, err := (Config{}).NewRunner(); err == nil {
+		t.Error("NewRunner: want error for an invalid Config, got nil")
+	}
+}
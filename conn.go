@@ -0,0 +1,61 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptrace"
+)
+
+// connInfo describes the connection on which a response was received.
+type connInfo struct {
+	reused bool
+	local  string // the local address of the connection
+}
+
+type connInfoKey struct{}
+
+// withConnTrace returns a copy of the specified request whose context records
+// the connection the request is sent on in the returned connInfo.
+func withConnTrace(req *http.Request) *http.Request {
+	ci := new(connInfo)
+	ctx := context.WithValue(req.Context(), connInfoKey{}, ci)
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			ci.reused = info.Reused
+			if info.Conn != nil {
+				ci.local = info.Conn.LocalAddr().String()
+			}
+		},
+	})
+	return req.WithContext(ctx)
+}
+
+// connOf returns the connInfo of the specified response, or a zero connInfo
+// if the response's request wasn't traced.
+func connOf(res *http.Response) connInfo {
+	if res.Request != nil {
+		if ci, ok := res.Request.Context().Value(connInfoKey{}).(*connInfo); ok {
+			return *ci
+		}
+	}
+	return connInfo{}
+}
+
+// drainBody is an io.ReadCloser that reads the rest of the underlying body
+// before closing it, so that its connection can be reused.
+type drainBody struct {
+	io.ReadCloser
+}
+
+// maxDrain caps the number of bytes read by drainBody's Close.
+const maxDrain = 1 << 20
+
+func (b drainBody) Close() error {
+	io.CopyN(ioutil.Discard, b.ReadCloser, maxDrain)
+	return b.ReadCloser.Close()
+}
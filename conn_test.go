@@ -0,0 +1,68 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestKeepAlive(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/close" {
+			w.Header().Set("Connection", "close")
+		}
+		w.Write([]byte(strings.Repeat("x", 10000)))
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	r := Request{RepeatIdempotent: 3, KeepAlive: true, Want: Response{Status: 200}}
+	if err := rn.Execute(r, "GET", "/"); err != nil {
+		t.Error(err)
+	}
+
+	r = Request{RepeatIdempotent: 2, Want: Response{Status: 200, Close: true}}
+	if err := rn.Execute(r, "GET", "/close"); err != nil {
+		t.Error(err)
+	}
+	if err := rn.Execute(r, "GET", "/"); err == nil || !strings.Contains(err.Error(), `Header["Connection"]`) {
+		t.Errorf("got err %v, want Connection header failure", err)
+	}
+
+	r = Request{RepeatIdempotent: 2, KeepAlive: true, Want: Response{Status: 200}}
+	if err := rn.Execute(r, "GET", "/close"); err == nil || !strings.Contains(err.Error(), "a new connection") {
+		t.Errorf("got err %v, want new connection failure", err)
+	}
+}
+
+func TestReceivedReused(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	// warm up a connection so that one of the copies can reuse it
+	if err := rn.Execute(Request{Want: Response{Status: 200}}, "GET", "/"); err != nil {
+		t.Fatal(err)
+	}
+	reused := 0
+	r := Request{Concurrent: 2, Invariant: func(rr []Received) error {
+		for _, r := range rr {
+			if r.Reused {
+				reused++
+			}
+		}
+		return nil
+	}}
+	if err := rn.Execute(r, "GET", "/"); err != nil {
+		t.Error(err)
+	}
+	if reused != 1 {
+		t.Errorf("got %d reused connections, want 1", reused)
+	}
+}
@@ -0,0 +1,233 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Coordinator is an http.Handler that lets a number of workers, e.g. on
+// separate machines, run the same Load in concert. Every worker joins, and
+// once all of them have joined they're told to start at the same moment;
+// every worker then posts its report and the Coordinator merges them.
+//
+// The Coordinator serves the following endpoints, which RunWorker uses:
+//
+//	POST /join      blocks until all the workers have joined and answers
+//	                with the delay after which the worker is to start
+//	POST /report    accepts the report of a worker
+type Coordinator struct {
+	// Delay is the time from the last join to the start, it gives the
+	// workers the time to receive the start signal, it defaults to 1s.
+	Delay time.Duration
+
+	workers int
+	mu      sync.Mutex
+	joined  int
+	ready   chan struct{}
+	reports []*LoadReport
+	done    chan struct{}
+}
+
+// NewCoordinator returns a Coordinator for the specified number of workers.
+func NewCoordinator(workers int) *Coordinator {
+	return &Coordinator{workers: workers, ready: make(chan struct{}), done: make(chan struct{})}
+}
+
+// joinResponse is the start signal sent to the workers. The delay is relative
+// so that the clocks of the machines don't need to agree.
+type joinResponse struct {
+	Worker  int   `json:"worker"`
+	StartIn int64 `json:"start_in_ns"`
+}
+
+func (c *Coordinator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	switch strings.TrimSuffix(r.URL.Path, "/") {
+	case "/join":
+		c.mu.Lock()
+		if c.joined == c.workers {
+			c.mu.Unlock()
+			http.Error(w, "all workers have joined", http.StatusConflict)
+			return
+		}
+		c.joined++
+		worker := c.joined
+		if c.joined == c.workers {
+			close(c.ready)
+		}
+		c.mu.Unlock()
+
+		select {
+		case <-c.ready:
+		case <-r.Context().Done():
+			return
+		}
+		delay := c.Delay
+		if delay <= 0 {
+			delay = time.Second
+		}
+		w.Header().Set("Content-Type", appjson)
+		json.NewEncoder(w).Encode(joinResponse{Worker: worker, StartIn: int64(delay)})
+	case "/report":
+		var wr wireReport
+		if err := json.NewDecoder(r.Body).Decode(&wr); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if len(c.reports) == c.workers {
+			http.Error(w, "all workers have reported", http.StatusConflict)
+			return
+		}
+		c.reports = append(c.reports, wr.report())
+		if len(c.reports) == c.workers {
+			close(c.done)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// Wait waits at most the specified time for all the workers to report and
+// returns their merged report.
+func (c *Coordinator) Wait(timeout time.Duration) (*LoadReport, error) {
+	select {
+	case <-c.done:
+	case <-time.After(timeout):
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return nil, fmt.Errorf("hit: %d of %d workers reported within %s", len(c.reports), c.workers, timeout)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return MergeLoadReports(c.reports...)
+}
+
+// MergeLoadReports merges the reports of workers that ran the same Load, the
+// phases of the merged report are those of the first report.
+func MergeLoadReports(rr ...*LoadReport) (*LoadReport, error) {
+	if len(rr) == 0 {
+		return &LoadReport{}, nil
+	}
+	m := &LoadReport{Phases: make([]PhaseResult, len(rr[0].Phases))}
+	for i, rep := range rr {
+		if len(rep.Phases) != len(m.Phases) {
+			return nil, fmt.Errorf("hit: report #%d has %d phases, want %d", i+1, len(rep.Phases), len(m.Phases))
+		}
+		for j, res := range rep.Phases {
+			p := &m.Phases[j]
+			if i == 0 {
+				p.Phase = res.Phase
+			}
+			p.Sent += res.Sent
+			p.Errors += res.Errors
+			p.Durations = append(p.Durations, res.Durations...)
+			for _, err := range res.FirstErrors {
+				if len(p.FirstErrors) < 5 {
+					p.FirstErrors = append(p.FirstErrors, err)
+				}
+			}
+		}
+	}
+	for i := range m.Phases {
+		m.Phases[i].Latency = latencyOf(m.Phases[i].Durations)
+	}
+	return m, nil
+}
+
+// RunWorker joins the Coordinator at the specified URL, waits for the start
+// signal, runs the Load and posts its report to the Coordinator. It returns
+// the worker's own report.
+func (l Load) RunWorker(coordinator string) (*LoadReport, error) {
+	return globalRunner().RunWorker(l, coordinator)
+}
+
+// RunWorker is like the Load's RunWorker but it runs the Load against the
+// Runner's target.
+func (rn *Runner) RunWorker(l Load, coordinator string) (*LoadReport, error) {
+	coordinator = strings.TrimSuffix(coordinator, "/")
+	// the join blocks until all the workers have joined, so it must not
+	// be subject to the client's timeout
+	res, err := http.Post(coordinator+"/join", appjson, nil)
+	if err != nil {
+		return nil, fmt.Errorf("hit: failed joining coordinator %s. %v", coordinator, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		b, _ := ioutil.ReadAll(res.Body)
+		return nil, fmt.Errorf("hit: coordinator %s refused join with %d %s", coordinator, res.StatusCode, bytes.TrimSpace(b))
+	}
+	var jr joinResponse
+	if err := json.NewDecoder(res.Body).Decode(&jr); err != nil {
+		return nil, fmt.Errorf("hit: invalid start signal from coordinator %s. %v", coordinator, err)
+	}
+	time.Sleep(time.Duration(jr.StartIn))
+
+	rep := rn.Load(l)
+	b, err := json.Marshal(newWireReport(rep))
+	if err != nil {
+		return rep, err
+	}
+	res, err = http.Post(coordinator+"/report", appjson, bytes.NewReader(b))
+	if err != nil {
+		return rep, fmt.Errorf("hit: failed posting report to coordinator %s. %v", coordinator, err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusNoContent {
+		return rep, fmt.Errorf("hit: coordinator %s refused report with %d", coordinator, res.StatusCode)
+	}
+	return rep, nil
+}
+
+// wireReport is the JSON form of a LoadReport sent by the workers.
+type wireReport struct {
+	Phases []wirePhase `json:"phases"`
+}
+
+type wirePhase struct {
+	Phase       Phase           `json:"phase"`
+	Sent        int             `json:"sent"`
+	Errors      int             `json:"errors"`
+	Durations   []time.Duration `json:"durations"`
+	FirstErrors []string        `json:"first_errors,omitempty"`
+}
+
+func newWireReport(rep *LoadReport) wireReport {
+	var wr wireReport
+	for _, res := range rep.Phases {
+		wp := wirePhase{Phase: res.Phase, Sent: res.Sent, Errors: res.Errors, Durations: res.Durations}
+		for _, err := range res.FirstErrors {
+			wp.FirstErrors = append(wp.FirstErrors, err.Error())
+		}
+		wr.Phases = append(wr.Phases, wp)
+	}
+	return wr
+}
+
+func (wr wireReport) report() *LoadReport {
+	rep := &LoadReport{}
+	for _, wp := range wr.Phases {
+		res := PhaseResult{Phase: wp.Phase, Sent: wp.Sent, Errors: wp.Errors, Durations: wp.Durations}
+		for _, s := range wp.FirstErrors {
+			res.FirstErrors = append(res.FirstErrors, errors.New(s))
+		}
+		res.Latency = latencyOf(res.Durations)
+		rep.Phases = append(rep.Phases, res)
+	}
+	return rep
+}
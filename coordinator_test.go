@@ -0,0 +1,85 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCoordinator(t *testing.T) {
+	var calls int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt64(&calls, 1)%4 == 0 {
+			w.WriteHeader(500)
+		}
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	c := NewCoordinator(3)
+	c.Delay = 10 * time.Millisecond
+	cs := httptest.NewServer(c)
+	defer cs.Close()
+
+	l := Load{
+		Method:  "GET",
+		Path:    "/",
+		Request: Request{Want: Response{Status: 200}},
+		Phases:  []Phase{{Name: "steady", Duration: 50 * time.Millisecond, From: 200}},
+	}
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = rn.RunWorker(l, cs.URL)
+		}(i)
+	}
+	rep, err := c.Wait(5 * time.Second)
+	wg.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("worker #%d: unexpected error %v", i, err)
+		}
+	}
+
+	p := rep.Phases[0]
+	if p.Phase.Name != "steady" || p.Sent != 30 || len(p.Durations) != 30 {
+		t.Errorf("got phase %q with %d sent and %d durations, want %q, 30 and 30",
+			p.Phase.Name, p.Sent, len(p.Durations), "steady")
+	}
+	if p.Errors != 7 || len(p.FirstErrors) != 5 {
+		t.Errorf("got %d errors and %d first errors, want 7 and 5", p.Errors, len(p.FirstErrors))
+	}
+
+	// a fourth worker is refused
+	if _, err := rn.RunWorker(l, cs.URL); err == nil {
+		t.Error("extra worker: want error, got nil")
+	}
+}
+
+func TestCoordinatorWaitTimeout(t *testing.T) {
+	c := NewCoordinator(2)
+	if _, err := c.Wait(10 * time.Millisecond); err == nil {
+		t.Error("want error, got nil")
+	}
+}
+
+func TestMergeLoadReports(t *testing.T) {
+	a := &LoadReport{Phases: []PhaseResult{{Phase: Phase{Name: "a"}, Sent: 1}}}
+	b := &LoadReport{Phases: []PhaseResult{{Sent: 1}, {Sent: 1}}}
+	if _, err := MergeLoadReports(a, b); err == nil {
+		t.Error("mismatched phases: want error, got nil")
+	}
+}
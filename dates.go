@@ -0,0 +1,62 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Dates describes the expected relations of the date headers of a response.
+type Dates struct {
+	// Skew, if greater than 0, is the maximum difference between the Date
+	// header and the client's clock at the time the response is checked.
+	// The one second resolution of the header is allowed for.
+	Skew time.Duration
+
+	// Order lists date headers whose values must not decrease in the
+	// listed order, e.g. {"Last-Modified", "Date", "Expires"}. Headers
+	// that are missing from the response are ignored.
+	Order []string
+}
+
+// check checks the date headers of the specified response header.
+func (d *Dates) check(h http.Header, now time.Time) error {
+	var msg string
+	if d.Skew > 0 {
+		v := h.Get("Date")
+		if t, err := http.ParseTime(v); err != nil {
+			msg += fmt.Sprintf("Header[%q] got = %s%q%s, want = %san HTTP date%s\n",
+				"Date", RedColor, v, StopColor, RedColor, StopColor)
+		} else if diff := now.Sub(t); diff < -d.Skew || diff > d.Skew+time.Second {
+			msg += fmt.Sprintf("Header[%q] got = %s%q%s, want = %swithin %s of %s%s\n",
+				"Date", RedColor, v, StopColor, RedColor, d.Skew, now.UTC().Format(http.TimeFormat), StopColor)
+		}
+	}
+
+	var prev string
+	var prevTime time.Time
+	for _, k := range d.Order {
+		v := h.Get(k)
+		if v == "" {
+			continue
+		}
+		t, err := http.ParseTime(v)
+		if err != nil {
+			msg += fmt.Sprintf("Header[%q] got = %s%q%s, want = %san HTTP date%s\n",
+				k, RedColor, v, StopColor, RedColor, StopColor)
+			continue
+		}
+		if prev != "" && t.Before(prevTime) {
+			msg += fmt.Sprintf("Header[%q] got = %s%q%s, want = %snot before %s %q%s\n",
+				k, RedColor, v, StopColor, RedColor, prev, h.Get(prev), StopColor)
+		}
+		prev, prevTime = k, t
+	}
+	if msg != "" {
+		return fmt.Errorf("%s", msg)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDatesCheck(t *testing.T) {
+	now := time.Date(2015, 6, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) string { return now.Add(d).Format(http.TimeFormat) }
+
+	tests := []struct {
+		d    Dates
+		h    http.Header
+		want []string
+	}{
+		{
+			d: Dates{Skew: 5 * time.Second, Order: []string{"Last-Modified", "Date", "Expires"}},
+			h: http.Header{
+				"Date":          {at(-3 * time.Second)},
+				"Last-Modified": {at(-time.Hour)},
+				"Expires":       {at(time.Hour)},
+			},
+		},
+		{
+			// missing headers are ignored
+			d: Dates{Order: []string{"Last-Modified", "Date", "Expires"}},
+			h: http.Header{"Date": {at(0)}},
+		},
+		{
+			d:    Dates{Skew: time.Second},
+			h:    http.Header{"Date": {at(-time.Minute)}},
+			want: []string{`Header["Date"]`, "within 1s"},
+		},
+		{
+			d:    Dates{Skew: time.Second},
+			h:    http.Header{"Date": {"yesterday"}},
+			want: []string{"an HTTP date"},
+		},
+		{
+			d: Dates{Order: []string{"Date", "Expires"}},
+			h: http.Header{
+				"Date":    {at(0)},
+				"Expires": {at(-time.Hour)},
+			},
+			want: []string{`Header["Expires"]`, "not before Date"},
+		},
+	}
+	for i, tt := range tests {
+		err := tt.d.check(tt.h, now)
+		if len(tt.want) == 0 {
+			if err != nil {
+				t.Errorf("#%d: unexpected error %v", i, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("#%d: want error, got nil", i)
+			continue
+		}
+		for _, w := range tt.want {
+			if !strings.Contains(err.Error(), w) {
+				t.Errorf("#%d: error %q does not contain %q", i, err, w)
+			}
+		}
+	}
+}
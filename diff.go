@@ -0,0 +1,145 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// DiffIgnoreHeaders lists the response headers that are expected to differ
+// between two deployments and are ignored in diff mode.
+var DiffIgnoreHeaders = []string{"Date", "Server", "Set-Cookie", "X-Request-Id"}
+
+// TestDiff sends each of the Hit's Requests to both of the specified base
+// URLs, e.g. "http://old.example.com" and "http://new.example.com", and
+// reports the differences between the two responses. The Requests' Wants
+// are not used.
+func (h Hit) TestDiff(t *testing.T, a, b string) {
+	for m, rr := range h.Requests {
+		for _, r := range rr {
+			if r.Skip {
+				continue
+			}
+			if err := r.Diff(m, h.Path, a, b); err != nil {
+				t.Error(err)
+			}
+		}
+	}
+}
+
+// Diff sends the request with the specified method and path to both of the
+// specified base URLs and returns an error describing the differences between
+// the two responses. JSON bodies are compared structurally.
+func (r Request) Diff(method, path, a, b string) error {
+	ra, ba, err := r.fetch(method, strings.TrimRight(a, "/")+path)
+	if err != nil {
+		return err
+	}
+	rb, bb, err := r.fetch(method, strings.TrimRight(b, "/")+path)
+	if err != nil {
+		return err
+	}
+
+	var fail string
+	if ra.StatusCode != rb.StatusCode {
+		fail += fmt.Sprintf("StatusCode %s = %s%d%s, %s = %s%d%s\n",
+			a, RedColor, ra.StatusCode, StopColor, b, RedColor, rb.StatusCode, StopColor)
+	}
+	rd := r.runner().Redactions
+	fail += diffHeader(rd, ra.Header, rb.Header, a, b)
+	fail += diffBody(rd, ba, bb, a, b)
+
+	if fail != "" {
+		return r.failure(method, path, fail)
+	}
+	return nil
+}
+
+// fetch sends the request to the specified URL and returns the response with
+// its body read in whole.
+func (r Request) fetch(method, urlStr string) (*http.Response, []byte, error) {
+	req, err := r.newRequestURL(method, urlStr)
+	if err != nil {
+		return nil, nil, err
+	}
+	res, err := r.do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("hit: %s %s failed. %v", method, urlStr, err)
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("hit: error reading http.Response.Body. %v", err)
+	}
+	return res, body, nil
+}
+
+func diffHeader(rd Redaction, ha, hb http.Header, a, b string) string {
+	// the bodies are compared on their own
+	ignore := map[string]bool{"Content-Length": true}
+	for _, k := range DiffIgnoreHeaders {
+		ignore[http.CanonicalHeaderKey(k)] = true
+	}
+	keys := make(map[string]bool)
+	for k := range ha {
+		keys[k] = true
+	}
+	for k := range hb {
+		keys[k] = true
+	}
+	var names []string
+	for k := range keys {
+		if !ignore[k] {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+
+	var fail string
+	for _, k := range names {
+		if va, vb := ha[k], hb[k]; !equalStrings(va, vb) {
+			fail += fmt.Sprintf("Header[%q] %s = %s%q%s, %s = %s%q%s\n",
+				k, a, RedColor, rd.redactHeaderValues(k, va), StopColor, b, RedColor, rd.redactHeaderValues(k, vb), StopColor)
+		}
+	}
+	return fail
+}
+
+func diffBody(rd Redaction, ba, bb []byte, a, b string) string {
+	var va, vb interface{}
+	if json.Unmarshal(ba, &va) == nil && json.Unmarshal(bb, &vb) == nil {
+		if err := matchJSON("", vb, va, 0); err != nil {
+			// the difference is described by the redacted bodies, unless
+			// it's only at the redacted paths
+			if rerr := matchJSON("", rd.redactJSON("", vb), rd.redactJSON("", va), 0); rerr != nil {
+				err = rerr
+			} else {
+				err = fmt.Errorf("the bodies differ at %s", Redacted)
+			}
+			return fmt.Sprintf("Body %s = %s%s%s, %s = %s%s%s\n%v\n",
+				a, RedColor, rd.redactJSONText(ba), StopColor, b, RedColor, rd.redactJSONText(bb), StopColor, err)
+		}
+		return ""
+	}
+	if !bytes.Equal(ba, bb) {
+		n := len(ba)
+		if len(bb) < n {
+			n = len(bb)
+		}
+		i := firstDiff(ba[:n], bb[:n])
+		if i < 0 {
+			i = n // one is a prefix of the other
+		}
+		return fmt.Sprintf("Body differs at byte %d, %s = %s%q%s, %s = %s%q%s\n",
+			i, a, RedColor, excerpt(ba, i), StopColor, b, RedColor, excerpt(bb, i), StopColor)
+	}
+	return ""
+}
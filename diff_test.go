@@ -0,0 +1,73 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestDiff(t *testing.T) {
+	handler := func(status int, header, body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Date", header) // ignored
+			w.Header().Set("X-Version", header)
+			w.WriteHeader(status)
+			w.Write([]byte(body))
+		}
+	}
+	tests := []struct {
+		a, b http.HandlerFunc
+		want []string
+	}{
+		{
+			a: handler(200, "1", `{"a":1,"b":[1,2]}`),
+			b: handler(200, "1", `{ "b": [1, 2], "a": 1 }`),
+		},
+		{
+			a:    handler(200, "1", `{"a":1}`),
+			b:    handler(404, "1", `{"a":1}`),
+			want: []string{"StatusCode"},
+		},
+		{
+			a:    handler(200, "1", `{"a":1}`),
+			b:    handler(200, "2", `{"a":1}`),
+			want: []string{`Header["X-Version"]`},
+		},
+		{
+			a:    handler(200, "1", `{"a":1,"b":"x"}`),
+			b:    handler(200, "1", `{"a":1,"b":"y"}`),
+			want: []string{"/b"},
+		},
+		{
+			a:    handler(200, "1", `hello world`),
+			b:    handler(200, "1", `hello`),
+			want: []string{"Body differs at byte 5"},
+		},
+	}
+	for i, tt := range tests {
+		sa, sb := httptest.NewServer(tt.a), httptest.NewServer(tt.b)
+		err := Request{}.Diff("GET", "/", sa.URL, sb.URL)
+		sa.Close()
+		sb.Close()
+
+		if len(tt.want) == 0 {
+			if err != nil {
+				t.Errorf("#%d: unexpected error %v", i, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("#%d: want error, got nil", i)
+			continue
+		}
+		for _, w := range tt.want {
+			if !strings.Contains(err.Error(), w) {
+				t.Errorf("#%d: error %q does not contain %q", i, err, w)
+			}
+		}
+	}
+}
@@ -0,0 +1,383 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Exchange is a request executed by a Request together with the response it
+// received, as recorded for Docs and OpenAPI. The bodies are truncated to
+// maxExample bytes.
+type Exchange struct {
+	Method, Path string
+	Header       http.Header
+	Body         []byte
+
+	Status         int
+	ResponseHeader http.Header
+	ResponseBody   []byte
+
+	rd Redaction // the Redaction of the Runner that executed the request
+}
+
+// maxExample is the number of bytes of a body kept in an Exchange.
+const maxExample = 64 << 10
+
+// observers are the functions called with every Exchange.
+var observers = struct {
+	sync.Mutex
+	fns map[int]func(Exchange)
+	n   int
+}{fns: make(map[int]func(Exchange))}
+
+// observe calls fn with every Exchange until the returned stop function is
+// called.
+func observe(fn func(Exchange)) (stop func()) {
+	observers.Lock()
+	defer observers.Unlock()
+	id := observers.n
+	observers.n++
+	observers.fns[id] = fn
+	return func() {
+		observers.Lock()
+		defer observers.Unlock()
+		delete(observers.fns, id)
+	}
+}
+
+// observed reports whether any function observes the exchanges.
+func observed() bool {
+	observers.Lock()
+	defer observers.Unlock()
+	return len(observers.fns) > 0
+}
+
+// exchangeRecorder records a single exchange until its response is compared.
+type exchangeRecorder struct {
+	x Exchange
+}
+
+// recordExchange starts recording the exchange of the specified request and
+// response, executed by the Runner rn, it returns nil if nobody observes the
+// exchanges.
+func recordExchange(rn *Runner, method, path string, req *http.Request, res *http.Response) *exchangeRecorder {
+	if !observed() {
+		return nil
+	}
+	rec := &exchangeRecorder{
+		x: Exchange{
+			Method:         method,
+			Path:           path,
+			Header:         req.Header,
+			Status:         res.StatusCode,
+			ResponseHeader: res.Header,
+			rd:             rn.Redactions,
+		},
+	}
+	if req.GetBody != nil {
+		if rc, err := req.GetBody(); err == nil {
+			rec.x.Body, _ = ioutil.ReadAll(io.LimitReader(rc, maxExample))
+			rc.Close()
+		}
+	}
+	if res.Body != nil {
+		// the body is read ahead of time since it may not be read in whole
+		// by the comparison, a read error is hit again by the comparison
+		head, _ := ioutil.ReadAll(io.LimitReader(res.Body, maxExample))
+		rec.x.ResponseBody = head
+		res.Body = peekedBody{io.MultiReader(bytes.NewReader(head), res.Body), res.Body}
+	}
+	return rec
+}
+
+// done passes the recorded exchange to the observers.
+func (rec *exchangeRecorder) done() {
+	if rec == nil {
+		return
+	}
+	observers.Lock()
+	defer observers.Unlock()
+	for _, fn := range observers.fns {
+		fn(rec.x)
+	}
+}
+
+// Docs documents the endpoints of a suite in Markdown, using its Hits and,
+// optionally, the responses recorded while they're executed, turning the
+// suite into living API documentation. The values redacted from the failure
+// messages are redacted from the documentation as well, those of the Hits by
+// the Redactions of the Docs' Runner and those of a recorded response by the
+// Redactions of the Runner that received it.
+type Docs struct {
+	Title string
+	// Runner, if set, executes the Hits of Test, otherwise they're
+	// executed as by the Hit's Test.
+	Runner *Runner
+
+	mu        sync.Mutex
+	hits      []Hit
+	exchanges map[string][]Exchange
+}
+
+// Add adds the Hits to the documentation.
+func (d *Docs) Add(hh ...Hit) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.hits = append(d.hits, hh...)
+}
+
+// Record records the exchanges of all the executed requests, until the
+// returned stop function is called, and includes them in the documentation
+// as the example responses of the Hits of the same method and path.
+func (d *Docs) Record() (stop func()) {
+	return observe(func(x Exchange) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if d.exchanges == nil {
+			d.exchanges = make(map[string][]Exchange)
+		}
+		k := x.Method + " " + x.Path
+		d.exchanges[k] = append(d.exchanges[k], x)
+	})
+}
+
+// Test adds the Hit to the documentation and executes it.
+func (d *Docs) Test(t *testing.T, h Hit) {
+	d.Add(h)
+	if d.Runner == nil {
+		h.Test(t)
+		return
+	}
+	d.Runner.Test(t, h)
+}
+
+// runner returns the Docs' Runner or, if it's nil, the default one.
+func (d *Docs) runner() *Runner {
+	if d.Runner != nil {
+		return d.Runner
+	}
+	return globalRunner()
+}
+
+// WriteMarkdown writes the documentation to w, one section per path with a
+// subsection per method listing the Requests and their expected, and
+// recorded, responses.
+func (d *Docs) WriteMarkdown(w io.Writer) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	byPath := make(map[string]Requests)
+	var paths []string
+	for _, h := range d.hits {
+		if byPath[h.Path] == nil {
+			byPath[h.Path] = make(Requests)
+			paths = append(paths, h.Path)
+		}
+		for m, rr := range h.Requests {
+			byPath[h.Path][m] = append(byPath[h.Path][m], rr...)
+		}
+	}
+	sort.Strings(paths)
+
+	rd := d.runner().Redactions
+	var b bytes.Buffer
+	if d.Title != "" {
+		fmt.Fprintf(&b, "# %s\n\n", d.Title)
+	}
+	for _, p := range paths {
+		fmt.Fprintf(&b, "## %s\n\n", p)
+		rs := byPath[p]
+		var methods []string
+		for m := range rs {
+			methods = append(methods, m)
+		}
+		sort.Strings(methods)
+		for _, m := range methods {
+			fmt.Fprintf(&b, "### %s\n\n", m)
+			recorded := d.exchanges[m+" "+p]
+			for i, r := range rs[m] {
+				if r.Skip {
+					continue
+				}
+				if len(rs[m]) > 1 {
+					name := r.Name
+					if name == "" {
+						name = fmt.Sprintf("Example %d", i+1)
+					}
+					fmt.Fprintf(&b, "#### %s\n\n", name)
+				}
+				d.writeRequest(&b, rd, m, p, r)
+				d.writeResponse(&b, rd, r.Want)
+				if x, ok := exampleOf(recorded, r.Want.Status); ok {
+					b.WriteString("Recorded response:\n\n")
+					writeExchange(&b, x)
+				}
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, rd.redact(b.String()))
+	return err
+}
+
+// writeRequest writes the example request of r.
+func (d *Docs) writeRequest(b *bytes.Buffer, rd Redaction, method, path string, r Request) {
+	b.WriteString("Request:\n\n```http\n")
+	fmt.Fprintf(b, "%s %s\n", method, path)
+	h := http.Header(r.Header)
+	if r.Body != nil {
+		h = cloneHeader(h)
+		h.Set("Content-Type", r.Body.Type())
+	}
+	writeHeader(b, rd, h)
+	if body := exampleBody(rd, r.Body); body != "" {
+		fmt.Fprintf(b, "\n%s\n", body)
+	}
+	b.WriteString("```\n\n")
+}
+
+// writeResponse writes the expected response.
+func (d *Docs) writeResponse(b *bytes.Buffer, rd Redaction, want Response) {
+	fmt.Fprintf(b, "Response: `%d %s`\n\n", want.Status, http.StatusText(want.Status))
+	if len(want.Header) > 0 {
+		b.WriteString("```http\n")
+		writeHeader(b, rd, http.Header(want.Header))
+		b.WriteString("```\n\n")
+	}
+	if v, ok := expectedJSON(want.Body); ok {
+		if j, err := json.MarshalIndent(rd.redactJSON("", v), "", "  "); err == nil {
+			fmt.Fprintf(b, "```json\n%s\n```\n\n", j)
+		}
+	}
+}
+
+// exampleOf returns the first of the recorded exchanges with the specified
+// status.
+func exampleOf(xx []Exchange, status int) (Exchange, bool) {
+	for _, x := range xx {
+		if x.Status == status {
+			return x, true
+		}
+	}
+	return Exchange{}, false
+}
+
+// writeExchange writes the recorded response of x redacted by the Redaction
+// of its Runner.
+func writeExchange(b *bytes.Buffer, x Exchange) {
+	b.WriteString("```http\n")
+	fmt.Fprintf(b, "%d %s\n", x.Status, http.StatusText(x.Status))
+	if ct := x.ResponseHeader.Get("Content-Type"); ct != "" {
+		fmt.Fprintf(b, "Content-Type: %s\n", ct)
+	}
+	if len(x.ResponseBody) > 0 {
+		fmt.Fprintf(b, "\n%s\n", x.rd.redact(prettyBody(x.rd, x.ResponseHeader.Get("Content-Type"), x.ResponseBody)))
+	}
+	b.WriteString("```\n\n")
+}
+
+func writeHeader(b *bytes.Buffer, rd Redaction, h http.Header) {
+	var keys []string
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range rd.redactHeaderValues(k, h[k]) {
+			fmt.Fprintf(b, "%s: %s\n", http.CanonicalHeaderKey(k), v)
+		}
+	}
+}
+
+func cloneHeader(h http.Header) http.Header {
+	c := make(http.Header, len(h)+1)
+	for k, vv := range h {
+		c[k] = append([]string(nil), vv...)
+	}
+	return c
+}
+
+// exampleBody returns the text of the request body, if it's a body of a
+// small, known, type.
+func exampleBody(rd Redaction, b Bodyer) string {
+	switch b.(type) {
+	case JSONBody, FormBody, encodedBody:
+	default:
+		return ""
+	}
+	r, err := b.Body()
+	if err != nil {
+		return ""
+	}
+	raw, err := ioutil.ReadAll(io.LimitReader(r, maxExample))
+	if err != nil {
+		return ""
+	}
+	return prettyBody(rd, b.Type(), raw)
+}
+
+// prettyBody returns the body indented, and with the values at the paths
+// of rd redacted, if it's JSON.
+func prettyBody(rd Redaction, contentType string, body []byte) string {
+	mt, _, _ := mime.ParseMediaType(contentType)
+	if mt == appjson || strings.HasSuffix(mt, "+json") {
+		var out bytes.Buffer
+		if err := json.Indent(&out, rd.redactJSONText(body), "", "  "); err == nil {
+			return out.String()
+		}
+	}
+	return string(body)
+}
+
+// expectedJSON returns the expected JSON value of the Comparer, with the
+// Matchers replaced by their descriptions, e.g. "hit.UUID()".
+func expectedJSON(c Comparer) (interface{}, bool) {
+	var v interface{}
+	switch b := c.(type) {
+	case JSONBody:
+		v = map[string]interface{}(b)
+	case Modal:
+		v = b
+	default:
+		return nil, false
+	}
+	v, err := normalize(v)
+	if err != nil {
+		return nil, false
+	}
+	return describeJSON(v), true
+}
+
+func describeJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case Modal:
+		return describeJSON(v.v)
+	case Matcher:
+		return fmt.Sprintf("%#v", v)
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, x := range v {
+			m[k] = describeJSON(x)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, x := range v {
+			s[i] = describeJSON(x)
+		}
+		return s
+	}
+	return v
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDocs(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == "POST" {
+			w.WriteHeader(201)
+			w.Write([]byte(`{"id":"0f8fad5b-d9cb-469f-a165-70867728950e","name":"ann"}`))
+			return
+		}
+		w.Write([]byte(`[{"id":"0f8fad5b-d9cb-469f-a165-70867728950e","name":"ann"}]`))
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+	rn.Redactions = Redaction{Headers: []string{"Authorization"}}
+
+	d := &Docs{Title: "Users API", Runner: rn}
+	stop := d.Record()
+	d.Test(t, Hit{
+		Path: "/users",
+		Requests: Requests{
+			"GET": {{Bearer: "tok-docs-1234", Want: Response{Status: 200}}},
+			"POST": {
+				{Name: "create", Header: Header{"Authorization": {"Bearer tok-docs-1234"}}, Body: JSONBody{"name": "ann"},
+					Want: Response{Status: 201, Body: JSONBody{"id": UUID(), "name": "ann"}}},
+				{Name: "invalid", Skip: true, Want: Response{Status: 400}},
+			},
+		},
+	})
+	stop()
+
+	var b bytes.Buffer
+	if err := d.WriteMarkdown(&b); err != nil {
+		t.Fatal(err)
+	}
+	doc := b.String()
+	for _, want := range []string{
+		"# Users API\n",
+		"## /users\n",
+		"### GET\n",
+		"### POST\n",
+		"#### create\n",
+		"Authorization: " + Redacted + "\n",
+		"Content-Type: application/json\n\n{\n  \"name\": \"ann\"\n}\n",
+		"Response: `201 Created`",
+		`"id": "hit.UUID()"`,
+		"Recorded response:\n\n```http\n200 OK\nContent-Type: application/json\n\n[\n  {\n",
+	} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("doc lacks %q:\n%s", want, doc)
+		}
+	}
+	if strings.Contains(doc, "tok-docs-1234") || strings.Contains(doc, "invalid") {
+		t.Errorf("doc has a secret or a skipped request:\n%s", doc)
+	}
+	if strings.Index(doc, "### GET") > strings.Index(doc, "### POST") {
+		t.Errorf("methods are not sorted:\n%s", doc)
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Download describes the expected metadata of a file download. Its fields
+// are compared to the parsed headers, rather than to the raw header strings,
+// so that equivalent encodings of the same value are accepted.
+type Download struct {
+	// Filename, if set, must be the filename of the Content-Disposition
+	// header, the extended "filename*" parameter takes precedence over
+	// the plain one.
+	Filename string
+	// Disposition, if set, must be the disposition type, e.g. "attachment"
+	// or "inline".
+	Disposition string
+	// Type, if set, must match the media type of the Content-Type header,
+	// any parameters it has must be present in the header.
+	Type string
+	// Length, if greater than 0, must be the Content-Length.
+	Length int64
+}
+
+// check compares the headers of the specified response to the receiver.
+func (d *Download) check(h http.Header) error {
+	var msg string
+	mismatch := func(name, got, want string) {
+		msg += fmt.Sprintf("Download %s got = %s%q%s, want = %s%q%s\n",
+			name, RedColor, got, StopColor, RedColor, want, StopColor)
+	}
+
+	if d.Filename != "" || d.Disposition != "" {
+		cd := h.Get("Content-Disposition")
+		disp, params, err := mime.ParseMediaType(cd)
+		if err != nil {
+			msg += fmt.Sprintf("Header[%q] got = %s%q%s, want = %sa valid disposition%s (%v)\n",
+				"Content-Disposition", RedColor, cd, StopColor, RedColor, StopColor, err)
+		} else {
+			// ParseMediaType decodes "filename*" into "filename"
+			if d.Filename != "" && params["filename"] != d.Filename {
+				mismatch("filename", params["filename"], d.Filename)
+			}
+			if d.Disposition != "" && !strings.EqualFold(disp, d.Disposition) {
+				mismatch("disposition", disp, d.Disposition)
+			}
+		}
+	}
+	if ct := h.Get("Content-Type"); d.Type != "" && !mediaTypeMatch(ct, d.Type) {
+		mismatch("type", ct, d.Type)
+	}
+	if d.Length > 0 {
+		cl := h.Get("Content-Length")
+		if n, err := strconv.ParseInt(cl, 10, 64); err != nil || n != d.Length {
+			mismatch("length", cl, strconv.FormatInt(d.Length, 10))
+		}
+	}
+	if msg != "" {
+		return fmt.Errorf("%s", msg)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDownloadCheck(t *testing.T) {
+	tests := []struct {
+		d    Download
+		h    http.Header
+		want []string
+	}{
+		{
+			d: Download{Filename: "report.pdf", Disposition: "attachment", Type: "application/pdf", Length: 1234},
+			h: http.Header{
+				"Content-Disposition": {`attachment; filename="report.pdf"`},
+				"Content-Type":        {"application/pdf"},
+				"Content-Length":      {"1234"},
+			},
+		},
+		{
+			// the extended filename is decoded and takes precedence
+			d: Download{Filename: "naïve.txt", Type: "text/plain; charset=utf-8"},
+			h: http.Header{
+				"Content-Disposition": {`attachment; filename="naive.txt"; filename*=UTF-8''na%C3%AFve.txt`},
+				"Content-Type":        {"text/plain;charset=UTF-8"},
+			},
+		},
+		{
+			d: Download{Filename: "a.csv", Disposition: "attachment", Type: "text/csv", Length: 10},
+			h: http.Header{
+				"Content-Disposition": {`inline; filename="b.csv"`},
+				"Content-Type":        {"application/json"},
+				"Content-Length":      {"11"},
+			},
+			want: []string{"filename", "disposition", "type", "length"},
+		},
+		{
+			d:    Download{Filename: "a.csv"},
+			h:    http.Header{},
+			want: []string{"Content-Disposition"},
+		},
+	}
+	for i, tt := range tests {
+		err := tt.d.check(tt.h)
+		if len(tt.want) == 0 {
+			if err != nil {
+				t.Errorf("#%d: unexpected error %v", i, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("#%d: want error, got nil", i)
+			continue
+		}
+		for _, w := range tt.want {
+			if !strings.Contains(err.Error(), w) {
+				t.Errorf("#%d: error %q does not contain %q", i, err, w)
+			}
+		}
+	}
+}
@@ -0,0 +1,236 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// StatusError is the failure of a response whose status is not the expected
+// one.
+type StatusError struct {
+	Got, Want int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("StatusCode got = %s%d%s, want %s%d%s\n",
+		RedColor,
+		e.Got,
+		StopColor,
+		RedColor,
+		e.Want,
+		StopColor,
+	)
+}
+
+// Is reports whether target is a *StatusError whose non-zero fields are
+// equal to those of e, e.g. errors.Is(err, &StatusError{}) matches any
+// status failure and errors.Is(err, &StatusError{Got: 500}) only those of
+// the responses with status 500.
+func (e *StatusError) Is(target error) bool {
+	t, ok := target.(*StatusError)
+	return ok && (t.Got == 0 || t.Got == e.Got) && (t.Want == 0 || t.Want == e.Want)
+}
+
+// headerForm is the kind of comparison whose failure a HeaderError reports.
+type headerForm int
+
+const (
+	headerEqual   headerForm = iota // the single value is equal
+	headerAll                       // all of the values are present
+	headerOrdered                   // the values are equal and in order
+	headerAbsent                    // the header is not present
+	headerMatch                     // the value matches a Matcher
+)
+
+// HeaderError is the failure of a response header whose values don't match
+// the expected ones. Want is nil if the header is not expected at all, and
+// it holds the description of the Matcher if the value had to match one.
+type HeaderError struct {
+	Name      string
+	Got, Want []string
+
+	form headerForm
+	err  error      // the Matcher's failure
+	rd   *Redaction // the Redaction of the Runner, if any
+}
+
+func (e *HeaderError) Error() string {
+	vals := redactionOf(e.rd).redactHeaderValues(e.Name, e.Got)
+	switch e.form {
+	case headerAll:
+		return fmt.Sprintf("Header[%q] got = %s%q%s, want all of %s%q%s\n",
+			e.Name, RedColor, vals, StopColor, RedColor, e.Want, StopColor)
+	case headerOrdered:
+		return fmt.Sprintf("Header[%q] got = %s%q%s, want in order %s%q%s\n",
+			e.Name, RedColor, vals, StopColor, RedColor, e.Want, StopColor)
+	case headerAbsent:
+		return fmt.Sprintf("Header[%q] got = %s%q%s, want = %s<not present>%s\n",
+			e.Name, RedColor, vals, StopColor, RedColor, StopColor)
+	}
+	var got, want string
+	if len(vals) > 0 {
+		got = vals[0]
+	}
+	if len(e.Want) > 0 {
+		want = e.Want[0]
+	}
+	if e.form == headerMatch {
+		return fmt.Sprintf("Header[%q] got = %s%q%s, want = %s%s%s (%v)\n",
+			e.Name, RedColor, got, StopColor, RedColor, want, StopColor, e.err)
+	}
+	return fmt.Sprintf("Header[%q] got = %s%q%s, want = %s%q%s\n",
+		e.Name, RedColor, got, StopColor, RedColor, want, StopColor)
+}
+
+// Is reports whether target is a *HeaderError with no Name or with the same,
+// canonicalized, Name as e.
+func (e *HeaderError) Is(target error) bool {
+	t, ok := target.(*HeaderError)
+	return ok && (t.Name == "" || http.CanonicalHeaderKey(t.Name) == http.CanonicalHeaderKey(e.Name))
+}
+
+// Unwrap returns the failure of the Matcher, if any.
+func (e *HeaderError) Unwrap() error {
+	return e.err
+}
+
+// BodyDiffError is the failure of a JSON body that doesn't match the expected
+// one. Got and Want are the decoded bodies, Diffs lists their differences and
+// Extra lists the JSON pointers of the fields that are not expected in strict
+// mode.
+type BodyDiffError struct {
+	Got, Want interface{}
+	Diffs     []BodyDiff
+	Extra     []string
+
+	rd *Redaction // the Redaction of the Runner, if any
+}
+
+// BodyDiff is a difference between two JSON bodies at the leaf with the
+// JSON pointer Path, e.g. Path "/items/3/price" and Msg "got 10, want 12".
+type BodyDiff struct {
+	Path, Msg string
+}
+
+func (d BodyDiff) String() string {
+	return d.Path + ": " + d.Msg
+}
+
+// maxBodyDiffs is the number of body differences shown in a failure message.
+const maxBodyDiffs = 20
+
+func (e *BodyDiffError) Error() string {
+	var msg string
+	for i, d := range e.Diffs {
+		if i == maxBodyDiffs {
+			msg += fmt.Sprintf("Body has %d more differences\n", len(e.Diffs)-i)
+			break
+		}
+		msg += fmt.Sprintf("Body %s: %s%s%s\n", d.Path, RedColor, d.Msg, StopColor)
+	}
+	if len(e.Diffs) == 0 && len(e.Extra) == 0 {
+		msg += fmt.Sprintf("Body got %s%#v%s, want %s%#v%s\n",
+			RedColor,
+			redactionOf(e.rd).redactJSON("", e.Got),
+			StopColor,
+			RedColor,
+			e.Want,
+			StopColor,
+		)
+	}
+	if len(e.Extra) > 0 {
+		msg += fmt.Sprintf("Body has unexpected fields %s%s%s\n", RedColor, strings.Join(e.Extra, ", "), StopColor)
+	}
+	return msg
+}
+
+// Is reports whether target is a *BodyDiffError.
+func (e *BodyDiffError) Is(target error) bool {
+	_, ok := target.(*BodyDiffError)
+	return ok
+}
+
+// ErrorList is a list of failures, e.g. those of a single response. Its
+// message is the concatenation of the failures' messages and errors.Is and
+// errors.As look at every one of them.
+type ErrorList []error
+
+func (l ErrorList) Error() string {
+	var msg string
+	for _, err := range l {
+		msg += err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the failures of the list.
+func (l ErrorList) Unwrap() []error {
+	return l
+}
+
+// add appends err, or the failures of err if it's an ErrorList, to the list.
+func (l *ErrorList) add(err error) {
+	if ll, ok := err.(ErrorList); ok {
+		*l = append(*l, ll...)
+	} else if err != nil {
+		*l = append(*l, err)
+	}
+}
+
+// err returns the list, or nil if it's empty.
+func (l ErrorList) err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
+
+// RequestError is returned by Request.Execute if the response doesn't match,
+// it describes the request and holds the typed failures, e.g. StatusError,
+// that can be examined with errors.Is and errors.As.
+type RequestError struct {
+	Method, Path string
+	Failures     []error
+
+	msg string
+}
+
+// NewRequestError returns a RequestError of the request with the specified
+// method and path whose message is the description of the request, e.g. its
+// method, path and header, followed by the messages of the failures. It's
+// meant for the packages that execute requests of their own.
+func NewRequestError(method, path, desc string, failures ...error) *RequestError {
+	msg := desc + "\n"
+	for _, f := range failures {
+		msg += f.Error()
+	}
+	return &RequestError{Method: method, Path: path, Failures: failures, msg: msg}
+}
+
+func (e *RequestError) Error() string {
+	return e.msg
+}
+
+// Unwrap returns the typed failures of the request.
+func (e *RequestError) Unwrap() []error {
+	return e.Failures
+}
+
+// messageError is an error whose message was rewritten, e.g. to redact it,
+// it keeps the original error for errors.Is and errors.As.
+type messageError struct {
+	msg string
+	err error
+}
+
+func (e *messageError) Error() string {
+	return e.msg
+}
+
+func (e *messageError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestTypedErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Foo", "bar")
+		w.WriteHeader(404)
+		w.Write([]byte(`{"a":1}`))
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	r := Request{Want: Response{Status: 200, Header: Header{"x-foo": {"baz"}}, Body: JSONBody{"a": 2}}}
+	plain, err := Config{Addr: rn.Addr, NoColor: true}.NewRunner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := Scenario{Name: "typed", Steps: []Step{{Method: "GET", Path: "/", Request: r}}}
+	errs := map[string]error{
+		"Execute":  rn.Execute(r, "GET", "/"),
+		"NoColor":  plain.Execute(r, "GET", "/"),
+		"Scenario": rn.Scenario(s),
+	}
+	for name, err := range errs {
+		var re *RequestError
+		if !errors.As(err, &re) || re.Method != "GET" || re.Path != "/" {
+			t.Errorf("%s: %v is not a *RequestError", name, err)
+		}
+		var se *StatusError
+		if !errors.As(err, &se) || se.Got != 404 || se.Want != 200 {
+			t.Errorf("%s: got StatusError %+v, want 404, 200", name, se)
+		}
+		var he *HeaderError
+		if !errors.As(err, &he) || !reflect.DeepEqual(he.Got, []string{"bar"}) || !reflect.DeepEqual(he.Want, []string{"baz"}) {
+			t.Errorf("%s: got HeaderError %+v", name, he)
+		}
+		var be *BodyDiffError
+		if !errors.As(err, &be) {
+			t.Errorf("%s: no BodyDiffError in %v", name, err)
+		}
+
+		for _, tt := range []struct {
+			target error
+			want   bool
+		}{
+			{&StatusError{}, true},
+			{&StatusError{Got: 404}, true},
+			{&StatusError{Got: 500}, false},
+			{&HeaderError{Name: "X-Foo"}, true},
+			{&HeaderError{Name: "X-Bar"}, false},
+			{&BodyDiffError{}, true},
+		} {
+			if got := errors.Is(err, tt.target); got != tt.want {
+				t.Errorf("%s: errors.Is(%#v) got %t, want %t", name, tt.target, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestErrorList(t *testing.T) {
+	var ll ErrorList
+	if ll.err() != nil {
+		t.Errorf("empty list got %v, want nil", ll.err())
+	}
+	ll.add(nil)
+	ll.add(&StatusError{Got: 1, Want: 2})
+	ll.add(ErrorList{&HeaderError{Name: "A"}, &BodyDiffError{}})
+	if len(ll) != 3 {
+		t.Fatalf("got %d errors, want 3", len(ll))
+	}
+	if want := ll[0].Error() + ll[1].Error() + ll[2].Error(); ll.Error() != want {
+		t.Errorf("got %q, want %q", ll.Error(), want)
+	}
+}
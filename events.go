@@ -0,0 +1,126 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Events, if set, receives every failure of a Request executed by the
+// package's functions as a JSON encoded Event, one per line, so that external
+// tools, e.g. notifiers or dashboards, can consume the results as they
+// happen. Write errors are ignored. It's the default writer of a new Runner,
+// see Config.Events.
+var Events io.Writer
+
+// events serializes the writes of the Events, the Runners may share a
+// writer.
+var events sync.Mutex
+
+// The kinds of the failure Events.
+const (
+	KindStatus  = "status"  // the StatusCode didn't match, see StatusError
+	KindHeader  = "header"  // a header didn't match, see HeaderError
+	KindBody    = "body"    // the JSON body didn't match, see BodyDiffError
+	KindFailure = "failure" // any other mismatch
+	KindError   = "error"   // the request couldn't be executed
+)
+
+// Event describes a single failure of a Request. The values are redacted and
+// the message is stripped of its colors.
+type Event struct {
+	Time   time.Time `json:"time"`
+	Name   string    `json:"name"`
+	Source string    `json:"source,omitempty"`
+	Method string    `json:"method"`
+	Path   string    `json:"path"`
+	Kind   string    `json:"kind"`
+
+	// Header is the name of the header of a KindHeader Event.
+	Header string `json:"header,omitempty"`
+	// Got and Want are the values of a KindStatus or a KindHeader Event.
+	Got  interface{} `json:"got,omitempty"`
+	Want interface{} `json:"want,omitempty"`
+	// Diff lists the differences of a KindBody Event, each one prefixed
+	// by the JSON pointer of the differing value.
+	Diff []string `json:"diff,omitempty"`
+
+	Message string `json:"message"`
+}
+
+// emitEvents writes an Event for every failure of err to the Runner's Events.
+func (rn *Runner) emitEvents(name, source, method, path string, err error) {
+	if rn.Events == nil || err == nil {
+		return
+	}
+	ee := rn.Redactions.failureEvents(err)
+	now := time.Now()
+	events.Lock()
+	defer events.Unlock()
+	enc := json.NewEncoder(rn.Events)
+	for _, e := range ee {
+		e.Time, e.Name, e.Source, e.Method, e.Path = now, name, source, method, path
+		enc.Encode(e)
+	}
+}
+
+// failureEvents returns the Events of the failures of err, without the
+// request's name, source, method and path. The failures of a list of errors,
+// e.g. those of a Request's Variants, are returned in order.
+func (rd Redaction) failureEvents(err error) []Event {
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if ll, ok := e.(ErrorList); ok {
+			var ee []Event
+			for _, err := range ll {
+				ee = append(ee, rd.failureEvents(err)...)
+			}
+			return ee
+		}
+	}
+	var re *RequestError
+	if !errors.As(err, &re) || len(re.Failures) == 0 {
+		return []Event{{Kind: KindError, Message: rd.eventText(err.Error())}}
+	}
+	ee := make([]Event, len(re.Failures))
+	for i, f := range re.Failures {
+		e := Event{Kind: KindFailure, Message: rd.eventText(f.Error())}
+		switch f := f.(type) {
+		case *StatusError:
+			e.Kind, e.Got, e.Want = KindStatus, f.Got, f.Want
+		case *HeaderError:
+			e.Kind, e.Header, e.Got = KindHeader, f.Name, rd.eventTexts(rd.redactHeaderValues(f.Name, f.Got))
+			if f.Want != nil {
+				e.Want = rd.eventTexts(f.Want)
+			}
+		case *BodyDiffError:
+			e.Kind = KindBody
+			for _, d := range f.Diffs {
+				e.Diff = append(e.Diff, rd.eventText(d.String()))
+			}
+			for _, p := range f.Extra {
+				e.Diff = append(e.Diff, p+": unexpected")
+			}
+		}
+		ee[i] = e
+	}
+	return ee
+}
+
+// eventText returns s redacted and without the ANSI colors.
+func (rd Redaction) eventText(s string) string {
+	return strings.TrimSuffix(rd.redact(ansiColor.ReplaceAllString(s, "")), "\n")
+}
+
+func (rd Redaction) eventTexts(ss []string) []string {
+	out := make([]string, len(ss))
+	for i, s := range ss {
+		out[i] = rd.eventText(s)
+	}
+	return out
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEvents(t *testing.T) {
+	var n int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Token", "tok-5e6f7a")
+		w.WriteHeader(404)
+		fmt.Fprintf(w, `{"n":%d}`, atomic.AddInt32(&n, 1))
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+	rn.Redactions = Redaction{Headers: []string{"X-Token"}}
+	var buf bytes.Buffer
+	rn.Events = &buf
+
+	r := Request{Name: "get n", Want: Response{Status: 200, Header: Header{"X-Token": {"none"}}, Body: JSONBody{"n": 0}}}
+	if err := rn.Execute(r, "GET", "/n"); err == nil {
+		t.Fatal("want error, got nil")
+	}
+	r = Request{RepeatIdempotent: 2, RepeatIdentical: true, Want: Response{Status: 404}}
+	if err := rn.Execute(r, "GET", "/n"); err == nil {
+		t.Fatal("want error, got nil")
+	}
+
+	var got []Event
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var e Event
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+		if e.Time.IsZero() || e.Method != "GET" || e.Path != "/n" {
+			t.Errorf("event %+v lacks the request", e)
+		}
+		e.Time, e.Method, e.Path, e.Message = time.Time{}, "", "", firstWord(e.Message)
+		got = append(got, e)
+	}
+	want := []Event{
+		{Name: "get n", Kind: KindStatus, Got: 404.0, Want: 200.0, Message: "StatusCode"},
+		{Name: "get n", Kind: KindHeader, Header: "X-Token", Got: []interface{}{Redacted}, Want: []interface{}{"none"}, Message: "Header[\"X-Token\"]"},
+		{Name: "get n", Kind: KindBody, Diff: []string{"/n: got 1, want 0"}, Message: "Body"},
+		{Name: "GET /n", Kind: KindFailure, Message: "Response"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d events, want %d:\n%s", len(got), len(want), buf.String())
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("#%d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if strings.Contains(buf.String(), "tok-5e6f7a") || strings.Contains(buf.String(), "\033[") {
+		t.Errorf("events are not redacted and plain:\n%s", buf.String())
+	}
+}
+
+func firstWord(s string) string {
+	if i := strings.IndexByte(s, ' '); i > 0 {
+		return s[:i]
+	}
+	return s
+}
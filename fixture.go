@@ -0,0 +1,172 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// Fixture prepares the server's state before a Hit or a Scenario is executed
+// and cleans it up afterwards.
+type Fixture interface {
+	Load() error
+	Cleanup() error
+}
+
+// runnerFixture is implemented by the Fixtures that send requests, they're
+// loaded and cleaned up by the Runner that executes the Hit or the Scenario.
+type runnerFixture interface {
+	loadWith(rn *Runner) error
+	cleanupWith(rn *Runner) error
+}
+
+// loadFixtures loads the specified fixtures in order. It returns a function
+// that cleans up the loaded fixtures in reverse order, it's returned even if
+// loading fails so that the fixtures loaded so far can be cleaned up.
+func (rn *Runner) loadFixtures(ff []Fixture) (cleanup func() error, err error) {
+	var loaded []Fixture
+	cleanup = func() error {
+		var msg string
+		for i := len(loaded) - 1; i >= 0; i-- {
+			if err := rn.cleanupFixture(loaded[i]); err != nil {
+				msg += fmt.Sprintf("hit: fixture %T cleanup failed. %v\n", loaded[i], err)
+			}
+		}
+		if msg != "" {
+			return fmt.Errorf("%s", msg)
+		}
+		return nil
+	}
+	for _, f := range ff {
+		if err := rn.loadFixture(f); err != nil {
+			return cleanup, fmt.Errorf("hit: fixture %T load failed. %v", f, err)
+		}
+		loaded = append(loaded, f)
+	}
+	return cleanup, nil
+}
+
+func (rn *Runner) loadFixture(f Fixture) error {
+	if rf, ok := f.(runnerFixture); ok {
+		return rf.loadWith(rn)
+	}
+	return f.Load()
+}
+
+func (rn *Runner) cleanupFixture(f Fixture) error {
+	if rf, ok := f.(runnerFixture); ok {
+		return rf.cleanupWith(rn)
+	}
+	return f.Cleanup()
+}
+
+// SQLFile is a Fixture that executes the statements of SQL files. The files
+// are split into statements at semicolons that end a line, each statement is
+// executed separately.
+type SQLFile struct {
+	DB *sql.DB
+	// Path is the file executed by Load.
+	Path string
+	// CleanupPath, if set, is the file executed by Cleanup.
+	CleanupPath string
+}
+
+// Load implements the Fixture interface.
+func (f SQLFile) Load() error { return execSQLFile(f.DB, f.Path) }
+
+// Cleanup implements the Fixture interface.
+func (f SQLFile) Cleanup() error {
+	if f.CleanupPath == "" {
+		return nil
+	}
+	return execSQLFile(f.DB, f.CleanupPath)
+}
+
+func execSQLFile(db *sql.DB, path string) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	for _, stmt := range splitSQL(string(b)) {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("%s: %q failed. %v", path, stmt, err)
+		}
+	}
+	return nil
+}
+
+// splitSQL splits the specified SQL into statements at semicolons that end a
+// line, lines that are empty or that hold only a comment are dropped.
+func splitSQL(s string) []string {
+	var stmts []string
+	var cur []string
+	for _, line := range strings.Split(s, "\n") {
+		t := strings.TrimSpace(line)
+		if t == "" || strings.HasPrefix(t, "--") {
+			continue
+		}
+		cur = append(cur, line)
+		if strings.HasSuffix(t, ";") {
+			stmts = append(stmts, strings.TrimSpace(strings.Join(cur, "\n")))
+			cur = nil
+		}
+	}
+	if len(cur) > 0 {
+		stmts = append(stmts, strings.TrimSpace(strings.Join(cur, "\n")))
+	}
+	return stmts
+}
+
+// HTTPSeed is a Fixture that seeds the server's state through its API. Load
+// sends the Seed request and Cleanup sends the Undo request, if set, both
+// to the target of the Runner that executes the Hit or the Scenario, or to
+// Addr. The responses must have a 2xx status.
+type HTTPSeed struct {
+	Seed SeedRequest
+	Undo *SeedRequest
+}
+
+// SeedRequest represents a request sent by an HTTPSeed.
+type SeedRequest struct {
+	Method string
+	Path   string
+	Header Header
+	Body   Bodyer
+}
+
+func (s SeedRequest) send(rn *Runner) error {
+	r := Request{Header: s.Header, Body: s.Body, rn: rn}
+	req, err := r.newRequest(s.Method, s.Path)
+	if err != nil {
+		return err
+	}
+	res, err := r.do(req)
+	if err != nil {
+		return err
+	}
+	ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("%s %s got status %d, want 2xx", s.Method, s.Path, res.StatusCode)
+	}
+	return nil
+}
+
+// Load implements the Fixture interface.
+func (s HTTPSeed) Load() error { return s.loadWith(globalRunner()) }
+
+// Cleanup implements the Fixture interface.
+func (s HTTPSeed) Cleanup() error { return s.cleanupWith(globalRunner()) }
+
+func (s HTTPSeed) loadWith(rn *Runner) error { return s.Seed.send(rn) }
+
+func (s HTTPSeed) cleanupWith(rn *Runner) error {
+	if s.Undo == nil {
+		return nil
+	}
+	return s.Undo.send(rn)
+}
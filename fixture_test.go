@@ -0,0 +1,135 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recDriver is a database/sql driver that records the executed statements.
+type recDriver struct {
+	mu    sync.Mutex
+	stmts []string
+}
+
+func (d *recDriver) Open(string) (driver.Conn, error) { return recConn{d}, nil }
+
+// reset forgets the recorded statements, once the test is done, so that the
+// next run of the test starts afresh.
+func (d *recDriver) reset(t *testing.T) {
+	t.Cleanup(func() {
+		d.mu.Lock()
+		d.stmts = nil
+		d.mu.Unlock()
+	})
+}
+
+type recConn struct{ d *recDriver }
+
+func (c recConn) Prepare(q string) (driver.Stmt, error) { return recStmt{c.d, q}, nil }
+func (c recConn) Close() error                          { return nil }
+func (c recConn) Begin() (driver.Tx, error)             { return nil, errors.New("not supported") }
+
+type recStmt struct {
+	d *recDriver
+	q string
+}
+
+func (s recStmt) Close() error  { return nil }
+func (s recStmt) NumInput() int { return -1 }
+func (s recStmt) Exec([]driver.Value) (driver.Result, error) {
+	if strings.Contains(s.q, "FAIL") {
+		return nil, errors.New("syntax error")
+	}
+	s.d.mu.Lock()
+	s.d.stmts = append(s.d.stmts, s.q)
+	s.d.mu.Unlock()
+	return driver.RowsAffected(1), nil
+}
+func (s recStmt) Query([]driver.Value) (driver.Rows, error) { return nil, errors.New("not supported") }
+
+var rec = &recDriver{}
+
+func init() { sql.Register("hitrec", rec) }
+
+func TestSplitSQL(t *testing.T) {
+	got := splitSQL("-- users\nINSERT INTO users\nVALUES (1, 'a;b');\n\nDELETE FROM x;\nSELECT 1")
+	want := []string{"INSERT INTO users\nVALUES (1, 'a;b');", "DELETE FROM x;", "SELECT 1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFixtures(t *testing.T) {
+	dir := t.TempDir()
+	load, undo := filepath.Join(dir, "load.sql"), filepath.Join(dir, "undo.sql")
+	os.WriteFile(load, []byte("INSERT INTO users VALUES (1);\n"), 0644)
+	os.WriteFile(undo, []byte("DELETE FROM users;\n"), 0644)
+	db, err := sql.Open("hitrec", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	rec.reset(t)
+
+	var mu sync.Mutex
+	var users []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case "POST":
+			b, _ := ioutil.ReadAll(r.Body)
+			users = append(users, string(b))
+			w.WriteHeader(201)
+		case "DELETE":
+			users = nil
+			w.WriteHeader(204)
+		case "GET":
+			w.Write([]byte(strings.Join(users, ",")))
+		}
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	seed := HTTPSeed{
+		Seed: SeedRequest{Method: "POST", Path: "/users", Body: FormBody{"name": {"joe"}}},
+		Undo: &SeedRequest{Method: "DELETE", Path: "/users"},
+	}
+	h := Hit{
+		Path:     "/users",
+		Fixtures: []Fixture{SQLFile{DB: db, Path: load, CleanupPath: undo}, seed},
+		Requests: Requests{"GET": {{Want: Response{Status: 200, Body: RawBody("name=joe")}}}},
+	}
+	rn.Test(t, h)
+
+	want := []string{"INSERT INTO users VALUES (1);", "DELETE FROM users;"}
+	if !reflect.DeepEqual(rec.stmts, want) {
+		t.Errorf("got statements %q, want %q", rec.stmts, want)
+	}
+	if len(users) != 0 {
+		t.Errorf("got users %q after cleanup, want none", users)
+	}
+
+	os.WriteFile(load, []byte("FAIL;\n"), 0644)
+	s := Scenario{Name: "broken", Fixtures: []Fixture{seed, SQLFile{DB: db, Path: load}}}
+	if err := rn.Scenario(s); err == nil || !strings.Contains(err.Error(), "syntax error") {
+		t.Errorf("got err %v, want fixture load failure", err)
+	}
+	if len(users) != 0 {
+		t.Errorf("got users %q, want the loaded seed to be cleaned up", users)
+	}
+}
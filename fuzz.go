@@ -0,0 +1,107 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strings"
+)
+
+// FuzzInputs are the values with which the fuzzer replaces the query and
+// path parameters of a Request.
+var FuzzInputs = []string{
+	"",
+	strings.Repeat("A", 8192),
+	"\x00",
+	"\r\n",
+	"\x1b[0m",
+	"-1",
+	"99999999999999999999999999",
+	"1.5e308",
+	"' OR '1'='1",
+	"1; DROP TABLE users--",
+	`"}]`,
+	"../../../etc/passwd",
+	"%00",
+	"%s%s%s%n",
+	"\u202e",
+	"\ufeff",
+	"\U0001F600",
+	"\xff\xfe",
+}
+
+// panicMarkers are substrings of bodies that look like a server crash dump.
+var panicMarkers = [][]byte{
+	[]byte("panic:"),
+	[]byte("goroutine "),
+	[]byte("Traceback (most recent call last)"),
+	[]byte("Exception in thread"),
+}
+
+// fuzz sends a copy of the request for every one of its query parameters and
+// path segments and for every one of the FuzzInputs, with that parameter's
+// value replaced by the input. It returns a failure message listing the inputs
+// that caused a 5xx response or a response whose body looks like a panic.
+func (r Request) fuzz(method, path string) (string, error) {
+	var fail string
+	for _, in := range FuzzInputs {
+		pp := append(r.pathProbes(path, in), r.queryProbes(path, in)...)
+		for _, p := range pp {
+			req, err := p.r.newRequest(method, p.path)
+			if err != nil {
+				return "", err
+			}
+			res, err := p.r.do(req)
+			if err != nil {
+				return "", fmt.Errorf("hit: %s %s failed. %v", method, p.path, err)
+			}
+			b, err := ioutil.ReadAll(res.Body)
+			res.Body.Close()
+			if err != nil {
+				return "", fmt.Errorf("hit: error reading http.Response.Body. %v", err)
+			}
+			if res.StatusCode >= 500 || isPanic(b) {
+				fail += fmt.Sprintf("StatusCode got = %s%d%s for %s = %s%q%s\n",
+					RedColor, res.StatusCode, StopColor, p.input, RedColor, in, StopColor)
+			}
+		}
+	}
+	return fail, nil
+}
+
+// pathProbes returns a copy of the receiver for every non-empty segment of
+// the specified path with that segment set to v.
+func (r Request) pathProbes(path, v string) []probe {
+	u, err := url.Parse(path)
+	if err != nil {
+		return nil
+	}
+	var pp []probe
+	segs := strings.Split(u.EscapedPath(), "/")
+	for i, s := range segs {
+		if s == "" {
+			continue
+		}
+		cp := append([]string(nil), segs...)
+		cp[i] = url.PathEscape(v)
+		p := strings.Join(cp, "/")
+		if u.RawQuery != "" {
+			p += "?" + u.RawQuery
+		}
+		pp = append(pp, probe{r, p, fmt.Sprintf("path segment #%d", i)})
+	}
+	return pp
+}
+
+func isPanic(b []byte) bool {
+	for _, m := range panicMarkers {
+		if bytes.Contains(b, m) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRequestFuzz(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/users/") {
+			w.WriteHeader(404)
+			return
+		}
+		if strings.Contains(r.URL.Path, "'") {
+			fmt.Fprint(w, "panic: runtime error: index out of range\n\ngoroutine 1 [running]:")
+			return
+		}
+		page := r.URL.Query().Get("page")
+		if len(page) > 10 {
+			w.WriteHeader(500)
+		} else if _, err := strconv.Atoi(page); err != nil {
+			w.WriteHeader(400)
+		}
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	r := Request{Want: Response{Status: 200}, Fuzz: true}
+	err := rn.Execute(r, "GET", "/users/123?page=1")
+	if err == nil {
+		t.Fatal("got err <nil>, want fuzz failure")
+	}
+	for _, want := range []string{
+		`path segment #2 = ` + RedColor + `"' OR '1'='1"`,
+		`query parameter "page" = ` + RedColor + `"99999999999999999999999999"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("got err %v, want it to contain %s", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), "path segment #1") {
+		t.Errorf("got err %v, want no failure for path segment #1", err)
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/mkopriva/hit
+
+go 1.24
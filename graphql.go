@@ -0,0 +1,145 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// GraphQL represents the expected body of a GraphQL response.
+type GraphQL struct {
+	// Data, if set, is compared to the response's data member like a
+	// JSONBody, it may contain Matchers and Modals.
+	Data interface{}
+
+	// Errors lists the errors that must be present in the response's
+	// errors member, in any order.
+	Errors []GraphQLError
+
+	// NoErrors, if set, fails the comparison if the response has any errors.
+	NoErrors bool
+}
+
+// GraphQLError represents an expected GraphQL error. Its zero fields match
+// any value.
+type GraphQLError struct {
+	// Path is the error's path with its segments joined by dots, e.g.
+	// "user.friends.0.name".
+	Path string
+	// Message is either the exact message or a Matcher.
+	Message interface{}
+	// Code is the value of the error's extensions.code member.
+	Code string
+}
+
+type graphqlError struct {
+	Message    string        `json:"message"`
+	Path       []interface{} `json:"path"`
+	Extensions struct {
+		Code interface{} `json:"code"`
+	} `json:"extensions"`
+}
+
+func (e graphqlError) path() string {
+	segs := make([]string, len(e.Path))
+	for i, s := range e.Path {
+		segs[i] = fmt.Sprint(s)
+	}
+	return strings.Join(segs, ".")
+}
+
+// Compare implements the Comparer interface.
+func (g GraphQL) Compare(r io.Reader) error {
+	var res struct {
+		Data   interface{}    `json:"data"`
+		Errors []graphqlError `json:"errors"`
+	}
+	d := json.NewDecoder(r)
+	d.UseNumber()
+	if err := d.Decode(&res); err != nil {
+		return fmt.Errorf("hit: error decoding http.Response.Body. %v", err)
+	}
+
+	var msg string
+	if g.NoErrors && len(res.Errors) > 0 {
+		msg += fmt.Sprintf("GraphQL errors got %s%s%s, want %s<none>%s\n",
+			RedColor, graphqlMessages(res.Errors), StopColor, RedColor, StopColor)
+	}
+	if g.Data != nil {
+		want, err := normalize(g.Data)
+		if err != nil {
+			return fmt.Errorf("hit: GraphQL.Data %+v, error %v", g.Data, err)
+		}
+		if err := matchJSON("/data", res.Data, want, DefaultMode); err != nil {
+			msg += fmt.Sprintf("GraphQL data got %s%#v%s, want %s%#v%s\n%v\n",
+				RedColor, res.Data, StopColor, RedColor, want, StopColor, err)
+		}
+	}
+	for _, w := range g.Errors {
+		if !w.presentIn(res.Errors) {
+			msg += fmt.Sprintf("GraphQL errors got %s%s%s, want %s%s%s\n",
+				RedColor, graphqlMessages(res.Errors), StopColor, RedColor, w, StopColor)
+		}
+	}
+
+	if msg != "" {
+		return fmt.Errorf("%s", msg)
+	}
+	return nil
+}
+
+// presentIn reports whether any of the specified errors matches the receiver.
+func (w GraphQLError) presentIn(errs []graphqlError) bool {
+	for _, e := range errs {
+		if w.Path != "" && w.Path != e.path() {
+			continue
+		}
+		if w.Code != "" && w.Code != fmt.Sprint(e.Extensions.Code) {
+			continue
+		}
+		switch m := w.Message.(type) {
+		case nil:
+		case Matcher:
+			if m.Match(e.Message) != nil {
+				continue
+			}
+		default:
+			if fmt.Sprint(m) != e.Message {
+				continue
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// String returns a description of the expected error.
+func (w GraphQLError) String() string {
+	var parts []string
+	if w.Path != "" {
+		parts = append(parts, "path="+w.Path)
+	}
+	if w.Message != nil {
+		parts = append(parts, fmt.Sprintf("message=%#v", w.Message))
+	}
+	if w.Code != "" {
+		parts = append(parts, "code="+w.Code)
+	}
+	return "[" + strings.Join(parts, " ") + "]"
+}
+
+func graphqlMessages(errs []graphqlError) string {
+	var parts []string
+	for _, e := range errs {
+		s := e.path() + ": " + e.Message
+		if e.Extensions.Code != nil {
+			s += fmt.Sprintf(" (%v)", e.Extensions.Code)
+		}
+		parts = append(parts, s)
+	}
+	return fmt.Sprintf("%q", parts)
+}
@@ -0,0 +1,41 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGraphQL(t *testing.T) {
+	const ok = `{"data":{"user":{"id":"1","name":"joe","friends":[{"name":"ann"}]}}}`
+	const partial = `{"data":{"user":{"id":"1","name":null}},"errors":[` +
+		`{"message":"not authorized","path":["user","name"],"extensions":{"code":"FORBIDDEN"}},` +
+		`{"message":"rate limited","path":["user","friends",0]}]}`
+
+	tests := []struct {
+		want GraphQL
+		body string
+		err  string
+	}{
+		{GraphQL{Data: Partial(JSONBody{"user": JSONBody{"id": "1", "name": "joe"}}), NoErrors: true}, ok, ""},
+		{GraphQL{Data: JSONBody{"user": JSONBody{"id": NotEmpty(), "name": "joe", "friends": Len(1)}}}, ok, ""},
+		{GraphQL{Data: JSONBody{"user": JSONBody{"id": "2"}}}, ok, "/data/user/id"},
+		{GraphQL{NoErrors: true}, partial, "want " + RedColor + "<none>"},
+		{GraphQL{Errors: []GraphQLError{{Path: "user.name", Code: "FORBIDDEN"}}}, partial, ""},
+		{GraphQL{Errors: []GraphQLError{{Path: "user.friends.0", Message: "rate limited"}}}, partial, ""},
+		{GraphQL{Errors: []GraphQLError{{Message: OneOf("not authorized")}}}, partial, ""},
+		{GraphQL{Errors: []GraphQLError{{Path: "user.name", Code: "NOT_FOUND"}}}, partial, "code=NOT_FOUND"},
+		{GraphQL{Errors: []GraphQLError{{Path: "user"}}}, ok, "path=user"},
+		{GraphQL{}, `{"data":`, "error decoding"},
+	}
+	for i, tt := range tests {
+		err := tt.want.Compare(strings.NewReader(tt.body))
+		if tt.err == "" && err != nil {
+			t.Errorf("#%d: got err %v, want <nil>", i, err)
+		} else if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("#%d: got err %v, want err containing %q", i, err, tt.err)
+		}
+	}
+}
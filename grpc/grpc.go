@@ -0,0 +1,341 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+
+// Package grpc tests unary gRPC endpoints in the table driven style of package
+// hit. Requests are sent over HTTP/2 to the target of a hit.Runner, or of
+// hit.DefaultRunner, without TLS unless the target is an https URL. The
+// response messages are compared as JSON using hit's Comparers and Matchers
+// and the failures are hit's typed errors, reported like those of package
+// hit's Requests.
+package grpc
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mkopriva/hit"
+)
+
+// Codec marshals and unmarshals messages, e.g. a thin wrapper around the
+// protobuf package's Marshal and Unmarshal.
+type Codec interface {
+	// Name returns the content-subtype of the codec, e.g. "proto".
+	Name() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// JSONCodec is a Codec that uses encoding/json, it requires the server to
+// have a codec with the "json" content-subtype registered.
+type JSONCodec struct{}
+
+func (JSONCodec) Name() string                               { return "json" }
+func (JSONCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+// DefaultCodec is used by Requests that don't have a Codec of their own.
+var DefaultCodec Codec = JSONCodec{}
+
+// Call represents a bunch of test requests against a specific gRPC method.
+type Call struct {
+	// the full method name, e.g. "/helloworld.Greeter/SayHello"
+	Method string
+
+	// the requests to be made to the above specified method
+	Requests []Request
+}
+
+// Test executes all of the Call's Requests.
+func (c Call) Test(t *testing.T) {
+	c.TestWith(t, hit.DefaultRunner())
+}
+
+// TestWith executes all of the Call's Requests against the target of the
+// specified Runner.
+func (c Call) TestWith(t *testing.T, rn *hit.Runner) {
+	skipped := 0
+	for _, r := range c.Requests {
+		if r.Skip {
+			skipped++
+			continue
+		}
+		if err := r.ExecuteWith(rn, c.Method); err != nil {
+			t.Error(err)
+		}
+	}
+	if skipped > 0 {
+		log.Printf("Warning: Skipped %d test(s) for %q.", skipped, c.Method)
+	}
+}
+
+// Request represents a unary gRPC request with its expected response.
+type Request struct {
+	Skip bool
+
+	// Metadata is sent as the request's headers.
+	Metadata hit.Header
+	// Message is the request message, it's marshaled with the Codec.
+	Message interface{}
+	Want    Response
+
+	// Codec, if set, is used in place of DefaultCodec.
+	Codec Codec
+	// NewResponse returns a new response message into which the response
+	// is unmarshaled. If nil, the response is unmarshaled into an
+	// interface{}, which works only with JSON based codecs.
+	NewResponse func() interface{}
+}
+
+// Response represents the expected response to a gRPC request.
+type Response struct {
+	// Code is the expected status code.
+	Code Code
+	// StatusMessage, if set, must match the grpc-message of the response.
+	StatusMessage hit.Matcher
+
+	// Metadata holds the response headers, and Trailer the response
+	// trailers, that must be present in the response.
+	Metadata hit.Header
+	Trailer  hit.Header
+
+	// Message, if set, is compared to the JSON encoding of the response
+	// message, e.g. a hit.JSONBody.
+	Message hit.Comparer
+}
+
+// Execute sends the receiver's Message to the specified method and compares
+// the response to the receiver's Want.
+func (r Request) Execute(method string) error {
+	return r.ExecuteWith(hit.DefaultRunner(), method)
+}
+
+// ExecuteWith is like Execute but it sends the Message to the target of the
+// specified Runner. Its failures are reported by the Runner, i.e. they're
+// redacted and written to hit.Events like those of a hit.Request.
+func (r Request) ExecuteWith(rn *hit.Runner, method string) error {
+	ll, _, err := r.call(rn, method)
+	if err == nil && len(ll) > 0 {
+		err = r.failure(method, ll)
+	}
+	return rn.Report(method, "POST", method, err)
+}
+
+// call sends the receiver's Message to the specified method and compares the
+// response to the receiver's Want. It returns the failures, if any, and the
+// JSON encoding of the response message, if there was one.
+func (r Request) call(rn *hit.Runner, method string) (ll hit.ErrorList, msg []byte, err error) {
+	codec := r.Codec
+	if codec == nil {
+		codec = DefaultCodec
+	}
+	req, err := r.newRequest(rn, method, codec)
+	if err != nil {
+		return nil, nil, err
+	}
+	res, err := clientOf(rn).Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("hit/grpc: %s failed. %w", method, err)
+	}
+	defer res.Body.Close()
+
+	var raw []byte
+	if res.StatusCode != http.StatusOK {
+		ll = append(ll, &hit.StatusError{Got: res.StatusCode, Want: http.StatusOK})
+	} else if raw, err = readMessage(res.Body); err != nil {
+		return nil, nil, fmt.Errorf("hit/grpc: %s failed reading the response. %w", method, err)
+	}
+	cl, msg := r.Want.compare(res, raw, codec, r.NewResponse)
+	return append(ll, cl...), msg, nil
+}
+
+// failure returns a *hit.RequestError describing the request to the specified
+// method followed by its failures.
+func (r Request) failure(method string, ll hit.ErrorList) error {
+	desc := fmt.Sprintf(" %s%s%s Metadata: %s%v%s Message: %s%+v%s",
+		hit.YellowColor, method, hit.StopColor,
+		hit.YellowColor, r.Metadata, hit.StopColor,
+		hit.YellowColor, r.Message, hit.StopColor)
+	return hit.NewRequestError("POST", method, desc, ll...)
+}
+
+func (r Request) newRequest(rn *hit.Runner, method string, codec Codec) (*http.Request, error) {
+	b, err := codec.Marshal(r.Message)
+	if err != nil {
+		return nil, fmt.Errorf("hit/grpc: failed marshaling %+v. %v", r.Message, err)
+	}
+	frame := make([]byte, 5, 5+len(b))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(b)))
+	frame = append(frame, b...)
+
+	req, err := http.NewRequest("POST", rn.URL(method), bytes.NewReader(frame))
+	if err != nil {
+		return nil, fmt.Errorf("hit/grpc: failed http.NewRequest for %s. %v", method, err)
+	}
+	if r.Metadata != nil {
+		r.Metadata.AddTo(req)
+	}
+	req.Header.Set("Content-Type", "application/grpc+"+codec.Name())
+	req.Header.Set("TE", "trailers")
+	return req, nil
+}
+
+// compare compares the response, whose message was already read, to the
+// receiver and returns the failures, if any, and the JSON encoding of the
+// message.
+func (w Response) compare(res *http.Response, msg []byte, codec Codec, newResponse func() interface{}) (ll hit.ErrorList, js []byte) {
+	add := func(err error) {
+		if l, ok := err.(hit.ErrorList); ok {
+			ll = append(ll, l...)
+		} else if err != nil {
+			ll = append(ll, err)
+		}
+	}
+
+	// a response without a message may carry its status in its headers
+	status, message := res.Trailer.Get("Grpc-Status"), res.Trailer.Get("Grpc-Message")
+	if status == "" {
+		status, message = res.Header.Get("Grpc-Status"), res.Header.Get("Grpc-Message")
+	}
+	code := Unknown
+	if n, err := strconv.ParseUint(status, 10, 32); err == nil {
+		code = Code(n)
+	}
+	if code != w.Code {
+		add(&CodeError{Got: code, Want: w.Code})
+	}
+	if w.StatusMessage != nil {
+		if err := w.StatusMessage.Match(decodeMessage(message)); err != nil {
+			add(fmt.Errorf("StatusMessage %s\n", strings.TrimSuffix(err.Error(), "\n")))
+		}
+	}
+	if w.Metadata != nil {
+		add(w.Metadata.Compare(res.Header))
+	}
+	if w.Trailer != nil {
+		add(w.Trailer.Compare(res.Trailer))
+	}
+
+	if msg != nil {
+		var v interface{}
+		if newResponse != nil {
+			v = newResponse()
+		} else {
+			v = new(interface{})
+		}
+		if err := codec.Unmarshal(msg, v); err != nil {
+			add(fmt.Errorf("hit/grpc: failed unmarshaling the response message. %w\n", err))
+		} else if js, err = json.Marshal(v); err != nil {
+			add(fmt.Errorf("hit/grpc: failed marshaling %+v to JSON. %w\n", v, err))
+		}
+	}
+	if w.Message != nil {
+		if msg == nil {
+			add(fmt.Errorf("Message got %s<none>%s\n", hit.RedColor, hit.StopColor))
+		} else if js != nil {
+			add(w.Message.Compare(bytes.NewReader(js)))
+		}
+	}
+	return ll, js
+}
+
+// readMessage reads a single length-prefixed message from the specified
+// reader and the rest of the stream. It returns nil if there's no message.
+func readMessage(r io.Reader) ([]byte, error) {
+	var prefix [5]byte
+	if _, err := io.ReadFull(r, prefix[:]); err == io.EOF {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if prefix[0] != 0 {
+		return nil, errors.New("compressed messages are not supported")
+	}
+	msg := make([]byte, binary.BigEndian.Uint32(prefix[1:]))
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+	// read to the end so that the trailers are available
+	if _, err := io.Copy(ioutil.Discard, r); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// decodeMessage decodes the percent-encoded grpc-message value.
+func decodeMessage(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' && i+2 < len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+3], 16, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 2
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// transports holds the HTTP/2 transports by their transportKey, so that the
+// Runners with the same configuration share connections.
+var transports sync.Map
+
+// transportKey identifies a transport by whether it uses TLS and by its TLS
+// configuration.
+type transportKey struct {
+	tls bool
+	cfg *tls.Config
+}
+
+// clientOf returns the client that sends the requests of the Runner, over
+// HTTP/2 with prior knowledge without TLS, over HTTP/2 with TLS if the
+// Runner's target is an https URL, or by the Runner's Transport if it has
+// one.
+func clientOf(rn *hit.Runner) *http.Client {
+	if rn.Transport != nil {
+		return &http.Client{Transport: rn.Transport, Timeout: rn.Timeout}
+	}
+	cfg := rn.TLS
+	if rn.Profile != nil && rn.Profile.TLS != nil {
+		cfg = rn.Profile.TLS
+	}
+	key := transportKey{}
+	if strings.HasPrefix(rn.URL(""), "https:") {
+		key = transportKey{true, cfg}
+	}
+	tr, ok := transports.Load(key)
+	if !ok {
+		tr, _ = transports.LoadOrStore(key, newTransport(key))
+	}
+	return &http.Client{Transport: tr.(*http.Transport), Timeout: rn.Timeout}
+}
+
+// newTransport returns an HTTP/2 only transport.
+func newTransport(key transportKey) *http.Transport {
+	tr := &http.Transport{DialContext: (&net.Dialer{}).DialContext}
+	tr.Protocols = new(http.Protocols)
+	if key.tls {
+		if key.cfg != nil {
+			tr.TLSClientConfig = key.cfg.Clone()
+		}
+		tr.Protocols.SetHTTP2(true)
+	} else {
+		tr.Protocols.SetUnencryptedHTTP2(true)
+	}
+	return tr
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package grpc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mkopriva/hit"
+)
+
+// greeter is a minimal gRPC server with a JSON codec.
+func greeter(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/grpc+json")
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+	if r.ProtoMajor != 2 || r.Header.Get("Content-Type") != "application/grpc+json" {
+		w.WriteHeader(415)
+		return
+	}
+	if r.URL.Path != "/helloworld.Greeter/SayHello" {
+		w.Header().Set("Grpc-Status", "12")
+		return
+	}
+	var prefix [5]byte
+	io.ReadFull(r.Body, prefix[:])
+	b := make([]byte, binary.BigEndian.Uint32(prefix[1:]))
+	io.ReadFull(r.Body, b)
+	var req struct{ Name string }
+	json.Unmarshal(b, &req)
+	if req.Name == "" {
+		w.Header().Set("Grpc-Status", "3")
+		w.Header().Set("Grpc-Message", "name%20is%20required")
+		return
+	}
+	w.Header().Set("X-Greeter", "1")
+	out, _ := json.Marshal(map[string]interface{}{"message": "Hello " + req.Name, "count": 1})
+	binary.BigEndian.PutUint32(prefix[1:], uint32(len(out)))
+	w.Write(prefix[:])
+	w.Write(out)
+	w.Header().Set("Grpc-Status", "0")
+}
+
+func TestCall(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(greeter))
+	ts.Config.Protocols = new(http.Protocols)
+	ts.Config.Protocols.SetHTTP1(true)
+	ts.Config.Protocols.SetUnencryptedHTTP2(true)
+	ts.Start()
+	defer ts.Close()
+	rn := hit.NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	c := Call{Method: "/helloworld.Greeter/SayHello", Requests: []Request{{
+		Message: map[string]string{"name": "World"},
+		Want: Response{
+			Code:     OK,
+			Metadata: hit.Header{"X-Greeter": {"1"}},
+			Message:  hit.JSONBody{"message": "Hello World", "count": hit.GT(0)},
+		},
+	}, {
+		Message: map[string]string{},
+		Want: Response{
+			Code:          InvalidArgument,
+			StatusMessage: hit.OneOf("name is required"),
+		},
+	}}}
+	c.TestWith(t, rn)
+
+	tests := []struct {
+		method string
+		r      Request
+		want   string
+	}{
+		{"/helloworld.Greeter/SayBye", Request{Want: Response{Code: OK}}, "Code got = " + hit.RedColor + "Unimplemented"},
+		{"/helloworld.Greeter/SayHello", Request{Message: map[string]string{"name": "Joe"}, Want: Response{
+			Message: hit.JSONBody{"message": "Hello World"},
+		}}, "Body /message"},
+		{"/helloworld.Greeter/SayHello", Request{Message: map[string]string{"name": "Joe"}, Want: Response{
+			Trailer: hit.Header{"Grpc-Status": {"1"}},
+		}}, `Header["Grpc-Status"]`},
+	}
+	for i, tt := range tests {
+		err := tt.r.ExecuteWith(rn, tt.method)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("#%d: got err %v, want err containing %q", i, err, tt.want)
+		}
+	}
+
+	// the failures are typed and reported like those of package hit
+	var buf bytes.Buffer
+	rn.Events = &buf
+	err := Request{Want: Response{Code: OK}}.ExecuteWith(rn, "/helloworld.Greeter/SayBye")
+	var re *hit.RequestError
+	var ce *CodeError
+	if !errors.As(err, &re) || re.Path != "/helloworld.Greeter/SayBye" || !errors.As(err, &ce) || ce.Got != Unimplemented {
+		t.Errorf("got err %#v, want a *hit.RequestError with a *CodeError", err)
+	}
+	if !strings.Contains(buf.String(), `"Code got = Unimplemented, want OK"`) {
+		t.Errorf("got events %q, want the code failure", buf.String())
+	}
+}
+
+func TestCodeString(t *testing.T) {
+	if got := Unauthenticated.String(); got != "Unauthenticated" {
+		t.Errorf("got %q, want %q", got, "Unauthenticated")
+	}
+	if got := Code(42).String(); got != "Code(42)" {
+		t.Errorf("got %q, want %q", got, "Code(42)")
+	}
+}
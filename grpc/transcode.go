@@ -0,0 +1,263 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package grpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mkopriva/hit"
+)
+
+// Dual represents a logical test case for a method that's exposed both over
+// gRPC and, transcoded to JSON, over plain HTTP, e.g. by grpc-gateway.
+type Dual struct {
+	// Method is the gRPC method to which the Request is sent, its
+	// response is compared to the Request's Want as usual.
+	Method  string
+	Request Request
+
+	// HTTPMethod and Path identify the transcoded HTTP endpoint.
+	HTTPMethod string
+	Path       string
+	// HTTPBody, if set, is sent to the HTTP endpoint. If not set, methods
+	// other than GET, HEAD and DELETE send the JSON encoding of the
+	// Request's Message.
+	HTTPBody hit.Bodyer
+}
+
+// Test executes the Dual.
+func (d Dual) Test(t *testing.T) {
+	if err := d.Execute(); err != nil {
+		t.Error(err)
+	}
+}
+
+// Execute calls the gRPC method and the HTTP endpoint and checks that the
+// HTTP status corresponds to the gRPC code, and that the transcoded JSON
+// response is semantically equal to the gRPC response message. Field names
+// are compared ignoring case and underscores, numbers are equal to strings
+// that hold the same number and missing fields are equal to zero values,
+// as per the proto3 JSON mapping.
+func (d Dual) Execute() error {
+	return d.ExecuteWith(hit.DefaultRunner())
+}
+
+// ExecuteWith is like Execute but it calls the method and the endpoint of
+// the target of the specified Runner. Its failures are reported by the
+// Runner, i.e. they're redacted and written to hit.Events like those of a
+// hit.Request.
+func (d Dual) ExecuteWith(rn *hit.Runner) error {
+	ll, want, err := d.Request.call(rn, d.Method)
+	if err == nil && len(ll) > 0 {
+		return rn.Report(d.Method, "POST", d.Method, d.Request.failure(d.Method, ll))
+	}
+	if err == nil {
+		err = d.transcoded(rn, want)
+	}
+	return rn.Report(d.HTTPMethod+" "+d.Path, d.HTTPMethod, d.Path, err)
+}
+
+// transcoded calls the HTTP endpoint and compares its response to the JSON
+// encoding of the gRPC response message want.
+func (d Dual) transcoded(rn *hit.Runner, want []byte) error {
+	req, err := d.newRequest(rn)
+	if err != nil {
+		return err
+	}
+	res, err := rn.Client().Do(req)
+	if err != nil {
+		return fmt.Errorf("hit/grpc: %s %s failed. %w", d.HTTPMethod, d.Path, err)
+	}
+	got, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return fmt.Errorf("hit/grpc: error reading http.Response.Body. %w", err)
+	}
+
+	var ll hit.ErrorList
+	status := d.Request.Want.Code.HTTPStatus()
+	if res.StatusCode != status {
+		ll = append(ll, &hit.StatusError{Got: res.StatusCode, Want: status})
+	} else if want != nil {
+		gv, err1 := decodeJSON(got)
+		wv, err2 := decodeJSON(want)
+		if err1 != nil {
+			ll = append(ll, fmt.Errorf("hit/grpc: failed decoding the HTTP response body %q. %w\n", got, err1))
+		} else if err2 != nil {
+			ll = append(ll, fmt.Errorf("hit/grpc: failed decoding the gRPC response %q. %w\n", want, err2))
+		} else if diffs := transcodedDiff("", gv, wv); len(diffs) > 0 {
+			ll = append(ll, &hit.BodyDiffError{Got: gv, Want: wv, Diffs: diffs})
+		}
+	}
+
+	if len(ll) > 0 {
+		desc := fmt.Sprintf(" %s%s %s%s (transcoding %s)", hit.YellowColor, d.HTTPMethod, d.Path, hit.StopColor, d.Method)
+		return hit.NewRequestError(d.HTTPMethod, d.Path, desc, ll...)
+	}
+	return nil
+}
+
+func (d Dual) newRequest(rn *hit.Runner) (*http.Request, error) {
+	var body io.Reader
+	ctype := ""
+	if d.HTTPBody != nil {
+		b, err := d.HTTPBody.Body()
+		if err != nil {
+			return nil, err
+		}
+		body, ctype = b, d.HTTPBody.Type()
+	} else if m := d.HTTPMethod; m != "GET" && m != "HEAD" && m != "DELETE" {
+		b, err := json.Marshal(d.Request.Message)
+		if err != nil {
+			return nil, fmt.Errorf("hit/grpc: failed marshaling %+v. %v", d.Request.Message, err)
+		}
+		body, ctype = bytes.NewReader(b), "application/json"
+	}
+	req, err := http.NewRequest(d.HTTPMethod, rn.URL(d.Path), body)
+	if err != nil {
+		return nil, fmt.Errorf("hit/grpc: failed http.NewRequest(%q, %q). %v", d.HTTPMethod, d.Path, err)
+	}
+	if d.Request.Metadata != nil {
+		d.Request.Metadata.AddTo(req)
+	}
+	if ctype != "" {
+		req.Header.Set("Content-Type", ctype)
+	}
+	return req, nil
+}
+
+func decodeJSON(b []byte) (interface{}, error) {
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	var v interface{}
+	err := d.Decode(&v)
+	return v, err
+}
+
+// transcodedDiff returns the semantic differences between the transcoded
+// JSON value got and the gRPC response value want.
+func transcodedDiff(path string, got, want interface{}) []hit.BodyDiff {
+	if isZero(got) && isZero(want) {
+		return nil
+	}
+	switch w := want.(type) {
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok {
+			break
+		}
+		gk, wk := fieldKeys(g), fieldKeys(w)
+		keys := make(map[string]bool)
+		for k := range gk {
+			keys[k] = true
+		}
+		for k := range wk {
+			keys[k] = true
+		}
+		var sorted []string
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+		var diffs []hit.BodyDiff
+		for _, k := range sorted {
+			name := wk[k]
+			if name == "" {
+				name = gk[k]
+			}
+			diffs = append(diffs, transcodedDiff(path+"/"+name, g[gk[k]], w[wk[k]])...)
+		}
+		return diffs
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok || len(g) != len(w) {
+			break
+		}
+		var diffs []hit.BodyDiff
+		for i := range w {
+			diffs = append(diffs, transcodedDiff(path+"/"+strconv.Itoa(i), g[i], w[i])...)
+		}
+		return diffs
+	default:
+		if scalarEqual(got, want) {
+			return nil
+		}
+	}
+	if path == "" {
+		path = "/"
+	}
+	gb, _ := json.Marshal(got)
+	wb, _ := json.Marshal(want)
+	return []hit.BodyDiff{{Path: path, Msg: fmt.Sprintf("got %s, want %s", gb, wb)}}
+}
+
+// fieldKeys maps the normalized field names of m to the field names, so that
+// "user_id" and "userId" are treated as the same field.
+func fieldKeys(m map[string]interface{}) map[string]string {
+	keys := make(map[string]string, len(m))
+	for k := range m {
+		keys[strings.ToLower(strings.Replace(k, "_", "", -1))] = k
+	}
+	return keys
+}
+
+// scalarEqual reports whether the two scalar JSON values are equal, numbers
+// are equal to strings holding the same number, e.g. int64 values.
+func scalarEqual(a, b interface{}) bool {
+	if a == b {
+		return true
+	}
+	_, an := a.(json.Number)
+	_, bn := b.(json.Number)
+	if !an && !bn {
+		return false
+	}
+	af, aok := number(a)
+	bf, bok := number(b)
+	return aok && bok && af == bf
+}
+
+func number(v interface{}) (float64, bool) {
+	var s string
+	switch n := v.(type) {
+	case json.Number:
+		s = string(n)
+	case string:
+		s = n
+	default:
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	return f, err == nil
+}
+
+// isZero reports whether v is the JSON representation of a zero value, which
+// proto3 allows to be omitted.
+func isZero(v interface{}) bool {
+	switch x := v.(type) {
+	case nil:
+		return true
+	case bool:
+		return !x
+	case string:
+		return x == ""
+	case json.Number:
+		f, err := x.Float64()
+		return err == nil && f == 0
+	case []interface{}:
+		return len(x) == 0
+	case map[string]interface{}:
+		return len(x) == 0
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package grpc
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mkopriva/hit"
+)
+
+func TestDual(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 {
+			greeter(w, r)
+			return
+		}
+		// the transcoded endpoint
+		name := strings.TrimPrefix(r.URL.Path, "/v1/hello/")
+		switch {
+		case name == "":
+			w.WriteHeader(400)
+		case name == "Bug":
+			w.Write([]byte(`{"message":"Hello bug","count":"1"}`))
+		default:
+			w.Write([]byte(`{"message":"Hello ` + name + `","count":"1","extra_field":[]}`))
+		}
+	}))
+	ts.Config.Protocols = new(http.Protocols)
+	ts.Config.Protocols.SetHTTP1(true)
+	ts.Config.Protocols.SetUnencryptedHTTP2(true)
+	ts.Start()
+	defer ts.Close()
+	rn := hit.NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	dual := func(name string, code Code) Dual {
+		return Dual{
+			Method:     "/helloworld.Greeter/SayHello",
+			Request:    Request{Message: map[string]string{"name": name}, Want: Response{Code: code}},
+			HTTPMethod: "GET",
+			Path:       "/v1/hello/" + name,
+		}
+	}
+	for _, d := range []Dual{dual("World", OK), dual("", InvalidArgument)} {
+		if err := d.ExecuteWith(rn); err != nil {
+			t.Error(err)
+		}
+	}
+
+	err := dual("Bug", OK).ExecuteWith(rn)
+	if !errors.Is(err, &hit.BodyDiffError{}) || !strings.Contains(err.Error(), "Body /message: ") {
+		t.Errorf("got err %v, want /message difference", err)
+	}
+}
+
+func TestTranscodedDiff(t *testing.T) {
+	tests := []struct {
+		got, want string
+		diffs     int
+	}{
+		{`{"userId":"12","name":"a"}`, `{"user_id":12,"name":"a"}`, 0},
+		{`{"name":"a"}`, `{"name":"a","count":0,"tags":[],"ok":false}`, 0},
+		{`{"name":"a","count":1}`, `{"name":"a"}`, 1},
+		{`{"items":[{"v":"1"},{"v":"2"}]}`, `{"items":[{"v":1},{"v":3}]}`, 1},
+		{`{"v":"007"}`, `{"v":"7"}`, 1},
+		{`[1,2]`, `[1]`, 1},
+	}
+	for i, tt := range tests {
+		g, _ := decodeJSON([]byte(tt.got))
+		w, _ := decodeJSON([]byte(tt.want))
+		if diffs := transcodedDiff("", g, w); len(diffs) != tt.diffs {
+			t.Errorf("#%d: got diffs %q, want %d", i, diffs, tt.diffs)
+		}
+	}
+}
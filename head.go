@@ -0,0 +1,154 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// headIgnore lists the headers that may legitimately differ between the
+// responses to a GET and a HEAD request, the HEAD request closes its
+// connection while the GET request keeps it alive.
+var headIgnore = map[string]bool{"Date": true, "Connection": true, "Keep-Alive": true}
+
+// CheckHEAD issues both a GET and a HEAD request to the specified path, with
+// the specified header, and checks that the HEAD response has the same status
+// and headers as the GET response and that it has no body.
+//
+// The HEAD request is written directly to a connection, of its own and over
+// TLS for https, so that a body sent by a misbehaving handler can be detected,
+// the http.Client would silently ignore it. The connection is dialed with the
+// package's Resolve but not with a Config's Transport.
+func CheckHEAD(path string, header Header) error {
+	return globalRunner().CheckHEAD(path, header)
+}
+
+// CheckHEAD is like the package's CheckHEAD but it checks the Runner's target.
+func (rn *Runner) CheckHEAD(path string, header Header) error {
+	req, err := http.NewRequest("GET", rn.baseURL()+path, nil)
+	if err != nil {
+		return err
+	}
+	if header != nil {
+		header.AddTo(req)
+	}
+	get, err := rn.httpClient().Do(req)
+	if err != nil && !isRedirectError(err) {
+		return fmt.Errorf("hit: GET %s failed. %v", path, err)
+	}
+	ioutil.ReadAll(get.Body)
+	get.Body.Close()
+
+	head, body, err := rn.rawHEAD(req)
+	if err != nil {
+		return fmt.Errorf("hit: HEAD %s failed. %v", path, err)
+	}
+
+	var msg string
+	if head.StatusCode != get.StatusCode {
+		msg += fmt.Sprintf("HEAD StatusCode got = %s%d%s, want %s%d%s\n",
+			RedColor, head.StatusCode, StopColor, RedColor, get.StatusCode, StopColor)
+	}
+	keys := make(map[string]bool)
+	for k := range get.Header {
+		keys[k] = true
+	}
+	for k := range head.Header {
+		keys[k] = true
+	}
+	var names []string
+	for k := range keys {
+		if !headIgnore[k] {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		if got, want := head.Header[k], get.Header[k]; !reflect.DeepEqual(got, want) {
+			msg += fmt.Sprintf("HEAD Header[%q] got = %s%q%s, want = %s%q%s\n",
+				k, RedColor, rn.Redactions.redactHeaderValues(k, got), StopColor, RedColor, rn.Redactions.redactHeaderValues(k, want), StopColor)
+		}
+	}
+	if len(body) > 0 {
+		msg += fmt.Sprintf("HEAD Body got %s%q%s, want %s<empty>%s\n",
+			RedColor, body, StopColor, RedColor, StopColor)
+	}
+	if msg != "" {
+		return fmt.Errorf(" %sHEAD %s%s Header: %s%v%s\n%s",
+			YellowColor, path, StopColor, YellowColor, rn.Redactions.redactHeaders(http.Header(header)), StopColor, msg)
+	}
+	return nil
+}
+
+// rawHEAD sends a HEAD copy of the specified request over a new connection,
+// dialed as the Runner's transport would, and returns the response together
+// with any bytes that the server sent after the response header.
+func (rn *Runner) rawHEAD(req *http.Request) (*http.Response, []byte, error) {
+	addr := req.URL.Host
+	if req.URL.Port() == "" {
+		port := "80"
+		if req.URL.Scheme == "https" {
+			port = "443"
+		}
+		addr = net.JoinHostPort(req.URL.Hostname(), port)
+	}
+	if a, ok := Resolve[addr]; ok {
+		addr = a
+	}
+	conn, err := net.DialTimeout("tcp", addr, 10*time.Second)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer conn.Close()
+	if req.URL.Scheme == "https" {
+		cfg := &tls.Config{}
+		if rn.Profile != nil && rn.Profile.TLS != nil {
+			cfg = rn.Profile.TLS.Clone()
+		} else if rn.TLS != nil {
+			cfg = rn.TLS.Clone()
+		}
+		if cfg.ServerName == "" {
+			cfg.ServerName = req.URL.Hostname()
+		}
+		// the request is written in HTTP/1.1
+		cfg.NextProtos = []string{"http/1.1"}
+		tc := tls.Client(conn, cfg)
+		tc.SetDeadline(time.Now().Add(10 * time.Second))
+		if err := tc.Handshake(); err != nil {
+			return nil, nil, err
+		}
+		conn = tc
+	}
+
+	head := req.Clone(req.Context())
+	head.Method = "HEAD"
+	head.Close = true
+	if err := head.Write(conn); err != nil {
+		return nil, nil, err
+	}
+
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	raw, err := ioutil.ReadAll(conn)
+	if err != nil && len(raw) == 0 {
+		return nil, nil, err
+	}
+	res, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(raw)), head)
+	if err != nil {
+		return nil, nil, err
+	}
+	var body []byte
+	if i := bytes.Index(raw, []byte("\r\n\r\n")); i >= 0 {
+		body = raw[i+4:]
+	}
+	return res, body, nil
+}
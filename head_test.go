@@ -0,0 +1,100 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCheckHEAD(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"foo":"bar"}`)
+	})
+	mux.HandleFunc("/bad", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "HEAD" {
+			w.WriteHeader(405)
+			return
+		}
+		w.Header().Set("X-Foo", "bar")
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	if err := rn.CheckHEAD("/ok", nil); err != nil {
+		t.Errorf("got err %v, want <nil>", err)
+	}
+	err := rn.CheckHEAD("/bad", Header{"Accept": {"*/*"}})
+	for _, want := range []string{"HEAD StatusCode got = " + RedColor + "405", `HEAD Header["X-Foo"]`} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("got err %v, want err containing %q", err, want)
+		}
+	}
+}
+
+func TestCheckHEADBody(t *testing.T) {
+	// net/http's server drops bodies written in response to HEAD
+	// requests so a raw listener is used to simulate one that doesn't
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				req, err := http.ReadRequest(bufio.NewReader(conn))
+				if err != nil {
+					return
+				}
+				fmt.Fprint(conn, "HTTP/1.1 200 OK\r\nContent-Length: 2\r\nConnection: close\r\n\r\n")
+				if req.Method != "HEAD" || req.URL.Path == "/bad" {
+					fmt.Fprint(conn, "hi")
+				}
+			}(conn)
+		}
+	}()
+	rn := NewRunner()
+	rn.Addr = ln.Addr().String()
+
+	if err := rn.CheckHEAD("/ok", nil); err != nil {
+		t.Errorf("got err %v, want <nil>", err)
+	}
+	if err := rn.CheckHEAD("/bad", nil); err == nil || !strings.Contains(err.Error(), "HEAD Body got") {
+		t.Errorf("got err %v, want body error", err)
+	}
+}
+
+func TestCheckHEADTLS(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// like nginx, echo the keep-alive of the connection
+		if !r.Close {
+			w.Header().Set("Keep-Alive", "timeout=5")
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprint(w, "hi")
+	}))
+	defer ts.Close()
+
+	rn := NewRunner()
+	rn.BaseURL = ts.URL
+	rn.TLS = ts.Client().Transport.(*http.Transport).TLSClientConfig
+	if err := rn.CheckHEAD("/", nil); err != nil {
+		t.Errorf("got err %v, want <nil>", err)
+	}
+}
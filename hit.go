@@ -5,25 +5,64 @@ package hit
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
+	"mime"
 	"mime/multipart"
+	"net"
 	"net/http"
 	"net/textproto"
 	"net/url"
-	"reflect"
+	"sort"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 var (
 	// Addr is the TCP network address used to construct requests. The user
 	// is free to set it to any other address value they want to test.
 	Addr = "localhost:3456"
+
+	// RateLimitRetries is the maximum number of times a request that got
+	// a 429 Too Many Requests response is retried. Before each retry the
+	// time specified by the response's Retry-After header is waited out.
+	RateLimitRetries = 0
+
+	// RateLimitMaxWait caps the time waited before a single retry of a
+	// rate limited request.
+	RateLimitMaxWait = time.Minute
+
+	// MaxBodySize, if greater than 0, is the maximum number of bytes of
+	// a response body that are read, reading past it fails the test. It
+	// protects the test process from runaway responses. It can be
+	// overridden per Response.
+	MaxBodySize int64 = 0
+
+	// BodyReadTimeout, if greater than 0, is the maximum time allowed for
+	// reading a response body, measured from the moment the response's
+	// header is received. It can be overridden per Response.
+	BodyReadTimeout time.Duration = 0
+
+	// BaselineHeaders lists the response headers that are always allowed
+	// by a Response with StrictHeader set.
+	BaselineHeaders = []string{"Date", "Content-Length", "Connection", "Keep-Alive"}
+
+	// Transport, if set, is used to send the requests in place of
+	// http.DefaultTransport, e.g. a Chaos transport.
+	Transport http.RoundTripper
+
+	// Resolve maps "host:port" addresses to the addresses that are dialed
+	// in their place, e.g. {"api.example.com:80": "127.0.0.1:3456"}. It
+	// pins names to addresses without touching DNS, the requests keep
+	// their original Host. It's not used by a custom Transport.
+	Resolve map[string]string
 )
 
 const (
@@ -42,26 +81,45 @@ type Hit struct {
 
 	// the requests to be made to the above specified endpoint
 	Requests Requests
+
+	// Host, if set, is sent as the Host header of those Requests that
+	// don't have a Host of their own.
+	Host string
+
+	// Signer, if set, is used to sign those Requests that don't
+	// have a Signer of their own.
+	Signer Signer
+
+	// RateLimit, if set, is used to check the rate limit headers of
+	// the responses to those Requests that don't have one of their own.
+	RateLimit *RateLimit
+
+	// Security, if set, is used to audit the security headers of the
+	// responses to those Requests that don't have a policy of their own.
+	Security *SecurityPolicy
+
+	// Fixtures are loaded, in order, before the Requests are executed and
+	// cleaned up, in reverse order, afterwards.
+	Fixtures []Fixture
+
+	// Config, if set, configures the Runner that executes the Requests in
+	// place of the package's variables, e.g. a DefaultConfig with some of
+	// its fields overridden.
+	Config *Config
 }
 
 // Test executes all of the Hit's Requests.
 func (h Hit) Test(t *testing.T) {
-	skipped := 0
-	for m, rr := range h.Requests {
-		for _, r := range rr {
-			if r.Skip {
-				skipped++
-				continue
-			}
-			err := r.Execute(m, h.Path)
-			if err != nil {
-				t.Error(err)
-			}
-		}
+	if h.Config == nil {
+		globalRunner().Test(t, h)
+		return
 	}
-	if skipped > 0 {
-		log.Printf("Warning: Skipped %d test(s) for %q.", skipped, h.Path)
+	rn, err := h.Config.NewRunner()
+	if err != nil {
+		t.Error(err)
+		return
 	}
+	rn.Test(t, h)
 }
 
 // The type Requests maps HTTP methods to Request slices.
@@ -84,102 +142,886 @@ type Request struct {
 	Header Header
 	Body   Bodyer
 	Want   Response
+
+	// BasicAuth, if set, is used to set the request's Authorization
+	// header using the Basic scheme.
+	BasicAuth *BasicAuth
+	// Bearer, if set, is sent as a Bearer token in the request's
+	// Authorization header.
+	Bearer string
+
+	// DigestAuth, if set, is used to answer a Digest authentication
+	// challenge. If the first response has status 401 and a Digest
+	// WWW-Authenticate header the request is retried with the computed
+	// credentials and the retry's response is compared to Want.
+	DigestAuth *DigestAuth
+
+	// Trailer, if set, is sent as the request's trailer, after the body,
+	// which is then sent with chunked transfer encoding.
+	Trailer Header
+
+	// Host, if set, is sent as the request's Host header in place of Addr,
+	// the connection is still made to Addr. It's needed for testing name
+	// based virtual hosting and ingress routing rules.
+	Host string
+
+	// Signer, if set, is used to sign the request before it's sent.
+	Signer Signer
+
+	// Mutate, if set, is called with the http.Request just before it's
+	// sent, after it's been signed, as an escape hatch for whatever the
+	// other fields don't cover.
+	Mutate func(*http.Request)
+
+	// Inspect, if set, is called with the http.Response before it's
+	// compared to Want, for custom assertions. Its error is reported
+	// along with the other failures. The body can be read, it's restored
+	// for the comparison.
+	Inspect func(*http.Response) error
+
+	// RateLimit, if set, is used to check the response's rate limit headers.
+	RateLimit *RateLimit
+
+	// Security, if set, is used to audit the response's security headers.
+	Security *SecurityPolicy
+
+	// RepeatIdempotent, if greater than 1, is the number of times the
+	// request is sent, every response is compared to Want. It's useful
+	// for catching non-idempotent PUT and DELETE handlers.
+	RepeatIdempotent int
+	// RepeatIdentical, if set, additionally requires the bodies of the
+	// repeated responses to be byte-identical.
+	RepeatIdentical bool
+
+	// KeepAlive, if set, requires the responses to the repeated request
+	// to have been received on one and the same connection. The response
+	// bodies are read to the end so that the connection can be reused.
+	KeepAlive bool
+
+	// Concurrent, if greater than 1, is the number of copies of the request
+	// that are sent concurrently. The set of responses is checked by
+	// Invariant, or, if Invariant is nil, each response is compared to Want.
+	Concurrent int
+	Invariant  Invariant
+
+	// Variants, if not empty, executes the request once per variant, each
+	// time with the variant's Header added to the request and with the
+	// variant's Want in place of the request's Want.
+	Variants []Variant
+
+	// Reflection, if set, additionally sends a copy of the request for each
+	// query parameter, header and top-level body field with that value
+	// replaced by the Canary, failing if the Canary appears unescaped in
+	// the response body.
+	Reflection bool
+
+	// Fuzz, if set, additionally sends a copy of the request for each of
+	// the FuzzInputs and each query parameter and path segment with that
+	// value replaced by the input, failing on any 5xx response or on any
+	// response whose body looks like a panic.
+	Fuzz bool
+
+	// Throttle, if greater than 0, is the rate in bytes per second at which
+	// the request body is written, it's useful for testing the server's
+	// read timeouts and its protections against slow clients.
+	Throttle int
+
+	// Abort, if set, closes the request's connection midway and, instead
+	// of comparing the response to Want, executes Abort.Then to check
+	// the consequences.
+	Abort *Abort
+
+	// LongPoll, if set, treats the request as a long-poll, its response
+	// must be held until LongPoll.Trigger is called and then arrive within
+	// the LongPoll's timeout.
+	LongPoll *LongPoll
+
+	// Template, if set, executes the path, the header values and the
+	// string values of the body as text/template templates, just before
+	// the request is built, with the values in Vars as their data, e.g.
+	// "/users/{{.id}}". The function iteration returns the number of the
+	// copy of a Concurrent request, or of a request sent by a Load, the
+	// repeats of RepeatIdempotent are one and the same request.
+	Template bool
+	// iter is the value of the template function iteration.
+	iter int
+
+	// Wire, if set, checks the bytes exchanged with the server.
+	Wire *Wire
+
+	// TransportError, if set, expects the request to fail at the transport
+	// level with an error of the class, e.g. ConnRefused for a port that's
+	// closed during a shutdown. Want is not checked then.
+	TransportError TransportError
+
+	// Name identifies the request, e.g. in a Quarantine, it defaults to
+	// the method followed by the path, e.g. "GET /users".
+	Name string
+	// Source is the file:line at which the request is defined, it's set
+	// by Here and prefixed to the request's failure messages.
+	Source string
+
+	// Tags label the request for the latency Budgets, its duration is
+	// recorded under each of them.
+	Tags []string
+
+	// Range, if set, is sent as the request's Range header. A response
+	// with status 206 must then have a Content-Range, and a length,
+	// consistent with the range, the status itself is checked by Want.
+	Range *ByteRange
+
+	// Snapshot, if set, additionally compares the response to the one
+	// recorded in the request's snapshot file.
+	Snapshot *Snapshot
+
+	// rn is the Runner executing the request, it's set by the Runner,
+	// nil stands for the one configured by the package's variables.
+	rn *Runner
+}
+
+// BasicAuth holds the credentials for the HTTP Basic authentication scheme.
+type BasicAuth struct {
+	User string
+	Pass string
 }
 
 // Execute prepares and executes an HTTP request with the specified method to
 // the speciefied path.
 func (r Request) Execute(method, path string) error {
+	rn := r.runner()
+	err := r.sourced(rn.Redactions.redactErr(r.execute(method, path)))
+	rn.emitEvents(r.name(method, path), r.Source, method, path, err)
+	return err
+}
+
+func (r Request) execute(method, path string) error {
+	if len(r.Variants) > 0 {
+		return r.variants(method, path)
+	}
+	if r.Abort != nil {
+		return r.abort(method, path)
+	}
+	if r.LongPoll != nil {
+		return r.longPoll(method, path)
+	}
+
+	req, err := r.newRequest(method, path)
+	if err != nil {
+		return err
+	}
+	var rec *wireRecorder
+	if r.Wire != nil {
+		if r.runner().Transport != nil {
+			return fmt.Errorf("hit: %s %s Wire can't capture the bytes of a custom Transport", method, path)
+		}
+		req, rec = withWire(req)
+	}
+
+	if r.Concurrent > 1 {
+		if fail := r.concurrent(method, path, req); fail != "" {
+			return r.failure(method, path, fail)
+		}
+		return nil
+	}
+
+	n := 1
+	if r.RepeatIdempotent > 1 {
+		n = r.RepeatIdempotent
+	}
+	var fail string
+	var errs []error
+	var first []byte
+	var prev connInfo
+	var prevClose bool
+	for i := 0; i < n && fail == ""; i++ {
+		if rec != nil {
+			rec.reset()
+		}
+		start := time.Now()
+		res, err := r.do(req)
+		if r.TransportError != 0 {
+			f := r.TransportError.check(err, res)
+			status := 0
+			if err == nil {
+				status = res.StatusCode
+			}
+			if err := r.record(method, path, status, time.Since(start), f != ""); err != nil {
+				return err
+			}
+			fail += f
+			continue
+		}
+		if err != nil {
+			if err := r.record(method, path, 0, time.Since(start), true); err != nil {
+				return err
+			}
+			return fmt.Errorf("hit: %s %s failed. %w", method, path, err)
+		}
+		xr := recordExchange(r.runner(), method, path, req, res)
+		ci := connOf(res)
+		if i > 0 && r.KeepAlive && (!ci.reused || ci.local != prev.local) {
+			fail += fmt.Sprintf("Response #%d got = %sa new connection%s, want = %sthe connection of response #1%s\n",
+				i+1, RedColor, StopColor, RedColor, StopColor)
+		}
+		if i > 0 && prevClose && ci.reused {
+			fail += fmt.Sprintf("Response #%d got = %sa reused connection%s, want = %sa new connection%s\n",
+				i+1, RedColor, StopColor, RedColor, StopColor)
+		}
+		prev, prevClose = ci, res.Close
+		if r.KeepAlive {
+			res.Body = drainBody{res.Body}
+		}
+		if r.RepeatIdentical {
+			b, err := ioutil.ReadAll(res.Body)
+			res.Body.Close()
+			if err != nil {
+				return fmt.Errorf("hit: error reading http.Response.Body. %v", err)
+			}
+			if i == 0 {
+				first = b
+			} else if !bytes.Equal(b, first) {
+				fail += fmt.Sprintf("Body of response #%d got %s%q%s, want identical to the first %s%q%s\n",
+					i+1, RedColor, b, StopColor, RedColor, first, StopColor)
+			}
+			res.Body = ioutil.NopCloser(bytes.NewReader(b))
+		}
+		if r.Snapshot != nil {
+			b, err := ioutil.ReadAll(res.Body)
+			res.Body.Close()
+			if err != nil {
+				return fmt.Errorf("hit: error reading http.Response.Body. %v", err)
+			}
+			f, err := r.Snapshot.compare(r, method, path, res, b)
+			if err != nil {
+				return err
+			}
+			fail += f
+			res.Body = ioutil.NopCloser(bytes.NewReader(b))
+		}
+		ll := r.check(res)
+		fail += ll.Error()
+		errs = append(errs, ll...)
+		if rec != nil {
+			fail += r.Wire.check(rec)
+		}
+		xr.done()
+		if err := r.record(method, path, res.StatusCode, time.Since(start), fail != ""); err != nil {
+			return err
+		}
+		if fail != "" && n > 1 {
+			fail = fmt.Sprintf("Response #%d of %d:\n%s", i+1, n, fail)
+		}
+	}
+	if r.Reflection {
+		f, err := r.reflection(method, path)
+		if err != nil {
+			return err
+		}
+		fail += f
+	}
+	if r.Fuzz {
+		f, err := r.fuzz(method, path)
+		if err != nil {
+			return err
+		}
+		fail += f
+	}
+
+	if fail != "" {
+		return r.failure(method, path, fail, errs...)
+	}
+	return nil
+}
+
+// record records the duration and the outcome of a request for the Budgets,
+// the baseline and the Results. The status is 0 if the request failed at the
+// transport level.
+func (r Request) record(method, path string, status int, d time.Duration, failed bool) error {
+	recordTiming(r.Tags, method, path, d)
+	recordOutcome(method, path, d, failed)
+	return r.runner().recordResult(method, path, status, d, failed)
+}
+
+// failure returns a *RequestError describing the request with the specified
+// method and path followed by the specified failure message, errs are the
+// typed failures the message is made of, if known. The part of the message
+// not covered by errs is added to the failures as is.
+func (r Request) failure(method, path, fail string, errs ...error) error {
+	rest := fail
+	for _, err := range errs {
+		rest = strings.Replace(rest, err.Error(), "", 1)
+	}
+	if strings.TrimSpace(rest) != "" {
+		errs = append(errs, errors.New(rest))
+	}
+
+	rd := r.runner().Redactions
+	msg := fmt.Sprintf(" %s%s %s%s Header: %s%v%s",
+		YellowColor,
+		method,
+		path,
+		StopColor,
+		YellowColor,
+		rd.redactHeaders(http.Header(r.Header)),
+		StopColor,
+	)
+	if r.Body != nil {
+		msg += fmt.Sprintf(" Body: %s%v%s", YellowColor, rd.redactBodyer(r.Body), StopColor)
+	}
+	return &RequestError{Method: method, Path: path, Failures: errs, msg: fmt.Sprintf("%s\n%s", msg, fail)}
+}
+
+// newRequest prepares an HTTP request with the specified method to the
+// specified path.
+func (r Request) newRequest(method, path string) (*http.Request, error) {
+	path, err := r.render(path)
+	if err != nil {
+		return nil, err
+	}
+	return r.newRequestURL(method, r.runner().baseURL()+path)
+}
+
+// newRequestURL prepares an HTTP request with the specified method to the
+// specified URL.
+func (r Request) newRequestURL(method, urlStr string) (*http.Request, error) {
 	var body io.Reader
 	var err error
+	if r.Header, err = r.renderHeader(r.Header); err != nil {
+		return nil, err
+	}
+	if r.Body, err = r.renderBody(r.Body); err != nil {
+		return nil, err
+	}
 	if r.Body != nil {
 		body, err = r.Body.Body()
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
-	// prepare request
-	urlStr := "http://" + Addr + path
+	// signers need the raw body, and retries need to resend it,
+	// so read it in whole
+	var raw []byte
+	rn := r.runner()
+	replay := r.Signer != nil || r.DigestAuth != nil || rn.RateLimitRetries > 0 ||
+		r.RepeatIdempotent > 1 || r.Concurrent > 1
+	if replay && body != nil {
+		if raw, err = ioutil.ReadAll(body); err != nil {
+			return nil, fmt.Errorf("hit: failed reading request body. %v", err)
+		}
+		body = bytes.NewReader(raw)
+	}
+
 	req, err := http.NewRequest(method, urlStr, body)
 	if err != nil {
 		log.Fatalf("hit: failed http.NewRequest(%q, %q, %v). %v", method, urlStr, body, err)
 	}
 	if r.Body != nil {
 		req.Header.Set("Content-Type", r.Body.Type())
+		// generated bodies know their size, let the server know it too
+		if s, ok := r.Body.(interface{ Size() int64 }); ok && req.ContentLength == 0 {
+			req.ContentLength = s.Size()
+		}
+	}
+	if r.Throttle > 0 && req.Body != nil {
+		req.Body = throttle(req.Body, r.Throttle)
+		if getBody := req.GetBody; getBody != nil {
+			req.GetBody = func() (io.ReadCloser, error) {
+				rc, err := getBody()
+				if err != nil {
+					return nil, err
+				}
+				return throttle(rc, r.Throttle), nil
+			}
+		}
 	}
 	if r.Header != nil {
 		r.Header.AddTo(req)
 	}
+	rn.Profile.addHeader(req)
+	if rn.RequestID != nil {
+		if err := rn.RequestID.inject(req); err != nil {
+			return nil, err
+		}
+	}
+	if r.Range != nil {
+		req.Header.Set("Range", r.Range.String())
+	}
+	if r.Host != "" {
+		req.Host = r.Host
+	}
+	if len(r.Trailer) > 0 {
+		req.Trailer = make(http.Header)
+		for k, vv := range r.Trailer {
+			for _, v := range vv {
+				req.Trailer.Add(k, v)
+			}
+		}
+		req.ContentLength = -1
+		req.TransferEncoding = []string{"chunked"}
+	}
+	if r.BasicAuth != nil {
+		req.SetBasicAuth(r.BasicAuth.User, r.BasicAuth.Pass)
+	}
+	if r.Bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+r.Bearer)
+	}
+	if r.Signer != nil {
+		sign := r.Signer.Sign
+		if s, ok := r.Signer.(runnerSigner); ok {
+			sign = func(req *http.Request, body []byte) error { return s.signWith(r.runner(), req, body) }
+		}
+		if err := sign(req, raw); err != nil {
+			return nil, fmt.Errorf("hit: failed signing %s %s. %v", method, urlStr, err)
+		}
+	}
+	if r.Mutate != nil {
+		r.Mutate(req)
+	}
+	return req, nil
+}
 
-	// execute request
-	res, err := client.Do(req)
-	if err != nil && !isRedirectError(err) {
-		log.Fatalf("hit: failed executing http.Client.Do with %+v. %v", req, err)
+// do sends the specified request and returns its response, it takes care of
+// answering digest challenges, of retrying rate limited requests and of
+// guarding the reading of the response body.
+func (r Request) do(req *http.Request) (*http.Response, error) {
+	rn := r.runner()
+	timeout := rn.BodyReadTimeout
+	if r.Want.BodyReadTimeout > 0 {
+		timeout = r.Want.BodyReadTimeout
+	}
+	max := rn.MaxBodySize
+	if r.Want.MaxBodySize > 0 {
+		max = r.Want.MaxBodySize
 	}
-	if err = r.Want.Compare(res); err != nil {
-		msg := fmt.Sprintf(" %s%s %s%s Header: %s%v%s",
-			YellowColor,
-			method,
-			path,
-			StopColor,
-			YellowColor,
-			r.Header,
-			StopColor,
-		)
-		if r.Body != nil {
-			msg += fmt.Sprintf(" Body: %s%v%s", YellowColor, r.Body, StopColor)
+
+	var res *http.Response
+	var err error
+	if timeout > 0 {
+		ctx, cancel := context.WithCancel(req.Context())
+		if res, err = r.send(req.WithContext(ctx)); err != nil {
+			cancel()
+			return nil, err
 		}
-		return errors.New(fmt.Sprintf("%s\n%s", msg, err.Error()))
+		tb := &timeoutBody{rc: res.Body, d: timeout, cancel: cancel}
+		tb.timer = time.AfterFunc(timeout, func() {
+			atomic.StoreInt32(&tb.fired, 1)
+			cancel()
+		})
+		res.Body = tb
+	} else if res, err = r.send(req); err != nil {
+		return nil, err
 	}
-	return nil
+
+	if max > 0 && res.Body != nil {
+		res.Body = &limitedBody{rc: res.Body, max: max, left: max}
+	}
+	return res, nil
+}
+
+// send sends the specified request and returns its response, it takes care
+// of answering digest challenges and of retrying rate limited requests.
+func (r Request) send(req *http.Request) (*http.Response, error) {
+	rn := r.runner()
+	res, err := rn.resend(req)
+	if err != nil {
+		return nil, err
+	}
+	if r.DigestAuth != nil && res.StatusCode == http.StatusUnauthorized {
+		if res, err = r.DigestAuth.retry(rn, req, res); err != nil {
+			return nil, fmt.Errorf("digest authentication failed. %w", err)
+		}
+	}
+	for i := 0; i < rn.RateLimitRetries && res.StatusCode == http.StatusTooManyRequests; i++ {
+		wait, ok := retryAfter(res.Header.Get("Retry-After"), time.Now())
+		if !ok {
+			wait = time.Second
+		}
+		if wait > rn.RateLimitMaxWait {
+			wait = rn.RateLimitMaxWait
+		}
+		ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		time.Sleep(wait)
+		if res, err = rn.resend(req); err != nil {
+			return nil, fmt.Errorf("retrying rate limited request failed. %w", err)
+		}
+	}
+
+	return res, nil
+}
+
+// limitedBody is an io.ReadCloser that fails with an error once more than max
+// bytes are read from the underlying reader.
+type limitedBody struct {
+	rc   io.ReadCloser
+	max  int64
+	left int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.left <= 0 {
+		// probe for data past the limit
+		var one [1]byte
+		n, err := b.rc.Read(one[:])
+		if n > 0 {
+			return 0, fmt.Errorf("hit: response body exceeds the limit of %d bytes", b.max)
+		}
+		return 0, err
+	}
+	if int64(len(p)) > b.left {
+		p = p[:b.left]
+	}
+	n, err := b.rc.Read(p)
+	b.left -= int64(n)
+	return n, err
+}
+
+func (b *limitedBody) Close() error { return b.rc.Close() }
+
+// timeoutBody is an io.ReadCloser that reports a timeout error if reading
+// from the underlying body fails because its request's deadline fired.
+type timeoutBody struct {
+	rc     io.ReadCloser
+	d      time.Duration
+	timer  *time.Timer
+	fired  int32
+	cancel context.CancelFunc
+}
+
+func (b *timeoutBody) Read(p []byte) (int, error) {
+	n, err := b.rc.Read(p)
+	if err != nil && err != io.EOF && atomic.LoadInt32(&b.fired) == 1 {
+		err = fmt.Errorf("hit: body read timed out after %s", b.d)
+	}
+	return n, err
+}
+
+func (b *timeoutBody) Close() error {
+	b.timer.Stop()
+	err := b.rc.Close()
+	b.cancel()
+	return err
+}
+
+// check compares the specified response to the receiver's expectations and
+// returns the failures, if any.
+func (r Request) check(res *http.Response) (ll ErrorList) {
+	if id := r.runner().RequestID; id != nil {
+		if err := id.check(res); err != nil {
+			ll.add(err)
+		}
+	}
+	if r.Range != nil {
+		if err := r.Range.check(res); err != nil {
+			ll.add(err)
+		}
+	}
+	if r.RateLimit != nil {
+		if err := r.RateLimit.check(res); err != nil {
+			ll.add(err)
+		}
+	}
+	if r.Security != nil {
+		if err := r.Security.Audit(res.Header); err != nil {
+			ll.add(err)
+		}
+	}
+	if r.Inspect != nil {
+		b, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			ll.add(fmt.Errorf("hit: error reading http.Response.Body. %v\n", err))
+			return ll
+		}
+		res.Body = ioutil.NopCloser(bytes.NewReader(b))
+		if err := r.Inspect(res); err != nil {
+			ll.add(fmt.Errorf("Inspect %s\n", strings.TrimSuffix(err.Error(), "\n")))
+		}
+		res.Body = ioutil.NopCloser(bytes.NewReader(b))
+	}
+	if err := r.Want.compare(res, r.runner().Redactions); err != nil {
+		ll.add(err)
+	}
+	return ll
 }
 
 // Response represents a trimmed down HTTP response.
 type Response struct {
 	Status int
 	Header Header
-	Body   JSONBody
+	Body   Comparer
+
+	// OrderedHeader holds headers whose values must be equal to those of
+	// the response, in the same order, e.g. multiple Link or Via values.
+	OrderedHeader Header
+
+	// HeaderMatch maps header names to Matchers that the first value
+	// of the corresponding response header must match.
+	HeaderMatch map[string]Matcher
+
+	// StrictHeader, if set, fails the comparison if the response has
+	// headers other than those in Header, HeaderMatch, AllowHeader or
+	// BaselineHeaders.
+	StrictHeader bool
+	AllowHeader  []string
+
+	// MaxBodySize, if greater than 0, overrides the package's MaxBodySize.
+	MaxBodySize int64
+	// BodyReadTimeout, if greater than 0, overrides the package's
+	// BodyReadTimeout.
+	BodyReadTimeout time.Duration
+
+	// NoDuplicateKeys, if set, fails the comparison if any JSON object in
+	// the response body has duplicate keys, which encoding/json would
+	// otherwise silently collapse. The body is read into memory in whole.
+	NoDuplicateKeys bool
+
+	// SniffContentType, if set, fails the comparison if the response's
+	// Content-Type is inconsistent with the type detected by
+	// http.DetectContentType from the first 512 bytes of the body, e.g.
+	// an HTML error page labeled as application/json.
+	SniffContentType bool
+
+	// Close, if set, requires the response to have asked for its
+	// connection to be closed, with "Connection: close", and, if the
+	// request is repeated, the next response to be received on a new
+	// connection.
+	Close bool
+
+	// Transform, if set, is applied to the raw response body before it's
+	// compared to Body. It can be used to unwrap envelopes or to scrub
+	// volatile fields. The body is read into memory in whole.
+	Transform func([]byte) ([]byte, error)
+
+	// Download, if set, is compared to the download metadata of the
+	// response, i.e. its Content-Disposition, Content-Type and
+	// Content-Length.
+	Download *Download
+
+	// Dates, if set, is compared to the response's date headers.
+	Dates *Dates
+
+	// BodySHA256, BodyMD5 and BodyCRC32, if set, are the hex encoded
+	// digests of the raw response body, the CRC-32 uses the IEEE
+	// polynomial. The body is hashed as it's read so that large
+	// downloads can be verified without holding them in memory.
+	BodySHA256 string
+	BodyMD5    string
+	BodyCRC32  string
 }
 
 // Compare compares the specified http.Repsonse to the receiver.
 func (r Response) Compare(res *http.Response) error {
+	return r.compare(res, redactionOf(nil))
+}
+
+// compare is like Compare but the failures are printed with the values
+// redacted by rd, e.g. by those of the Runner.
+func (r Response) compare(res *http.Response, rd Redaction) error {
 	if res.Body != nil {
 		defer res.Body.Close()
 	}
-	var msg string
+	var ll ErrorList
 
+	var checkDigests func() error
+	if dd := r.digests(); len(dd) > 0 && res.Body != nil {
+		checkDigests = teeDigests(res, dd)
+	}
 	if err := r.CompareStatus(res.StatusCode); err != nil {
-		msg += err.Error()
+		ll.add(err)
 	}
 	if r.Header != nil {
 		if err := r.Header.Compare(res.Header); err != nil {
-			msg += err.Error()
+			ll.add(err)
 		}
 	}
-	if r.Body != nil {
-		if err := r.Body.Compare(res.Body); err != nil {
-			msg += err.Error()
+	if r.OrderedHeader != nil {
+		if err := r.OrderedHeader.CompareOrdered(res.Header); err != nil {
+			ll.add(err)
+		}
+	}
+	if r.HeaderMatch != nil {
+		if err := compareHeaderMatch(r.HeaderMatch, res.Header); err != nil {
+			ll.add(err)
+		}
+	}
+	if r.StrictHeader {
+		if err := r.compareHeaderSet(res.Header); err != nil {
+			ll.add(err)
+		}
+	}
+	if r.Dates != nil {
+		if err := r.Dates.check(res.Header, time.Now()); err != nil {
+			ll.add(err)
+		}
+	}
+	if r.Download != nil {
+		if err := r.Download.check(res.Header); err != nil {
+			ll.add(err)
+		}
+	}
+	if r.Close && !res.Close {
+		ll.add(&HeaderError{Name: "Connection", Got: res.Header["Connection"], Want: []string{"close"}})
+	}
+	if r.SniffContentType && res.Body != nil {
+		if err := sniffContentType(res); err != nil {
+			ll.add(err)
+		}
+	}
+	if r.NoDuplicateKeys && res.Body != nil {
+		b, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			return fmt.Errorf("hit: error reading http.Response.Body. %v", err)
+		}
+		if dups, err := duplicateKeys(b); err != nil {
+			ll.add(fmt.Errorf("hit: error scanning http.Response.Body for duplicate keys. %v\n", err))
+		} else if len(dups) > 0 {
+			ll.add(fmt.Errorf("Body has duplicate keys %s%s%s\n", RedColor, strings.Join(dups, ", "), StopColor))
+		}
+		res.Body = ioutil.NopCloser(bytes.NewReader(b))
+	}
+	if !nilBody(r.Body) {
+		body, err := r.transform(res.Body)
+		if err != nil {
+			ll.add(err)
+		} else if tc, ok := r.Body.(typedComparer); ok {
+			if err := tc.compareTyped(res.Header.Get("Content-Type"), body, rd); err != nil {
+				ll.add(err)
+			}
+		} else if err := r.Body.Compare(body); err != nil {
+			ll.add(err)
+		}
+	}
+	if checkDigests != nil {
+		if err := checkDigests(); err != nil {
+			ll.add(err)
 		}
 	}
 
-	if msg != "" {
-		return errors.New(msg)
+	for _, err := range ll {
+		if e, ok := err.(*HeaderError); ok {
+			e.rd = &rd
+		}
+	}
+	return ll.err()
+}
+
+// sniffContentType checks that the media type of the specified response's
+// Content-Type is consistent with the media type detected from the beginning
+// of its body. The generic text/plain and application/octet-stream detections
+// are consistent with any declared type. The peeked bytes are put back so
+// that the body can still be read in whole.
+func sniffContentType(res *http.Response) error {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(res.Body, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return fmt.Errorf("hit: error reading http.Response.Body. %v\n", err)
+	}
+	head = head[:n]
+	res.Body = peekedBody{io.MultiReader(bytes.NewReader(head), res.Body), res.Body}
+	if n == 0 {
+		return nil
+	}
+
+	declared := res.Header.Get("Content-Type")
+	detected := http.DetectContentType(head)
+	dt, _, _ := mime.ParseMediaType(detected)
+	if dt == "text/plain" || dt == "application/octet-stream" {
+		return nil
+	}
+	if mt, _, err := mime.ParseMediaType(declared); err == nil && mt == dt {
+		return nil
+	}
+	return fmt.Errorf("Content-Type declared = %s%q%s, detected = %s%q%s\n",
+		RedColor,
+		declared,
+		StopColor,
+		RedColor,
+		detected,
+		StopColor,
+	)
+}
+
+// peekedBody is an io.ReadCloser whose leading bytes were read ahead of time.
+type peekedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// compareHeaderSet checks that the specified http.Header has no headers other
+// than the ones allowed by the receiver.
+func (r Response) compareHeaderSet(hh http.Header) error {
+	allowed := make(map[string]bool)
+	for _, k := range BaselineHeaders {
+		allowed[http.CanonicalHeaderKey(k)] = true
+	}
+	for _, k := range r.AllowHeader {
+		allowed[http.CanonicalHeaderKey(k)] = true
+	}
+	for k := range r.Header {
+		allowed[http.CanonicalHeaderKey(k)] = true
+	}
+	for k := range r.OrderedHeader {
+		allowed[http.CanonicalHeaderKey(k)] = true
+	}
+	for k := range r.HeaderMatch {
+		allowed[http.CanonicalHeaderKey(k)] = true
+	}
+
+	var keys []string
+	for k := range hh {
+		if !allowed[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var ll ErrorList
+	for _, k := range keys {
+		ll = append(ll, &HeaderError{Name: k, Got: hh[k], form: headerAbsent})
+	}
+	return ll.err()
+}
+
+// transform returns a reader of the specified body with the receiver's
+// Transform applied to it. If Transform is nil body is returned as is.
+func (r Response) transform(body io.Reader) (io.Reader, error) {
+	if r.Transform == nil || body == nil {
+		return body, nil
+	}
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("hit: error reading http.Response.Body. %v\n", err)
+	}
+	if b, err = r.Transform(b); err != nil {
+		return nil, fmt.Errorf("hit: Response.Transform failed. %v\n", err)
+	}
+	return bytes.NewReader(b), nil
+}
+
+// Unwrap returns a function, usable as a Response's Transform, that replaces
+// a JSON object with the value of its field named by key. It's useful for
+// responses that wrap their payload in an envelope like {"data": ...}.
+func Unwrap(key string) func([]byte) ([]byte, error) {
+	return func(b []byte) ([]byte, error) {
+		var env map[string]json.RawMessage
+		if err := json.Unmarshal(b, &env); err != nil {
+			return nil, err
+		}
+		v, ok := env[key]
+		if !ok {
+			return nil, fmt.Errorf("envelope has no %q field", key)
+		}
+		return v, nil
 	}
-	return nil
 }
 
 // CompareStatus checks if the specified status is equal to the receiver's Status.
 // If they are not equal a formatted error is returned.
 func (r Response) CompareStatus(status int) error {
 	if status != r.Status {
-		return fmt.Errorf("StatusCode got = %s%d%s, want %s%d%s\n",
-			RedColor,
-			status,
-			StopColor,
-			RedColor,
-			r.Status,
-			StopColor,
-		)
+		return &StatusError{Got: status, Want: r.Status}
 	}
 	return nil
 }
@@ -196,29 +1038,124 @@ func (h Header) AddTo(r *http.Request) {
 	}
 }
 
-// TODO:(mkopriva) check all values of a field not just the first one.
 // Compare checks if all of the receiver's key-value pairs are present in the
-// specified http.Header returning an error if not.
+// specified http.Header returning an error if not. If a key has multiple values
+// all of them must be present, in any order.
+//
+// Content-Type values are compared as media types, the parameters of the
+// actual value are compared only if they're present in the expected value,
+// e.g. "application/json" matches "application/json; charset=utf-8" while
+// "text/html; charset=utf-8" doesn't match "text/html; charset=iso-8859-1".
 func (h Header) Compare(hh http.Header) error {
-	var msg string
-	for k, v := range h {
-		val := hh.Get(k)
-		if val != v[0] {
-			msg += fmt.Sprintf("Header[%q] got = %s%q%s, want = %s%q%s\n",
-				k,
-				RedColor,
-				val,
-				StopColor,
-				RedColor,
-				v[0],
-				StopColor,
-			)
-		}
-	}
-	if msg != "" {
-		return fmt.Errorf(msg)
+	var keys []string
+	for k := range h {
+		keys = append(keys, k)
 	}
-	return nil
+	sort.Strings(keys)
+
+	var ll ErrorList
+	for _, k := range keys {
+		v, got := h[k], hh[http.CanonicalHeaderKey(k)]
+		eq := equalString
+		if http.CanonicalHeaderKey(k) == "Content-Type" {
+			eq = mediaTypeMatch
+		}
+		if containsAll(got, v, eq) {
+			continue
+		}
+		form := headerAll
+		if len(v) == 1 && len(got) <= 1 {
+			form = headerEqual
+		}
+		ll = append(ll, &HeaderError{Name: k, Got: got, Want: v, form: form})
+	}
+	return ll.err()
+}
+
+// CompareOrdered checks if the values of each of the receiver's keys are equal
+// to the values of the same key in the specified http.Header, in the same
+// order. Values that the server combined into a single comma separated line
+// are split before they are compared.
+func (h Header) CompareOrdered(hh http.Header) error {
+	var keys []string
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var ll ErrorList
+	for _, k := range keys {
+		v, got := h[k], hh[http.CanonicalHeaderKey(k)]
+		if equalStrings(got, v) || equalStrings(splitHeaderValues(got), v) {
+			continue
+		}
+		ll = append(ll, &HeaderError{Name: k, Got: got, Want: v, form: headerOrdered})
+	}
+	return ll.err()
+}
+
+// containsAll reports whether all of the want values are present in got,
+// values are compared using the eq function.
+func containsAll(got, want []string, eq func(got, want string) bool) bool {
+	for _, w := range want {
+		found := false
+		for _, g := range got {
+			if eq(g, w) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func equalString(a, b string) bool { return a == b }
+
+// mediaTypeMatch reports whether the got media type is equal to the want media
+// type and whether it has all of the want media type's parameters. Media type
+// names and the charset parameter are compared case-insensitively.
+func mediaTypeMatch(got, want string) bool {
+	wt, wp, err := mime.ParseMediaType(want)
+	if err != nil {
+		return got == want
+	}
+	gt, gp, err := mime.ParseMediaType(got)
+	if err != nil || gt != wt {
+		return false
+	}
+	for k, v := range wp {
+		if g, ok := gp[k]; !ok || g != v && !(k == "charset" && strings.EqualFold(g, v)) {
+			return false
+		}
+	}
+	return true
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// splitHeaderValues splits comma separated header values into a list of
+// trimmed values.
+func splitHeaderValues(vv []string) []string {
+	var out []string
+	for _, v := range vv {
+		for _, s := range strings.Split(v, ",") {
+			out = append(out, strings.TrimSpace(s))
+		}
+	}
+	return out
 }
 
 const (
@@ -228,6 +1165,21 @@ const (
 	appjson    = "application/json"
 )
 
+// Comparer is the interface implemented by expected response bodies.
+type Comparer interface {
+	// Compare compares the receiver to the contents of the specified reader
+	// and returns an error describing the differences, if any.
+	Compare(r io.Reader) error
+}
+
+// nilBody reports whether the specified Response body is nil or a nil
+// JSONBody. Neither is compared, as a nil JSONBody wasn't before the
+// Response's Body became a Comparer.
+func nilBody(b Comparer) bool {
+	jb, ok := b.(JSONBody)
+	return b == nil || ok && jb == nil
+}
+
 // Bodyer
 type Bodyer interface {
 	Type() string
@@ -251,37 +1203,21 @@ func (b JSONBody) Body() (io.Reader, error) {
 }
 
 // Compare compares the receiver's contents to the contents of the specified reader.
+// The receiver may contain Matchers in place of literal values.
+// The body is compared as it's being read, token by token, so large bodies are
+// never buffered in whole.
 func (b JSONBody) Compare(r io.Reader) error {
-	got, want := make(map[string]interface{}), make(map[string]interface{})
-
-	d := json.NewDecoder(r)
-	d.UseNumber()
-	if err := d.Decode(&got); err != nil && err != io.EOF {
-		return fmt.Errorf("hit: error decoding http.Response.Body into %#v. %v", got, err)
-	}
+	return b.compare(r, redactionOf(nil))
+}
 
-	r2, err := b.Body()
+// compare is like Compare but the failure is printed with the values
+// redacted by rd.
+func (b JSONBody) compare(r io.Reader, rd Redaction) error {
+	want, err := normalize(map[string]interface{}(b))
 	if err != nil {
 		return fmt.Errorf("hit: Bodyer %+v, error %v", b, err)
 	}
-
-	d = json.NewDecoder(r2)
-	d.UseNumber()
-	if err := d.Decode(&want); err != nil && err != io.EOF {
-		return fmt.Errorf("hit: error decoding hit.Response.Bodyer into %#v. %v", want, err)
-	}
-
-	if !reflect.DeepEqual(got, want) {
-		return fmt.Errorf("Body got %s%#v%s, want %s%#v%s\n",
-			RedColor,
-			got,
-			StopColor,
-			RedColor,
-			want,
-			StopColor,
-		)
-	}
-	return nil
+	return compareJSONStream(r, want, DefaultMode, rd)
 }
 
 // FormBody represents an http request body whose content is of type application/x-www-form-urlencoded.
@@ -351,11 +1287,36 @@ func (b MultipartBody) Body() (io.Reader, error) {
 
 // client is an http.Client that does not follow redirects.
 var client = &http.Client{
+	Transport: transport{},
 	CheckRedirect: func(r *http.Request, via []*http.Request) error {
 		return errRedirect
 	},
 }
 
+// transport is an http.RoundTripper that uses the package's Transport.
+type transport struct{}
+
+func (transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if Transport != nil {
+		return Transport.RoundTrip(r)
+	}
+	return defaultTransport.RoundTrip(r)
+}
+
+// defaultTransport is http.DefaultTransport with its dialer consulting
+// the package's Resolve map.
+var defaultTransport = func() *http.Transport {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	tr.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if a, ok := Resolve[addr]; ok {
+			addr = a
+		}
+		return dialer.DialContext(ctx, network, addr)
+	}
+	return tr
+}()
+
 var errRedirect = errors.New("just a redirect")
 
 // The isRedirectError function returns true if the given error contains the
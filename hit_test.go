@@ -4,6 +4,7 @@
 package hit
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -11,6 +12,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 var requestExecuteTests = []struct {
@@ -19,20 +21,22 @@ var requestExecuteTests = []struct {
 	r      Request
 	err    error
 }{
-	{"GET", "/foo/bar", Request{false, nil, nil, Response{200, nil, nil}}, nil},
-	{"GET", "/foo/bar", Request{false, Header{"Auth": {"6tygfd4"}}, nil, Response{
-		201,
-		Header{"Foo": {"baz"}},
-		JSONBody{"Hello": "World"},
+	{"GET", "/foo/bar", Request{Want: Response{Status: 200}}, nil},
+	{"GET", "/foo/bar", Request{Header: Header{"Auth": {"6tygfd4"}}, Want: Response{
+		Status: 201,
+		Header: Header{"Foo": {"baz"}},
+		Body:   JSONBody{"Hello": "World"},
 	}}, fmt.Errorf(
 		" %sGET /foo/bar%s Header: %smap[Auth:[6tygfd4]]%s\n"+
 			"StatusCode got = %s200%s, want %s201%s\n"+
 			"Header[\"Foo\"] got = %s\"\"%s, want = %s\"baz\"%s\n"+
-			"Body got %smap[string]interface {}{\"foo\":\"bar\"}%s, want %smap[string]interface {}{\"Hello\":\"World\"}%s\n",
+			"Body /Hello: %sgot <missing>, want \"World\"%s\n"+
+			"Body /foo: %sgot \"bar\", want <missing>%s\n",
 		YellowColor, StopColor, YellowColor, StopColor,
 		RedColor, StopColor, RedColor, StopColor,
 		RedColor, StopColor, RedColor, StopColor,
-		RedColor, StopColor, RedColor, StopColor,
+		RedColor, StopColor,
+		RedColor, StopColor,
 	)},
 }
 
@@ -47,8 +51,9 @@ func TestRequestExecute(t *testing.T) {
 
 	Addr = ts.URL[len("http://"):]
 	for i, tt := range requestExecuteTests {
+		// the errors are typed, only their messages are compared
 		err := tt.r.Execute(tt.method, tt.path)
-		if !reflect.DeepEqual(err, tt.err) {
+		if fmt.Sprint(err) != fmt.Sprint(tt.err) {
 			t.Errorf("#%d: err got: \"%v\"\nwant: \"%v\"", i, err, tt.err)
 		}
 	}
@@ -60,38 +65,43 @@ var responseCompareTests = []struct {
 	want error
 }{
 	{
-		Response{200, nil, nil}, &http.Response{StatusCode: 200}, nil,
+		Response{Status: 200}, &http.Response{StatusCode: 200}, nil,
 	}, {
-		Response{400, nil, nil}, &http.Response{StatusCode: 404},
+		Response{Status: 400}, &http.Response{StatusCode: 404},
 		fmt.Errorf("StatusCode got = %s404%s, want %s400%s\n", RedColor, StopColor, RedColor, StopColor),
 	}, {
-		Response{200, Header{"Foo": {"bar"}}, nil},
+		Response{Status: 200, Header: Header{"Foo": {"bar"}}},
 		&http.Response{StatusCode: 200, Header: http.Header{"Foo": {"bar"}}},
 		nil,
 	}, {
-		Response{200, Header{"Foo": {"bar"}}, nil},
+		Response{Status: 200, Header: Header{"Foo": {"bar"}}},
 		&http.Response{StatusCode: 200, Header: http.Header{"Foo": {"baz"}}},
 		fmt.Errorf("Header[\"Foo\"] got = %s\"baz\"%s, want = %s\"bar\"%s\n", RedColor, StopColor, RedColor, StopColor),
 	}, {
-		Response{200, nil, JSONBody{"Hello": "World"}},
+		Response{Status: 200, Body: JSONBody{"Hello": "World"}},
 		&http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(`{"Hello":"World"}`))},
 		nil,
 	}, {
-		Response{200, nil, JSONBody{"Hello": "World"}},
+		Response{Status: 200, Body: JSONBody{"Hello": "World"}},
 		&http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(`{"olleH":"dlroW"}`))},
-		fmt.Errorf("Body got %smap[string]interface {}{\"olleH\":\"dlroW\"}%s, want %smap[string]interface {}{\"Hello\":\"World\"}%s\n", RedColor, StopColor, RedColor, StopColor),
+		fmt.Errorf("Body /Hello: %sgot <missing>, want \"World\"%s\nBody /olleH: %sgot \"dlroW\", want <missing>%s\n", RedColor, StopColor, RedColor, StopColor),
 	}, {
-		Response{200, Header{"Foo": {"bar"}}, JSONBody{"Hello": "World"}},
+		Response{Status: 200, Header: Header{"Foo": {"bar"}}, Body: JSONBody{"Hello": "World"}},
 		&http.Response{StatusCode: 200, Header: http.Header{"Foo": {"bar"}}, Body: ioutil.NopCloser(strings.NewReader(`{"Hello":"World"}`))},
 		nil,
 	}, {
-		Response{400, Header{"Foo": {"bar"}}, JSONBody{"Hello": "World"}},
+		Response{Status: 400, Header: Header{"Foo": {"bar"}}, Body: JSONBody{"Hello": "World"}},
 		&http.Response{StatusCode: 404, Header: http.Header{"Foo": {"baz"}}, Body: ioutil.NopCloser(strings.NewReader(`{"olleH":"dlroW"}`))},
 		fmt.Errorf("%s%s%s",
 			fmt.Sprintf("StatusCode got = %s404%s, want %s400%s\n", RedColor, StopColor, RedColor, StopColor),
 			fmt.Sprintf("Header[\"Foo\"] got = %s\"baz\"%s, want = %s\"bar\"%s\n", RedColor, StopColor, RedColor, StopColor),
-			fmt.Sprintf("Body got %smap[string]interface {}{\"olleH\":\"dlroW\"}%s, want %smap[string]interface {}{\"Hello\":\"World\"}%s\n", RedColor, StopColor, RedColor, StopColor),
+			fmt.Sprintf("Body /Hello: %sgot <missing>, want \"World\"%s\nBody /olleH: %sgot \"dlroW\", want <missing>%s\n", RedColor, StopColor, RedColor, StopColor),
 		),
+	}, {
+		// a nil JSONBody isn't compared, like a nil Body
+		Response{Status: 200, Body: JSONBody(nil)},
+		&http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(`<html></html>`))},
+		nil,
 	},
 }
 
@@ -99,7 +109,7 @@ func TestResponseCompare(t *testing.T) {
 	//t.SkipNow()
 	for i, tt := range responseCompareTests {
 		got := tt.r.Compare(tt.res)
-		if !reflect.DeepEqual(got, tt.want) {
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
 			t.Errorf("#%d: got: \"%s\"\nwant: \"%s\"", i, got, tt.want)
 		}
 	}
@@ -141,6 +151,34 @@ func TestHeaderCompare(t *testing.T) {
 		t.Error("got err <nil>, want err")
 	}
 
+	h = Header{"A": {"bar", "foo"}}
+	if err := h.Compare(hh); err != nil {
+		t.Errorf("got err %v, want <nil>", err)
+	}
+	h = Header{"A": {"foo", "baz"}}
+	w := fmt.Sprintf(`Header["A"] got = %s["foo" "bar"]%s, want all of %s["foo" "baz"]%s`, RedColor, StopColor, RedColor, StopColor)
+	if err := h.Compare(hh); err == nil || !strings.Contains(err.Error(), w) {
+		t.Errorf("error got %v, should contain %q", err, w)
+	}
+}
+
+func TestHeaderCompareOrdered(t *testing.T) {
+	hh := http.Header{"Link": {"<a>; rel=next", "<b>; rel=last"}, "Via": {"1.1 a, 1.1 b"}}
+	tests := []struct {
+		h  Header
+		ok bool
+	}{
+		{Header{"Link": {"<a>; rel=next", "<b>; rel=last"}}, true},
+		{Header{"Link": {"<b>; rel=last", "<a>; rel=next"}}, false},
+		{Header{"Link": {"<a>; rel=next"}}, false},
+		{Header{"Via": {"1.1 a", "1.1 b"}}, true},
+		{Header{"Via": {"1.1 b", "1.1 a"}}, false},
+	}
+	for i, tt := range tests {
+		if err := tt.h.CompareOrdered(hh); (err == nil) != tt.ok {
+			t.Errorf("#%d: got err %v, want ok %t", i, err, tt.ok)
+		}
+	}
 }
 
 var bodyerTests = []struct {
@@ -210,3 +248,311 @@ func TestRequestsSkip(t *testing.T) {
 		t.Errorf("got %+v, want %+v", got, want)
 	}
 }
+
+func TestResponseTransform(t *testing.T) {
+	r := Response{Status: 200, Body: JSONBody{"id": 1}, Transform: Unwrap("data")}
+	res := &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(`{"data":{"id":1}}`))}
+	if err := r.Compare(res); err != nil {
+		t.Errorf("got err %v, want <nil>", err)
+	}
+
+	res = &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(`{"id":1}`))}
+	if err := r.Compare(res); err == nil || !strings.Contains(err.Error(), "Response.Transform failed") {
+		t.Errorf("got err %v, want Transform error", err)
+	}
+}
+
+func TestRequestRepeatIdempotent(t *testing.T) {
+	count := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		if r.Method == "DELETE" && count > 1 {
+			w.WriteHeader(404)
+			return
+		}
+		fmt.Fprintf(w, `{"n":%d}`, count)
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	tests := []struct {
+		method string
+		r      Request
+		want   string
+	}{
+		{"PUT", Request{RepeatIdempotent: 3, Body: JSONBody{"a": 1}, Want: Response{Status: 200}}, ""},
+		{"DELETE", Request{RepeatIdempotent: 3, Want: Response{Status: 200}}, "Response #2 of 3"},
+		{"PUT", Request{RepeatIdempotent: 2, RepeatIdentical: true, Want: Response{Status: 200}}, "want identical to the first"},
+	}
+	for i, tt := range tests {
+		count = 0
+		err := rn.Execute(tt.r, tt.method, "/")
+		if tt.want == "" && err != nil {
+			t.Errorf("#%d: got err %v, want <nil>", i, err)
+		} else if tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+			t.Errorf("#%d: got err %v, want err containing %q", i, err, tt.want)
+		}
+	}
+	if count != 2 {
+		t.Errorf("got %d calls, want 2", count)
+	}
+}
+
+func TestResponseStrictHeader(t *testing.T) {
+	res := &http.Response{StatusCode: 200, Header: http.Header{
+		"Date":         {"Mon, 02 Jan 2006 15:04:05 GMT"},
+		"Content-Type": {"application/json"},
+		"X-Powered-By": {"PHP/5.6"},
+		"Etag":         {`"abc"`},
+	}}
+	r := Response{Status: 200, StrictHeader: true, Header: Header{"Content-Type": {"application/json"}}, AllowHeader: []string{"ETag"}}
+	want := fmt.Sprintf("Header[\"X-Powered-By\"] got = %s[\"PHP/5.6\"]%s, want = %s<not present>%s\n", RedColor, StopColor, RedColor, StopColor)
+	if err := r.Compare(res); err == nil || err.Error() != want {
+		t.Errorf("got err %v, want %q", err, want)
+	}
+
+	r.AllowHeader = append(r.AllowHeader, "x-powered-by")
+	if err := r.Compare(res); err != nil {
+		t.Errorf("got err %v, want <nil>", err)
+	}
+}
+
+func TestResponseNoDuplicateKeys(t *testing.T) {
+	r := Response{Status: 200, NoDuplicateKeys: true, Body: JSONBody{"a": json.Number("2")}}
+	res := &http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(`{"a":2,"a":2}`))}
+	want := "Body has duplicate keys " + RedColor + "/a" + StopColor + "\n"
+	if err := r.Compare(res); err == nil || err.Error() != want {
+		t.Errorf("got err %v, want %q", err, want)
+	}
+}
+
+func TestMaxBodySize(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"data":"`+strings.Repeat("x", 1000)+`"}`)
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	rn.MaxBodySize = 100
+	r := Request{Want: Response{Status: 200, Body: Partial(JSONBody{})}}
+	if err := rn.Execute(r, "GET", "/"); err == nil || !strings.Contains(err.Error(), "exceeds the limit of 100 bytes") {
+		t.Errorf("got err %v, want limit error", err)
+	}
+	r.Want.MaxBodySize = 2000
+	if err := rn.Execute(r, "GET", "/"); err != nil {
+		t.Errorf("got err %v, want <nil>", err)
+	}
+	rn.MaxBodySize = 1011
+	r.Want.MaxBodySize = 0
+	if err := rn.Execute(r, "GET", "/"); err != nil {
+		t.Errorf("got err %v, want <nil> for body of exactly the limit", err)
+	}
+}
+
+func TestBodyReadTimeout(t *testing.T) {
+	done := make(chan struct{})
+	defer close(done)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"data":`)
+		w.(http.Flusher).Flush()
+		if r.URL.Path == "/stall" {
+			select {
+			case <-done:
+			case <-r.Context().Done():
+			}
+		}
+		fmt.Fprint(w, `1}`)
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	r := Request{Want: Response{Status: 200, Body: JSONBody{"data": 1}, BodyReadTimeout: 50 * time.Millisecond}}
+	if err := rn.Execute(r, "GET", "/ok"); err != nil {
+		t.Errorf("got err %v, want <nil>", err)
+	}
+	if err := rn.Execute(r, "GET", "/stall"); err == nil || !strings.Contains(err.Error(), "body read timed out after 50ms") {
+		t.Errorf("got err %v, want timeout error", err)
+	}
+}
+
+func TestHeaderCompareContentType(t *testing.T) {
+	tests := []struct {
+		want string
+		got  string
+		ok   bool
+	}{
+		{"application/json", "application/json", true},
+		{"application/json", "application/json; charset=utf-8", true},
+		{"application/json", "Application/JSON", true},
+		{"application/json", "text/html; charset=utf-8", false},
+		{"application/json; charset=utf-8", "application/json", false},
+		{"application/json; charset=utf-8", "application/json; charset=UTF-8", true},
+		{"text/html; charset=utf-8", "text/html; charset=iso-8859-1", false},
+		{"multipart/form-data; boundary=abc", "multipart/form-data; boundary=abc; charset=utf-8", true},
+		{"multipart/form-data; boundary=abc", "multipart/form-data; boundary=ABC", false},
+		{"not a ; media type", "not a ; media type", true},
+	}
+	for i, tt := range tests {
+		err := Header{"Content-Type": {tt.want}}.Compare(http.Header{"Content-Type": {tt.got}})
+		if (err == nil) != tt.ok {
+			t.Errorf("#%d: got err %v, want ok %t", i, err, tt.ok)
+		}
+	}
+}
+
+func TestResponseSniffContentType(t *testing.T) {
+	tests := []struct {
+		ctype string
+		body  string
+		ok    bool
+	}{
+		{"application/json", `{"message":"Hello"}`, true},
+		{"application/json", "<!DOCTYPE html><html><body>Internal Server Error</body></html>", false},
+		{"text/html; charset=utf-8", "<html><body>Hello</body></html>", true},
+		{"image/png", "\x89PNG\x0D\x0A\x1A\x0A", true},
+		{"application/json", "\x89PNG\x0D\x0A\x1A\x0A", false},
+		{"application/json", "", true},
+	}
+	for i, tt := range tests {
+		res := &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{"Content-Type": {tt.ctype}},
+			Body:       ioutil.NopCloser(strings.NewReader(tt.body)),
+		}
+		want := Response{Status: 200, SniffContentType: true, Body: RawBody(tt.body)}
+		err := want.Compare(res)
+		if (err == nil) != tt.ok {
+			t.Errorf("#%d: got err %v, want ok %t", i, err, tt.ok)
+		}
+	}
+}
+
+func TestRequestHost(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "api.example.com" {
+			w.WriteHeader(404)
+		}
+	}))
+	defer ts.Close()
+
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+	if err := rn.Execute(Request{Host: "api.example.com", Want: Response{Status: 200}}, "GET", "/"); err != nil {
+		t.Error(err)
+	}
+	if err := rn.Execute(Request{Want: Response{Status: 404}}, "GET", "/"); err != nil {
+		t.Error(err)
+	}
+
+	defer func(m map[string]string) { Resolve = m }(Resolve)
+	Resolve = map[string]string{"api.example.com:80": ts.URL[len("http://"):]}
+	rn.Addr = "api.example.com:80"
+	if err := rn.Execute(Request{Host: "api.example.com", Want: Response{Status: 200}}, "GET", "/"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRequestTrailer(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		if len(r.TransferEncoding) == 0 || r.TransferEncoding[0] != "chunked" {
+			w.WriteHeader(411)
+			return
+		}
+		if r.Trailer.Get("X-Checksum") != fmt.Sprintf("%d", len(b)) {
+			w.WriteHeader(400)
+		}
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	tests := []Request{
+		{Body: FormBody{"a": {"b"}}, Trailer: Header{"X-Checksum": {"3"}}, Want: Response{Status: 200}},
+		{Body: FormBody{"a": {"b"}}, Trailer: Header{"X-Checksum": {"4"}}, Want: Response{Status: 400}},
+		{Body: FormBody{"a": {"b"}}, Want: Response{Status: 411}},
+	}
+	for i, r := range tests {
+		if err := rn.Execute(r, "POST", "/"); err != nil {
+			t.Errorf("#%d: %v", i, err)
+		}
+	}
+}
+
+func TestRequestMutate(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Quirk") != "1" || r.URL.RawQuery != "v=2" {
+			w.WriteHeader(400)
+		}
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	r := Request{
+		Mutate: func(req *http.Request) {
+			req.Header.Set("X-Quirk", "1")
+			req.URL.RawQuery = "v=2"
+		},
+		Want: Response{Status: 200},
+	}
+	if err := rn.Execute(r, "GET", "/"); err != nil {
+		t.Error(err)
+	}
+	if err := rn.Execute(Request{Want: Response{Status: 400}}, "GET", "/"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRequestInspect(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`{"id":1}`))
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	inspectBody := func(res *http.Response) error {
+		b, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(string(b), `"id"`) {
+			return fmt.Errorf("body %s has no id", b)
+		}
+		return nil
+	}
+	etag := func(res *http.Response) error {
+		return fmt.Errorf("ETag %s is weak", res.Header.Get("ETag"))
+	}
+	tests := []struct {
+		inspect func(*http.Response) error
+		want    []string
+	}{
+		// the body read by Inspect is still compared
+		{inspectBody, nil},
+		{etag, []string{`Inspect ETag "v1" is weak`}},
+	}
+	for i, tt := range tests {
+		err := rn.Execute(Request{Inspect: tt.inspect, Want: Response{Status: 200, Body: JSONBody{"id": 1}}}, "GET", "/")
+		if tt.want == nil {
+			if err != nil {
+				t.Errorf("#%d: unexpected error %v", i, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("#%d: want error, got nil", i)
+			continue
+		}
+		for _, s := range tt.want {
+			if !strings.Contains(err.Error(), s) {
+				t.Errorf("#%d: error %q does not contain %q", i, err, s)
+			}
+		}
+	}
+}
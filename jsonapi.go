@@ -0,0 +1,123 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// JSONAPIResource returns the expectation of a JSON:API resource object with
+// the specified type, id and attributes. The comparison is partial, members
+// other than those specified, e.g. links or meta, and attributes other than
+// those in attrs are ignored. Relationships can be added to the result's
+// JSONBody under the "relationships" key.
+func JSONAPIResource(typ, id string, attrs JSONBody) Modal {
+	res := JSONBody{"type": typ, "id": id}
+	if attrs != nil {
+		res["attributes"] = attrs
+	}
+	return Partial(res)
+}
+
+// JSONAPIRef returns the expectation of a JSON:API resource identifier object.
+func JSONAPIRef(typ, id string) JSONBody {
+	return JSONBody{"type": typ, "id": id}
+}
+
+// JSONAPIToOne returns the expectation of a to-one relationship object whose
+// resource linkage is the specified resource identifier, or null if ref is nil.
+func JSONAPIToOne(ref JSONBody) Modal {
+	if ref == nil {
+		return Partial(JSONBody{"data": Null()})
+	}
+	return Partial(JSONBody{"data": ref})
+}
+
+// JSONAPIToMany returns the expectation of a to-many relationship object
+// whose resource linkage consists of the specified resource identifiers,
+// in any order.
+func JSONAPIToMany(refs ...JSONBody) Modal {
+	data := make([]interface{}, len(refs))
+	for i, r := range refs {
+		data[i] = r
+	}
+	return Partial(JSONBody{"data": Unordered(data)})
+}
+
+// JSONAPIError represents an expected JSON:API error object, its zero fields
+// match any value.
+type JSONAPIError struct {
+	Status string
+	Code   string
+	Title  string
+	Detail string
+	// Pointer is the JSON pointer in the error's source member.
+	Pointer string
+}
+
+func (e JSONAPIError) expectation() Modal {
+	want := JSONBody{}
+	for k, v := range map[string]string{"status": e.Status, "code": e.Code, "title": e.Title, "detail": e.Detail} {
+		if v != "" {
+			want[k] = v
+		}
+	}
+	if e.Pointer != "" {
+		want["source"] = JSONBody{"pointer": e.Pointer}
+	}
+	return Partial(want)
+}
+
+// JSONAPI represents the expected body of a JSON:API document.
+type JSONAPI struct {
+	// Data, if set, is compared to the document's primary data, e.g. a
+	// JSONAPIResource or a slice of them.
+	Data interface{}
+
+	// Included lists resources that must be present in the document's
+	// included member, in any order.
+	Included []interface{}
+
+	// Errors lists the errors that must be present in the document's
+	// errors member, in any order.
+	Errors []JSONAPIError
+}
+
+// Compare implements the Comparer interface.
+func (j JSONAPI) Compare(r io.Reader) error {
+	var doc map[string]interface{}
+	d := json.NewDecoder(r)
+	d.UseNumber()
+	if err := d.Decode(&doc); err != nil {
+		return fmt.Errorf("hit: error decoding http.Response.Body. %v", err)
+	}
+
+	var msg string
+	check := func(path string, got, want interface{}) {
+		w, err := normalize(want)
+		if err != nil {
+			msg += fmt.Sprintf("hit: JSONAPI %+v, error %v\n", want, err)
+			return
+		}
+		if err := matchJSON(path, got, w, DefaultMode); err != nil {
+			msg += fmt.Sprintf("JSONAPI %s got %s%#v%s\n%v\n", path, RedColor, got, StopColor, err)
+		}
+	}
+	if j.Data != nil {
+		check("/data", doc["data"], j.Data)
+	}
+	for _, inc := range j.Included {
+		check("/included", doc["included"], ContainsItem(inc))
+	}
+	for _, e := range j.Errors {
+		check("/errors", doc["errors"], ContainsItem(e.expectation()))
+	}
+
+	if msg != "" {
+		return fmt.Errorf("%s", msg)
+	}
+	return nil
+}
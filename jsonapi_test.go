@@ -0,0 +1,58 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestJSONAPI(t *testing.T) {
+	const article = `{
+		"data": {
+			"type": "articles", "id": "1",
+			"attributes": {"title": "JSON:API", "body": "..."},
+			"relationships": {
+				"author": {"data": {"type": "people", "id": "9"}, "links": {"self": "/articles/1/relationships/author"}},
+				"tags": {"data": [{"type": "tags", "id": "2"}, {"type": "tags", "id": "1"}]},
+				"editor": {"data": null}
+			},
+			"links": {"self": "/articles/1"}
+		},
+		"included": [{"type": "people", "id": "9", "attributes": {"name": "Dan"}}]
+	}`
+	const invalid = `{"errors": [
+		{"status": "422", "code": "blank", "title": "Invalid Attribute", "source": {"pointer": "/data/attributes/title"}}
+	]}`
+
+	withRels := JSONAPIResource("articles", "1", JSONBody{"title": "JSON:API"})
+	withRels.v.(JSONBody)["relationships"] = JSONBody{
+		"author": JSONAPIToOne(JSONAPIRef("people", "9")),
+		"tags":   JSONAPIToMany(JSONAPIRef("tags", "1"), JSONAPIRef("tags", "2")),
+		"editor": JSONAPIToOne(nil),
+	}
+
+	tests := []struct {
+		want JSONAPI
+		body string
+		err  string
+	}{
+		{JSONAPI{Data: JSONAPIResource("articles", "1", JSONBody{"title": "JSON:API"})}, article, ""},
+		{JSONAPI{Data: withRels}, article, ""},
+		{JSONAPI{Data: JSONAPIResource("articles", "2", nil)}, article, "/data/id"},
+		{JSONAPI{Included: []interface{}{JSONAPIResource("people", "9", JSONBody{"name": "Dan"})}}, article, ""},
+		{JSONAPI{Included: []interface{}{JSONAPIResource("people", "8", nil)}}, article, "JSONAPI /included"},
+		{JSONAPI{Errors: []JSONAPIError{{Status: "422", Pointer: "/data/attributes/title"}}}, invalid, ""},
+		{JSONAPI{Errors: []JSONAPIError{{Code: "taken"}}}, invalid, "JSONAPI /errors"},
+		{JSONAPI{Errors: []JSONAPIError{{Code: "blank"}}}, article, "JSONAPI /errors"},
+	}
+	for i, tt := range tests {
+		err := tt.want.Compare(strings.NewReader(tt.body))
+		if tt.err == "" && err != nil {
+			t.Errorf("#%d: got err %v, want <nil>", i, err)
+		} else if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("#%d: got err %v, want err containing %q", i, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// JWT holds the parameters of a JSON Web Token to be minted for a test. It
+// implements the Signer interface by setting the token as a Bearer token in
+// the request's Authorization header, which makes it easy to table requests
+// with expired, misaddressed or badly signed tokens.
+type JWT struct {
+	// The signing algorithm, either "HS256" or "RS256". Defaults to "HS256".
+	Alg string
+	// The signing key, a []byte for HS256 or an *rsa.PrivateKey for RS256.
+	Key interface{}
+	// The token's claims, e.g. "sub", "aud", "iss".
+	Claims map[string]interface{}
+	// Expiry, if not zero, is added to the current time and set as the
+	// token's "exp" claim. A negative Expiry produces an expired token.
+	Expiry time.Duration
+	// KeyID, if set, is added to the token's header as "kid".
+	KeyID string
+}
+
+// Sign implements the Signer interface.
+func (j JWT) Sign(r *http.Request, body []byte) error {
+	tok, err := j.Token()
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Authorization", "Bearer "+tok)
+	return nil
+}
+
+// Token returns the signed and encoded token.
+func (j JWT) Token() (string, error) {
+	alg := j.Alg
+	if alg == "" {
+		alg = "HS256"
+	}
+	header := map[string]interface{}{"alg": alg, "typ": "JWT"}
+	if j.KeyID != "" {
+		header["kid"] = j.KeyID
+	}
+
+	now := time.Now()
+	claims := map[string]interface{}{"iat": now.Unix()}
+	if j.Expiry != 0 {
+		claims["exp"] = now.Add(j.Expiry).Unix()
+	}
+	for k, v := range j.Claims {
+		claims[k] = v
+	}
+
+	h, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("hit: failed marshaling JWT claims. %v", err)
+	}
+	enc := base64.RawURLEncoding
+	signing := enc.EncodeToString(h) + "." + enc.EncodeToString(c)
+
+	var sig []byte
+	switch alg {
+	case "HS256":
+		key, ok := j.Key.([]byte)
+		if !ok {
+			return "", fmt.Errorf("hit: HS256 JWT requires a []byte Key, got %T", j.Key)
+		}
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(signing))
+		sig = mac.Sum(nil)
+	case "RS256":
+		key, ok := j.Key.(*rsa.PrivateKey)
+		if !ok {
+			return "", fmt.Errorf("hit: RS256 JWT requires an *rsa.PrivateKey Key, got %T", j.Key)
+		}
+		sum := sha256.Sum256([]byte(signing))
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:]); err != nil {
+			return "", fmt.Errorf("hit: failed signing JWT. %v", err)
+		}
+	default:
+		return "", fmt.Errorf("hit: unsupported JWT algorithm %q", alg)
+	}
+	return signing + "." + enc.EncodeToString(sig), nil
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJWTToken(t *testing.T) {
+	rsakey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		j      JWT
+		verify func(signing string, sig []byte) bool
+	}{
+		{JWT{Key: []byte("secret"), Claims: map[string]interface{}{"sub": "jdoe"}, Expiry: -time.Minute},
+			func(signing string, sig []byte) bool {
+				mac := hmac.New(sha256.New, []byte("secret"))
+				mac.Write([]byte(signing))
+				return hmac.Equal(sig, mac.Sum(nil))
+			}},
+		{JWT{Alg: "RS256", Key: rsakey, Claims: map[string]interface{}{"sub": "jdoe"}, Expiry: -time.Minute},
+			func(signing string, sig []byte) bool {
+				sum := sha256.Sum256([]byte(signing))
+				return rsa.VerifyPKCS1v15(&rsakey.PublicKey, crypto.SHA256, sum[:], sig) == nil
+			}},
+	}
+	for i, tt := range tests {
+		tok, err := tt.j.Token()
+		if err != nil {
+			t.Fatalf("#%d: got err %v, want <nil>", i, err)
+		}
+		parts := strings.Split(tok, ".")
+		if len(parts) != 3 {
+			t.Fatalf("#%d: got %d token parts, want 3", i, len(parts))
+		}
+		sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		if !tt.verify(parts[0]+"."+parts[1], sig) {
+			t.Errorf("#%d: signature verification failed", i)
+		}
+
+		var claims struct {
+			Sub string
+			Exp int64
+		}
+		b, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		if err := json.Unmarshal(b, &claims); err != nil {
+			t.Fatal(err)
+		}
+		if claims.Sub != "jdoe" || claims.Exp >= time.Now().Unix() {
+			t.Errorf("#%d: got claims %+v, want sub jdoe and expired", i, claims)
+		}
+	}
+
+	if _, err := (JWT{Key: "not bytes"}).Token(); err == nil {
+		t.Error("got err <nil>, want err")
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Link represents a typed link to a resource.
+type Link struct {
+	Rel    string
+	Href   string
+	Params map[string]string
+}
+
+// ParseLinks parses the RFC 8288 Link headers of the specified http.Header.
+// A link with multiple space separated relation types is returned once per
+// relation type.
+func ParseLinks(h http.Header) []Link {
+	var links []Link
+	for _, v := range h["Link"] {
+		for _, s := range splitLinks(v) {
+			s = strings.TrimSpace(s)
+			if !strings.HasPrefix(s, "<") {
+				continue
+			}
+			end := strings.Index(s, ">")
+			if end < 0 {
+				continue
+			}
+			href, params := s[1:end], make(map[string]string)
+			for _, p := range strings.Split(s[end+1:], ";") {
+				p = strings.TrimSpace(p)
+				if p == "" {
+					continue
+				}
+				k, v := p, ""
+				if i := strings.Index(p, "="); i >= 0 {
+					k, v = strings.TrimSpace(p[:i]), strings.Trim(strings.TrimSpace(p[i+1:]), `"`)
+				}
+				params[strings.ToLower(k)] = v
+			}
+			for _, rel := range strings.Fields(params["rel"]) {
+				links = append(links, Link{Rel: rel, Href: href, Params: params})
+			}
+		}
+	}
+	return links
+}
+
+// splitLinks splits a Link header value at the commas that separate the links,
+// ignoring commas inside of URIs and quoted strings.
+func splitLinks(v string) []string {
+	var out []string
+	var inURI, inQuote bool
+	start := 0
+	for i := 0; i < len(v); i++ {
+		switch c := v[i]; {
+		case c == '<' && !inQuote:
+			inURI = true
+		case c == '>' && !inQuote:
+			inURI = false
+		case c == '"' && !inURI:
+			inQuote = !inQuote
+		case c == ',' && !inURI && !inQuote:
+			out = append(out, v[start:i])
+			start = i + 1
+		}
+	}
+	return append(out, v[start:])
+}
+
+// HALLinks parses the _links member of the specified HAL document. Relations
+// with an array of links are returned once per link, templated links are
+// returned as they are.
+func HALLinks(body []byte) ([]Link, error) {
+	var doc struct {
+		Links map[string]json.RawMessage `json:"_links"`
+	}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, err
+	}
+	type halLink struct {
+		Href      string `json:"href"`
+		Templated bool   `json:"templated"`
+		Type      string `json:"type"`
+		Name      string `json:"name"`
+		Title     string `json:"title"`
+	}
+	rels := make([]string, 0, len(doc.Links))
+	for rel := range doc.Links {
+		rels = append(rels, rel)
+	}
+	sort.Strings(rels)
+
+	var links []Link
+	for _, rel := range rels {
+		var many []halLink
+		if err := json.Unmarshal(doc.Links[rel], &many); err != nil {
+			var one halLink
+			if err := json.Unmarshal(doc.Links[rel], &one); err != nil {
+				return nil, err
+			}
+			many = []halLink{one}
+		}
+		for _, l := range many {
+			params := make(map[string]string)
+			for k, v := range map[string]string{"type": l.Type, "name": l.Name, "title": l.Title} {
+				if v != "" {
+					params[k] = v
+				}
+			}
+			if l.Templated {
+				params["templated"] = "true"
+			}
+			links = append(links, Link{Rel: rel, Href: l.Href, Params: params})
+		}
+	}
+	return links, nil
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestParseLinks(t *testing.T) {
+	h := http.Header{"Link": {
+		`</items?page=2>; rel="next", </items?a=1,2>; rel="prev first"; title="a, b"`,
+		`<https://example.com/x>;rel=alternate;type="text/html"`,
+	}}
+	want := []Link{
+		{Rel: "next", Href: "/items?page=2", Params: map[string]string{"rel": "next"}},
+		{Rel: "prev", Href: "/items?a=1,2", Params: map[string]string{"rel": "prev first", "title": "a, b"}},
+		{Rel: "first", Href: "/items?a=1,2", Params: map[string]string{"rel": "prev first", "title": "a, b"}},
+		{Rel: "alternate", Href: "https://example.com/x", Params: map[string]string{"rel": "alternate", "type": "text/html"}},
+	}
+	if got := ParseLinks(h); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestHALLinks(t *testing.T) {
+	body := []byte(`{"_links": {
+		"self": {"href": "/orders/1"},
+		"items": [{"href": "/items/1"}, {"href": "/items/2", "title": "two"}],
+		"find": {"href": "/orders{?id}", "templated": true}
+	}, "total": 10}`)
+	want := []Link{
+		{Rel: "find", Href: "/orders{?id}", Params: map[string]string{"templated": "true"}},
+		{Rel: "items", Href: "/items/1", Params: map[string]string{}},
+		{Rel: "items", Href: "/items/2", Params: map[string]string{"title": "two"}},
+		{Rel: "self", Href: "/orders/1", Params: map[string]string{}},
+	}
+	got, err := HALLinks(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if _, err := HALLinks([]byte(`{"_links": {"self": 1}}`)); err == nil {
+		t.Error("got err <nil>, want error for a malformed link")
+	}
+}
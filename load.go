@@ -0,0 +1,201 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Load sends a request at a shaped rate, phase after phase. The requests are
+// sent on schedule whether or not the earlier ones have been answered, so a
+// slow server faces a growing number of requests in flight, as it would in
+// production.
+type Load struct {
+	Name    string
+	Method  string
+	Path    string
+	Request Request
+	Phases  []Phase
+}
+
+// Phase is a period of a Load during which the rate changes linearly from
+// From to To requests per second, a To of 0 keeps the rate at From. The
+// phase fails if its requests exceed its criteria.
+type Phase struct {
+	Name     string
+	Duration time.Duration
+	From, To float64
+
+	// MaxErrorRate is the fraction of requests, between 0 and 1, that are
+	// allowed to fail.
+	MaxErrorRate float64
+	// MaxP95, if greater than 0, is the maximum 95th percentile latency.
+	MaxP95 time.Duration
+}
+
+// Ramp returns a phase that ramps the rate linearly from from to to requests
+// per second over the specified duration. A to of 0 holds the rate at from,
+// as it does in a Phase.
+func Ramp(from, to float64, d time.Duration) Phase {
+	return Phase{Name: fmt.Sprintf("ramp %g-%g", from, to), Duration: d, From: from, To: to}
+}
+
+// Steps returns n phases of the specified duration each, the first at from
+// requests per second and every following one step requests per second
+// faster.
+func Steps(from, step float64, n int, d time.Duration) []Phase {
+	pp := make([]Phase, n)
+	for i := range pp {
+		rps := from + float64(i)*step
+		pp[i] = Phase{Name: fmt.Sprintf("step %g", rps), Duration: d, From: rps}
+	}
+	return pp
+}
+
+// Spike returns the phases "base", "spike" and "recovery", the spike bursts
+// from base to peak requests per second, the other two hold the base rate.
+func Spike(base, peak float64, before, spike, after time.Duration) []Phase {
+	return []Phase{
+		{Name: "base", Duration: before, From: base},
+		{Name: "spike", Duration: spike, From: peak},
+		{Name: "recovery", Duration: after, From: base},
+	}
+}
+
+// rate returns the rate of the phase at the specified time into it.
+func (p Phase) rate(at time.Duration) float64 {
+	if p.To == 0 || p.Duration <= 0 {
+		return p.From
+	}
+	return p.From + (p.To-p.From)*float64(at)/float64(p.Duration)
+}
+
+// due returns the number of requests the phase sends in the specified time
+// into it, i.e. the integral of its rate.
+func (p Phase) due(at time.Duration) int {
+	if at > p.Duration {
+		at = p.Duration
+	}
+	s := at.Seconds()
+	return int(math.Floor(s * (p.From + p.rate(at)) / 2))
+}
+
+// PhaseResult holds the outcome of the requests of one phase.
+type PhaseResult struct {
+	Phase     Phase
+	Sent      int
+	Errors    int
+	Durations []time.Duration
+	Latency   Latency
+	// FirstErrors holds up to the first five errors.
+	FirstErrors []error
+}
+
+// LoadReport is the outcome of a Load.
+type LoadReport struct {
+	Phases []PhaseResult
+}
+
+// loadTick is the interval at which the due requests are sent.
+var loadTick = 10 * time.Millisecond
+
+// Run executes the Load and returns its report. It waits for the requests of
+// a phase to be answered before it reports the phase, but it starts the next
+// phase on time.
+func (l Load) Run() *LoadReport {
+	return globalRunner().Load(l)
+}
+
+// Load executes the Load against the Runner's target and returns its report.
+func (rn *Runner) Load(l Load) *LoadReport {
+	rep := &LoadReport{Phases: make([]PhaseResult, len(l.Phases))}
+	var wg sync.WaitGroup
+	seq := 0 // the number of the next request, for templates
+	for i, p := range l.Phases {
+		res := &rep.Phases[i]
+		res.Phase = p
+		var mu sync.Mutex
+		start := time.Now()
+		for {
+			at := time.Since(start)
+			for n := p.due(at); res.Sent < n; res.Sent++ {
+				r := l.Request
+				r.iter = seq
+				seq++
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					t := time.Now()
+					err := rn.Execute(r, l.Method, l.Path)
+					d := time.Since(t)
+					mu.Lock()
+					defer mu.Unlock()
+					res.Durations = append(res.Durations, d)
+					if err != nil {
+						res.Errors++
+						if len(res.FirstErrors) < 5 {
+							res.FirstErrors = append(res.FirstErrors, err)
+						}
+					}
+				}()
+			}
+			if at >= p.Duration {
+				break
+			}
+			time.Sleep(loadTick)
+		}
+	}
+	wg.Wait()
+	for i := range rep.Phases {
+		rep.Phases[i].Latency = latencyOf(rep.Phases[i].Durations)
+	}
+	return rep
+}
+
+// Test executes the Load and fails if any phase exceeds its criteria.
+func (l Load) Test(t *testing.T) {
+	rep := l.Run()
+	t.Log(rep)
+	if err := l.check(rep); err != nil {
+		t.Error(err)
+	}
+}
+
+func (l Load) check(rep *LoadReport) error {
+	var msg string
+	for _, res := range rep.Phases {
+		p := res.Phase
+		if res.Sent > 0 && float64(res.Errors)/float64(res.Sent) > p.MaxErrorRate {
+			msg += fmt.Sprintf("Phase %q failed %s%d%s of %d requests, want at most %s%.1f%%%s\n",
+				p.Name, RedColor, res.Errors, StopColor, res.Sent, RedColor, p.MaxErrorRate*100, StopColor)
+			for _, err := range res.FirstErrors {
+				msg += fmt.Sprintf("%v\n", err)
+			}
+		}
+		if p.MaxP95 > 0 && res.Latency.P95 > p.MaxP95 {
+			msg += fmt.Sprintf("Phase %q p95 latency got = %s%s%s, want at most %s%s%s\n",
+				p.Name, RedColor, res.Latency.P95, StopColor, RedColor, p.MaxP95, StopColor)
+		}
+	}
+	if msg != "" {
+		return fmt.Errorf("%sLoad %q:%s\n%s", PurpleColor, l.Name, StopColor, msg)
+	}
+	return nil
+}
+
+// String returns the report formatted as a table of the phases.
+func (rep *LoadReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-16s %8s %8s %12s %12s %12s\n", "phase", "sent", "errors", "p50", "p95", "max")
+	for _, res := range rep.Phases {
+		fmt.Fprintf(&b, "%-16s %8d %8d %12s %12s %12s\n", res.Phase.Name,
+			res.Sent, res.Errors, res.Latency.P50, res.Latency.P95, res.Latency.Max)
+	}
+	return b.String()
+}
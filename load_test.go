@@ -0,0 +1,89 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPhaseDue(t *testing.T) {
+	tests := []struct {
+		phase Phase
+		at    time.Duration
+		want  int
+	}{
+		{Phase{Duration: time.Second, From: 100}, 500 * time.Millisecond, 50},
+		{Phase{Duration: time.Second, From: 100}, 2 * time.Second, 100},
+		{Ramp(0, 100, time.Second), 500 * time.Millisecond, 12},
+		{Ramp(0, 100, time.Second), time.Second, 50},
+		{Ramp(100, 0, time.Second), time.Second, 100},
+		{Ramp(100, 300, time.Second), time.Second, 200},
+	}
+	for i, tt := range tests {
+		if got := tt.phase.due(tt.at); got != tt.want {
+			t.Errorf("#%d: got %d, want %d", i, got, tt.want)
+		}
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	pp := Steps(10, 5, 3, time.Second)
+	if len(pp) != 3 || pp[0].From != 10 || pp[2].From != 20 || pp[2].Name != "step 20" {
+		t.Errorf("Steps got %+v", pp)
+	}
+	pp = Spike(10, 100, time.Second, 2*time.Second, time.Second)
+	if len(pp) != 3 || pp[1].Name != "spike" || pp[1].From != 100 || pp[1].Duration != 2*time.Second || pp[2].From != 10 {
+		t.Errorf("Spike got %+v", pp)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	var calls int64
+	var spiking int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&calls, 1)
+		if atomic.LoadInt32(&spiking) == 1 {
+			w.WriteHeader(503)
+		}
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	l := Load{
+		Name:    "ping",
+		Method:  "GET",
+		Path:    "/",
+		Request: Request{Want: Response{Status: 200}},
+		Phases: []Phase{
+			{Name: "warm", Duration: 100 * time.Millisecond, From: 100},
+			{Name: "ramp", Duration: 100 * time.Millisecond, From: 100, To: 300, MaxP95: time.Second},
+		},
+	}
+	rep := rn.Load(l)
+	if got := rep.Phases[0].Sent; got != 10 {
+		t.Errorf("warm sent %d, want 10", got)
+	}
+	if got := rep.Phases[1].Sent; got != 20 {
+		t.Errorf("ramp sent %d, want 20", got)
+	}
+	if got := atomic.LoadInt64(&calls); got != 30 {
+		t.Errorf("server got %d requests, want 30", got)
+	}
+	if err := l.check(rep); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	atomic.StoreInt32(&spiking, 1)
+	l.Phases = []Phase{{Name: "spike", Duration: 50 * time.Millisecond, From: 100, MaxErrorRate: 0.5}}
+	err := l.check(rn.Load(l))
+	if err == nil || !strings.Contains(err.Error(), `Phase "spike" failed`) {
+		t.Errorf("spike: got error %v, want phase failure", err)
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// LongPoll describes the expected behavior of a long-poll endpoint: the
+// response is held until Trigger causes an event, after which it must
+// arrive within the timeout.
+type LongPoll struct {
+	// Hold is how long the response is expected to be held before Trigger
+	// is called, a response that arrives earlier fails the test. It
+	// defaults to 100 milliseconds.
+	Hold time.Duration
+	// Trigger is called while the request is held, it should cause the
+	// side action that the endpoint is waiting for.
+	Trigger func() error
+	// Within is the time allowed for the response to arrive after Trigger
+	// returns, it defaults to 5 seconds.
+	Within time.Duration
+}
+
+// longPoll sends the request, calls the trigger while the response is held
+// and compares the response to Want.
+func (r Request) longPoll(method, path string) error {
+	lp := r.LongPoll
+	hold, within := lp.Hold, lp.Within
+	if hold == 0 {
+		hold = 100 * time.Millisecond
+	}
+	if within == 0 {
+		within = 5 * time.Second
+	}
+
+	req, err := r.newRequest(method, path)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	type result struct {
+		res  *http.Response
+		body []byte
+		err  error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		res, err := r.runner().resend(req.WithContext(ctx))
+		if err != nil {
+			ch <- result{err: err}
+			return
+		}
+		b, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		ch <- result{res, b, err}
+	}()
+
+	select {
+	case rr := <-ch:
+		if rr.err != nil {
+			return fmt.Errorf("hit: %s %s failed. %v", method, path, rr.err)
+		}
+		return r.failure(method, path, fmt.Sprintf("Response got = %sbefore the trigger%s, want = %sheld for %s%s\n",
+			RedColor, StopColor, RedColor, hold, StopColor))
+	case <-time.After(hold):
+	}
+
+	if lp.Trigger != nil {
+		if err := lp.Trigger(); err != nil {
+			return fmt.Errorf("hit: %s %s long-poll trigger failed. %v", method, path, err)
+		}
+	}
+
+	select {
+	case rr := <-ch:
+		if rr.err != nil {
+			return fmt.Errorf("hit: %s %s failed. %v", method, path, rr.err)
+		}
+		rr.res.Body = ioutil.NopCloser(bytes.NewReader(rr.body))
+		if ll := r.check(rr.res); len(ll) > 0 {
+			return r.failure(method, path, ll.Error(), ll...)
+		}
+		return nil
+	case <-time.After(within):
+		return r.failure(method, path, fmt.Sprintf("Response got = %s<none>%s, want = %sa response within %s of the trigger%s\n",
+			RedColor, StopColor, RedColor, within, StopColor))
+	}
+}
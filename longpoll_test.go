@@ -0,0 +1,49 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequestLongPoll(t *testing.T) {
+	events := make(chan string, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/now" {
+			w.Write([]byte(`{"event":"none"}`))
+			return
+		}
+		select {
+		case e := <-events:
+			w.Write([]byte(`{"event":"` + e + `"}`))
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	trigger := func() error { events <- "created"; return nil }
+	r := Request{
+		LongPoll: &LongPoll{Hold: 50 * time.Millisecond, Trigger: trigger},
+		Want:     Response{Status: 200, Body: JSONBody{"event": "created"}},
+	}
+	if err := rn.Execute(r, "GET", "/poll"); err != nil {
+		t.Error(err)
+	}
+
+	r.Want.Body = JSONBody{"event": "none"}
+	if err := rn.Execute(r, "GET", "/now"); err == nil || !strings.Contains(err.Error(), "before the trigger") {
+		t.Errorf("got err %v, want early response failure", err)
+	}
+
+	r.LongPoll = &LongPoll{Hold: 10 * time.Millisecond, Within: 50 * time.Millisecond, Trigger: func() error { return nil }}
+	if err := rn.Execute(r, "GET", "/poll"); err == nil || !strings.Contains(err.Error(), "a response within") {
+		t.Errorf("got err %v, want timeout failure", err)
+	}
+}
@@ -0,0 +1,377 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Matcher is the interface implemented by values that can be used in place of
+// a literal expected value, inside a JSONBody or a Response's HeaderMatch, to
+// match a range of actual values.
+//
+// Values from a JSON body are passed to Match as decoded by encoding/json with
+// UseNumber set, i.e. as a string, json.Number, bool, nil, []interface{} or
+// map[string]interface{}. Header values are passed to Match as strings.
+type Matcher interface {
+	Match(got interface{}) error
+}
+
+// MatcherFunc is an adapter that allows the use of an ordinary function as
+// a Matcher. The name is used to describe the Matcher in failure messages.
+func MatcherFunc(name string, fn func(got interface{}) error) Matcher {
+	return funcMatcher{name, fn}
+}
+
+type funcMatcher struct {
+	name string
+	fn   func(got interface{}) error
+}
+
+func (m funcMatcher) Match(got interface{}) error { return m.fn(got) }
+func (m funcMatcher) GoString() string            { return "hit." + m.name }
+
+// searchMatcher is a Matcher that tries the got values against the expected
+// ones in search of a match, using the mode of the enclosing comparison.
+// Values are captured only from the match it settles on, and not at all
+// while it's itself part of a search.
+type searchMatcher struct {
+	name string
+	fn   func(got interface{}, mode Mode) error
+}
+
+func (m searchMatcher) Match(got interface{}) error { return m.fn(got, DefaultMode) }
+func (m searchMatcher) GoString() string            { return "hit." + m.name }
+
+func (m searchMatcher) matchMode(got interface{}, mode Mode) error {
+	return m.fn(got, mode)
+}
+
+// Any returns a Matcher that matches any value, including null. It's useful for
+// fields that must be present but whose value is not of interest.
+func Any() Matcher {
+	return MatcherFunc("Any()", func(got interface{}) error { return nil })
+}
+
+// Null returns a Matcher that matches a JSON null. Unlike Missing it requires
+// the field to be present. A nil expected value has the same meaning.
+func Null() Matcher {
+	return MatcherFunc("Null()", func(got interface{}) error {
+		if got != nil {
+			return fmt.Errorf("got %#v, want null", got)
+		}
+		return nil
+	})
+}
+
+// Missing returns a Matcher that, used as the value of an expected object's
+// field, matches only if the field is absent from the actual object. A field
+// present with a null value is not considered missing.
+func Missing() Matcher { return missing{} }
+
+type missing struct{}
+
+func (missing) Match(got interface{}) error { return fmt.Errorf("got %#v, want field to be missing", got) }
+func (missing) GoString() string            { return "hit.Missing()" }
+
+// NotEmpty returns a Matcher that matches any value other than null, an empty
+// string, an empty array or an empty object.
+func NotEmpty() Matcher {
+	return MatcherFunc("NotEmpty()", func(got interface{}) error {
+		switch v := got.(type) {
+		case nil:
+			return fmt.Errorf("got null, want non-empty value")
+		case string:
+			if v == "" {
+				return fmt.Errorf("got empty string, want non-empty value")
+			}
+		case []interface{}:
+			if len(v) == 0 {
+				return fmt.Errorf("got empty array, want non-empty value")
+			}
+		case map[string]interface{}:
+			if len(v) == 0 {
+				return fmt.Errorf("got empty object, want non-empty value")
+			}
+		}
+		return nil
+	})
+}
+
+// OneOf returns a Matcher that matches a value equal to, or matched by, any of
+// the specified values.
+func OneOf(vals ...interface{}) Matcher {
+	names := make([]string, len(vals))
+	for i, v := range vals {
+		names[i] = fmt.Sprintf("%#v", v)
+	}
+	return searchMatcher{"OneOf(" + strings.Join(names, ", ") + ")", func(got interface{}, mode Mode) error {
+		for _, v := range vals {
+			want, err := normalize(v)
+			if err != nil {
+				return err
+			}
+			if matchJSON("", got, want, mode|dryCapture) == nil {
+				return matchJSON("", got, want, mode)
+			}
+		}
+		return fmt.Errorf("%#v is not one of %s", got, strings.Join(names, ", "))
+	}}
+}
+
+// Len returns a Matcher that matches a string with n characters, an array with
+// n elements or an object with n fields.
+func Len(n int) Matcher {
+	return MatcherFunc(fmt.Sprintf("Len(%d)", n), func(got interface{}) error {
+		var l int
+		switch v := got.(type) {
+		case string:
+			l = utf8.RuneCountInString(v)
+		case []interface{}:
+			l = len(v)
+		case map[string]interface{}:
+			l = len(v)
+		default:
+			return fmt.Errorf("got %T, want string, array or object", got)
+		}
+		if l != n {
+			return fmt.Errorf("got length %d, want %d", l, n)
+		}
+		return nil
+	})
+}
+
+// LenIs returns a Matcher that matches an array with exactly n elements, it's
+// Len restricted to arrays.
+func LenIs(n int) Matcher {
+	m := Len(n)
+	return MatcherFunc(fmt.Sprintf("LenIs(%d)", n), func(got interface{}) error {
+		if _, ok := got.([]interface{}); !ok {
+			return fmt.Errorf("got %T, want array", got)
+		}
+		return m.Match(got)
+	})
+}
+
+// ContainsItem returns a Matcher that matches an array that has at least one
+// element equal to, or matched by, v.
+func ContainsItem(v interface{}) Matcher {
+	return searchMatcher{fmt.Sprintf("ContainsItem(%#v)", v), func(got interface{}, mode Mode) error {
+		arr, ok := got.([]interface{})
+		if !ok {
+			return fmt.Errorf("got %T, want array", got)
+		}
+		want, err := normalize(v)
+		if err != nil {
+			return err
+		}
+		for _, g := range arr {
+			if matchJSON("", g, want, mode|dryCapture) == nil {
+				return matchJSON("", g, want, mode)
+			}
+		}
+		return fmt.Errorf("no element matches %#v", v)
+	}}
+}
+
+// SubsetOf returns a Matcher that matches an array whose every element is
+// equal to, or matched by, one of the specified values.
+func SubsetOf(vals ...interface{}) Matcher {
+	return searchMatcher{fmt.Sprintf("SubsetOf(%#v)", vals), func(got interface{}, mode Mode) error {
+		arr, ok := got.([]interface{})
+		if !ok {
+			return fmt.Errorf("got %T, want array", got)
+		}
+		want, err := normalize(vals)
+		if err != nil {
+			return err
+		}
+	elems:
+		for i, g := range arr {
+			for _, w := range want.([]interface{}) {
+				if matchJSON("", g, w, mode|dryCapture) == nil {
+					matchJSON("", g, w, mode)
+					continue elems
+				}
+			}
+			return fmt.Errorf("element %d (%#v) is not in the set", i, g)
+		}
+		return nil
+	}}
+}
+
+// GT returns a Matcher that matches a number greater than n.
+func GT(n interface{}) Matcher {
+	return compareNumber("GT", n, func(a, b float64) bool { return a > b })
+}
+
+// LT returns a Matcher that matches a number less than n.
+func LT(n interface{}) Matcher {
+	return compareNumber("LT", n, func(a, b float64) bool { return a < b })
+}
+
+func compareNumber(name string, n interface{}, cmp func(a, b float64) bool) Matcher {
+	return MatcherFunc(fmt.Sprintf("%s(%v)", name, n), func(got interface{}) error {
+		want, err := toFloat(n)
+		if err != nil {
+			return err
+		}
+		g, err := toFloat(got)
+		if err != nil {
+			return err
+		}
+		if !cmp(g, want) {
+			return fmt.Errorf("%v is not %s %v", got, name, n)
+		}
+		return nil
+	})
+}
+
+// toFloat converts a numeric value, a json.Number or a numeric string
+// into a float64.
+func toFloat(v interface{}) (float64, error) {
+	switch v := v.(type) {
+	case json.Number:
+		return v.Float64()
+	case string:
+		return strconv.ParseFloat(v, 64)
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	}
+	return 0, fmt.Errorf("got %T, want number", v)
+}
+
+// TimeNear returns a Matcher that matches a time value that's within d of t.
+// The value can be a string in the RFC 3339 or in the HTTP date format, or a
+// number representing a unix timestamp in seconds.
+func TimeNear(t time.Time, d time.Duration) Matcher {
+	name := fmt.Sprintf("TimeNear(%s, %s)", t.Format(time.RFC3339), d)
+	return MatcherFunc(name, func(got interface{}) error {
+		tt, err := parseTime(got)
+		if err != nil {
+			return err
+		}
+		if diff := tt.Sub(t); diff > d || diff < -d {
+			return fmt.Errorf("time %s is %s away from %s", tt.Format(time.RFC3339), diff, t.Format(time.RFC3339))
+		}
+		return nil
+	})
+}
+
+// RFC3339 returns a Matcher that matches any string formatted as an RFC 3339
+// timestamp, with or without fractional seconds.
+func RFC3339() Matcher {
+	return MatcherFunc("RFC3339()", func(got interface{}) error {
+		s, ok := got.(string)
+		if !ok {
+			return fmt.Errorf("got %T, want string", got)
+		}
+		if _, err := time.Parse(time.RFC3339Nano, s); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+// UUID returns a Matcher that matches any string formatted as a UUID in its
+// canonical, hyphenated, form regardless of its version.
+func UUID() Matcher {
+	return MatcherFunc("UUID()", func(got interface{}) error {
+		return matchRegexp(got, uuidRegexp, "UUID")
+	})
+}
+
+// UUIDv4 returns a Matcher that matches any string formatted as a version 4
+// UUID in its canonical, hyphenated, form.
+func UUIDv4() Matcher {
+	return MatcherFunc("UUIDv4()", func(got interface{}) error {
+		return matchRegexp(got, uuidv4Regexp, "version 4 UUID")
+	})
+}
+
+// ULID returns a Matcher that matches any string formatted as a ULID.
+func ULID() Matcher {
+	return MatcherFunc("ULID()", func(got interface{}) error {
+		return matchRegexp(got, ulidRegexp, "ULID")
+	})
+}
+
+var (
+	uuidRegexp   = regexp.MustCompile(`^(?i)[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	uuidv4Regexp = regexp.MustCompile(`^(?i)[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	// the first character of a ULID is at most 7 as it's 128 bits long
+	ulidRegexp = regexp.MustCompile(`^(?i)[0-7][0-9a-hjkmnp-tv-z]{25}$`)
+)
+
+func matchRegexp(got interface{}, re *regexp.Regexp, what string) error {
+	s, ok := got.(string)
+	if !ok {
+		return fmt.Errorf("got %T, want string", got)
+	}
+	if !re.MatchString(s) {
+		return fmt.Errorf("%q is not a valid %s", s, what)
+	}
+	return nil
+}
+
+// parseTime parses a time value as accepted by TimeNear.
+func parseTime(v interface{}) (time.Time, error) {
+	switch v := v.(type) {
+	case string:
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return t, nil
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			return t, nil
+		}
+		return time.Time{}, fmt.Errorf("%q is not a valid time", v)
+	case json.Number:
+		n, err := v.Float64()
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(0, int64(n*float64(time.Second))), nil
+	}
+	return time.Time{}, fmt.Errorf("got %T, want a time string or number", v)
+}
+
+// compareHeaderMatch checks that the specified http.Header's values match the
+// corresponding Matchers.
+func compareHeaderMatch(hm map[string]Matcher, hh http.Header) error {
+	var keys []string
+	for k := range hm {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var ll ErrorList
+	for _, k := range keys {
+		if err := hm[k].Match(hh.Get(k)); err != nil {
+			ll = append(ll, &HeaderError{
+				Name: k,
+				Got:  hh.Values(k),
+				Want: []string{fmt.Sprintf("%#v", hm[k])},
+				form: headerMatch,
+				err:  err,
+			})
+		}
+	}
+	return ll.err()
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+var matcherTests = []struct {
+	m    Matcher
+	got  interface{}
+	want bool
+}{
+	{Any(), nil, true},
+	{Any(), "foo", true},
+	{NotEmpty(), "foo", true},
+	{NotEmpty(), json.Number("0"), true},
+	{NotEmpty(), "", false},
+	{NotEmpty(), nil, false},
+	{NotEmpty(), []interface{}{}, false},
+	{NotEmpty(), map[string]interface{}{}, false},
+	{OneOf("a", "b"), "b", true},
+	{OneOf("a", "b"), "c", false},
+	{OneOf(1, 2), json.Number("2"), true},
+	{OneOf(1, NotEmpty()), "x", true},
+	{Len(3), "föo", true},
+	{Len(3), []interface{}{1, 2, 3}, true},
+	{Len(1), map[string]interface{}{"a": 1}, true},
+	{Len(2), "foo", false},
+	{Len(2), json.Number("12"), false},
+	{GT(0), json.Number("0.5"), true},
+	{GT(0), json.Number("0"), false},
+	{GT(1.5), "2", true},
+	{LT(uint8(10)), json.Number("9"), true},
+	{LT(10), json.Number("10"), false},
+	{LT(10), true, false},
+	{LenIs(2), []interface{}{1, 2}, true},
+	{LenIs(2), []interface{}{1}, false},
+	{LenIs(2), "ab", false},
+	{ContainsItem("b"), []interface{}{"a", "b"}, true},
+	{ContainsItem(map[string]interface{}{"id": 2}), []interface{}{map[string]interface{}{"id": json.Number("2")}}, true},
+	{ContainsItem(GT(5)), []interface{}{json.Number("1"), json.Number("2")}, false},
+	{ContainsItem("b"), "b", false},
+	{SubsetOf("a", "b", "c"), []interface{}{"c", "a"}, true},
+	{SubsetOf("a", "b"), []interface{}{}, true},
+	{SubsetOf("a", "b"), []interface{}{"a", "d"}, false},
+	{TimeNear(time.Date(2015, 1, 2, 3, 4, 5, 0, time.UTC), time.Second), "2015-01-02T03:04:05.5Z", true},
+	{TimeNear(time.Date(2015, 1, 2, 3, 4, 5, 0, time.UTC), time.Second), "2015-01-02T03:04:07Z", false},
+	{TimeNear(time.Date(2015, 1, 2, 3, 4, 5, 0, time.UTC), time.Second), "Fri, 02 Jan 2015 03:04:05 GMT", true},
+	{TimeNear(time.Date(2015, 1, 2, 3, 4, 5, 0, time.UTC), time.Second), json.Number("1420167845"), true},
+	{TimeNear(time.Date(2015, 1, 2, 3, 4, 5, 0, time.UTC), time.Second), true, false},
+	{RFC3339(), "2015-01-02T03:04:05+01:00", true},
+	{RFC3339(), "2015-01-02 03:04:05", false},
+	{RFC3339(), json.Number("1"), false},
+	{UUID(), "6ba7b810-9dad-11d1-80b4-00c04fd430c8", true},
+	{UUID(), "6ba7b8109dad11d180b400c04fd430c8", false},
+	{UUIDv4(), "f47ac10b-58cc-4372-a567-0e02b2c3d479", true},
+	{UUIDv4(), "F47AC10B-58CC-4372-A567-0E02B2C3D479", true},
+	{UUIDv4(), "6ba7b810-9dad-11d1-80b4-00c04fd430c8", false},
+	{UUIDv4(), json.Number("4"), false},
+	{ULID(), "01ARZ3NDEKTSV4RRFFQ69G5FAV", true},
+	{ULID(), "01arz3ndektsv4rrffq69g5fav", true},
+	{ULID(), "01ARZ3NDEKTSV4RRFFQ69G5FAU", false},
+	{ULID(), "81ARZ3NDEKTSV4RRFFQ69G5FAV", false},
+}
+
+func TestMatchers(t *testing.T) {
+	for i, tt := range matcherTests {
+		if err := tt.m.Match(tt.got); (err == nil) != tt.want {
+			t.Errorf("#%d: %#v.Match(%#v) got err %v, want match %t", i, tt.m, tt.got, err, tt.want)
+		}
+	}
+}
+
+func TestJSONBodyMatchers(t *testing.T) {
+	now := time.Now()
+	b := JSONBody{"id": 1, "created": TimeNear(now, time.Minute), "items": []interface{}{map[string]interface{}{"at": RFC3339()}}}
+	body := `{"id":1,"created":"` + now.Format(time.RFC3339) + `","items":[{"at":"2015-01-02T03:04:05Z"}]}`
+	if err := b.Compare(strings.NewReader(body)); err != nil {
+		t.Errorf("got err %v, want <nil>", err)
+	}
+	body = `{"id":1,"created":"` + now.Add(-time.Hour).Format(time.RFC3339) + `","items":[{"at":"2015-01-02T03:04:05Z"}]}`
+	if err := b.Compare(strings.NewReader(body)); err == nil || !strings.Contains(err.Error(), "hit.TimeNear(") {
+		t.Errorf("got err %v, want TimeNear mismatch", err)
+	}
+}
+
+func TestSearchMatchersMode(t *testing.T) {
+	body := `{"a":[{"x":1,"y":2}],"b":{"x":1,"y":2},"c":[[2,1]]}`
+	tests := []struct {
+		b    Comparer
+		pass bool
+	}{
+		{JSONBody{"a": ContainsItem(JSONBody{"x": 1})}, false},
+		{Partial(JSONBody{"a": ContainsItem(JSONBody{"x": 1})}), true},
+		{Partial(JSONBody{"a": SubsetOf(JSONBody{"x": 1})}), true},
+		{Partial(JSONBody{"b": OneOf(JSONBody{"x": 2}, JSONBody{"x": 1})}), true},
+		{Partial(JSONBody{"c": ContainsItem([]int{1, 2})}), false},
+		{Partial(Unordered(JSONBody{"c": ContainsItem([]int{1, 2})})), true},
+	}
+	for i, tt := range tests {
+		if err := tt.b.Compare(strings.NewReader(body)); (err == nil) != tt.pass {
+			t.Errorf("#%d: got err %v, want pass %t", i, err, tt.pass)
+		}
+	}
+}
+
+func TestResponseHeaderMatch(t *testing.T) {
+	r := Response{Status: 200, HeaderMatch: map[string]Matcher{"Date": TimeNear(time.Now(), time.Minute)}}
+	res := &http.Response{StatusCode: 200, Header: http.Header{"Date": {time.Now().UTC().Format(http.TimeFormat)}}}
+	if err := r.Compare(res); err != nil {
+		t.Errorf("got err %v, want <nil>", err)
+	}
+	res.Header.Set("Date", "yesterday")
+	if err := r.Compare(res); err == nil || !strings.Contains(err.Error(), `Header["Date"] got = `+RedColor+`"yesterday"`) {
+		t.Errorf("got err %v, want Date mismatch", err)
+	}
+	var he *HeaderError
+	if err := r.Compare(res); !errors.As(err, &he) || he.Name != "Date" || len(he.Want) != 1 || !strings.HasPrefix(he.Want[0], "hit.TimeNear(") {
+		t.Errorf("got err %#v, want a *HeaderError with the Matcher", err)
+	}
+	if err := r.Compare(res); !errors.Is(err, &HeaderError{Name: "date"}) {
+		t.Errorf("got err %v, want it to be a Date HeaderError", err)
+	}
+}
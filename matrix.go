@@ -0,0 +1,183 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"strings"
+)
+
+// Matrix expands a base Request across the combinations of the values of its
+// Axes, e.g. header sets × body variants, into named Requests, e.g.
+//
+//	Matrix{
+//		Base: Request{Body: JSONBody{"name": "foo"}, Want: Response{Status: 201}},
+//		Axes: []Axis{{
+//			Name: "auth",
+//			Values: []AxisValue{
+//				{Name: "valid", Change: WithHeader("Authorization", "Bearer t0ken")},
+//				{Name: "none"},
+//			},
+//		}, {
+//			Name: "body",
+//			Values: []AxisValue{
+//				{Name: "valid"},
+//				{Name: "empty", Change: WithBody(JSONBody{})},
+//			},
+//		}},
+//		Want: func(c map[string]string) Response { ... },
+//	}.Expand()
+type Matrix struct {
+	Base Request
+	Axes []Axis
+
+	// Want, if set, returns the expected response of a combination, which
+	// maps each axis name to the name of the value picked from it, e.g.
+	// a 401 if c["auth"] == "none" and a 422 if c["body"] == "empty".
+	// It's called after the values' Changes are applied.
+	Want func(c map[string]string) Response
+
+	// Pairwise, if set, expands into fewer Requests that still combine
+	// every value of each axis with every value of each other axis, rather
+	// than into every combination of all the axes.
+	Pairwise bool
+}
+
+// Axis represents one dimension of a Matrix.
+type Axis struct {
+	Name   string
+	Values []AxisValue
+}
+
+// AxisValue represents a named value of an Axis, its Change, if any, is
+// applied to the Requests combining it with the values of the other Axes.
+type AxisValue struct {
+	Name   string
+	Change Change
+}
+
+// Expand returns a Request for every combination, or every pairwise
+// combination, of the Matrix's Axes, named after the base Request's Name
+// followed by the axis=value pairs of the combination, e.g. "auth=none
+// body=empty".
+func (m Matrix) Expand() []Request {
+	var rows [][]int
+	if m.Pairwise && len(m.Axes) > 2 {
+		rows = pairwise(m.Axes)
+	} else {
+		rows = product(m.Axes)
+	}
+
+	rr := make([]Request, 0, len(rows))
+	for _, row := range rows {
+		r := m.Base.Clone()
+		c := make(map[string]string, len(m.Axes))
+		names := make([]string, 0, len(m.Axes)+1)
+		if m.Base.Name != "" {
+			names = append(names, m.Base.Name)
+		}
+		for i, a := range m.Axes {
+			v := a.Values[row[i]]
+			if v.Change != nil {
+				v.Change(&r)
+			}
+			c[a.Name] = v.Name
+			names = append(names, a.Name+"="+v.Name)
+		}
+		if m.Want != nil {
+			r.Want = m.Want(c)
+		}
+		r.Name = strings.Join(names, " ")
+		rr = append(rr, r)
+	}
+	return rr
+}
+
+// product returns every combination of the indexes of the axes' values, in
+// order, with the last axis varying fastest. An axis without values yields
+// no combinations.
+func product(axes []Axis) [][]int {
+	rows := [][]int{{}}
+	for _, a := range axes {
+		var next [][]int
+		for _, row := range rows {
+			for v := range a.Values {
+				next = append(next, append(row[:len(row):len(row)], v))
+			}
+		}
+		rows = next
+	}
+	return rows
+}
+
+// pairwise returns combinations of the indexes of the axes' values that
+// cover every pair of values of any two axes. Each combination is seeded
+// with the first pair not yet covered and is completed greedily, axis by
+// axis, with the value that covers the most pairs not yet covered.
+func pairwise(axes []Axis) [][]int {
+	for _, a := range axes {
+		if len(a.Values) == 0 {
+			return nil
+		}
+	}
+	type pair struct{ i, a, j, b int }
+	covered := make(map[pair]bool)
+	next := func() (pair, bool) {
+		for i := range axes {
+			for j := i + 1; j < len(axes); j++ {
+				for a := range axes[i].Values {
+					for b := range axes[j].Values {
+						if p := (pair{i, a, j, b}); !covered[p] {
+							return p, true
+						}
+					}
+				}
+			}
+		}
+		return pair{}, false
+	}
+
+	var rows [][]int
+	for {
+		p, ok := next()
+		if !ok {
+			return rows
+		}
+		row := make([]int, len(axes))
+		for k := range row {
+			row[k] = -1
+		}
+		row[p.i], row[p.j] = p.a, p.b
+		for k := range axes {
+			if row[k] >= 0 {
+				continue
+			}
+			best, most := 0, -1
+			for v := range axes[k].Values {
+				n := 0
+				for l, w := range row {
+					if w < 0 || l == k {
+						continue
+					}
+					q := pair{l, w, k, v}
+					if k < l {
+						q = pair{k, v, l, w}
+					}
+					if !covered[q] {
+						n++
+					}
+				}
+				if n > most {
+					best, most = v, n
+				}
+			}
+			row[k] = best
+		}
+		for i := range row {
+			for j := i + 1; j < len(row); j++ {
+				covered[pair{i, row[i], j, row[j]}] = true
+			}
+		}
+		rows = append(rows, row)
+	}
+}
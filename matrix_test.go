@@ -0,0 +1,123 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestMatrix(t *testing.T) {
+	m := Matrix{
+		Base: Request{
+			Name:   "create user",
+			Header: Header{"Accept": {"application/json"}},
+			Body:   JSONBody{"name": "foo"},
+		},
+		Axes: []Axis{{
+			Name: "auth",
+			Values: []AxisValue{
+				{Name: "valid", Change: WithHeader("Authorization", "Bearer t0ken")},
+				{Name: "none"},
+			},
+		}, {
+			Name: "body",
+			Values: []AxisValue{
+				{Name: "valid"},
+				{Name: "empty", Change: WithBody(JSONBody{})},
+			},
+		}},
+		Want: func(c map[string]string) Response {
+			switch {
+			case c["auth"] == "none":
+				return Response{Status: 401}
+			case c["body"] == "empty":
+				return Response{Status: 422}
+			}
+			return Response{Status: 201}
+		},
+	}
+
+	want := []Request{{
+		Name:   "create user auth=valid body=valid",
+		Header: Header{"Accept": {"application/json"}, "Authorization": {"Bearer t0ken"}},
+		Body:   JSONBody{"name": "foo"},
+		Want:   Response{Status: 201},
+	}, {
+		Name:   "create user auth=valid body=empty",
+		Header: Header{"Accept": {"application/json"}, "Authorization": {"Bearer t0ken"}},
+		Body:   JSONBody{},
+		Want:   Response{Status: 422},
+	}, {
+		Name:   "create user auth=none body=valid",
+		Header: Header{"Accept": {"application/json"}},
+		Body:   JSONBody{"name": "foo"},
+		Want:   Response{Status: 401},
+	}, {
+		Name:   "create user auth=none body=empty",
+		Header: Header{"Accept": {"application/json"}},
+		Body:   JSONBody{},
+		Want:   Response{Status: 401},
+	}}
+	if got := m.Expand(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+	if len(m.Base.Header) != 1 {
+		t.Errorf("got base modified: %+v", m.Base)
+	}
+
+	m.Axes = append(m.Axes, Axis{Name: "empty"})
+	if got := m.Expand(); len(got) != 0 {
+		t.Errorf("got %d requests of an axis without values, want 0", len(got))
+	}
+}
+
+func TestMatrixPairwise(t *testing.T) {
+	tests := []struct {
+		sizes []int
+		max   int
+	}{
+		{sizes: []int{2, 2, 2}, max: 6},
+		{sizes: []int{3, 3, 3, 3}, max: 12},
+		{sizes: []int{4, 2, 3, 2, 2}, max: 16},
+	}
+
+	for i, tt := range tests {
+		m := Matrix{Pairwise: true}
+		total := 1
+		for a, n := range tt.sizes {
+			ax := Axis{Name: strconv.Itoa(a)}
+			for v := 0; v < n; v++ {
+				ax.Values = append(ax.Values, AxisValue{Name: strconv.Itoa(v)})
+			}
+			m.Axes = append(m.Axes, ax)
+			total *= n
+		}
+
+		rr := m.Expand()
+		if len(rr) > tt.max || len(rr) >= total {
+			t.Errorf("#%d: got %d requests, want at most %d", i, len(rr), tt.max)
+		}
+		seen := make(map[string]bool)
+		for _, row := range pairwise(m.Axes) {
+			for a := range row {
+				for b := a + 1; b < len(row); b++ {
+					seen[strconv.Itoa(a)+"="+strconv.Itoa(row[a])+","+strconv.Itoa(b)+"="+strconv.Itoa(row[b])] = true
+				}
+			}
+		}
+		for a, n := range tt.sizes {
+			for b := a + 1; b < len(tt.sizes); b++ {
+				for x := 0; x < n; x++ {
+					for y := 0; y < tt.sizes[b]; y++ {
+						if k := strconv.Itoa(a) + "=" + strconv.Itoa(x) + "," + strconv.Itoa(b) + "=" + strconv.Itoa(y); !seen[k] {
+							t.Errorf("#%d: got pair %s uncovered", i, k)
+						}
+					}
+				}
+			}
+		}
+	}
+}
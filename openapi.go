@@ -0,0 +1,325 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OpenAPI builds a draft OpenAPI 3 document from the exchanges of the
+// executed requests, e.g. to bootstrap the specification of a service that
+// has none. It records the paths, with the numeric and the UUID segments
+// turned into parameters, the methods, the query parameters, the observed
+// status codes and the schemas inferred from the JSON bodies.
+type OpenAPI struct {
+	Title   string
+	Version string
+
+	mu    sync.Mutex
+	paths map[string]map[string]*apiOperation
+}
+
+type apiOperation struct {
+	params    []string
+	query     map[string]bool
+	request   *apiContent
+	responses map[int]*apiContent
+}
+
+// apiContent is the observed content of a request or a response body.
+type apiContent struct {
+	typ    string
+	schema *apiSchema
+}
+
+// apiSchema is the subset of the OpenAPI schema object that is inferred from
+// the observed JSON values.
+type apiSchema struct {
+	Type       string                `json:"type,omitempty"`
+	Format     string                `json:"format,omitempty"`
+	Nullable   bool                  `json:"nullable,omitempty"`
+	Items      *apiSchema            `json:"items,omitempty"`
+	Properties map[string]*apiSchema `json:"properties,omitempty"`
+	Required   []string              `json:"required,omitempty"`
+}
+
+// Record records the exchanges of all the executed requests until the
+// returned stop function is called.
+func (o *OpenAPI) Record() (stop func()) {
+	return observe(o.add)
+}
+
+// add adds the exchange to the document.
+func (o *OpenAPI) add(x Exchange) {
+	u, err := url.Parse(x.Path)
+	if err != nil {
+		return
+	}
+	path, params := templatePath(u.Path)
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.paths == nil {
+		o.paths = make(map[string]map[string]*apiOperation)
+	}
+	if o.paths[path] == nil {
+		o.paths[path] = make(map[string]*apiOperation)
+	}
+	m := strings.ToLower(x.Method)
+	op := o.paths[path][m]
+	if op == nil {
+		op = &apiOperation{params: params, query: make(map[string]bool), responses: make(map[int]*apiContent)}
+		o.paths[path][m] = op
+	}
+	for k := range u.Query() {
+		op.query[k] = true
+	}
+	if len(x.Body) > 0 {
+		op.request = op.request.add(x.Header.Get("Content-Type"), x.Body)
+	}
+	res := op.responses[x.Status]
+	if len(x.ResponseBody) > 0 {
+		res = res.add(x.ResponseHeader.Get("Content-Type"), x.ResponseBody)
+	} else if res == nil {
+		res = &apiContent{}
+	}
+	op.responses[x.Status] = res
+}
+
+// add merges the body of the specified content type into c, which may be
+// nil, and returns the result.
+func (c *apiContent) add(contentType string, body []byte) *apiContent {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mt = "application/octet-stream"
+	}
+	s := &apiSchema{Type: "string"}
+	if mt == appjson || strings.HasSuffix(mt, "+json") {
+		var v interface{}
+		d := json.NewDecoder(bytes.NewReader(body))
+		d.UseNumber()
+		if d.Decode(&v) == nil {
+			s = inferSchema(v)
+		}
+	}
+	if c == nil || c.typ == "" {
+		return &apiContent{typ: mt, schema: s}
+	}
+	if c.typ == mt {
+		c.schema = mergeSchema(c.schema, s)
+	}
+	return c
+}
+
+// paramSegment matches the path segments that are turned into parameters.
+var paramSegment = regexp.MustCompile(`^([0-9]+|(?i)[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})$`)
+
+// templatePath returns the path with its numeric and UUID segments replaced
+// by the parameters {id}, {id2} and so on, and the names of the parameters.
+func templatePath(path string) (string, []string) {
+	segs := strings.Split(path, "/")
+	var params []string
+	for i, s := range segs {
+		if !paramSegment.MatchString(s) {
+			continue
+		}
+		name := "id"
+		if len(params) > 0 {
+			name += strconv.Itoa(len(params) + 1)
+		}
+		params = append(params, name)
+		segs[i] = "{" + name + "}"
+	}
+	return strings.Join(segs, "/"), params
+}
+
+// inferSchema returns the schema of the decoded JSON value.
+func inferSchema(v interface{}) *apiSchema {
+	switch v := v.(type) {
+	case nil:
+		return &apiSchema{Nullable: true}
+	case bool:
+		return &apiSchema{Type: "boolean"}
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return &apiSchema{Type: "integer"}
+		}
+		return &apiSchema{Type: "number"}
+	case string:
+		s := &apiSchema{Type: "string"}
+		if uuidRegexp.MatchString(v) {
+			s.Format = "uuid"
+		} else if _, err := time.Parse(time.RFC3339, v); err == nil {
+			s.Format = "date-time"
+		}
+		return s
+	case []interface{}:
+		s := &apiSchema{Type: "array"}
+		for _, x := range v {
+			s.Items = mergeSchema(s.Items, inferSchema(x))
+		}
+		if s.Items == nil {
+			s.Items = &apiSchema{}
+		}
+		return s
+	case map[string]interface{}:
+		s := &apiSchema{Type: "object", Properties: make(map[string]*apiSchema, len(v))}
+		for k, x := range v {
+			s.Properties[k] = inferSchema(x)
+			s.Required = append(s.Required, k)
+		}
+		sort.Strings(s.Required)
+		return s
+	}
+	return &apiSchema{}
+}
+
+// mergeSchema returns a schema that describes the values of both a and b,
+// either of which may be nil. The properties of an object are required only
+// if they're present in both.
+func mergeSchema(a, b *apiSchema) *apiSchema {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	case a.Type == "" && a.Nullable:
+		c := *b
+		c.Nullable = true
+		return &c
+	case b.Type == "" && b.Nullable:
+		c := *a
+		c.Nullable = true
+		return &c
+	}
+	nullable := a.Nullable || b.Nullable
+	if a.Type != b.Type {
+		if (a.Type == "integer" || a.Type == "number") && (b.Type == "integer" || b.Type == "number") {
+			return &apiSchema{Type: "number", Nullable: nullable}
+		}
+		return &apiSchema{Nullable: nullable}
+	}
+	c := &apiSchema{Type: a.Type, Nullable: nullable}
+	if a.Format == b.Format {
+		c.Format = a.Format
+	}
+	switch a.Type {
+	case "array":
+		c.Items = mergeSchema(a.Items, b.Items)
+	case "object":
+		c.Properties = make(map[string]*apiSchema)
+		for k, s := range a.Properties {
+			c.Properties[k] = mergeSchema(s, b.Properties[k])
+		}
+		for k, s := range b.Properties {
+			if _, ok := a.Properties[k]; !ok {
+				c.Properties[k] = s
+			}
+		}
+		for _, k := range a.Required {
+			if _, ok := b.Properties[k]; ok && containsString(b.Required, k) {
+				c.Required = append(c.Required, k)
+			}
+		}
+	}
+	return c
+}
+
+func containsString(ss []string, s string) bool {
+	for _, x := range ss {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Document returns the OpenAPI document of the exchanges recorded so far.
+func (o *OpenAPI) Document() map[string]interface{} {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	title, version := o.Title, o.Version
+	if title == "" {
+		title = "API"
+	}
+	if version == "" {
+		version = "0.0.0"
+	}
+	paths := make(map[string]interface{}, len(o.paths))
+	for p, ops := range o.paths {
+		item := make(map[string]interface{}, len(ops))
+		for m, op := range ops {
+			item[m] = op.document()
+		}
+		paths[p] = item
+	}
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info":    map[string]interface{}{"title": title, "version": version},
+		"paths":   paths,
+	}
+}
+
+func (op *apiOperation) document() map[string]interface{} {
+	doc := make(map[string]interface{})
+	var params []interface{}
+	for _, p := range op.params {
+		params = append(params, map[string]interface{}{
+			"name": p, "in": "path", "required": true, "schema": &apiSchema{Type: "string"},
+		})
+	}
+	var query []string
+	for k := range op.query {
+		query = append(query, k)
+	}
+	sort.Strings(query)
+	for _, k := range query {
+		params = append(params, map[string]interface{}{
+			"name": k, "in": "query", "schema": &apiSchema{Type: "string"},
+		})
+	}
+	if len(params) > 0 {
+		doc["parameters"] = params
+	}
+	if op.request != nil {
+		doc["requestBody"] = map[string]interface{}{"content": op.request.document()}
+	}
+	responses := make(map[string]interface{}, len(op.responses))
+	for status, c := range op.responses {
+		res := map[string]interface{}{"description": http.StatusText(status)}
+		if c.typ != "" {
+			res["content"] = c.document()
+		}
+		responses[strconv.Itoa(status)] = res
+	}
+	doc["responses"] = responses
+	return doc
+}
+
+func (c *apiContent) document() map[string]interface{} {
+	return map[string]interface{}{c.typ: map[string]interface{}{"schema": c.schema}}
+}
+
+// WriteJSON writes the OpenAPI document, indented, to w.
+func (o *OpenAPI) WriteJSON(w io.Writer) error {
+	b, err := json.MarshalIndent(o.Document(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("hit: failed encoding the OpenAPI document. %v", err)
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
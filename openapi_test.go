@@ -0,0 +1,142 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOpenAPI(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		switch {
+		case r.Method == "POST":
+			w.WriteHeader(201)
+			fmt.Fprint(w, `{"id":3,"name":"cid","created":"2015-01-02T03:04:05Z"}`)
+		case r.URL.Path == "/users":
+			fmt.Fprint(w, `[{"id":1,"name":"ann","team":null},{"id":2,"name":"bob","team":"x"}]`)
+		case r.URL.Path == "/users/2":
+			fmt.Fprint(w, `{"id":2,"name":"bob","score":1.5}`)
+		case r.URL.Path == "/users/1":
+			fmt.Fprint(w, `{"id":1,"name":"ann","score":2}`)
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	o := &OpenAPI{Title: "Users", Version: "1"}
+	stop := o.Record()
+	for _, x := range []struct {
+		method, path string
+		r            Request
+	}{
+		{"GET", "/users?limit=2", Request{Want: Response{Status: 200}}},
+		{"POST", "/users", Request{Body: JSONBody{"name": "cid"}, Want: Response{Status: 201}}},
+		{"GET", "/users/1", Request{Want: Response{Status: 200}}},
+		{"GET", "/users/2", Request{Want: Response{Status: 200}}},
+		{"GET", "/users/0f8fad5b-d9cb-469f-a165-70867728950e", Request{Want: Response{Status: 404}}},
+	} {
+		if err := rn.Execute(x.r, x.method, x.path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stop()
+	if err := rn.Execute(Request{Want: Response{Status: 404}}, "GET", "/after/stop"); err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err := o.WriteJSON(&b); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+		Info    struct{ Title, Version string }
+		Paths   map[string]map[string]struct {
+			Parameters []struct{ Name, In string }
+			Responses  map[string]struct {
+				Content map[string]struct{ Schema apiSchema }
+			}
+			RequestBody *struct {
+				Content map[string]struct{ Schema apiSchema }
+			}
+		}
+	}
+	if err := json.Unmarshal(b.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.OpenAPI != "3.0.3" || doc.Info.Title != "Users" || doc.Info.Version != "1" {
+		t.Errorf("got header %q %+v", doc.OpenAPI, doc.Info)
+	}
+	var paths []string
+	for p := range doc.Paths {
+		paths = append(paths, p)
+	}
+	if want := []string{"/users", "/users/{id}"}; len(paths) != 2 || doc.Paths["/users"] == nil || doc.Paths["/users/{id}"] == nil {
+		t.Fatalf("got paths %q, want %q", paths, want)
+	}
+
+	list := doc.Paths["/users"]["get"]
+	if len(list.Parameters) != 1 || list.Parameters[0].Name != "limit" || list.Parameters[0].In != "query" {
+		t.Errorf("got list parameters %+v", list.Parameters)
+	}
+	items := list.Responses["200"].Content["application/json"].Schema.Items
+	if items == nil || items.Properties["team"] == nil || !items.Properties["team"].Nullable || items.Properties["team"].Type != "string" {
+		t.Errorf("got list items %+v", items)
+	}
+
+	create := doc.Paths["/users"]["post"]
+	if create.RequestBody == nil || create.RequestBody.Content["application/json"].Schema.Properties["name"] == nil {
+		t.Errorf("got request body %+v", create.RequestBody)
+	}
+	if s := create.Responses["201"].Content["application/json"].Schema; s.Properties["created"] == nil || s.Properties["created"].Format != "date-time" {
+		t.Errorf("got created schema %+v", s)
+	}
+
+	get := doc.Paths["/users/{id}"]["get"]
+	if len(get.Parameters) != 1 || get.Parameters[0].Name != "id" || get.Parameters[0].In != "path" {
+		t.Errorf("got get parameters %+v", get.Parameters)
+	}
+	s := get.Responses["200"].Content["application/json"].Schema
+	if s.Properties["score"] == nil || s.Properties["score"].Type != "number" || s.Properties["id"].Type != "integer" {
+		t.Errorf("got user schema %+v", s)
+	}
+	if !reflect.DeepEqual(s.Required, []string{"id", "name", "score"}) {
+		t.Errorf("got required %q", s.Required)
+	}
+	if _, ok := get.Responses["404"]; !ok {
+		t.Errorf("got responses %v, want a 404", get.Responses)
+	}
+	if strings.Contains(b.String(), "/after/stop") {
+		t.Error("document has an exchange recorded after stop")
+	}
+}
+
+func TestTemplatePath(t *testing.T) {
+	tests := []struct {
+		path, want string
+		params     []string
+	}{
+		{"/users", "/users", nil},
+		{"/users/42", "/users/{id}", []string{"id"}},
+		{"/users/42/posts/0F8FAD5B-D9CB-469F-A165-70867728950E", "/users/{id}/posts/{id2}", []string{"id", "id2"}},
+		{"/v2/users", "/v2/users", nil},
+	}
+	for i, tt := range tests {
+		got, params := templatePath(tt.path)
+		if got != tt.want || !reflect.DeepEqual(params, tt.params) {
+			t.Errorf("#%d: got %q %q, want %q %q", i, got, params, tt.want, tt.params)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// BytesOfSize returns a Bodyer of type application/octet-stream whose body
+// is n bytes long. The bytes are generated as the body is read, so large
+// payloads don't need to be kept in memory or in the test binary.
+func BytesOfSize(n int64) Bodyer { return bytesBody(n) }
+
+type bytesBody int64
+
+func (b bytesBody) Type() string { return "application/octet-stream" }
+
+func (b bytesBody) Body() (io.Reader, error) {
+	return io.LimitReader(repeatReader('a'), int64(b)), nil
+}
+
+func (b bytesBody) Size() int64 { return int64(b) }
+
+// RepeatField returns a Bodyer of type application/json whose body is a JSON
+// object with a single string field with the specified name whose value is
+// n bytes long. Like with BytesOfSize the value is generated lazily.
+func RepeatField(name string, n int64) Bodyer {
+	key, _ := json.Marshal(name)
+	return repeatField{prefix: "{" + string(key) + `:"`, n: n}
+}
+
+type repeatField struct {
+	prefix string
+	n      int64
+}
+
+func (b repeatField) Type() string { return appjson }
+
+func (b repeatField) Body() (io.Reader, error) {
+	return io.MultiReader(
+		strings.NewReader(b.prefix),
+		io.LimitReader(repeatReader('x'), b.n),
+		strings.NewReader(`"}`),
+	), nil
+}
+
+func (b repeatField) Size() int64 { return int64(len(b.prefix)) + b.n + 2 }
+
+// repeatReader is an endless io.Reader of the same byte.
+type repeatReader byte
+
+func (r repeatReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = byte(r)
+	}
+	return len(p), nil
+}
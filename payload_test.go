@@ -0,0 +1,80 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPayloadGenerators(t *testing.T) {
+	tests := []struct {
+		body  Bodyer
+		typ   string
+		check func([]byte) bool
+	}{
+		{BytesOfSize(0), "application/octet-stream", func(b []byte) bool { return len(b) == 0 }},
+		{BytesOfSize(100000), "application/octet-stream", func(b []byte) bool {
+			return len(b) == 100000 && strings.Count(string(b), "a") == 100000
+		}},
+		{RepeatField("x", 10000), appjson, func(b []byte) bool {
+			var v map[string]string
+			return json.Unmarshal(b, &v) == nil && len(v["x"]) == 10000
+		}},
+		{RepeatField(`"quoted"`, 3), appjson, func(b []byte) bool {
+			return string(b) == `{"\"quoted\"":"xxx"}`
+		}},
+	}
+	for i, tt := range tests {
+		if got := tt.body.Type(); got != tt.typ {
+			t.Errorf("#%d: Type() got %q, want %q", i, got, tt.typ)
+		}
+		r, err := tt.body.Body()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !tt.check(b) {
+			t.Errorf("#%d: unexpected body of %d bytes", i, len(b))
+		}
+		if s := tt.body.(interface{ Size() int64 }).Size(); s != int64(len(b)) {
+			t.Errorf("#%d: Size() got %d, want %d", i, s, len(b))
+		}
+	}
+}
+
+func TestPayloadTooLarge(t *testing.T) {
+	const limit = 1 << 20
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > limit {
+			w.WriteHeader(413)
+			return
+		}
+		n, _ := io.Copy(ioutil.Discard, r.Body)
+		if n != r.ContentLength {
+			w.WriteHeader(400)
+		}
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	for _, r := range []Request{
+		{Body: BytesOfSize(limit), Want: Response{Status: 200}},
+		{Body: BytesOfSize(limit + 1), Want: Response{Status: 413}},
+		{Body: RepeatField("x", limit), Want: Response{Status: 413}},
+	} {
+		if err := rn.Execute(r, "POST", "/"); err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// ProfileEnv is the environment variable that names the profile selected by
+// SelectProfile.
+const ProfileEnv = "HIT_PROFILE"
+
+// Profile describes a target environment, e.g. local, staging or production,
+// so that the same suite can be run against any of them.
+type Profile struct {
+	Name string
+	// BaseURL, if set, is used in place of "http://"+Addr, e.g.
+	// "https://staging.example.com".
+	BaseURL string
+	// Header is added to every request that doesn't set the same header.
+	Header Header
+	// TLS, if set, configures the TLS client of the default transport.
+	TLS *tls.Config
+	// ReadOnly, if set, skips the requests with methods other than GET,
+	// HEAD and OPTIONS, e.g. for running a suite against production.
+	ReadOnly bool
+}
+
+// profile is the active profile, if any.
+var profile *Profile
+
+// UseProfile makes the specified profile the active one.
+func UseProfile(p Profile) {
+	profile = &p
+	defaultTransport.TLSClientConfig = p.TLS
+	defaultTransport.CloseIdleConnections()
+}
+
+// SelectProfile makes the profile named by the HIT_PROFILE environment
+// variable the active one. If the variable is not set the active profile is
+// left as it is, if it names none of the specified profiles an error is
+// returned.
+func SelectProfile(profiles ...Profile) error {
+	name := os.Getenv(ProfileEnv)
+	if name == "" {
+		return nil
+	}
+	var names []string
+	for _, p := range profiles {
+		if p.Name == name {
+			UseProfile(p)
+			return nil
+		}
+		names = append(names, p.Name)
+	}
+	return fmt.Errorf("hit: unknown profile %q, want one of %q", name, names)
+}
+
+// ActiveProfile returns the active profile, if any.
+func ActiveProfile() (Profile, bool) {
+	if profile == nil {
+		return Profile{}, false
+	}
+	return *profile, true
+}
+
+// addHeader adds the profile's headers to the request, p may be nil.
+func (p *Profile) addHeader(req *http.Request) {
+	if p == nil {
+		return
+	}
+	for k, vv := range p.Header {
+		if _, ok := req.Header[http.CanonicalHeaderKey(k)]; ok {
+			continue
+		}
+		for _, v := range vv {
+			req.Header.Add(k, v)
+		}
+	}
+}
+
+// skips reports whether the profile skips the specified method, p may be nil.
+func (p *Profile) skips(method string) bool {
+	if p == nil || !p.ReadOnly {
+		return false
+	}
+	switch strings.ToUpper(method) {
+	case "GET", "HEAD", "OPTIONS":
+		return false
+	}
+	return true
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestProfile(t *testing.T) {
+	var posts int
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			posts++
+		}
+		if r.Header.Get("X-Env") != "staging" || r.Header.Get("X-Other") != "mine" {
+			w.WriteHeader(400)
+		}
+	}))
+	defer ts.Close()
+	defer func(p *Profile) {
+		profile = p
+		defaultTransport.TLSClientConfig = nil
+	}(profile)
+	defer os.Setenv(ProfileEnv, os.Getenv(ProfileEnv))
+
+	profiles := []Profile{
+		{Name: "local", BaseURL: "http://localhost:1"},
+		{
+			Name:     "staging",
+			BaseURL:  ts.URL + "/",
+			Header:   Header{"X-Env": {"staging"}, "X-Other": {"default"}},
+			TLS:      ts.Client().Transport.(*http.Transport).TLSClientConfig,
+			ReadOnly: true,
+		},
+	}
+	os.Setenv(ProfileEnv, "staging")
+	if err := SelectProfile(profiles...); err != nil {
+		t.Fatal(err)
+	}
+	if p, ok := ActiveProfile(); !ok || p.Name != "staging" {
+		t.Fatalf("got active profile %q, want %q", p.Name, "staging")
+	}
+
+	h := Hit{Path: "/users", Requests: Requests{
+		"GET":  {{Header: Header{"X-Other": {"mine"}}, Want: Response{Status: 200}}},
+		"POST": {{Header: Header{"X-Other": {"mine"}}, Want: Response{Status: 201}}},
+	}}
+	h.Test(t)
+	if posts != 0 {
+		t.Errorf("got %d POST requests, want 0 in a read-only profile", posts)
+	}
+
+	os.Setenv(ProfileEnv, "prod")
+	if err := SelectProfile(profiles...); err == nil || !strings.Contains(err.Error(), `"prod"`) {
+		t.Errorf("got err %v, want unknown profile error", err)
+	}
+}
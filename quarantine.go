@@ -0,0 +1,107 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+// Quarantine lists the known-flaky Requests, by name, that are executed and
+// reported but whose failures don't fail the tests. Every entry expires, after
+// which the Request's failures fail the tests again, so that no Request is
+// quarantined and forgotten.
+var Quarantine []Quarantined
+
+// Quarantined is an entry of a Quarantine.
+type Quarantined struct {
+	// Name is the Name of the quarantined Request, which defaults to the
+	// method followed by the path.
+	Name   string
+	Reason string
+	// Until is the day on which the entry expires.
+	Until time.Time
+}
+
+// quarantineEntry is an entry of a quarantine file.
+type quarantineEntry struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+	Until  string `json:"until"`
+}
+
+// LoadQuarantine reads a quarantine file, a JSON array of entries, e.g.
+//
+//	[{"name": "GET /reports", "reason": "#123 slow replica", "until": "2016-03-01"}]
+//
+// Every entry must have an expiry date, in the form YYYY-MM-DD.
+func LoadQuarantine(file string) ([]Quarantined, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("hit: failed reading quarantine. %v", err)
+	}
+	var ee []quarantineEntry
+	if err := json.Unmarshal(b, &ee); err != nil {
+		return nil, fmt.Errorf("hit: failed decoding quarantine %q. %v", file, err)
+	}
+	qq := make([]Quarantined, len(ee))
+	for i, e := range ee {
+		if e.Name == "" {
+			return nil, fmt.Errorf("hit: quarantine %q entry #%d has no name", file, i+1)
+		}
+		until, err := time.ParseInLocation("2006-01-02", e.Until, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("hit: quarantine %q entry %q has an invalid expiry date. %v", file, e.Name, err)
+		}
+		qq[i] = Quarantined{Name: e.Name, Reason: e.Reason, Until: until}
+	}
+	return qq, nil
+}
+
+// name returns the Request's Name or its default.
+func (r Request) name(method, path string) string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return method + " " + path
+}
+
+// quarantined returns the Runner's Quarantine entry of the named Request.
+func (rn *Runner) quarantined(name string) (Quarantined, bool) {
+	for _, q := range rn.Quarantine {
+		if q.Name == name {
+			return q, true
+		}
+	}
+	return Quarantined{}, false
+}
+
+// expired reports whether the entry has expired by the specified time, the
+// entry holds through the whole day of Until.
+func (q Quarantined) expired(now time.Time) bool {
+	return !now.Before(q.Until.AddDate(0, 0, 1))
+}
+
+// report logs the outcome of the quarantined Request and returns the error
+// that fails the test, if any, i.e. the Request's error once the entry has
+// expired.
+func (q Quarantined) report(t *testing.T, err error) error {
+	until := q.Until.Format("2006-01-02")
+	if q.expired(time.Now()) {
+		if err != nil {
+			return fmt.Errorf("%vQuarantine of %q (%s) expired on %s.\n", err, q.Name, q.Reason, until)
+		}
+		t.Logf("Quarantine of %q expired on %s, the request passes, remove it from the quarantine.", q.Name, until)
+		return nil
+	}
+	if err != nil {
+		t.Logf("Quarantined %q (%s) until %s failed:\n%v", q.Name, q.Reason, until, err)
+	} else {
+		t.Logf("Quarantined %q (%s) until %s passed.", q.Name, q.Reason, until)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadQuarantine(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		json string
+		want string
+	}{
+		{`[{"name": "GET /a", "reason": "#1", "until": "2016-03-01"}]`, ""},
+		{`[{"name": "GET /a", "reason": "#1"}]`, "invalid expiry date"},
+		{`[{"reason": "#1", "until": "2016-03-01"}]`, "has no name"},
+		{`{}`, "failed decoding"},
+	}
+	for i, tt := range tests {
+		file := filepath.Join(dir, "quarantine.json")
+		if err := ioutil.WriteFile(file, []byte(tt.json), 0644); err != nil {
+			t.Fatal(err)
+		}
+		qq, err := LoadQuarantine(file)
+		if tt.want != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("#%d: got error %v, want it to contain %q", i, err, tt.want)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("#%d: unexpected error %v", i, err)
+			continue
+		}
+		want := time.Date(2016, 3, 1, 0, 0, 0, 0, time.Local)
+		if len(qq) != 1 || qq[0].Name != "GET /a" || qq[0].Reason != "#1" || !qq[0].Until.Equal(want) {
+			t.Errorf("#%d: got %+v", i, qq)
+		}
+	}
+}
+
+func TestQuarantined(t *testing.T) {
+	today := time.Now()
+	day := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.Local)
+	fail := errors.New("failed\n")
+	tests := []struct {
+		until time.Time
+		err   error
+		want  string
+	}{
+		{until: day, err: fail},
+		{until: day, err: nil},
+		{until: day.AddDate(0, 0, -1), err: nil},
+		{until: day.AddDate(0, 0, -1), err: fail, want: "expired on"},
+	}
+	for i, tt := range tests {
+		q := Quarantined{Name: "GET /a", Reason: "#1", Until: tt.until}
+		err := q.report(t, tt.err)
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("#%d: unexpected error %v", i, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("#%d: got error %v, want it to contain %q", i, err, tt.want)
+		}
+	}
+}
+
+func TestQuarantineHit(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(503)
+	}))
+	defer ts.Close()
+
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+	rn.Quarantine = []Quarantined{
+		{Name: "GET /a", Until: time.Now().AddDate(0, 1, 0)},
+		{Name: "flaky", Until: time.Now().AddDate(0, 1, 0)},
+	}
+	if _, ok := rn.quarantined((Request{}).name("GET", "/b")); ok {
+		t.Error("GET /b: got quarantined, want not")
+	}
+	// both failing requests are quarantined, one by default name, one by
+	// its own, so the test passes
+	rn.Test(t, Hit{Path: "/a", Requests: Requests{"GET": {
+		{Want: Response{Status: 200}},
+	}}})
+	rn.Test(t, Hit{Path: "/b", Requests: Requests{"GET": {
+		{Name: "flaky", Want: Response{Status: 200}},
+	}}})
+}
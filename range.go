@@ -0,0 +1,196 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ByteRange represents a single range of a Range request, End is inclusive.
+// An End less than 0 requests the rest of the resource from Start on.
+type ByteRange struct {
+	Start, End int64
+}
+
+// String returns the range in the form of a Range header value.
+func (br ByteRange) String() string {
+	if br.End < 0 {
+		return fmt.Sprintf("bytes=%d-", br.Start)
+	}
+	return fmt.Sprintf("bytes=%d-%d", br.Start, br.End)
+}
+
+// contentRange parses the value of a Content-Range header, the total length
+// is -1 if it's unknown, i.e. "*". The start and the end are -1 for the
+// "bytes */total" form of unsatisfiable ranges.
+func contentRange(s string) (start, end, total int64, err error) {
+	bad := fmt.Errorf("invalid Content-Range %q", s)
+	if !strings.HasPrefix(s, "bytes ") {
+		return 0, 0, 0, bad
+	}
+	s = strings.TrimPrefix(s, "bytes ")
+	i := strings.IndexByte(s, '/')
+	if i < 0 {
+		return 0, 0, 0, bad
+	}
+	rng, size := s[:i], s[i+1:]
+	if total = -1; size != "*" {
+		if total, err = strconv.ParseInt(size, 10, 64); err != nil {
+			return 0, 0, 0, bad
+		}
+	}
+	if rng == "*" {
+		return -1, -1, total, nil
+	}
+	j := strings.IndexByte(rng, '-')
+	if j < 0 {
+		return 0, 0, 0, bad
+	}
+	if start, err = strconv.ParseInt(rng[:j], 10, 64); err != nil {
+		return 0, 0, 0, bad
+	}
+	if end, err = strconv.ParseInt(rng[j+1:], 10, 64); err != nil || end < start {
+		return 0, 0, 0, bad
+	}
+	return start, end, total, nil
+}
+
+// check checks that the Content-Range of the specified 206 response is
+// consistent with the requested range and with the response's length.
+func (br ByteRange) check(res *http.Response) error {
+	if res.StatusCode != http.StatusPartialContent {
+		return nil
+	}
+	cr := res.Header.Get("Content-Range")
+	start, end, total, err := contentRange(cr)
+	if err != nil || start < 0 {
+		return fmt.Errorf("Header[%q] got = %s%q%s, want = %sa range of %s%s\n",
+			"Content-Range", RedColor, cr, StopColor, RedColor, br, StopColor)
+	}
+	wantEnd := br.End
+	if total >= 0 && (wantEnd < 0 || wantEnd > total-1) {
+		wantEnd = total - 1
+	}
+	var msg string
+	if start != br.Start || (wantEnd >= 0 && end != wantEnd) {
+		msg += fmt.Sprintf("Header[%q] got = %s%q%s, want = %sa range of %s%s\n",
+			"Content-Range", RedColor, cr, StopColor, RedColor, br, StopColor)
+	}
+	if res.ContentLength >= 0 && res.ContentLength != end-start+1 {
+		msg += fmt.Sprintf("Header[%q] got = %s%d%s, want = %s%d%s, the length of %q\n",
+			"Content-Length", RedColor, res.ContentLength, StopColor, RedColor, end-start+1, StopColor, cr)
+	}
+	if msg != "" {
+		return fmt.Errorf("%s", msg)
+	}
+	return nil
+}
+
+// CheckRanges downloads the resource at the specified path in whole and then
+// in consecutive ranges of the specified size, sending the specified header
+// with every request. It checks that every ranged response has status 206,
+// a correct Content-Range and length, that the ranges put together reproduce
+// the whole body and that a range past the end is answered with 416.
+func CheckRanges(path string, header Header, size int64) error {
+	return globalRunner().CheckRanges(path, header, size)
+}
+
+// CheckRanges is like the package's CheckRanges but it checks the Runner's
+// target.
+func (rn *Runner) CheckRanges(path string, header Header, size int64) error {
+	if size <= 0 {
+		return fmt.Errorf("hit: CheckRanges size must be greater than 0, got %d", size)
+	}
+	get := func(br *ByteRange) (*http.Response, []byte, error) {
+		req, err := http.NewRequest("GET", rn.baseURL()+path, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		if header != nil {
+			header.AddTo(req)
+		}
+		if br != nil {
+			req.Header.Set("Range", br.String())
+		}
+		res, err := rn.resend(req)
+		if err != nil {
+			return nil, nil, fmt.Errorf("hit: GET %s failed. %v", path, err)
+		}
+		defer res.Body.Close()
+		b, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			return nil, nil, fmt.Errorf("hit: error reading http.Response.Body. %v", err)
+		}
+		return res, b, nil
+	}
+
+	res, full, err := get(nil)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("hit: GET %s got status %d, want 200", path, res.StatusCode)
+	}
+
+	var msg string
+	var joined []byte
+	for off := int64(0); off < int64(len(full)) && msg == ""; off += size {
+		br := ByteRange{off, off + size - 1}
+		res, b, err := get(&br)
+		if err != nil {
+			return err
+		}
+		if res.StatusCode != http.StatusPartialContent {
+			msg += fmt.Sprintf("Range %s StatusCode got = %s%d%s, want %s%d%s\n",
+				br, RedColor, res.StatusCode, StopColor, RedColor, http.StatusPartialContent, StopColor)
+			break
+		}
+		if err := br.check(res); err != nil {
+			msg += fmt.Sprintf("Range %s %s", br, err)
+		}
+		if _, _, total, _ := contentRange(res.Header.Get("Content-Range")); total >= 0 && total != int64(len(full)) {
+			msg += fmt.Sprintf("Range %s total length got = %s%d%s, want %s%d%s\n",
+				br, RedColor, total, StopColor, RedColor, len(full), StopColor)
+		}
+		joined = append(joined, b...)
+	}
+	if msg == "" && !bytes.Equal(joined, full) {
+		n := len(full)
+		if len(joined) < n {
+			n = len(joined)
+		}
+		i := firstDiff(joined[:n], full[:n])
+		if i < 0 {
+			i = n // one is a prefix of the other
+		}
+		msg += fmt.Sprintf("Ranges joined differ from the whole body at byte %s%d%s, got %s%q%s, want %s%q%s\n",
+			RedColor, i, StopColor, RedColor, excerpt(joined, i), StopColor, RedColor, excerpt(full, i), StopColor)
+	}
+
+	past := ByteRange{int64(len(full)), -1}
+	res, _, err = get(&past)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+		msg += fmt.Sprintf("Range %s StatusCode got = %s%d%s, want %s%d%s\n",
+			past, RedColor, res.StatusCode, StopColor, RedColor, http.StatusRequestedRangeNotSatisfiable, StopColor)
+	} else if cr := res.Header.Get("Content-Range"); cr != "" {
+		if _, _, total, err := contentRange(cr); err != nil || total != int64(len(full)) {
+			msg += fmt.Sprintf("Range %s Header[%q] got = %s%q%s, want = %s%q%s\n",
+				past, "Content-Range", RedColor, cr, StopColor, RedColor, fmt.Sprintf("bytes */%d", len(full)), StopColor)
+		}
+	}
+
+	if msg != "" {
+		return fmt.Errorf(" %sGET %s%s Header: %s%v%s\n%s",
+			YellowColor, path, StopColor, YellowColor, rn.Redactions.redactHeaders(http.Header(header)), StopColor, msg)
+	}
+	return nil
+}
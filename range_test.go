@@ -0,0 +1,120 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequestRange(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/bad" {
+			w.Header().Set("Content-Range", "bytes 0-9/100")
+			w.WriteHeader(206)
+			w.Write(content[:10])
+			return
+		}
+		http.ServeContent(w, r, "a.txt", time.Time{}, bytes.NewReader(content))
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	r := Request{Range: &ByteRange{10, 19}, Want: Response{Status: 206, Body: RawBody(content[10:20])}}
+	if err := rn.Execute(r, "GET", "/"); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	r = Request{Range: &ByteRange{90, -1}, Want: Response{Status: 206, Body: RawBody(content[90:])}}
+	if err := rn.Execute(r, "GET", "/"); err != nil {
+		t.Errorf("open range: unexpected error %v", err)
+	}
+	r = Request{Range: &ByteRange{10, 19}, Want: Response{Status: 206}}
+	if err := rn.Execute(r, "GET", "/bad"); err == nil || !strings.Contains(err.Error(), "Content-Range") {
+		t.Errorf("got error %v, want Content-Range mismatch", err)
+	}
+}
+
+func TestCheckRanges(t *testing.T) {
+	content := bytes.Repeat([]byte("abcdefghij"), 25)
+	good := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "a.txt", time.Time{}, bytes.NewReader(content))
+	})
+	// ignores the range end and always sends the rest of the resource
+	sloppy := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var start int
+		if _, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-", &start); err != nil {
+			w.Write(content)
+			return
+		}
+		if start >= len(content) {
+			w.WriteHeader(416)
+			return
+		}
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(content)-1, len(content)))
+		w.WriteHeader(206)
+		w.Write(content[start:])
+	})
+	// doesn't support ranges at all
+	none := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(content)
+	})
+
+	tests := []struct {
+		h    http.Handler
+		want string
+	}{
+		{good, ""},
+		{sloppy, "Content-Range"},
+		{none, "StatusCode got = \033[91m200"},
+	}
+	for i, tt := range tests {
+		ts := httptest.NewServer(tt.h)
+		rn := NewRunner()
+		rn.Addr = ts.URL[len("http://"):]
+		err := rn.CheckRanges("/file", nil, 64)
+		ts.Close()
+
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("#%d: unexpected error %v", i, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("#%d: got error %v, want it to contain %q", i, err, tt.want)
+		}
+	}
+}
+
+func TestContentRange(t *testing.T) {
+	tests := []struct {
+		s                 string
+		start, end, total int64
+		err               bool
+	}{
+		{"bytes 0-9/100", 0, 9, 100, false},
+		{"bytes 5-5/*", 5, 5, -1, false},
+		{"bytes */100", -1, -1, 100, false},
+		{"bytes 9-0/100", 0, 0, 0, true},
+		{"0-9/100", 0, 0, 0, true},
+		{"bytes 0-9", 0, 0, 0, true},
+	}
+	for _, tt := range tests {
+		start, end, total, err := contentRange(tt.s)
+		if (err != nil) != tt.err {
+			t.Errorf("%q: got error %v, want error %t", tt.s, err, tt.err)
+			continue
+		}
+		if !tt.err && (start != tt.start || end != tt.end || total != tt.total) {
+			t.Errorf("%q: got %d, %d, %d, want %d, %d, %d", tt.s, start, end, total, tt.start, tt.end, tt.total)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimit checks the rate limit headers of consecutive responses. It
+// asserts that the remaining count is a sane number that decreases from one
+// response to the next within the same window and that the reset value is
+// either a sane number of seconds or a sane unix timestamp.
+//
+// RateLimit keeps state between responses, it must not be copied after first
+// use, use a pointer.
+type RateLimit struct {
+	// The names of the rate limit headers, they default to X-RateLimit-Limit,
+	// X-RateLimit-Remaining and X-RateLimit-Reset respectively.
+	Limit     string
+	Remaining string
+	Reset     string
+
+	// MaxReset is the maximum distance of the reset time from now,
+	// it defaults to 24 hours.
+	MaxReset time.Duration
+
+	mu        sync.Mutex
+	seen      bool
+	last      int64
+	lastReset time.Time
+}
+
+func (rl *RateLimit) check(res *http.Response) error {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	limitName, remName, resetName := "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset"
+	if rl.Limit != "" {
+		limitName = rl.Limit
+	}
+	if rl.Remaining != "" {
+		remName = rl.Remaining
+	}
+	if rl.Reset != "" {
+		resetName = rl.Reset
+	}
+	maxReset := rl.MaxReset
+	if maxReset == 0 {
+		maxReset = 24 * time.Hour
+	}
+
+	var msg string
+	now := time.Now()
+
+	rem, err := strconv.ParseInt(res.Header.Get(remName), 10, 64)
+	if err != nil || rem < 0 {
+		return fmt.Errorf("Header[%q] got = %s%q%s, want = %sa non-negative integer%s\n",
+			remName, RedColor, res.Header.Get(remName), StopColor, RedColor, StopColor)
+	}
+	if v := res.Header.Get(limitName); v != "" {
+		if limit, err := strconv.ParseInt(v, 10, 64); err != nil || limit < rem {
+			msg += fmt.Sprintf("Header[%q] got = %s%q%s, want = %san integer >= %d%s\n",
+				limitName, RedColor, v, StopColor, RedColor, rem, StopColor)
+		}
+	}
+
+	var reset time.Time
+	if v := res.Header.Get(resetName); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		switch {
+		case err != nil:
+		case n < 1e9: // delta seconds
+			reset = now.Add(time.Duration(n) * time.Second)
+		default: // unix timestamp
+			reset = time.Unix(n, 0)
+		}
+		if reset.IsZero() || reset.Before(now.Add(-time.Second)) || reset.After(now.Add(maxReset)) {
+			msg += fmt.Sprintf("Header[%q] got = %s%q%s, want = %sa reset time within %s%s\n",
+				resetName, RedColor, v, StopColor, RedColor, maxReset, StopColor)
+		}
+	}
+
+	// The count is expected to decrease only within the same window, delta
+	// second resets are computed at slightly different times so allow for
+	// some rounding.
+	sameWindow := reset.IsZero() || rl.lastReset.IsZero() || absDuration(reset.Sub(rl.lastReset)) <= time.Second
+	if rl.seen && sameWindow && rem != 0 && rem >= rl.last {
+		msg += fmt.Sprintf("Header[%q] got = %s%d%s, want = %sless than %d%s\n",
+			remName, RedColor, rem, StopColor, RedColor, rl.last, StopColor)
+	}
+	rl.seen, rl.last, rl.lastReset = true, rem, reset
+
+	if msg != "" {
+		return fmt.Errorf("%s", msg)
+	}
+	return nil
+}
+
+// retryAfter parses the value of a Retry-After header, which can be either
+// a number of seconds or an HTTP-date, and returns the duration to wait
+// relative to now. The boolean result reports whether the value was valid.
+func retryAfter(v string, now time.Time) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0, false
+	}
+	if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+		if n < 0 {
+			return 0, false
+		}
+		return time.Duration(n) * time.Second, true
+	}
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
+	}
+	if d := t.Sub(now); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
+
+// ParseRetryAfter parses the value of a Retry-After header, which can be
+// either a number of seconds or an HTTP-date, and returns the duration to
+// wait relative to now. A date in the past yields 0.
+func ParseRetryAfter(v string, now time.Time) (time.Duration, error) {
+	d, ok := retryAfter(v, now)
+	if !ok {
+		return 0, fmt.Errorf("invalid Retry-After %q", v)
+	}
+	return d, nil
+}
+
+// RetryAfter returns a Matcher that matches a Retry-After value, in seconds
+// or as an HTTP-date, that asks to wait at least min and at most max, e.g.
+// in a Response's HeaderMatch. An HTTP-date is resolved relative to the time
+// of the match, allowing for its one second resolution.
+func RetryAfter(min, max time.Duration) Matcher {
+	return MatcherFunc(fmt.Sprintf("RetryAfter(%s, %s)", min, max), func(got interface{}) error {
+		s, ok := got.(string)
+		if !ok {
+			return fmt.Errorf("got %T, want string", got)
+		}
+		d, err := ParseRetryAfter(s, time.Now())
+		if err != nil {
+			return err
+		}
+		lo, hi := min, max
+		if _, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64); err != nil {
+			lo -= time.Second
+		}
+		if d < lo || d > hi {
+			return fmt.Errorf("wait of %s is not between %s and %s", d, min, max)
+		}
+		return nil
+	})
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimitRetries(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(429)
+			return
+		}
+		w.WriteHeader(200)
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	rn.RateLimitRetries = 1
+	if err := rn.Execute(Request{Want: Response{Status: 429}}, "GET", "/"); err != nil {
+		t.Error(err)
+	}
+	rn.RateLimitRetries = 5
+	calls = 0
+	if err := rn.Execute(Request{Body: FormBody{"a": {"b"}}, Want: Response{Status: 200}}, "POST", "/"); err != nil {
+		t.Error(err)
+	}
+	if calls != 3 {
+		t.Errorf("got %d calls, want 3", calls)
+	}
+}
+
+func TestRateLimitCheck(t *testing.T) {
+	reset := strconv.FormatInt(time.Now().Add(time.Minute).Unix(), 10)
+	res := func(limit, rem, reset string) *http.Response {
+		return &http.Response{Header: http.Header{
+			"X-Ratelimit-Limit":     {limit},
+			"X-Ratelimit-Remaining": {rem},
+			"X-Ratelimit-Reset":     {reset},
+		}}
+	}
+	tests := []struct {
+		res  *http.Response
+		want string
+	}{
+		{res("10", "9", reset), ""},
+		{res("10", "8", reset), ""},
+		{res("10", "8", reset), "less than 8"},
+		{res("10", "0", reset), ""},
+		{res("10", "11", "60"), "an integer >= 11"},
+		{res("10", "x", "60"), "a non-negative integer"},
+		{res("10", "5", "99999999"), "a reset time within"},
+	}
+	rl := &RateLimit{}
+	for i, tt := range tests {
+		err := rl.check(tt.res)
+		if tt.want == "" && err != nil {
+			t.Errorf("#%d: got err %v, want <nil>", i, err)
+		} else if tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+			t.Errorf("#%d: got err %v, want err containing %q", i, err, tt.want)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2015, 10, 21, 7, 28, 0, 0, time.UTC)
+	tests := []struct {
+		v    string
+		want time.Duration
+		ok   bool
+	}{
+		{"120", 2 * time.Minute, true},
+		{"Wed, 21 Oct 2015 07:28:30 GMT", 30 * time.Second, true},
+		{"Wed, 21 Oct 2015 07:27:00 GMT", 0, true},
+		{"-1", 0, false},
+		{"soon", 0, false},
+	}
+	for i, tt := range tests {
+		got, ok := retryAfter(tt.v, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("#%d: got %v, %t, want %v, %t", i, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRetryAfterMatcher(t *testing.T) {
+	m := RetryAfter(time.Second, time.Minute)
+	tests := []struct {
+		v  interface{}
+		ok bool
+	}{
+		{"1", true},
+		{"60", true},
+		{"0", false},
+		{"61", false},
+		{time.Now().Add(30 * time.Second).UTC().Format(http.TimeFormat), true},
+		{time.Now().Add(time.Hour).UTC().Format(http.TimeFormat), false},
+		{"soon", false},
+		{30, false},
+	}
+	for i, tt := range tests {
+		if err := m.Match(tt.v); (err == nil) != tt.ok {
+			t.Errorf("#%d: %v got err %v, want ok %t", i, tt.v, err, tt.ok)
+		}
+	}
+	if _, err := ParseRetryAfter("soon", time.Now()); err == nil {
+		t.Error("ParseRetryAfter: want error, got nil")
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// RawRequest represents bytes written as is to a new connection to Addr, or
+// to the Addr of the Runner that executes it, it can be used to test how the
+// server handles malformed requests that net/http refuses to construct.
+type RawRequest struct {
+	// Name, if set, identifies the request in failure messages in place
+	// of the first line of Data.
+	Name string
+	// Data is written to the connection as is, lines must be terminated
+	// with "\r\n" explicitly.
+	Data string
+	Want RawResponse
+
+	// Timeout is the time allowed for the server to respond and to close
+	// the connection, it defaults to 5 seconds.
+	Timeout time.Duration
+}
+
+// RawResponse represents the expected reaction of the server to a RawRequest.
+type RawResponse struct {
+	// Status, if not 0, is the expected status of the first response.
+	Status int
+	// Count, if greater than 0, is the expected number of responses, e.g.
+	// 1 if the data must not be interpreted as more than one request.
+	Count int
+	// NoResponse, if set, requires the server to not respond at all.
+	NoResponse bool
+	// Closed, if set, requires the server to close the connection once
+	// it has responded.
+	Closed bool
+	// Reject, if set, requires the server to either respond with a 4xx or
+	// a 5xx status and to close the connection, or to close the connection
+	// without responding. Nothing after the rejected request may be
+	// responded to.
+	Reject bool
+}
+
+// Execute writes the receiver's Data to a new connection, reads everything
+// the server sends back until it closes the connection or until the timeout,
+// and compares that to the receiver's Want.
+func (r RawRequest) Execute() error {
+	return globalRunner().RawRequest(r)
+}
+
+// RawRequest executes the RawRequest against the Runner's Addr.
+func (rn *Runner) RawRequest(r RawRequest) error {
+	timeout := r.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+	addr := normAddr(rn.Addr)
+	if a, ok := Resolve[addr]; ok {
+		addr = a
+	}
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return fmt.Errorf("hit: raw request to %s failed. %v", addr, err)
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := io.WriteString(conn, r.Data); err != nil {
+		return fmt.Errorf("hit: raw request to %s failed. %v", addr, err)
+	}
+	raw, err := ioutil.ReadAll(conn)
+	closed := true
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		closed = false
+	} else if err != nil && len(raw) == 0 && !isConnReset(err) {
+		return fmt.Errorf("hit: reading raw response from %s failed. %v", addr, err)
+	}
+
+	statuses := readStatuses(raw)
+	var msg string
+	if r.Want.NoResponse && len(raw) > 0 {
+		msg += fmt.Sprintf("Response got = %s%q%s, want = %s<none>%s\n",
+			RedColor, firstLine(string(raw)), StopColor, RedColor, StopColor)
+	}
+	if r.Want.Status != 0 {
+		got := 0
+		if len(statuses) > 0 {
+			got = statuses[0]
+		}
+		if got != r.Want.Status {
+			msg += fmt.Sprintf("StatusCode got = %s%d%s, want %s%d%s\n",
+				RedColor, got, StopColor, RedColor, r.Want.Status, StopColor)
+		}
+	}
+	if r.Want.Count > 0 && len(statuses) != r.Want.Count {
+		msg += fmt.Sprintf("Responses got = %s%d %v%s, want %s%d%s\n",
+			RedColor, len(statuses), statuses, StopColor, RedColor, r.Want.Count, StopColor)
+	}
+	if r.Want.Reject {
+		if len(statuses) > 0 && statuses[0] < 400 {
+			msg += fmt.Sprintf("StatusCode got = %s%d%s, want %s4xx or 5xx%s\n",
+				RedColor, statuses[0], StopColor, RedColor, StopColor)
+		}
+		if len(statuses) > 1 {
+			msg += fmt.Sprintf("Responses got = %s%d %v%s, want %sat most 1%s\n",
+				RedColor, len(statuses), statuses, StopColor, RedColor, StopColor)
+		}
+	}
+	if (r.Want.Closed || r.Want.Reject) && !closed {
+		msg += fmt.Sprintf("Connection got = %sopen after %s%s, want = %sclosed%s\n",
+			RedColor, timeout, StopColor, RedColor, StopColor)
+	}
+
+	if msg != "" {
+		name := fmt.Sprintf("%q", firstLine(r.Data))
+		if r.Name != "" {
+			name = r.Name
+		}
+		return fmt.Errorf(" %sRAW %s%s\n%s", YellowColor, name, StopColor, msg)
+	}
+	return nil
+}
+
+// readStatuses returns the status codes of the consecutive HTTP responses
+// in raw, it stops at the first bytes that don't parse as a response.
+func readStatuses(raw []byte) []int {
+	var codes []int
+	br := bufio.NewReader(bytes.NewReader(raw))
+	for {
+		res, err := http.ReadResponse(br, nil)
+		if err != nil {
+			return codes
+		}
+		codes = append(codes, res.StatusCode)
+		if _, err := io.Copy(ioutil.Discard, res.Body); err != nil {
+			return codes
+		}
+		res.Body.Close()
+	}
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexAny(s, "\r\n"); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// isConnReset reports whether err is caused by the peer resetting the
+// connection, which servers may do when they reject a request.
+func isConnReset(err error) bool {
+	return strings.Contains(err.Error(), "connection reset")
+}
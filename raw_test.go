@@ -0,0 +1,46 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRawRequest(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	const get = "GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"
+	tests := []struct {
+		raw  RawRequest
+		want string
+	}{
+		{RawRequest{Data: get, Want: RawResponse{Status: 200, Count: 1}}, ""},
+		{RawRequest{Data: get + get, Want: RawResponse{Status: 200, Count: 2}}, ""},
+		{RawRequest{Data: "GET / HTTP/1.1\r\nHost: example.com\r\nConnection: close\r\n\r\n", Want: RawResponse{Status: 200, Closed: true}}, ""},
+		{RawRequest{Data: "GARBAGE\r\n\r\n", Want: RawResponse{Status: 400, Closed: true}}, ""},
+		{RawRequest{Data: "GET / HTTP/1.1\r\nHost: a\r\nHost: b\r\n\r\n", Want: RawResponse{Status: 400}}, ""},
+		{RawRequest{Data: get, Want: RawResponse{Closed: true}}, "Connection got"},
+		{RawRequest{Data: get, Want: RawResponse{NoResponse: true}}, `Response got = ` + RedColor + `"HTTP/1.1 200 OK"`},
+		{RawRequest{Data: get + get, Want: RawResponse{Count: 1}}, "Responses got"},
+		{RawRequest{Data: get, Want: RawResponse{Status: 404}}, "StatusCode got"},
+	}
+	for i, tt := range tests {
+		tt.raw.Timeout = 200 * time.Millisecond
+		err := rn.RawRequest(tt.raw)
+		if tt.want == "" && err != nil {
+			t.Errorf("#%d: got err %v, want <nil>", i, err)
+		} else if tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+			t.Errorf("#%d: got err %v, want err containing %q", i, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+)
+
+// WaitReady polls the specified path, e.g. "/healthz", every interval until
+// it responds with one of the expected statuses, any 2xx status if none is
+// specified, or until the timeout passes, e.g. while the target boots in
+// docker-compose. It's meant to be called from TestMain before m.Run, the
+// connection errors of a target that's not listening yet are retried. Each
+// poll is cut short by the timeout, so a target that accepts connections
+// but doesn't respond can't block it.
+func WaitReady(path string, timeout, interval time.Duration, status ...int) error {
+	return globalRunner().WaitReady(path, timeout, interval, status...)
+}
+
+// WaitReady is like the package's WaitReady but it polls the Runner's target.
+func (rn *Runner) WaitReady(path string, timeout, interval time.Duration, status ...int) error {
+	if interval <= 0 {
+		interval = 100 * time.Millisecond
+	}
+	r := Request{rn: rn}
+	deadline := time.Now().Add(timeout)
+	var last string
+	for {
+		req, err := r.newRequest("GET", path)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := context.WithDeadline(req.Context(), deadline)
+		res, err := r.do(req.WithContext(ctx))
+		if err == nil {
+			_, err = io.Copy(ioutil.Discard, res.Body)
+			res.Body.Close()
+		}
+		cancel()
+		if err == nil {
+			if readyStatus(res.StatusCode, status) {
+				return nil
+			}
+			last = fmt.Sprintf("status %d", res.StatusCode)
+		} else {
+			last = err.Error()
+		}
+		if time.Now().Add(interval).After(deadline) {
+			return fmt.Errorf("hit: GET %s not ready after %s, last got %s", path, timeout, last)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// readyStatus reports whether the status is one of the expected ones, or a
+// 2xx status if none is expected.
+func readyStatus(got int, want []int) bool {
+	if len(want) == 0 {
+		return got >= 200 && got < 300
+	}
+	for _, s := range want {
+		if got == s {
+			return true
+		}
+	}
+	return false
+}
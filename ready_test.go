@@ -0,0 +1,104 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWaitReady(t *testing.T) {
+	var n int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" || atomic.AddInt32(&n, 1) < 3 {
+			w.WriteHeader(503)
+		}
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	if err := rn.WaitReady("/healthz", time.Second, 10*time.Millisecond); err != nil {
+		t.Errorf("got err %v, want <nil>", err)
+	}
+	if got := atomic.LoadInt32(&n); got != 3 {
+		t.Errorf("got %d polls, want 3", got)
+	}
+	err := rn.WaitReady("/down", 50*time.Millisecond, 10*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "last got status 503") {
+		t.Errorf("got err %v, want a timeout with status 503", err)
+	}
+
+	// a target that starts listening late
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	rn.Addr = addr
+	late := make(chan net.Listener, 1)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			close(late)
+			return
+		}
+		late <- l
+		http.Serve(l, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	}()
+	defer func() {
+		if l, ok := <-late; ok {
+			l.Close()
+		}
+	}()
+	if err := rn.WaitReady("/", 2*time.Second, 20*time.Millisecond); err != nil {
+		t.Errorf("late: got err %v, want <nil>", err)
+	}
+}
+
+func TestWaitReadyStatusAndHang(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(401)
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+	if err := rn.WaitReady("/", time.Second, 10*time.Millisecond, 401); err != nil {
+		t.Errorf("got err %v, want <nil>", err)
+	}
+	if err := rn.WaitReady("/", 50*time.Millisecond, 10*time.Millisecond); err == nil {
+		t.Error("got err <nil>, want not ready with status 401")
+	}
+
+	// a target that accepts the connection but never responds
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer c.Close()
+		}
+	}()
+	rn.Addr = l.Addr().String()
+	start := time.Now()
+	if err := rn.WaitReady("/", 100*time.Millisecond, 10*time.Millisecond); err == nil {
+		t.Error("got err <nil>, want not ready")
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("got WaitReady blocked for %s, want it to give up after the timeout", d)
+	}
+}
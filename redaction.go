@@ -0,0 +1,202 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Redaction holds the rules by which the sensitive values are redacted from
+// every failure message and report, so that the output of a suite can be
+// shared without exposing tokens or personal data.
+type Redaction struct {
+	// Headers lists the names of the request and response headers whose
+	// values are redacted, e.g. "Authorization" or "Set-Cookie".
+	Headers []string
+	// Paths lists the JSON pointers, e.g. "/user/email", of the request
+	// and response body values that are redacted. An object or an array
+	// is redacted in whole.
+	Paths []string
+	// Patterns lists the regular expressions whose matches are redacted,
+	// e.g. card numbers, wherever they appear in the output.
+	Patterns []*regexp.Regexp
+}
+
+// Redactions holds the rules applied to the output of the package's functions
+// and the default rules of a new Runner, see Config.Redactions. The values of the headers and of the body paths are redacted wherever the
+// headers and the bodies are printed, e.g. in failure messages, Events and
+// Docs.
+var Redactions Redaction
+
+// redactionOf returns the Redaction rd points to or, if it's nil, that of
+// the default Runner, e.g. for the failure of a Comparer used on its own.
+func redactionOf(rd *Redaction) Redaction {
+	if rd != nil {
+		return *rd
+	}
+	return DefaultConfig().Redactions
+}
+
+// redactedHeader reports whether the values of the named header are redacted.
+func (rd Redaction) redactedHeader(name string) bool {
+	for _, k := range rd.Headers {
+		if strings.EqualFold(k, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactHeaderValues returns the values of the named header, replaced by
+// Redacted if the header is redacted.
+func (rd Redaction) redactHeaderValues(name string, vv []string) []string {
+	if vv == nil || !rd.redactedHeader(name) {
+		return vv
+	}
+	out := make([]string, len(vv))
+	for i := range out {
+		out[i] = Redacted
+	}
+	return out
+}
+
+// redactHeaders returns the header with the values of the redacted headers
+// replaced by Redacted, h itself is left alone.
+func (rd Redaction) redactHeaders(h http.Header) http.Header {
+	if len(rd.Headers) == 0 || h == nil {
+		return h
+	}
+	out := make(http.Header, len(h))
+	for k, vv := range h {
+		out[k] = rd.redactHeaderValues(k, vv)
+	}
+	return out
+}
+
+// redactedPath reports whether the value at the JSON pointer ptr is redacted,
+// i.e. whether ptr is one of the paths or a pointer inside one.
+func (rd Redaction) redactedPath(ptr string) bool {
+	ptr = pathOrRoot(ptr)
+	for _, p := range rd.Paths {
+		if p == ptr || strings.HasPrefix(ptr, strings.TrimSuffix(p, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// redactedWithin reports whether any value at, or inside, the JSON pointer
+// ptr is redacted.
+func (rd Redaction) redactedWithin(ptr string) bool {
+	if rd.redactedPath(ptr) {
+		return true
+	}
+	for _, p := range rd.Paths {
+		if strings.HasPrefix(p, ptr+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// redactJSON returns a copy of the decoded JSON value v, found at the JSON
+// pointer path, with the values at the paths replaced by Redacted.
+// An object or an array at one of the paths is replaced in whole.
+func (rd Redaction) redactJSON(path string, v interface{}) interface{} {
+	if len(rd.Paths) == 0 {
+		return v
+	}
+	if rd.redactedPath(path) {
+		return Redacted
+	}
+	switch x := v.(type) {
+	case JSONBody:
+		return rd.redactJSON(path, map[string]interface{}(x))
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(x))
+		for k, e := range x {
+			out[k] = rd.redactJSON(pointer(path, k), e)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(x))
+		for i, e := range x {
+			out[i] = rd.redactJSON(pointer(path, strconv.Itoa(i)), e)
+		}
+		return out
+	}
+	return v
+}
+
+// redactJSONText returns the JSON text b with the values at the paths
+// replaced by Redacted, text that is not JSON is returned as is.
+func (rd Redaction) redactJSONText(b []byte) []byte {
+	if len(rd.Paths) == 0 {
+		return b
+	}
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return b
+	}
+	out, err := json.Marshal(rd.redactJSON("", v))
+	if err != nil {
+		return b
+	}
+	return out
+}
+
+// redactBodyer returns the request body for printing, a JSONBody with the
+// values at the paths replaced by Redacted.
+func (rd Redaction) redactBodyer(b Bodyer) interface{} {
+	if jb, ok := b.(JSONBody); ok {
+		return rd.redactJSON("", jb)
+	}
+	return b
+}
+
+// redactPatterns returns s with the matches of the patterns
+// replaced by Redacted.
+func (rd Redaction) redactPatterns(s string) string {
+	for _, re := range rd.Patterns {
+		s = re.ReplaceAllString(s, Redacted)
+	}
+	return s
+}
+
+// lookupPointer returns the value of v at the specified JSON pointer.
+func lookupPointer(v interface{}, ptr string) (interface{}, bool) {
+	if ptr == "" || ptr == "/" {
+		return v, true
+	}
+	if !strings.HasPrefix(ptr, "/") {
+		return nil, false
+	}
+	for _, tok := range strings.Split(ptr[1:], "/") {
+		tok = strings.Replace(tok, "~1", "/", -1)
+		tok = strings.Replace(tok, "~0", "~", -1)
+		switch x := v.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if v, ok = x[tok]; !ok {
+				return nil, false
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(tok)
+			if err != nil || i < 0 || i >= len(x) {
+				return nil, false
+			}
+			v = x[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRedactions(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Session", "sess-1d2e3f")
+		w.Write([]byte(`{"user":{"email":"ann@example.com","name":"Ann"},"card":"4111 1111 1111 1111"}`))
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+	rn.Redactions = Redaction{
+		Headers:  []string{"authorization", "X-Session"},
+		Paths:    []string{"/user/email", "/password"},
+		Patterns: []*regexp.Regexp{regexp.MustCompile(`\d{4} \d{4} \d{4} \d{4}`)},
+	}
+
+	r := Request{
+		Bearer: "tok-9a8b7c",
+		Body:   JSONBody{"password": "hunter22"},
+		Want: Response{Status: 201, Header: Header{"X-Session": {"none"}},
+			Body: JSONBody{"user": JSONBody{"email": "", "name": "Ann"}}},
+	}
+	secrets.RLock()
+	n := len(secrets.values)
+	secrets.RUnlock()
+	err := rn.Execute(r, "POST", "/")
+	if err == nil {
+		t.Fatal("want error, got nil")
+	}
+	msg := err.Error()
+	for _, leak := range []string{"tok-9a8b7c", "sess-1d2e3f", "hunter22", "ann@example.com", "4111"} {
+		if strings.Contains(msg, leak) {
+			t.Errorf("error leaks %q:\n%s", leak, msg)
+		}
+	}
+	if !strings.Contains(msg, "Body /user/email") {
+		t.Errorf("error redacts more than it should:\n%s", msg)
+	}
+
+	// the values are redacted where they're printed, they're not
+	// registered for redaction from the output that follows
+	secrets.RLock()
+	got := len(secrets.values)
+	secrets.RUnlock()
+	if got != n {
+		t.Errorf("got %d redacted values, want %d", got, n)
+	}
+	if s := "Ann sess-1d2e3f ann@example.com"; rn.Redactions.redact(s) != s {
+		t.Errorf("got %q redacted, want it as is", rn.Redactions.redact(s))
+	}
+}
+
+func TestRunnerRedactions(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Session", "sess-1d2e3f")
+		w.Write([]byte(`{"user":{"email":"ann@example.com"}}`))
+	}))
+	defer ts.Close()
+
+	// the package's Redactions are empty, the Runner has its own
+	var events bytes.Buffer
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+	rn.Events = &events
+	rn.Redactions = Redaction{Headers: []string{"X-Session"}, Paths: []string{"/user/email"}}
+	err := rn.Execute(Request{Want: Response{Status: 200, Header: Header{"X-Session": {"none"}},
+		Body: JSONBody{"user": JSONBody{"email": ""}}}}, "GET", "/")
+	if err == nil {
+		t.Fatal("want error, got nil")
+	}
+	for _, out := range []string{err.Error(), events.String()} {
+		for _, leak := range []string{"sess-1d2e3f", "ann@example.com"} {
+			if strings.Contains(out, leak) {
+				t.Errorf("output leaks %q:\n%s", leak, out)
+			}
+		}
+	}
+	if !strings.Contains(events.String(), `"kind":"header"`) {
+		t.Errorf("got events %s, want the header failure", events.String())
+	}
+}
+
+func TestRedactJSON(t *testing.T) {
+	rd := Redaction{Paths: []string{"/user/email", "/cards"}}
+
+	v := map[string]interface{}{
+		"user":  map[string]interface{}{"email": "ann@example.com", "name": "Ann"},
+		"cards": []interface{}{"4111"},
+		"email": "bob@example.com",
+	}
+	want := map[string]interface{}{
+		"user":  map[string]interface{}{"email": Redacted, "name": "Ann"},
+		"cards": Redacted,
+		"email": "bob@example.com",
+	}
+	if got := rd.redactJSON("", v); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if v["user"].(map[string]interface{})["email"] != "ann@example.com" {
+		t.Errorf("got the value modified: %v", v)
+	}
+	if got := rd.redactJSON("/user", JSONBody{"email": "x"}); !reflect.DeepEqual(got, map[string]interface{}{"email": Redacted}) {
+		t.Errorf("got %v, want the email redacted", got)
+	}
+}
+
+func TestLookupPointer(t *testing.T) {
+	v := map[string]interface{}{
+		"a":   []interface{}{"x", map[string]interface{}{"b": "y"}},
+		"c/d": "z",
+	}
+	tests := []struct {
+		ptr  string
+		want interface{}
+		ok   bool
+	}{
+		{"/a/1/b", "y", true},
+		{"/a/0", "x", true},
+		{"/c~1d", "z", true},
+		{"/a/2", nil, false},
+		{"/a/-1", nil, false},
+		{"/missing", nil, false},
+		{"a", nil, false},
+	}
+	for i, tt := range tests {
+		got, ok := lookupPointer(v, tt.ptr)
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("#%d: got %v, %t, want %v, %t", i, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"sort"
+)
+
+// Canary is the string injected into requests by the reflected input check.
+// It contains characters that must be escaped when they're echoed back in
+// HTML or JSON, so finding it verbatim in a response body means that the
+// input was reflected unescaped.
+var Canary = `<hit'"canary>`
+
+// reflection sends a copy of the request for every query parameter, header
+// and top-level body field, each time with that one value replaced by the
+// Canary, and returns a failure message listing the inputs that were
+// reflected unescaped in the response body.
+func (r Request) reflection(method, path string) (string, error) {
+	var fail string
+	for _, p := range r.probes(path, Canary) {
+		req, err := p.r.newRequest(method, p.path)
+		if err != nil {
+			return "", err
+		}
+		res, err := p.r.do(req)
+		if err != nil {
+			return "", fmt.Errorf("hit: %s %s failed. %v", method, p.path, err)
+		}
+		b, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			return "", fmt.Errorf("hit: error reading http.Response.Body. %v", err)
+		}
+		if bytes.Contains(b, []byte(Canary)) {
+			fail += fmt.Sprintf("Body reflects %s%s%s unescaped\n", RedColor, p.input, StopColor)
+		}
+	}
+	return fail, nil
+}
+
+// probe is a copy of a request with one of its inputs replaced.
+type probe struct {
+	r     Request
+	path  string
+	input string // describes the replaced input
+}
+
+// probes returns a copy of the receiver for every one of its query
+// parameters, headers and top-level body fields with that input's value
+// set to v. Inputs are visited in sorted order.
+func (r Request) probes(path, v string) []probe {
+	pp := r.queryProbes(path, v)
+
+	for _, k := range sortedKeys(r.Header) {
+		cp := r
+		cp.Header = Header(cloneValues(r.Header))
+		cp.Header[k] = []string{v}
+		pp = append(pp, probe{cp, path, fmt.Sprintf("header %q", k)})
+	}
+
+	switch b := r.Body.(type) {
+	case FormBody:
+		for _, k := range sortedKeys(b) {
+			cp := r
+			body := FormBody(cloneValues(b))
+			body[k] = []string{v}
+			cp.Body = body
+			pp = append(pp, probe{cp, path, fmt.Sprintf("body field %q", k)})
+		}
+	case JSONBody:
+		keys := make([]string, 0, len(b))
+		for k := range b {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			cp := r
+			body := make(JSONBody, len(b))
+			for kk, vv := range b {
+				body[kk] = vv
+			}
+			body[k] = v
+			cp.Body = body
+			pp = append(pp, probe{cp, path, fmt.Sprintf("body field %q", k)})
+		}
+	case MultipartBody:
+		keys := make([]string, 0, len(b))
+		for k, vv := range b {
+			if len(vv) > 0 {
+				if _, ok := vv[0].(string); ok {
+					keys = append(keys, k)
+				}
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			cp := r
+			body := make(MultipartBody, len(b))
+			for kk, vv := range b {
+				body[kk] = vv
+			}
+			body[k] = []interface{}{v}
+			cp.Body = body
+			pp = append(pp, probe{cp, path, fmt.Sprintf("body field %q", k)})
+		}
+	}
+	return pp
+}
+
+// queryProbes returns a copy of the receiver for every query parameter of
+// the specified path with that parameter's value set to v.
+func (r Request) queryProbes(path, v string) []probe {
+	u, err := url.Parse(path)
+	if err != nil {
+		return nil
+	}
+	var pp []probe
+	q := u.Query()
+	for _, k := range sortedKeys(q) {
+		cp := cloneValues(q)
+		cp.Set(k, v)
+		u2 := *u
+		u2.RawQuery = cp.Encode()
+		pp = append(pp, probe{r, u2.String(), fmt.Sprintf("query parameter %q", k)})
+	}
+	return pp
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func cloneValues(m map[string][]string) url.Values {
+	cp := make(url.Values, len(m))
+	for k, vv := range m {
+		cp[k] = append([]string(nil), vv...)
+	}
+	return cp
+}
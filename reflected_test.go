@@ -0,0 +1,58 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestReflection(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/echo": // reflects q and X-Name unescaped, escapes the rest
+			r.ParseForm()
+			fmt.Fprintf(w, "<p>%s</p><p>%s</p><p>%s</p>", r.URL.Query().Get("q"),
+				r.Header.Get("X-Name"), html.EscapeString(r.PostForm.Get("email")))
+		case "/json":
+			var v map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&v)
+			json.NewEncoder(w).Encode(v)
+		}
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	r := Request{
+		Header:     Header{"X-Name": {"joe"}, "X-Other": {"1"}},
+		Body:       FormBody{"email": {"joe@example.com"}},
+		Want:       Response{Status: 200},
+		Reflection: true,
+	}
+	err := rn.Execute(r, "POST", "/echo?q=a&page=1")
+	if err == nil {
+		t.Fatal("got err <nil>, want reflection failure")
+	}
+	for _, want := range []string{`query parameter "q"`, `header "X-Name"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("got err %v, want it to contain %s", err, want)
+		}
+	}
+	for _, notwant := range []string{`query parameter "page"`, `header "X-Other"`, `body field "email"`} {
+		if strings.Contains(err.Error(), notwant) {
+			t.Errorf("got err %v, want it to not contain %s", err, notwant)
+		}
+	}
+
+	r = Request{Body: JSONBody{"name": "joe"}, Want: Response{Status: 200}, Reflection: true}
+	if err := rn.Execute(r, "POST", "/json"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,369 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// LogEntry represents a single request read from an access log.
+type LogEntry struct {
+	Time   time.Time
+	Method string
+	Path   string
+	Status int
+
+	// Duration is the time it took to serve the original request, it's 0
+	// if the log doesn't record it.
+	Duration time.Duration
+}
+
+// Request returns a Request that expects the entry's original status.
+func (e LogEntry) Request() Request {
+	return Request{Want: Response{Status: e.Status}}
+}
+
+// combinedLine matches a line in the Common or the Combined Log Format,
+// optionally followed by the request time in seconds as logged by e.g.
+// nginx's $request_time.
+var combinedLine = regexp.MustCompile(`^\S+ \S+ \S+ \[([^\]]+)\] "(\S+) (\S+)[^"]*" (\d{3}) \S+(?: "[^"]*" "[^"]*")?(?: ([0-9.]+))?\s*$`)
+
+const clfTime = "02/Jan/2006:15:04:05 -0700"
+
+// ParseCombinedLog parses an access log in the Common or the Combined Log
+// Format, e.g. as written by Apache or nginx. A request time in seconds,
+// appended to a combined line, is read into the entry's Duration.
+func ParseCombinedLog(r io.Reader) ([]LogEntry, error) {
+	var ee []LogEntry
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := s.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		m := combinedLine.FindStringSubmatch(line)
+		if m == nil {
+			return nil, fmt.Errorf("hit: access log line %d is not in the combined log format", n)
+		}
+		t, err := time.Parse(clfTime, m[1])
+		if err != nil {
+			return nil, fmt.Errorf("hit: access log line %d has an invalid time. %v", n, err)
+		}
+		status, _ := strconv.Atoi(m[4])
+		e := LogEntry{Time: t, Method: m[2], Path: m[3], Status: status}
+		if m[5] != "" {
+			if e.Duration, err = parseSeconds(m[5]); err != nil {
+				return nil, fmt.Errorf("hit: access log line %d has an invalid request time. %v", n, err)
+			}
+		}
+		ee = append(ee, e)
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("hit: error reading access log. %v", err)
+	}
+	return ee, nil
+}
+
+// ParseJSONLog parses an access log with one JSON object per line. The
+// following fields are recognized, any other fields are ignored:
+//
+//	method                        the request method
+//	path, uri, request_uri, url   the request target, an absolute url is
+//	                              reduced to its path and query
+//	status                        the response status
+//	time, timestamp               the time of the request, in RFC 3339
+//	duration, latency,            the time it took to serve the request,
+//	request_time                  in seconds or as a Go duration string
+func ParseJSONLog(r io.Reader) ([]LogEntry, error) {
+	var ee []LogEntry
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<20)
+	for n := 1; s.Scan(); n++ {
+		line := bytes.TrimSpace(s.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var m map[string]interface{}
+		d := json.NewDecoder(bytes.NewReader(line))
+		d.UseNumber()
+		if err := d.Decode(&m); err != nil {
+			return nil, fmt.Errorf("hit: access log line %d is not a JSON object. %v", n, err)
+		}
+		e, err := jsonLogEntry(m)
+		if err != nil {
+			return nil, fmt.Errorf("hit: access log line %d %v", n, err)
+		}
+		ee = append(ee, e)
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("hit: error reading access log. %v", err)
+	}
+	return ee, nil
+}
+
+func jsonLogEntry(m map[string]interface{}) (e LogEntry, err error) {
+	field := func(names ...string) string {
+		for _, k := range names {
+			if v, ok := m[k]; ok && v != nil {
+				return fmt.Sprint(v)
+			}
+		}
+		return ""
+	}
+
+	if e.Method = field("method"); e.Method == "" {
+		return e, fmt.Errorf("has no method")
+	}
+	if e.Path = field("path", "uri", "request_uri", "url"); e.Path == "" {
+		return e, fmt.Errorf("has no path")
+	}
+	if u, err := url.Parse(e.Path); err == nil && u.IsAbs() {
+		e.Path = u.RequestURI()
+	}
+	if s := field("status"); s != "" {
+		if e.Status, err = strconv.Atoi(s); err != nil {
+			return e, fmt.Errorf("has an invalid status. %v", err)
+		}
+	}
+	if s := field("time", "timestamp"); s != "" {
+		if e.Time, err = time.Parse(time.RFC3339Nano, s); err != nil {
+			return e, fmt.Errorf("has an invalid time. %v", err)
+		}
+	}
+	if s := field("duration", "latency", "request_time"); s != "" {
+		if e.Duration, err = parseSeconds(s); err != nil {
+			if e.Duration, err = time.ParseDuration(s); err != nil {
+				return e, fmt.Errorf("has an invalid duration. %v", err)
+			}
+		}
+	}
+	return e, nil
+}
+
+func parseSeconds(s string) (time.Duration, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(f * float64(time.Second)), nil
+}
+
+// Replay replays the requests of an access log against Addr and compares the
+// outcome, in aggregate, to the original one. The log records neither the
+// request bodies nor the headers so it's best suited for read only traffic,
+// entries of other methods can be filtered out beforehand.
+type Replay struct {
+	Entries []LogEntry
+
+	// Timing, if set, sends the requests at their original pace, relative
+	// to the Time of the first entry, scaled by Speed, e.g. 2 replays the
+	// traffic twice as fast. Otherwise the requests are sent one after the
+	// other.
+	Timing bool
+	Speed  float64
+
+	// MaxMismatch is the fraction of requests, between 0 and 1, whose
+	// status is allowed to differ from the original one.
+	MaxMismatch float64
+
+	// MaxSlowdown, if greater than 0, is the maximum allowed ratio of the
+	// replayed to the original 95th percentile latency, e.g. 1.5. It's
+	// ignored if the log doesn't record durations.
+	MaxSlowdown float64
+}
+
+// ReplayResult represents the outcome of a single replayed request.
+type ReplayResult struct {
+	Entry    LogEntry
+	Status   int
+	Duration time.Duration
+	// Err is set if the request couldn't be sent.
+	Err error
+}
+
+// Latency summarizes a set of request durations.
+type Latency struct {
+	P50, P95, Max time.Duration
+}
+
+func latencyOf(dd []time.Duration) (l Latency) {
+	if len(dd) == 0 {
+		return l
+	}
+	dd = append([]time.Duration(nil), dd...)
+	sort.Slice(dd, func(i, j int) bool { return dd[i] < dd[j] })
+	// nearest rank
+	at := func(p float64) time.Duration { return dd[int(math.Ceil(p*float64(len(dd))))-1] }
+	return Latency{P50: at(0.5), P95: at(0.95), Max: dd[len(dd)-1]}
+}
+
+func (l Latency) String() string {
+	return fmt.Sprintf("p50 %s, p95 %s, max %s", l.P50, l.P95, l.Max)
+}
+
+// ReplayReport is the aggregate outcome of a Replay.
+type ReplayReport struct {
+	Results []ReplayResult
+
+	// Original and Replayed count the responses by status.
+	Original, Replayed map[int]int
+	// Mismatched is the number of requests whose status differs from the
+	// original one, including the ones that failed.
+	Mismatched int
+
+	// OriginalLatency is the zero value if the log doesn't record
+	// durations.
+	OriginalLatency, ReplayedLatency Latency
+}
+
+// Run replays the entries and returns the report.
+func (rp Replay) Run() *ReplayReport {
+	return globalRunner().Replay(rp)
+}
+
+// Replay replays the entries against the Runner's target and returns the
+// report.
+func (rn *Runner) Replay(rp Replay) *ReplayReport {
+	results := make([]ReplayResult, len(rp.Entries))
+	if rp.Timing && len(rp.Entries) > 0 {
+		speed := rp.Speed
+		if speed <= 0 {
+			speed = 1
+		}
+		start, first := time.Now(), rp.Entries[0].Time
+		var wg sync.WaitGroup
+		for i, e := range rp.Entries {
+			offset := time.Duration(float64(e.Time.Sub(first)) / speed)
+			time.Sleep(time.Until(start.Add(offset)))
+			wg.Add(1)
+			go func(i int, e LogEntry) {
+				defer wg.Done()
+				results[i] = rn.replay(e)
+			}(i, e)
+		}
+		wg.Wait()
+	} else {
+		for i, e := range rp.Entries {
+			results[i] = rn.replay(e)
+		}
+	}
+
+	rep := &ReplayReport{Results: results, Original: make(map[int]int), Replayed: make(map[int]int)}
+	var orig, got []time.Duration
+	for _, r := range results {
+		rep.Original[r.Entry.Status]++
+		if r.Err != nil {
+			rep.Mismatched++
+			continue
+		}
+		rep.Replayed[r.Status]++
+		if r.Status != r.Entry.Status {
+			rep.Mismatched++
+		}
+		if r.Entry.Duration > 0 {
+			orig = append(orig, r.Entry.Duration)
+		}
+		got = append(got, r.Duration)
+	}
+	rep.OriginalLatency, rep.ReplayedLatency = latencyOf(orig), latencyOf(got)
+	return rep
+}
+
+// replay sends the request of the specified entry and reads its response.
+func (rn *Runner) replay(e LogEntry) ReplayResult {
+	res := ReplayResult{Entry: e}
+	req, err := Request{rn: rn}.newRequest(e.Method, e.Path)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	start := time.Now()
+	r, err := rn.resend(req)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	io.Copy(ioutil.Discard, r.Body)
+	r.Body.Close()
+	res.Status, res.Duration = r.StatusCode, time.Since(start)
+	return res
+}
+
+// Test replays the entries and fails if the report exceeds the Replay's
+// thresholds.
+func (rp Replay) Test(t *testing.T) {
+	rep := rp.Run()
+	t.Log(rep)
+	if err := rp.check(rep); err != nil {
+		t.Error(err)
+	}
+}
+
+func (rp Replay) check(rep *ReplayReport) error {
+	var msg string
+	if n := len(rep.Results); n > 0 && float64(rep.Mismatched)/float64(n) > rp.MaxMismatch {
+		msg += fmt.Sprintf("Replay mismatched %s%d%s of %d statuses, want at most %s%.0f%%%s\n",
+			RedColor, rep.Mismatched, StopColor, n, RedColor, rp.MaxMismatch*100, StopColor)
+		for _, r := range rep.Results {
+			if r.Err != nil {
+				msg += fmt.Sprintf("  %s %s: %v\n", r.Entry.Method, r.Entry.Path, r.Err)
+			} else if r.Status != r.Entry.Status {
+				msg += fmt.Sprintf("  %s %s: got %d, want %d\n", r.Entry.Method, r.Entry.Path, r.Status, r.Entry.Status)
+			}
+		}
+	}
+	if o := rep.OriginalLatency.P95; rp.MaxSlowdown > 0 && o > 0 {
+		if max := time.Duration(float64(o) * rp.MaxSlowdown); rep.ReplayedLatency.P95 > max {
+			msg += fmt.Sprintf("Replay p95 latency got = %s%s%s, want at most %s%s%s\n",
+				RedColor, rep.ReplayedLatency.P95, StopColor, RedColor, max, StopColor)
+		}
+	}
+	if msg != "" {
+		return fmt.Errorf("%s", msg)
+	}
+	return nil
+}
+
+// String returns the report formatted as a table of the status counts
+// followed by the latencies.
+func (rep *ReplayReport) String() string {
+	codes := make(map[int]bool)
+	for c := range rep.Original {
+		codes[c] = true
+	}
+	for c := range rep.Replayed {
+		codes[c] = true
+	}
+	var cc []int
+	for c := range codes {
+		cc = append(cc, c)
+	}
+	sort.Ints(cc)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-8s %10s %10s\n", "status", "original", "replayed")
+	for _, c := range cc {
+		fmt.Fprintf(&b, "%-8d %10d %10d\n", c, rep.Original[c], rep.Replayed[c])
+	}
+	fmt.Fprintf(&b, "mismatched %d of %d\n", rep.Mismatched, len(rep.Results))
+	if rep.OriginalLatency.Max > 0 {
+		fmt.Fprintf(&b, "original latency %s\n", rep.OriginalLatency)
+	}
+	fmt.Fprintf(&b, "replayed latency %s\n", rep.ReplayedLatency)
+	return b.String()
+}
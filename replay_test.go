@@ -0,0 +1,114 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCombinedLog(t *testing.T) {
+	log := `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326
+10.0.0.1 - - [10/Oct/2000:13:55:37 -0700] "POST /users?x=1 HTTP/1.1" 201 12 "http://example.com/" "Mozilla/5.0" 0.250
+
+`
+	got, err := ParseCombinedLog(strings.NewReader(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	loc := time.FixedZone("", -7*60*60)
+	want := []LogEntry{
+		{Time: time.Date(2000, 10, 10, 13, 55, 36, 0, loc), Method: "GET", Path: "/apache_pb.gif", Status: 200},
+		{Time: time.Date(2000, 10, 10, 13, 55, 37, 0, loc), Method: "POST", Path: "/users?x=1", Status: 201, Duration: 250 * time.Millisecond},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !got[i].Time.Equal(want[i].Time) {
+			t.Errorf("#%d: got time %s, want %s", i, got[i].Time, want[i].Time)
+		}
+		got[i].Time, want[i].Time = time.Time{}, time.Time{}
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("#%d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	if _, err := ParseCombinedLog(strings.NewReader("not a log line\n")); err == nil {
+		t.Error("want error for a malformed line, got nil")
+	}
+}
+
+func TestParseJSONLog(t *testing.T) {
+	log := `{"method":"GET","url":"http://example.com/a?b=c","status":200,"time":"2015-01-02T03:04:05Z","duration":0.5}
+{"method":"DELETE","path":"/users/1","status":"204","latency":"30ms","extra":true}
+`
+	got, err := ParseJSONLog(strings.NewReader(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []LogEntry{
+		{Time: time.Date(2015, 1, 2, 3, 4, 5, 0, time.UTC), Method: "GET", Path: "/a?b=c", Status: 200, Duration: 500 * time.Millisecond},
+		{Method: "DELETE", Path: "/users/1", Status: 204, Duration: 30 * time.Millisecond},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	if _, err := ParseJSONLog(strings.NewReader(`{"path":"/"}`)); err == nil {
+		t.Error("want error for an entry without a method, got nil")
+	}
+}
+
+func TestReplay(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(404)
+		}
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	t0 := time.Now()
+	entries := []LogEntry{
+		{Time: t0, Method: "GET", Path: "/a", Status: 200, Duration: time.Second},
+		{Time: t0.Add(40 * time.Millisecond), Method: "GET", Path: "/b", Status: 200, Duration: time.Second},
+		{Time: t0.Add(80 * time.Millisecond), Method: "GET", Path: "/gone", Status: 200, Duration: time.Second},
+	}
+
+	rep := rn.Replay(Replay{Entries: entries})
+	if rep.Mismatched != 1 {
+		t.Errorf("got %d mismatched, want 1", rep.Mismatched)
+	}
+	if got, want := rep.Replayed, map[int]int{200: 2, 404: 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got replayed %v, want %v", got, want)
+	}
+	if rep.OriginalLatency.P95 != time.Second {
+		t.Errorf("got original p95 %s, want 1s", rep.OriginalLatency.P95)
+	}
+
+	if err := (Replay{MaxMismatch: 0.5}).check(rep); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err := (Replay{}).check(rep); err == nil || !strings.Contains(err.Error(), "GET /gone: got 404, want 200") {
+		t.Errorf("got error %v, want mismatch of /gone", err)
+	}
+	if err := (Replay{MaxMismatch: 1, MaxSlowdown: 1e-9}).check(rep); err == nil {
+		t.Error("want latency error, got nil")
+	}
+
+	start := time.Now()
+	rep = rn.Replay(Replay{Entries: entries, Timing: true, Speed: 2})
+	if d := time.Since(start); d < 40*time.Millisecond {
+		t.Errorf("timed replay took %s, want at least 40ms", d)
+	}
+	if rep.Mismatched != 1 {
+		t.Errorf("timed: got %d mismatched, want 1", rep.Mismatched)
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/http"
+)
+
+// RequestID configures the injection of a unique id into every request and
+// the check that the server echoes it back, e.g. to validate the tracing
+// middleware of every endpoint in a suite.
+type RequestID struct {
+	// Header is the request header that carries the id, it defaults to
+	// "X-Request-Id". A request that sets the header itself keeps its
+	// own value.
+	Header string
+	// Echo is the response header that must carry the same id, it
+	// defaults to Header.
+	Echo string
+}
+
+// RequestIDs, if set, is the default RequestID of the Runners.
+var RequestIDs *RequestID
+
+func (id *RequestID) names() (header, echo string) {
+	header, echo = id.Header, id.Echo
+	if header == "" {
+		header = "X-Request-Id"
+	}
+	if echo == "" {
+		echo = header
+	}
+	return header, echo
+}
+
+// inject sets the request's id header to a new random UUID.
+func (id *RequestID) inject(req *http.Request) error {
+	header, _ := id.names()
+	if req.Header.Get(header) != "" {
+		return nil
+	}
+	v, err := newUUID()
+	if err != nil {
+		return fmt.Errorf("hit: failed generating request id. %v", err)
+	}
+	req.Header.Set(header, v)
+	return nil
+}
+
+// newUUID returns a new random, version 4, UUID.
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // variant 10
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// check checks that the response echoes the id of its request.
+func (id *RequestID) check(res *http.Response) error {
+	if res.Request == nil {
+		return nil
+	}
+	header, echo := id.names()
+	want := res.Request.Header.Get(header)
+	if got := res.Header.Get(echo); got != want {
+		return fmt.Errorf("Header[%q] got = %s%q%s, want = %s%q%s, the request's %s\n",
+			echo, RedColor, got, StopColor, RedColor, want, StopColor, header)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	var seen []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-Id")
+		seen = append(seen, id)
+		switch r.URL.Path {
+		case "/echo":
+			w.Header().Set("X-Request-Id", id)
+		case "/trace":
+			w.Header().Set("X-Trace-Id", id)
+		case "/other":
+			w.Header().Set("X-Request-Id", "something-else")
+		}
+	}))
+	defer ts.Close()
+
+	c := DefaultConfig()
+	c.Addr = ts.URL[len("http://"):]
+	c.RequestID = &RequestID{}
+	rn, err := c.NewRunner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok := Request{Want: Response{Status: 200}}
+
+	for i := 0; i < 2; i++ {
+		if err := rn.Execute(ok, "GET", "/echo"); err != nil {
+			t.Errorf("echo: unexpected error %v", err)
+		}
+	}
+	if len(seen) != 2 || seen[0] == seen[1] || !uuidv4Regexp.MatchString(seen[0]) {
+		t.Errorf("got ids %q, want two distinct UUIDs", seen)
+	}
+
+	// a request's own id is kept
+	own := Request{Header: Header{"X-Request-Id": {"abc"}}, Want: Response{Status: 200}}
+	if err := rn.Execute(own, "GET", "/echo"); err != nil {
+		t.Errorf("own id: unexpected error %v", err)
+	}
+	if seen[len(seen)-1] != "abc" {
+		t.Errorf("got id %q, want %q", seen[len(seen)-1], "abc")
+	}
+
+	for _, path := range []string{"/other", "/none"} {
+		if err := rn.Execute(ok, "GET", path); err == nil || !strings.Contains(err.Error(), `Header["X-Request-Id"]`) {
+			t.Errorf("%s: got error %v, want echo mismatch", path, err)
+		}
+	}
+
+	rn.RequestID = &RequestID{Echo: "X-Trace-Id"}
+	if err := rn.Execute(ok, "GET", "/trace"); err != nil {
+		t.Errorf("trace: unexpected error %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Reruns is the maximum number of times a failed Request of a Hit is rerun. A
+// Request that passes on a rerun is flaky, it's logged but doesn't fail the
+// test, a Request that fails every rerun is a hard failure. Mind that the
+// reruns resend requests that are not idempotent as well.
+var Reruns = 0
+
+// Rerun records a Request that failed and was rerun.
+type Rerun struct {
+	Method, Path string
+	// Attempts is the number of times the Request was executed.
+	Attempts int
+	// Flaky reports whether the Request passed on a rerun.
+	Flaky bool
+	// Err is the error of the first attempt.
+	Err error
+}
+
+// reran records the rerun Requests.
+var reran = struct {
+	sync.Mutex
+	rr []Rerun
+}{}
+
+// Reran returns the Requests rerun so far, the flaky ones separately from the
+// hard failures.
+func Reran() (flaky, hard []Rerun) {
+	reran.Lock()
+	defer reran.Unlock()
+	for _, r := range reran.rr {
+		if r.Flaky {
+			flaky = append(flaky, r)
+		} else {
+			hard = append(hard, r)
+		}
+	}
+	return flaky, hard
+}
+
+// RerunReport returns a summary of the Requests rerun so far, listing the
+// flaky ones and the hard failures separately, e.g. to be printed by TestMain
+// after m.Run so that the flaky ones stay visible.
+func RerunReport() string {
+	flaky, hard := Reran()
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d flaky, %d hard failures\n", len(flaky), len(hard))
+	for _, r := range flaky {
+		fmt.Fprintf(&b, "  flaky %s %s, passed on attempt %d\n", r.Method, r.Path, r.Attempts)
+	}
+	for _, r := range hard {
+		fmt.Fprintf(&b, "  hard  %s %s, failed %d attempts\n", r.Method, r.Path, r.Attempts)
+	}
+	return b.String()
+}
+
+// rerun reruns the failed Request up to the Runner's Reruns times. It logs
+// the failure if a rerun passes and returns nil, otherwise it returns the
+// first error.
+func (rn *Runner) rerun(t *testing.T, r Request, method, path string, err error) error {
+	rec := Rerun{Method: method, Path: path, Attempts: 1, Err: err}
+	for rec.Attempts <= rn.Reruns {
+		rec.Attempts++
+		if rn.Execute(r, method, path) == nil {
+			rec.Flaky = true
+			break
+		}
+	}
+	reran.Lock()
+	reran.rr = append(reran.rr, rec)
+	reran.Unlock()
+
+	if rec.Flaky {
+		t.Logf("Flaky: %s %s passed on attempt %d of %d, the first failed with:\n%v",
+			method, path, rec.Attempts, rn.Reruns+1, err)
+		return nil
+	}
+	return fmt.Errorf("%vFailed all %d attempts.\n", err, rec.Attempts)
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestReruns(t *testing.T) {
+	var calls, failFirst int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt64(&calls, 1) <= atomic.LoadInt64(&failFirst) {
+			w.WriteHeader(503)
+		}
+	}))
+	defer ts.Close()
+	defer func(rr []Rerun) { reran.rr = rr }(reran.rr)
+	reran.rr = nil
+
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+	rn.Reruns = 2
+	r := Request{Want: Response{Status: 200}}
+
+	tests := []struct {
+		failFirst int64
+		calls     int64
+		hard      bool
+	}{
+		{failFirst: 0, calls: 1},
+		{failFirst: 1, calls: 2},
+		{failFirst: 2, calls: 3},
+		{failFirst: 3, calls: 3, hard: true},
+	}
+	for i, tt := range tests {
+		atomic.StoreInt64(&calls, 0)
+		atomic.StoreInt64(&failFirst, tt.failFirst)
+		err := rn.Execute(r, "GET", "/")
+		if err != nil {
+			err = rn.rerun(t, r, "GET", "/", err)
+		}
+		if got := atomic.LoadInt64(&calls); got != tt.calls {
+			t.Errorf("#%d: got %d calls, want %d", i, got, tt.calls)
+		}
+		if tt.hard != (err != nil) {
+			t.Errorf("#%d: got error %v, want hard failure %t", i, err, tt.hard)
+		}
+		if err != nil && !strings.Contains(err.Error(), "Failed all 3 attempts") {
+			t.Errorf("#%d: got error %v, want attempt count", i, err)
+		}
+	}
+
+	flaky, hard := Reran()
+	if len(flaky) != 2 || flaky[0].Attempts != 2 || flaky[1].Attempts != 3 || flaky[0].Err == nil {
+		t.Errorf("got flaky %+v", flaky)
+	}
+	if len(hard) != 1 || hard[0].Attempts != 3 || hard[0].Flaky {
+		t.Errorf("got hard %+v", hard)
+	}
+	if rep := RerunReport(); !strings.HasPrefix(rep, "2 flaky, 1 hard failures\n") {
+		t.Errorf("got report %q", rep)
+	}
+
+	// a flaky request doesn't fail the Hit
+	atomic.StoreInt64(&calls, 0)
+	atomic.StoreInt64(&failFirst, 1)
+	rn.Test(t, Hit{Path: "/", Requests: Requests{"GET": {r}}})
+}
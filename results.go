@@ -0,0 +1,189 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Results, if set, records the status and the duration of every request
+// executed by the package's functions, e.g. for the duration of a test
+// binary's run. It's the default store of a new Runner, see Config.Results.
+var Results *ResultStore
+
+// ResultStore records the outcome of the executed requests of a run in a
+// database so that it can be compared to the earlier runs, e.g. to catch an
+// endpoint that got 40% slower since last week. The statements are written
+// for SQLite, the database is opened by the caller with a driver of their
+// choice, e.g. github.com/mattn/go-sqlite3 or modernc.org/sqlite.
+type ResultStore struct {
+	db      *sql.DB
+	name    string
+	run     int64
+	started time.Time
+	mu      sync.Mutex
+}
+
+var resultSchema = []string{
+	`CREATE TABLE IF NOT EXISTS hit_runs (
+		id INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		started INTEGER NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS hit_results (
+		run INTEGER NOT NULL REFERENCES hit_runs (id),
+		method TEXT NOT NULL,
+		path TEXT NOT NULL,
+		status INTEGER NOT NULL,
+		duration INTEGER NOT NULL,
+		failed INTEGER NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS hit_results_run ON hit_results (run)`,
+}
+
+// NewResultStore creates the store's tables in the specified database, if
+// they don't exist yet, and starts a new run of the named suite. Only the
+// runs of the same name are compared with each other.
+func NewResultStore(db *sql.DB, name string) (*ResultStore, error) {
+	for _, q := range resultSchema {
+		if _, err := db.Exec(q); err != nil {
+			return nil, fmt.Errorf("hit: failed creating the results schema. %v", err)
+		}
+	}
+	s := &ResultStore{db: db, name: name, started: time.Now()}
+	res, err := db.Exec(`INSERT INTO hit_runs (name, started) VALUES (?, ?)`, name, s.started.UnixNano())
+	if err != nil {
+		return nil, fmt.Errorf("hit: failed starting a run. %v", err)
+	}
+	if s.run, err = res.LastInsertId(); err != nil {
+		return nil, fmt.Errorf("hit: failed starting a run. %v", err)
+	}
+	return s, nil
+}
+
+// Record records the outcome of a request of the current run.
+func (s *ResultStore) Record(method, path string, status int, d time.Duration, failed bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.db.Exec(`INSERT INTO hit_results (run, method, path, status, duration, failed) VALUES (?, ?, ?, ?, ?, ?)`,
+		s.run, method, path, status, int64(d), failed)
+	if err != nil {
+		return fmt.Errorf("hit: failed recording the result of %s %s. %v", method, path, err)
+	}
+	return nil
+}
+
+// recordResult records the outcome of a request in the Runner's Results, if
+// set.
+func (rn *Runner) recordResult(method, path string, status int, d time.Duration, failed bool) error {
+	if rn.Results == nil {
+		return nil
+	}
+	return rn.Results.Record(method, path, status, d, failed)
+}
+
+// Trend compares the median duration of an endpoint in the current run to
+// its median duration in the earlier runs.
+type Trend struct {
+	Method, Path string
+	Before, Now  time.Duration
+	// Runs is the number of earlier runs that requested the endpoint.
+	Runs int
+}
+
+// Change returns the relative change of the median duration, e.g. 0.4 for
+// an endpoint that got 40% slower.
+func (t Trend) Change() float64 {
+	if t.Before == 0 {
+		return 0
+	}
+	return float64(t.Now)/float64(t.Before) - 1
+}
+
+func (t Trend) String() string {
+	return fmt.Sprintf("%s %s %s -> %s (%+.0f%% over %d runs)", t.Method, t.Path, t.Before, t.Now, t.Change()*100, t.Runs)
+}
+
+// Trends returns the trends of the endpoints requested both by the current
+// run and by the earlier runs started since the specified time, sorted by
+// their change, the most slowed down first.
+func (s *ResultStore) Trends(since time.Time) ([]Trend, error) {
+	rows, err := s.db.Query(`SELECT r.run, r.method, r.path, r.duration FROM hit_results r
+		JOIN hit_runs u ON u.id = r.run
+		WHERE u.name = ? AND u.started >= ?`, s.name, since.UnixNano())
+	if err != nil {
+		return nil, fmt.Errorf("hit: failed querying results. %v", err)
+	}
+	defer rows.Close()
+
+	type endpoint struct{ method, path string }
+	type samples struct {
+		before, now []time.Duration
+		runs        map[int64]bool
+	}
+	m := make(map[endpoint]*samples)
+	for rows.Next() {
+		var run, d int64
+		var e endpoint
+		if err := rows.Scan(&run, &e.method, &e.path, &d); err != nil {
+			return nil, fmt.Errorf("hit: failed reading results. %v", err)
+		}
+		ss := m[e]
+		if ss == nil {
+			ss = &samples{runs: make(map[int64]bool)}
+			m[e] = ss
+		}
+		if run == s.run {
+			ss.now = append(ss.now, time.Duration(d))
+		} else {
+			ss.before = append(ss.before, time.Duration(d))
+			ss.runs[run] = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("hit: failed reading results. %v", err)
+	}
+
+	var tt []Trend
+	for e, ss := range m {
+		if len(ss.now) == 0 || len(ss.before) == 0 {
+			continue
+		}
+		tt = append(tt, Trend{Method: e.method, Path: e.path,
+			Before: latencyOf(ss.before).P50, Now: latencyOf(ss.now).P50, Runs: len(ss.runs)})
+	}
+	sort.Slice(tt, func(i, j int) bool {
+		if ci, cj := tt[i].Change(), tt[j].Change(); ci != cj {
+			return ci > cj
+		}
+		return tt[i].Method+" "+tt[i].Path < tt[j].Method+" "+tt[j].Path
+	})
+	return tt, nil
+}
+
+// CheckTrends returns an error listing the endpoints whose median duration
+// grew by more than the specified fraction, e.g. 0.4, compared to the runs
+// started since the specified time.
+func (s *ResultStore) CheckTrends(since time.Time, max float64) error {
+	tt, err := s.Trends(since)
+	if err != nil {
+		return err
+	}
+	var msg string
+	for _, t := range tt {
+		if t.Change() > max {
+			msg += fmt.Sprintf("%s %s median got = %s%s%s, want at most %s%s%s, %.0f%% slower than %s over %d runs\n",
+				t.Method, t.Path, RedColor, t.Now, StopColor, RedColor,
+				time.Duration(float64(t.Before)*(1+max)), StopColor, t.Change()*100, t.Before, t.Runs)
+		}
+	}
+	if msg != "" {
+		return fmt.Errorf("%s", msg)
+	}
+	return nil
+}
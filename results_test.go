@@ -0,0 +1,211 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDB is an in-memory database/sql driver that understands just the
+// statements of the ResultStore.
+type fakeDB struct {
+	mu      sync.Mutex
+	runs    [][]driver.Value // id, name, started
+	results [][]driver.Value // run, method, path, status, duration, failed
+}
+
+var fakeDBs = struct {
+	sync.Mutex
+	m map[string]*fakeDB
+}{m: make(map[string]*fakeDB)}
+
+type fakeDriver struct{}
+
+func init() { sql.Register("hitfake", fakeDriver{}) }
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	fakeDBs.Lock()
+	defer fakeDBs.Unlock()
+	db := fakeDBs.m[name]
+	if db == nil {
+		db = &fakeDB{}
+		fakeDBs.m[name] = db
+	}
+	return fakeConn{db}, nil
+}
+
+type fakeConn struct{ db *fakeDB }
+
+func (c fakeConn) Prepare(q string) (driver.Stmt, error) { return fakeStmt{c.db, q}, nil }
+func (fakeConn) Close() error                            { return nil }
+func (fakeConn) Begin() (driver.Tx, error)               { return nil, errors.New("not supported") }
+
+type fakeStmt struct {
+	db *fakeDB
+	q  string
+}
+
+func (fakeStmt) Close() error  { return nil }
+func (fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	switch {
+	case strings.HasPrefix(s.q, "CREATE"):
+		return driver.RowsAffected(0), nil
+	case strings.HasPrefix(s.q, "INSERT INTO hit_runs"):
+		id := int64(len(s.db.runs) + 1)
+		s.db.runs = append(s.db.runs, append([]driver.Value{id}, args...))
+		return fakeResult(id), nil
+	case strings.HasPrefix(s.q, "INSERT INTO hit_results"):
+		s.db.results = append(s.db.results, args)
+		return fakeResult(0), nil
+	}
+	return nil, errors.New("unexpected statement " + s.q)
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	name, since := args[0].(string), args[1].(int64)
+	rows := &fakeRows{}
+	for _, u := range s.db.runs {
+		if u[1].(string) != name || u[2].(int64) < since {
+			continue
+		}
+		for _, r := range s.db.results {
+			if r[0].(int64) == u[0].(int64) {
+				rows.vv = append(rows.vv, []driver.Value{r[0], r[1], r[2], r[4]})
+			}
+		}
+	}
+	return rows, nil
+}
+
+type fakeResult int64
+
+func (r fakeResult) LastInsertId() (int64, error) { return int64(r), nil }
+func (fakeResult) RowsAffected() (int64, error)   { return 1, nil }
+
+type fakeRows struct{ vv [][]driver.Value }
+
+func (*fakeRows) Columns() []string { return []string{"run", "method", "path", "duration"} }
+func (*fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.vv) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.vv[0])
+	r.vv = r.vv[1:]
+	return nil
+}
+
+func TestResultStoreTrends(t *testing.T) {
+	db, err := sql.Open("hitfake", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	runs := []struct {
+		name string
+		a, b time.Duration
+	}{
+		{"api", 10 * time.Millisecond, 10 * time.Millisecond},
+		{"api", 10 * time.Millisecond, 10 * time.Millisecond},
+		// other suites are not compared
+		{"web", time.Second, time.Second},
+		{"api", 20 * time.Millisecond, 11 * time.Millisecond},
+	}
+	var s *ResultStore
+	for _, r := range runs {
+		if s, err = NewResultStore(db, r.name); err != nil {
+			t.Fatal(err)
+		}
+		s.Record("GET", "/a", 200, r.a, false)
+		s.Record("GET", "/b", 200, r.b, false)
+	}
+	s.Record("GET", "/new", 200, time.Millisecond, false)
+
+	tt, err := s.Trends(time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tt) != 2 || tt[0].Path != "/a" || tt[0].Runs != 2 || tt[0].Change() != 1 || tt[1].Path != "/b" {
+		t.Errorf("got trends %v", tt)
+	}
+
+	err = s.CheckTrends(time.Time{}, 0.4)
+	if err == nil || !strings.Contains(err.Error(), "GET /a") || strings.Contains(err.Error(), "GET /b") {
+		t.Errorf("got error %v, want one for GET /a only", err)
+	}
+	if err := s.CheckTrends(time.Now(), 0.4); err != nil {
+		t.Errorf("no earlier runs: unexpected error %v", err)
+	}
+}
+
+func TestResultsRecording(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	db, err := sql.Open("hitfake", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if rn.Results, err = NewResultStore(db, "api"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := rn.Execute(Request{Want: Response{Status: 200}}, "GET", "/missing"); err == nil {
+		t.Error("want error, got nil")
+	}
+	fake := fakeDBs.m[t.Name()]
+	if len(fake.results) != 1 {
+		t.Fatalf("got %d results, want 1", len(fake.results))
+	}
+	r := fake.results[0]
+	if r[1] != "GET" || r[2] != "/missing" || r[3] != int64(404) || r[5] != true {
+		t.Errorf("got result %v", r)
+	}
+
+	// the requests that fail at the transport level are recorded as well
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rn.Addr = l.Addr().String()
+	l.Close()
+	if err := rn.Execute(Request{Want: Response{Status: 200}}, "GET", "/refused"); err == nil {
+		t.Error("want error, got nil")
+	}
+	if err := rn.Execute(Request{TransportError: ConnRefused}, "GET", "/expected"); err != nil {
+		t.Errorf("got err %v, want <nil>", err)
+	}
+	if len(fake.results) != 3 {
+		t.Fatalf("got %d results, want 3", len(fake.results))
+	}
+	if r := fake.results[1]; r[2] != "/refused" || r[3] != int64(0) || r[5] != true {
+		t.Errorf("got result %v, want a failure without a status", r)
+	}
+	if r := fake.results[2]; r[2] != "/expected" || r[3] != int64(0) || r[5] != false {
+		t.Errorf("got result %v, want a success without a status", r)
+	}
+}
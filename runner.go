@@ -0,0 +1,204 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Runner executes Hits and Requests with a configuration of its own in place
+// of the package's variables, so that suites against different targets can
+// run concurrently, e.g. in parallel tests. The package's variables serve only
+// as the defaults of a new Runner, changing them doesn't affect existing ones.
+type Runner struct {
+	Config
+
+	once   sync.Once
+	client *http.Client
+}
+
+// NewRunner returns a Runner configured by DefaultConfig.
+func NewRunner() *Runner {
+	return &Runner{Config: DefaultConfig()}
+}
+
+// globalRunner returns a Runner configured by the package's variables that
+// shares the package's client, and so its connections, with all the others.
+func globalRunner() *Runner {
+	rn := NewRunner()
+	rn.client = client
+	return rn
+}
+
+// DefaultRunner returns a Runner configured by the package's variables, like
+// the one that executes the package's functions, e.g. for the packages that
+// build requests of their own.
+func DefaultRunner() *Runner {
+	return globalRunner()
+}
+
+// runner returns the Runner executing the request.
+func (r Request) runner() *Runner {
+	if r.rn != nil {
+		return r.rn
+	}
+	return globalRunner()
+}
+
+// Test executes all of the Hit's Requests.
+func (rn *Runner) Test(t *testing.T, h Hit) {
+	cleanup, err := rn.loadFixtures(h.Fixtures)
+	defer func() {
+		if err := cleanup(); err != nil {
+			t.Error(rn.plain(err))
+		}
+	}()
+	if err != nil {
+		t.Error(rn.plain(err))
+		return
+	}
+
+	skipped := 0
+	for _, mr := range rn.order(h.Requests) {
+		m, r := mr.method, mr.r
+		if r.Skip || rn.Profile.skips(m) {
+			skipped++
+			continue
+		}
+		if !inShard(r.shardKey(m, h.Path, mr.index)) {
+			continue
+		}
+		if r.Host == "" {
+			r.Host = h.Host
+		}
+		if r.Signer == nil {
+			r.Signer = h.Signer
+		}
+		if r.RateLimit == nil {
+			r.RateLimit = h.RateLimit
+		}
+		if r.Security == nil {
+			r.Security = h.Security
+		}
+		err := rn.Execute(r, m, h.Path)
+		if err != nil && rn.Reruns > 0 {
+			err = rn.rerun(t, r, m, h.Path, err)
+		}
+		if q, ok := rn.quarantined(r.name(m, h.Path)); ok {
+			err = q.report(t, err)
+		}
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	if skipped > 0 {
+		log.Printf("Warning: Skipped %d test(s) for %q.", skipped, h.Path)
+	}
+	if rn.Shuffle != 0 && t.Failed() {
+		t.Logf("Requests shuffled with seed %d, set %s=%d to reproduce.", rn.Shuffle, ShuffleEnv, rn.Shuffle)
+	}
+}
+
+// Execute executes the Request with the specified method to the specified
+// path.
+func (rn *Runner) Execute(r Request, method, path string) error {
+	r.rn = rn
+	r.Want.Body = rn.withMode(r.Want.Body)
+	return rn.plain(r.Execute(method, path))
+}
+
+// baseURL returns the Runner's BaseURL, or the BaseURL of its profile, or the
+// URL of its Addr.
+func (rn *Runner) baseURL() string {
+	if rn.BaseURL != "" {
+		return strings.TrimRight(rn.BaseURL, "/")
+	}
+	if rn.Profile != nil && rn.Profile.BaseURL != "" {
+		return strings.TrimRight(rn.Profile.BaseURL, "/")
+	}
+	return "http://" + normAddr(rn.Addr)
+}
+
+// Report returns err, the failure of the named request with the specified
+// method and path, redacted and stripped of its colors if the Runner's
+// NoColor is set, and writes its Events to the Runner's Events. It's meant
+// for the packages that execute requests of their own, their failures are
+// then reported like those of a Request.
+func (rn *Runner) Report(name, method, path string, err error) error {
+	err = rn.Redactions.redactErr(err)
+	rn.emitEvents(name, "", method, path, err)
+	return rn.plain(err)
+}
+
+// URL returns the URL of the specified path on the Runner's target, e.g. for
+// the packages that build requests of their own.
+func (rn *Runner) URL(path string) string {
+	return rn.baseURL() + path
+}
+
+// Client returns the http.Client with which the Runner sends its requests, it
+// doesn't follow redirects.
+func (rn *Runner) Client() *http.Client {
+	return rn.httpClient()
+}
+
+// httpClient returns the Runner's http.Client, which doesn't follow redirects.
+func (rn *Runner) httpClient() *http.Client {
+	rn.once.Do(func() {
+		if rn.client != nil {
+			return
+		}
+		tr := defaultTransport.Clone()
+		tr.TLSClientConfig = rn.TLS
+		if rn.Profile != nil && rn.Profile.TLS != nil {
+			tr.TLSClientConfig = rn.Profile.TLS
+		}
+		rn.client = &http.Client{
+			Transport:     runnerTransport{rn, tr},
+			CheckRedirect: client.CheckRedirect,
+			Timeout:       rn.Timeout,
+		}
+	})
+	return rn.client
+}
+
+// runnerTransport is an http.RoundTripper that uses the Runner's Transport
+// or, if that's nil, the Runner's own transport.
+type runnerTransport struct {
+	rn  *Runner
+	own *http.Transport
+}
+
+func (t runnerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if t.rn.Transport != nil {
+		return t.rn.Transport.RoundTrip(r)
+	}
+	return t.own.RoundTrip(r)
+}
+
+// resend sends a copy of the specified request, which must have been created
+// with a replayable body, using the Runner's client.
+func (rn *Runner) resend(req *http.Request) (*http.Response, error) {
+	cp := withConnTrace(req.Clone(req.Context()))
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		cp.Body = body
+	}
+	c := rn.httpClient()
+	if rec := wireOf(req); rec != nil {
+		c = rn.wireClient(rec)
+	}
+	res, err := c.Do(cp)
+	if err != nil && !isRedirectError(err) {
+		return nil, err
+	}
+	return res, nil
+}
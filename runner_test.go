@@ -0,0 +1,141 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunner(t *testing.T) {
+	newServer := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"server":%q}`, name)
+		}))
+	}
+	ta, tb := newServer("a"), newServer("b")
+
+	// the package's Addr points nowhere, each runner has its own; the
+	// parallel subtests run after this function returns
+	addr := Addr
+	Addr = "127.0.0.1:1"
+	t.Cleanup(func() {
+		Addr = addr
+		ta.Close()
+		tb.Close()
+	})
+
+	for _, ts := range []struct {
+		name string
+		srv  *httptest.Server
+	}{{"a", ta}, {"b", tb}} {
+		ts := ts
+		t.Run(ts.name, func(t *testing.T) {
+			t.Parallel()
+			rn := NewRunner()
+			rn.Addr = ts.srv.URL[len("http://"):]
+			for i := 0; i < 20; i++ {
+				err := rn.Execute(Request{Want: Response{
+					Status: 200,
+					Body:   JSONBody{"server": ts.name},
+				}}, "GET", "/")
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestNewRunner(t *testing.T) {
+	defer func(a string, m int64) { Addr, MaxBodySize = a, m }(Addr, MaxBodySize)
+	Addr, MaxBodySize = "example.com:80", 10
+
+	rn := NewRunner()
+	Addr, MaxBodySize = "example.org:80", 20
+	if rn.Addr != "example.com:80" || rn.MaxBodySize != 10 {
+		t.Errorf("got %q and %d, want the defaults at the time of NewRunner", rn.Addr, rn.MaxBodySize)
+	}
+	if got, want := rn.baseURL(), "http://example.com:80"; got != want {
+		t.Errorf("got base url %q, want %q", got, want)
+	}
+	rn.Profile = &Profile{BaseURL: "https://staging.example.com/"}
+	if got, want := rn.baseURL(), "https://staging.example.com"; got != want {
+		t.Errorf("got base url %q, want %q", got, want)
+	}
+}
+
+func TestRunnerHelpers(t *testing.T) {
+	var seeds int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"access_token":"t0ken"}`))
+		case "/me":
+			if r.Header.Get("Authorization") != "Bearer t0ken" {
+				w.WriteHeader(401)
+			}
+		case "/seed":
+			atomic.AddInt32(&seeds, 1)
+			w.WriteHeader(201)
+		default:
+			http.ServeContent(w, r, "file.txt", time.Time{}, bytes.NewReader([]byte("0123456789")))
+		}
+	}))
+	defer ts.Close()
+
+	// the package's Addr points nowhere, the helpers must use the Runner's
+	defer func(a string) { Addr = a }(Addr)
+	Addr = "127.0.0.1:1"
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	r := Request{Signer: &ClientCredentials{TokenURL: "/token"}, Want: Response{Status: 200}}
+	if err := rn.Execute(r, "GET", "/me"); err != nil {
+		t.Errorf("ClientCredentials: got err %v, want <nil>", err)
+	}
+	if err := rn.CheckHEAD("/file", nil); err != nil {
+		t.Errorf("CheckHEAD: got err %v, want <nil>", err)
+	}
+	if err := rn.CheckRanges("/file", nil, 4); err != nil {
+		t.Errorf("CheckRanges: got err %v, want <nil>", err)
+	}
+	rep := rn.Replay(Replay{Entries: []LogEntry{{Method: "GET", Path: "/file", Status: 200}}})
+	if rep.Mismatched != 0 {
+		t.Errorf("Replay: got %d mismatched, want 0: %+v", rep.Mismatched, rep.Results)
+	}
+
+	seed := HTTPSeed{Seed: SeedRequest{Method: "POST", Path: "/seed"}, Undo: &SeedRequest{Method: "POST", Path: "/seed"}}
+	cfg := rn.Config
+	Hit{
+		Path:     "/file",
+		Fixtures: []Fixture{seed},
+		Requests: Requests{"GET": {{Want: Response{Status: 200}}}},
+		Config:   &cfg,
+	}.Test(t)
+	if n := atomic.LoadInt32(&seeds); n != 2 {
+		t.Errorf("HTTPSeed: got %d seed requests, want 2", n)
+	}
+	s := Scenario{Name: "s", Fixtures: []Fixture{seed}, Steps: []Step{{Method: "GET", Path: "/file", Request: Request{Want: Response{Status: 200}}}}}
+	if err := rn.Scenario(s); err != nil {
+		t.Errorf("Scenario: got err %v, want <nil>", err)
+	}
+	if err := rn.RawRequest(RawRequest{Data: "GET /file HTTP/1.1\r\nHost: x\r\nConnection: close\r\n\r\n", Want: RawResponse{Status: 200}}); err != nil {
+		t.Errorf("RawRequest: got err %v, want <nil>", err)
+	}
+	if rep := rn.Load(Load{Method: "GET", Path: "/file", Request: Request{Want: Response{Status: 200}}, Phases: []Phase{{From: 100, Duration: 30 * time.Millisecond}}}); rep.Phases[0].Errors != 0 {
+		t.Errorf("Load: got errors %v, want none", rep.Phases[0].FirstErrors)
+	}
+	up := ResumableUpload{Create: "/nowhere", Data: []byte("x"), ChunkSize: 1}
+	if err := rn.ResumableUpload(up); err == nil || strings.Contains(err.Error(), "127.0.0.1:1") {
+		t.Errorf("ResumableUpload: got err %v, want a failure of the Runner's target", err)
+	}
+}
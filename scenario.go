@@ -0,0 +1,149 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+// Scenario represents a sequence of dependent requests, executed in order.
+// Unlike a Hit's Requests a Step can use the response to the previous Step,
+// e.g. to follow one of its links.
+type Scenario struct {
+	Name  string
+	Steps []Step
+
+	// Fixtures are loaded, in order, before the Steps are executed and
+	// cleaned up, in reverse order, afterwards.
+	Fixtures []Fixture
+}
+
+// Step represents a single request of a Scenario.
+type Step struct {
+	Method  string
+	Path    string
+	Request Request
+
+	// Follow, if set, is the relation of the previous Step's response link
+	// that's used in place of Path, the link is taken from the response's
+	// Link header or from its HAL _links member. Method defaults to GET.
+	Follow string
+}
+
+// Test executes the Scenario, unless it belongs to another Shard.
+func (s Scenario) Test(t *testing.T) {
+	if !inShard(s.Name) {
+		t.Logf("Scenario %q belongs to another shard, skipped.", s.Name)
+		return
+	}
+	if err := s.Execute(); err != nil {
+		t.Error(err)
+	}
+}
+
+// Execute executes the Scenario's Steps in order, it stops at the first Step
+// that fails.
+func (s Scenario) Execute() error {
+	return globalRunner().Scenario(s)
+}
+
+// Scenario executes the Scenario's Steps in order against the Runner's
+// target, it stops at the first Step that fails.
+func (rn *Runner) Scenario(s Scenario) (err error) {
+	cleanup, err := rn.loadFixtures(s.Fixtures)
+	defer func() {
+		if cerr := cleanup(); cerr != nil && err == nil {
+			err = fmt.Errorf("%sScenario %q:%s\n%v", PurpleColor, s.Name, StopColor, cerr)
+		}
+	}()
+	if err != nil {
+		return fmt.Errorf("%sScenario %q:%s\n%v", PurpleColor, s.Name, StopColor, err)
+	}
+
+	var prev *received
+	for i, st := range s.Steps {
+		method, path := st.Method, st.Path
+		if st.Follow != "" {
+			if prev == nil {
+				return s.failure(rn, i, fmt.Errorf("hit: cannot follow %q, there's no previous response", st.Follow))
+			}
+			href, err := prev.follow(st.Follow)
+			if err != nil {
+				return s.failure(rn, i, err)
+			}
+			path = href
+			if method == "" {
+				method = "GET"
+			}
+		}
+		res, err := rn.step(st.Request, method, path)
+		if err != nil {
+			return s.failure(rn, i, err)
+		}
+		prev = res
+	}
+	return nil
+}
+
+// failure returns the error of the Step at index i prefixed with the name of
+// the Scenario and the Step's number, redacted by the Runner.
+func (s Scenario) failure(rn *Runner, i int, err error) error {
+	return rn.Redactions.redactErr(fmt.Errorf("%sScenario %q step #%d:%s\n%w", PurpleColor, s.Name, i+1, StopColor, err))
+}
+
+// received holds a response to a Scenario's Step.
+type received struct {
+	url    *url.URL
+	header http.Header
+	body   []byte
+}
+
+// follow returns the path of the specified relation's link, resolved against
+// the URL of the request that the response was received for.
+func (r *received) follow(rel string) (string, error) {
+	links := ParseLinks(r.header)
+	if hal, err := HALLinks(r.body); err == nil {
+		links = append(links, hal...)
+	}
+	for _, l := range links {
+		if l.Rel == rel {
+			ref, err := url.Parse(l.Href)
+			if err != nil {
+				return "", fmt.Errorf("hit: bad %q link %q. %v", rel, l.Href, err)
+			}
+			return r.url.ResolveReference(ref).RequestURI(), nil
+		}
+	}
+	return "", fmt.Errorf("Link[%q] got = %s<not present>%s, want = %sa link%s\n", rel, RedColor, StopColor, RedColor, StopColor)
+}
+
+// step sends the request and compares the response to Want like Execute does,
+// it returns the response for the next Step to use.
+func (rn *Runner) step(r Request, method, path string) (*received, error) {
+	r.rn = rn
+	r.Want.Body = rn.withMode(r.Want.Body)
+	req, err := r.newRequest(method, path)
+	if err != nil {
+		return nil, err
+	}
+	res, err := r.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("hit: %s %s failed. %v", method, path, err)
+	}
+	b, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("hit: error reading http.Response.Body. %v", err)
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(b))
+	if ll := r.check(res); len(ll) > 0 {
+		return nil, rn.plain(r.failure(method, path, ll.Error(), ll...))
+	}
+	return &received{url: req.URL, header: res.Header, body: b}, nil
+}
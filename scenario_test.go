@@ -0,0 +1,56 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestScenarioFollow(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.RequestURI() {
+		case "/orders":
+			w.Header().Set("Link", `</orders?page=2>; rel="next"`)
+			w.Write([]byte(`{"_links": {"first": {"href": "orders/1"}}}`))
+		case "/orders?page=2":
+			w.Write([]byte(`{"page": 2}`))
+		case "/orders/1":
+			w.Write([]byte(`{"id": 1}`))
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	s := Scenario{Name: "orders", Steps: []Step{
+		{Method: "GET", Path: "/orders", Request: Request{Want: Response{Status: 200}}},
+		{Follow: "next", Request: Request{Want: Response{Status: 200, Body: JSONBody{"page": 2}}}},
+	}}
+	if err := rn.Scenario(s); err != nil {
+		t.Error(err)
+	}
+
+	s.Steps[1] = Step{Follow: "first", Request: Request{Want: Response{Status: 200, Body: JSONBody{"id": 1}}}}
+	if err := rn.Scenario(s); err != nil {
+		t.Error(err)
+	}
+
+	s.Steps[1] = Step{Follow: "last", Request: Request{Want: Response{Status: 200}}}
+	if err := rn.Scenario(s); err == nil || !strings.Contains(err.Error(), `Link["last"]`) || !strings.Contains(err.Error(), "step #2") {
+		t.Errorf("got err %v, want missing link failure at step #2", err)
+	}
+
+	s = Scenario{Name: "missing", Steps: []Step{
+		{Method: "GET", Path: "/nope", Request: Request{Want: Response{Status: 200}}},
+		{Follow: "next"},
+	}}
+	if err := rn.Scenario(s); err == nil || !strings.Contains(err.Error(), "step #1") {
+		t.Errorf("got err %v, want failure at step #1", err)
+	}
+}
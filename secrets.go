@@ -0,0 +1,149 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Redacted is printed in place of the redacted values.
+const Redacted = "[REDACTED]"
+
+// SecretProvider returns the value of the named secret, e.g. an API token.
+// Any function of this type can serve as a provider, e.g. one that reads
+// from a vault.
+type SecretProvider func(name string) (string, error)
+
+// Secrets is the provider of the values returned by Secret and by the
+// template function secret, and the default provider of a new Runner, see
+// Config.Secrets. It defaults to the environment.
+var Secrets SecretProvider = EnvSecrets("")
+
+// EnvSecrets returns a SecretProvider that reads the secrets from the
+// environment variables named by the prefix followed by the secret's name.
+func EnvSecrets(prefix string) SecretProvider {
+	return func(name string) (string, error) {
+		v, ok := os.LookupEnv(prefix + name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", prefix+name)
+		}
+		return v, nil
+	}
+}
+
+// FileSecrets returns a SecretProvider that reads every secret from the file
+// of the same name in the specified directory, e.g. the secrets mounted into
+// a container. A trailing newline is trimmed from the value.
+func FileSecrets(dir string) SecretProvider {
+	return func(name string) (string, error) {
+		if name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+			return "", fmt.Errorf("invalid secret name %q", name)
+		}
+		b, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(b), "\r\n"), nil
+	}
+}
+
+// secrets holds the secret values handed out so far, they're redacted from
+// the failure messages. The replacer is rebuilt lazily, by redact, after a
+// value is added.
+var secrets = struct {
+	sync.RWMutex
+	values map[string]bool
+	r      *strings.Replacer
+}{values: make(map[string]bool)}
+
+// Secret returns the value of the named secret from Secrets. The value is
+// redacted from every failure message and report from then on.
+func Secret(name string) (string, error) {
+	return globalRunner().Secret(name)
+}
+
+// Secret is like the package's Secret but it gets the value from the
+// Runner's Secrets.
+func (rn *Runner) Secret(name string) (string, error) {
+	if rn.Secrets == nil {
+		return "", fmt.Errorf("hit: no secret provider for secret %q", name)
+	}
+	v, err := rn.Secrets(name)
+	if err != nil {
+		return "", fmt.Errorf("hit: failed getting secret %q. %v", name, err)
+	}
+	if v == "" {
+		return "", fmt.Errorf("hit: secret %q is empty", name)
+	}
+	addSecret(v)
+	return v, nil
+}
+
+// addSecret registers the value for redaction.
+func addSecret(v string) {
+	secrets.Lock()
+	defer secrets.Unlock()
+	if secrets.values[v] {
+		return
+	}
+	secrets.values[v] = true
+	secrets.r = nil
+}
+
+// secretsReplacer returns the replacer of the secret values, rebuilding it
+// if a value was added since it was last built.
+func secretsReplacer() *strings.Replacer {
+	secrets.RLock()
+	r, n := secrets.r, len(secrets.values)
+	secrets.RUnlock()
+	if r != nil || n == 0 {
+		return r
+	}
+
+	secrets.Lock()
+	defer secrets.Unlock()
+	if secrets.r != nil {
+		return secrets.r
+	}
+	// the longer values first, so that a value containing another one
+	// is redacted in whole
+	vv := make([]string, 0, len(secrets.values))
+	for v := range secrets.values {
+		vv = append(vv, v)
+	}
+	sort.Slice(vv, func(i, j int) bool { return len(vv[i]) > len(vv[j]) })
+	var oldnew []string
+	for _, v := range vv {
+		oldnew = append(oldnew, v, Redacted)
+	}
+	secrets.r = strings.NewReplacer(oldnew...)
+	return secrets.r
+}
+
+// redact returns s with the secret values, and the matches of the
+// patterns, replaced by Redacted.
+func (rd Redaction) redact(s string) string {
+	if r := secretsReplacer(); r != nil {
+		s = r.Replace(s)
+	}
+	return rd.redactPatterns(s)
+}
+
+// redactErr returns err with the secret values, and the matches of the
+// patterns, in its message replaced by Redacted.
+func (rd Redaction) redactErr(err error) error {
+	if err == nil {
+		return nil
+	}
+	if s := rd.redact(err.Error()); s != err.Error() {
+		return &messageError{s, err}
+	}
+	return err
+}
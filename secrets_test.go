@@ -0,0 +1,96 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSecretProviders(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "token"), []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("HIT_SECRET_token", "from-env")
+	defer os.Unsetenv("HIT_SECRET_token")
+
+	tests := []struct {
+		p    SecretProvider
+		name string
+		want string
+		err  bool
+	}{
+		{EnvSecrets("HIT_SECRET_"), "token", "from-env", false},
+		{EnvSecrets("HIT_SECRET_"), "missing", "", true},
+		{FileSecrets(dir), "token", "from-file", false},
+		{FileSecrets(dir), "missing", "", true},
+		{FileSecrets(dir), "../token", "", true},
+		{func(string) (string, error) { return "cb", nil }, "any", "cb", false},
+		{func(string) (string, error) { return "", errors.New("vault sealed") }, "any", "", true},
+		{func(string) (string, error) { return "", nil }, "empty", "", true},
+	}
+	defer func(p SecretProvider) { Secrets = p }(Secrets)
+	for i, tt := range tests {
+		Secrets = tt.p
+		got, err := Secret(tt.name)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("#%d: got %q, %v, want %q, error %t", i, got, err, tt.want, tt.err)
+		}
+	}
+	// a Runner has a provider of its own
+	Secrets = nil
+	rn := NewRunner()
+	rn.Secrets = func(name string) (string, error) { return "rn-" + name, nil }
+	if got, err := rn.Secret("x"); err != nil || got != "rn-x" {
+		t.Errorf("Runner: got %q, %v, want %q", got, err, "rn-x")
+	}
+	if _, err := Secret("x"); err == nil {
+		t.Error("package: want error without a provider, got nil")
+	}
+}
+
+func TestSecretRedaction(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"echo":"` + r.Header.Get("X-Api-Key") + `"}`))
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+	rn.Secrets = func(name string) (string, error) { return "s3cr3t-" + name, nil }
+
+	key, err := rn.Secret("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests := []Request{
+		// the secret is printed as part of the header and the body
+		{Header: Header{"X-Api-Key": {key}}, Want: Response{Status: 404, Body: JSONBody{"echo": ""}}},
+		{Template: true, Header: Header{"X-Api-Key": {`{{secret "tmpl"}}`}}, Want: Response{Status: 404, Body: JSONBody{"echo": ""}}},
+	}
+	for i, r := range requests {
+		err := rn.Execute(r, "GET", "/")
+		if err == nil {
+			t.Fatalf("#%d: want error, got nil", i)
+		}
+		if strings.Contains(err.Error(), "s3cr3t") || !strings.Contains(err.Error(), Redacted) {
+			t.Errorf("#%d: error %q leaks the secret", i, err)
+		}
+	}
+
+	s := Scenario{Name: "leak", Steps: []Step{{Method: "GET", Path: "/", Request: requests[0]}}}
+	if err := rn.Scenario(s); err == nil || strings.Contains(err.Error(), "s3cr3t") {
+		t.Errorf("scenario: error %v leaks the secret", err)
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SecurityPolicy describes the security headers that responses are expected
+// to have. A zero SecurityPolicy expects nothing.
+type SecurityPolicy struct {
+	// HSTSMaxAge, if greater than 0, requires a Strict-Transport-Security
+	// header with a max-age of at least HSTSMaxAge.
+	HSTSMaxAge time.Duration
+	// HSTSIncludeSubDomains, if set, requires the Strict-Transport-Security
+	// header to have the includeSubDomains directive.
+	HSTSIncludeSubDomains bool
+
+	// NoSniff, if set, requires the "X-Content-Type-Options: nosniff" header.
+	NoSniff bool
+
+	// FrameOptions, if not empty, lists the allowed values of the
+	// X-Frame-Options header. A Content-Security-Policy with the
+	// frame-ancestors directive is accepted in its place.
+	FrameOptions []string
+
+	// CSP, if set, requires a Content-Security-Policy header that has
+	// all of the CSPDirectives.
+	CSP           bool
+	CSPDirectives []string
+
+	// ReferrerPolicy, if not empty, lists the allowed values of the
+	// Referrer-Policy header.
+	ReferrerPolicy []string
+}
+
+// DefaultSecurityPolicy is a reasonable SecurityPolicy for APIs served
+// over HTTPS.
+var DefaultSecurityPolicy = SecurityPolicy{
+	HSTSMaxAge:     180 * 24 * time.Hour,
+	NoSniff:        true,
+	FrameOptions:   []string{"DENY", "SAMEORIGIN"},
+	CSP:            true,
+	ReferrerPolicy: []string{"no-referrer", "same-origin", "strict-origin", "strict-origin-when-cross-origin"},
+}
+
+// Audit checks the specified http.Header against the receiver returning an
+// error listing all of the violations, if any.
+func (p *SecurityPolicy) Audit(h http.Header) error {
+	var msg string
+	violation := func(key, got, want string) {
+		msg += fmt.Sprintf("Header[%q] got = %s%q%s, want = %s%s%s\n",
+			key, RedColor, got, StopColor, RedColor, want, StopColor)
+	}
+
+	if p.HSTSMaxAge > 0 || p.HSTSIncludeSubDomains {
+		v := h.Get("Strict-Transport-Security")
+		dirs := parseDirectives(v, ";")
+		if p.HSTSMaxAge > 0 {
+			n, err := strconv.ParseInt(strings.Trim(dirs["max-age"], `"`), 10, 64)
+			if err != nil || time.Duration(n)*time.Second < p.HSTSMaxAge {
+				violation("Strict-Transport-Security", v, fmt.Sprintf("max-age of at least %d", int64(p.HSTSMaxAge/time.Second)))
+			}
+		}
+		if _, ok := dirs["includesubdomains"]; p.HSTSIncludeSubDomains && !ok {
+			violation("Strict-Transport-Security", v, "includeSubDomains")
+		}
+	}
+
+	if p.NoSniff {
+		if v := h.Get("X-Content-Type-Options"); !strings.EqualFold(strings.TrimSpace(v), "nosniff") {
+			violation("X-Content-Type-Options", v, `"nosniff"`)
+		}
+	}
+
+	csp := h.Get("Content-Security-Policy")
+	cspDirs := parseDirectives(csp, ";")
+	if len(p.FrameOptions) > 0 {
+		v := h.Get("X-Frame-Options")
+		if _, ok := cspDirs["frame-ancestors"]; !ok && !containsFold(p.FrameOptions, strings.TrimSpace(v)) {
+			violation("X-Frame-Options", v, fmt.Sprintf("one of %q", p.FrameOptions))
+		}
+	}
+	if p.CSP {
+		if csp == "" {
+			violation("Content-Security-Policy", csp, "a policy")
+		}
+		for _, d := range p.CSPDirectives {
+			if _, ok := cspDirs[strings.ToLower(d)]; !ok && csp != "" {
+				violation("Content-Security-Policy", csp, fmt.Sprintf("the %s directive", d))
+			}
+		}
+	}
+
+	if len(p.ReferrerPolicy) > 0 {
+		// The last recognized value of a comma separated list is the one
+		// browsers use.
+		v := h.Get("Referrer-Policy")
+		vals := splitHeaderValues(h["Referrer-Policy"])
+		if len(vals) == 0 || !containsFold(p.ReferrerPolicy, vals[len(vals)-1]) {
+			violation("Referrer-Policy", v, fmt.Sprintf("one of %q", p.ReferrerPolicy))
+		}
+	}
+
+	if msg != "" {
+		return fmt.Errorf("%s", msg)
+	}
+	return nil
+}
+
+// parseDirectives parses a sep separated list of directives, each of which
+// is a name optionally followed by a value, into a map keyed by lower case
+// directive names.
+func parseDirectives(v, sep string) map[string]string {
+	dirs := make(map[string]string)
+	for _, d := range strings.Split(v, sep) {
+		d = strings.TrimSpace(d)
+		if d == "" {
+			continue
+		}
+		name, val := d, ""
+		if i := strings.IndexAny(d, "= "); i >= 0 {
+			name, val = d[:i], strings.TrimSpace(d[i+1:])
+		}
+		dirs[strings.ToLower(name)] = val
+	}
+	return dirs
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
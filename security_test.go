@@ -0,0 +1,80 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSecurityPolicyAudit(t *testing.T) {
+	secure := http.Header{
+		"Strict-Transport-Security": {"max-age=31536000; includeSubDomains"},
+		"X-Content-Type-Options":    {"nosniff"},
+		"X-Frame-Options":           {"DENY"},
+		"Content-Security-Policy":   {"default-src 'none'; frame-ancestors 'none'"},
+		"Referrer-Policy":           {"no-referrer"},
+	}
+	with := func(k, v string) http.Header {
+		h := http.Header{}
+		for kk, vv := range secure {
+			h[kk] = vv
+		}
+		if v == "" {
+			delete(h, k)
+		} else {
+			h.Set(k, v)
+		}
+		return h
+	}
+
+	tests := []struct {
+		policy SecurityPolicy
+		header http.Header
+		want   string
+	}{
+		{DefaultSecurityPolicy, secure, ""},
+		{SecurityPolicy{}, http.Header{}, ""},
+		{DefaultSecurityPolicy, with("Strict-Transport-Security", ""), "max-age of at least"},
+		{DefaultSecurityPolicy, with("Strict-Transport-Security", "max-age=60"), "max-age of at least"},
+		{SecurityPolicy{HSTSIncludeSubDomains: true}, with("Strict-Transport-Security", "max-age=60"), "includeSubDomains"},
+		{DefaultSecurityPolicy, with("X-Content-Type-Options", ""), `"nosniff"`},
+		{DefaultSecurityPolicy, with("X-Frame-Options", "ALLOW-FROM x"), ""}, // frame-ancestors
+		{SecurityPolicy{FrameOptions: []string{"DENY"}}, http.Header{"X-Frame-Options": {"sameorigin"}}, "one of"},
+		{SecurityPolicy{FrameOptions: []string{"DENY"}}, http.Header{"X-Frame-Options": {"deny"}}, ""},
+		{DefaultSecurityPolicy, with("Content-Security-Policy", ""), "a policy"},
+		{SecurityPolicy{CSP: true, CSPDirectives: []string{"default-src", "script-src"}}, secure, "the script-src directive"},
+		{DefaultSecurityPolicy, with("Referrer-Policy", "unsafe-url"), "one of"},
+		{DefaultSecurityPolicy, with("Referrer-Policy", "unsafe-url, strict-origin"), ""},
+	}
+	for i, tt := range tests {
+		err := tt.policy.Audit(tt.header)
+		if tt.want == "" && err != nil {
+			t.Errorf("#%d: got err %v, want <nil>", i, err)
+		} else if tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+			t.Errorf("#%d: got err %v, want err containing %q", i, err, tt.want)
+		}
+	}
+}
+
+func TestRequestSecurity(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	r := Request{Security: &SecurityPolicy{NoSniff: true}, Want: Response{Status: 200}}
+	if err := rn.Execute(r, "GET", "/"); err != nil {
+		t.Error(err)
+	}
+	r.Security = &SecurityPolicy{NoSniff: true, FrameOptions: []string{"DENY"}}
+	err := rn.Execute(r, "GET", "/")
+	if err == nil || !strings.Contains(err.Error(), "X-Frame-Options") || !strings.Contains(err.Error(), "GET /") {
+		t.Errorf("got err %v, want X-Frame-Options violation for GET /", err)
+	}
+}
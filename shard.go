@@ -0,0 +1,76 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"fmt"
+	"hash/fnv"
+	"log"
+	"os"
+	"sync"
+)
+
+// ShardEnv is the environment variable that, if set, selects the shard of
+// this process in the form "index/total", e.g. "0/4", see Shard.
+const ShardEnv = "HIT_SHARD"
+
+// shard is the shard of this process, a total of 0 runs everything.
+var shard = struct {
+	sync.Mutex
+	index, total int
+}{}
+
+func init() {
+	if v := os.Getenv(ShardEnv); v != "" {
+		var index, total int
+		if _, err := fmt.Sscanf(v, "%d/%d", &index, &total); err != nil {
+			log.Printf("Warning: ignoring %s=%q, want index/total. %v", ShardEnv, v, err)
+			return
+		}
+		if err := Shard(index, total); err != nil {
+			log.Printf("Warning: ignoring %s=%q. %v", ShardEnv, v, err)
+		}
+	}
+}
+
+// Shard makes this process execute only its share of the Requests of the
+// Hits and of the Scenarios, so that a suite can be split over total CI jobs,
+// each with its own index, counting from 0. The Requests and the Scenarios
+// are assigned to the shards by a hash of their name, or of their position in
+// the table if they have none, so the partition is the same in every job and
+// doesn't depend on the order of the tests. A total of
+// 0 or 1 executes everything.
+func Shard(index, total int) error {
+	if total < 0 || index < 0 || (total > 0 && index >= total) {
+		return fmt.Errorf("hit: invalid shard %d of %d", index, total)
+	}
+	shard.Lock()
+	defer shard.Unlock()
+	shard.index, shard.total = index, total
+	return nil
+}
+
+// shardKey returns the key by which the Request is assigned to a shard, its
+// Name or, if it has none, its method and path along with its index among the
+// Requests of the method, so that the rows of a table are spread over the
+// shards rather than all assigned to the shard of their endpoint.
+func (r Request) shardKey(method, path string, index int) string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return fmt.Sprintf("%s %s #%d", method, path, index)
+}
+
+// inShard reports whether the named Request or Scenario belongs to the shard
+// of this process.
+func inShard(name string) bool {
+	shard.Lock()
+	defer shard.Unlock()
+	if shard.total <= 1 {
+		return true
+	}
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return int(h.Sum32()%uint32(shard.total)) == shard.index
+}
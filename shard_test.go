@@ -0,0 +1,110 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestShard(t *testing.T) {
+	defer Shard(shard.index, shard.total)
+
+	for _, s := range [][2]int{{-1, 2}, {2, 2}, {0, -1}} {
+		if err := Shard(s[0], s[1]); err == nil {
+			t.Errorf("Shard(%d, %d): want error, got nil", s[0], s[1])
+		}
+	}
+
+	// every name belongs to exactly one of the shards
+	const total = 3
+	var names []string
+	for i := 0; i < 100; i++ {
+		names = append(names, fmt.Sprintf("GET /items/%d", i))
+	}
+	owners := make(map[string]int)
+	for index := 0; index < total; index++ {
+		Shard(index, total)
+		n := 0
+		for _, name := range names {
+			if inShard(name) {
+				owners[name]++
+				n++
+			}
+		}
+		if n == 0 || n == len(names) {
+			t.Errorf("shard %d got %d of %d names, want a share", index, n, len(names))
+		}
+	}
+	for _, name := range names {
+		if owners[name] != 1 {
+			t.Errorf("%q belongs to %d shards, want 1", name, owners[name])
+		}
+	}
+}
+
+func TestShardHit(t *testing.T) {
+	defer Shard(shard.index, shard.total)
+
+	var mu sync.Mutex
+	got := make(map[string]int)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		got[r.URL.Path]++
+		mu.Unlock()
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	var rr []Request
+	for i := 0; i < 20; i++ {
+		rr = append(rr, Request{Name: fmt.Sprint(i), Want: Response{Status: 200}})
+	}
+	for index := 0; index < 2; index++ {
+		Shard(index, 2)
+		rn.Test(t, Hit{Path: "/", Requests: Requests{"GET": rr}})
+	}
+	if got["/"] != len(rr) {
+		t.Errorf("got %d requests over all shards, want %d", got["/"], len(rr))
+	}
+}
+
+func TestShardUnnamedRows(t *testing.T) {
+	defer Shard(shard.index, shard.total)
+
+	var mu sync.Mutex
+	got := make(map[string]int)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		got[r.Header.Get("Row")]++
+		mu.Unlock()
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	// the rows of one endpoint without names
+	var rr []Request
+	for i := 0; i < 20; i++ {
+		rr = append(rr, Request{Header: Header{"Row": {fmt.Sprint(i)}}, Want: Response{Status: 200}})
+	}
+	const total = 3
+	for index := 0; index < total; index++ {
+		Shard(index, total)
+		before := len(got)
+		rn.Test(t, Hit{Path: "/items", Requests: Requests{"GET": rr}})
+		if n := len(got) - before; n == 0 || n == len(rr) {
+			t.Errorf("shard %d got %d of %d rows, want a share", index, n, len(rr))
+		}
+	}
+	for i := range rr {
+		if n := got[fmt.Sprint(i)]; n != 1 {
+			t.Errorf("row %d executed %d times over all shards, want 1", i, n)
+		}
+	}
+}
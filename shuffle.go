@@ -0,0 +1,72 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"log"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ShuffleEnv is the environment variable that, if set, initializes Shuffle,
+// "on" picks a seed from the clock, a number is used as the seed.
+const ShuffleEnv = "HIT_SHUFFLE"
+
+// Shuffle, if not 0, is the seed with which the Requests of every Hit are
+// shuffled, to flush out hidden dependencies on their order. The order of a
+// Hit's Requests depends only on the seed and on the Requests themselves, so
+// a failing order is reproduced by setting the same seed. The Steps of a
+// Scenario are never shuffled.
+var Shuffle int64
+
+func init() {
+	switch v := os.Getenv(ShuffleEnv); v {
+	case "", "off":
+	case "on":
+		Shuffle = time.Now().UnixNano()
+		log.Printf("hit: shuffling with seed %d, set %s=%d to reproduce.", Shuffle, ShuffleEnv, Shuffle)
+	default:
+		seed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			log.Printf("Warning: ignoring %s=%q, want on, off or a seed. %v", ShuffleEnv, v, err)
+			return
+		}
+		Shuffle = seed
+	}
+}
+
+// methodRequest is a Request along with its method and its index among the
+// Requests of the method.
+type methodRequest struct {
+	method string
+	index  int
+	r      Request
+}
+
+// order returns the Requests in the order of their execution. Without a
+// Shuffle seed the Requests of a method keep their order.
+func (rn *Runner) order(rs Requests) []methodRequest {
+	var mm []string
+	for m := range rs {
+		mm = append(mm, m)
+	}
+	if rn.Shuffle != 0 {
+		// start from a fixed order so that the seed alone decides
+		sort.Strings(mm)
+	}
+	var rr []methodRequest
+	for _, m := range mm {
+		for i, r := range rs[m] {
+			rr = append(rr, methodRequest{m, i, r})
+		}
+	}
+	if rn.Shuffle != 0 {
+		rnd := rand.New(rand.NewSource(rn.Shuffle))
+		rnd.Shuffle(len(rr), func(i, j int) { rr[i], rr[j] = rr[j], rr[i] })
+	}
+	return rr
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestShuffleOrder(t *testing.T) {
+	rs := Requests{}
+	for _, m := range []string{"GET", "POST", "DELETE"} {
+		for _, name := range []string{"a", "b", "c", "d"} {
+			rs[m] = append(rs[m], Request{Name: m + " " + name})
+		}
+	}
+	names := func(seed int64) []string {
+		rn := NewRunner()
+		rn.Shuffle = seed
+		var nn []string
+		for _, mr := range rn.order(rs) {
+			nn = append(nn, mr.r.Name)
+		}
+		return nn
+	}
+
+	// without a seed the requests of a method keep their order
+	pos := make(map[string]int)
+	for i, n := range names(0) {
+		pos[n] = i
+	}
+	for m, rr := range rs {
+		for i := 1; i < len(rr); i++ {
+			if pos[rr[i].Name] < pos[rr[i-1].Name] {
+				t.Errorf("%s: %q executed before %q", m, rr[i].Name, rr[i-1].Name)
+			}
+		}
+	}
+
+	a, b := names(42), names(42)
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("same seed got different orders %v and %v", a, b)
+	}
+	if len(a) != 12 {
+		t.Errorf("got %d requests, want 12", len(a))
+	}
+	if c := names(43); reflect.DeepEqual(a, c) {
+		t.Errorf("different seeds got the same order %v", a)
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Shutdown checks that a server shuts down gracefully, i.e. that the requests
+// in flight when the shutdown starts complete while the new ones are refused,
+// e.g. as expected of a pod that Kubernetes takes out of service.
+type Shutdown struct {
+	// InFlight are sent at once before the shutdown, they should take
+	// longer than Delay to be served, e.g. slow or long-polling requests.
+	// Each one must get its expected response.
+	InFlight []Step
+	// Delay is the time between sending the InFlight requests and
+	// triggering the shutdown, it defaults to 100ms.
+	Delay time.Duration
+
+	// Trigger starts the shutdown, e.g. by signaling the server's process
+	// or by calling its http.Server's Shutdown. It may block until the
+	// shutdown completes.
+	Trigger func() error
+
+	// After are sent once Settle has passed since the shutdown started.
+	// A Request without an expected Status or TransportError must fail
+	// with a ConnRefused transport error, set a Status to expect e.g. 503
+	// responses of a server that keeps listening while it drains.
+	After  []Step
+	Settle time.Duration
+}
+
+// Test executes the Shutdown.
+func (s Shutdown) Test(t *testing.T) {
+	if err := s.Execute(); err != nil {
+		t.Error(err)
+	}
+}
+
+// Execute executes the Shutdown and returns an error describing all of its
+// failures, if any.
+func (s Shutdown) Execute() error {
+	return globalRunner().Shutdown(s)
+}
+
+// Shutdown executes the Shutdown against the Runner's target and returns an
+// error describing all of its failures, if any.
+func (rn *Runner) Shutdown(s Shutdown) error {
+	if s.Trigger == nil {
+		return fmt.Errorf("hit: Shutdown has no Trigger")
+	}
+	delay := s.Delay
+	if delay <= 0 {
+		delay = 100 * time.Millisecond
+	}
+
+	var wg sync.WaitGroup
+	inFlight := make([]error, len(s.InFlight))
+	for i, st := range s.InFlight {
+		wg.Add(1)
+		go func(i int, st Step) {
+			defer wg.Done()
+			inFlight[i] = rn.Execute(st.Request, st.Method, st.Path)
+		}(i, st)
+	}
+
+	time.Sleep(delay)
+	triggered := make(chan error, 1)
+	go func() { triggered <- s.Trigger() }()
+	time.Sleep(s.Settle)
+
+	after := make([]error, len(s.After))
+	for i, st := range s.After {
+		r := st.Request
+		if r.TransportError == 0 && r.Want.Status == 0 {
+			r.TransportError = ConnRefused
+		}
+		after[i] = rn.Execute(r, st.Method, st.Path)
+	}
+	wg.Wait()
+
+	var msg string
+	for i, err := range inFlight {
+		if err != nil {
+			msg += fmt.Sprintf("%sIn-flight request #%d%s %s %s:\n%v\n", PurpleColor, i+1, StopColor, s.InFlight[i].Method, s.InFlight[i].Path, err)
+		}
+	}
+	for i, err := range after {
+		if err != nil {
+			msg += fmt.Sprintf("%sRequest #%d after the shutdown%s %s %s:\n%v\n", PurpleColor, i+1, StopColor, s.After[i].Method, s.After[i].Path, err)
+		}
+	}
+	if err := <-triggered; err != nil {
+		msg += fmt.Sprintf("hit: Shutdown trigger failed. %v\n", err)
+	}
+	if msg != "" {
+		return fmt.Errorf("%s", msg)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShutdown(t *testing.T) {
+	rn := NewRunner()
+	serve := func() *http.Server {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(300 * time.Millisecond)
+			w.Write([]byte("done"))
+		})}
+		go srv.Serve(l)
+		rn.Addr = l.Addr().String()
+		return srv
+	}
+	steps := []Step{
+		{Method: "GET", Path: "/slow/1", Request: Request{Want: Response{Status: 200, Body: RawBody("done")}}},
+		{Method: "GET", Path: "/slow/2", Request: Request{Want: Response{Status: 200}}},
+	}
+
+	srv := serve()
+	err := rn.Shutdown(Shutdown{
+		InFlight: steps,
+		Trigger:  func() error { return srv.Shutdown(context.Background()) },
+		After:    []Step{{Method: "GET", Path: "/new"}},
+		Settle:   50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Errorf("graceful: got err %v, want <nil>", err)
+	}
+
+	srv = serve()
+	err = rn.Shutdown(Shutdown{
+		InFlight: steps,
+		Trigger:  srv.Close,
+		After:    []Step{{Method: "GET", Path: "/new", Request: Request{Want: Response{Status: 503}}}},
+	})
+	for _, want := range []string{"In-flight request #1", "In-flight request #2", "Request #1 after the shutdown"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("abrupt: got err %v, want it to contain %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Signer is the interface implemented by types that can sign an HTTP request
+// before it's sent. The body argument holds the request's raw body, it's nil
+// if the request has no body.
+type Signer interface {
+	Sign(r *http.Request, body []byte) error
+}
+
+// runnerSigner is implemented by the Signers that send requests of their own,
+// e.g. to obtain a token, they're passed the Runner executing the signed
+// request so that their requests use its target and client.
+type runnerSigner interface {
+	signWith(rn *Runner, r *http.Request, body []byte) error
+}
+
+// HMAC is a Signer that computes a keyed-hash message authentication code over
+// the request's method, path and body and sets its hex encoded value as the
+// value of the request's header named by Header.
+//
+// The signed message is the method, the request URI (path and query) and the
+// body, joined by newlines.
+type HMAC struct {
+	// The secret key.
+	Key []byte
+	// The name of the header to which the signature will be set,
+	// defaults to "X-Signature".
+	Header string
+	// The hash function used to compute the signature, defaults to sha256.New.
+	Hash func() hash.Hash
+	// Prefix, if set, is prepended to the signature, e.g. "sha256=".
+	Prefix string
+}
+
+// Sign implements the Signer interface.
+func (s HMAC) Sign(r *http.Request, body []byte) error {
+	fn := s.Hash
+	if fn == nil {
+		fn = sha256.New
+	}
+	name := s.Header
+	if name == "" {
+		name = "X-Signature"
+	}
+
+	mac := hmac.New(fn, s.Key)
+	mac.Write([]byte(r.Method + "\n" + r.URL.RequestURI() + "\n"))
+	mac.Write(body)
+	r.Header.Set(name, s.Prefix+hex.EncodeToString(mac.Sum(nil)))
+	return nil
+}
+
+// AWSV4 is a Signer that signs requests using the AWS Signature Version 4
+// signing process, it can be used to test endpoints authenticated by AWS IAM,
+// e.g. API Gateway endpoints.
+type AWSV4 struct {
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+	Region       string
+	Service      string
+
+	// Time, if set, is used as the signing time instead of the current time.
+	Time time.Time
+}
+
+// Sign implements the Signer interface.
+func (s AWSV4) Sign(r *http.Request, body []byte) error {
+	if s.AccessKey == "" || s.SecretKey == "" {
+		return fmt.Errorf("AWSV4 requires both AccessKey and SecretKey")
+	}
+	t := s.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	t = t.UTC()
+	amzdate, date := t.Format("20060102T150405Z"), t.Format("20060102")
+	payload := sha256hex(body)
+
+	r.Header.Set("X-Amz-Date", amzdate)
+	if s.SessionToken != "" {
+		r.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+	if s.Service == "s3" {
+		r.Header.Set("X-Amz-Content-Sha256", payload)
+	}
+
+	// canonical headers
+	host := r.Host
+	if host == "" {
+		host = r.URL.Host
+	}
+	hdr := map[string]string{"host": host}
+	for k, vv := range r.Header {
+		lk := strings.ToLower(k)
+		if strings.HasPrefix(lk, "x-amz-") || lk == "content-type" || lk == "content-md5" {
+			vals := make([]string, len(vv))
+			for i, v := range vv {
+				vals[i] = strings.Join(strings.Fields(v), " ")
+			}
+			hdr[lk] = strings.Join(vals, ",")
+		}
+	}
+	names := make([]string, 0, len(hdr))
+	for k := range hdr {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonHeader string
+	for _, k := range names {
+		canonHeader += k + ":" + hdr[k] + "\n"
+	}
+	signed := strings.Join(names, ";")
+
+	// canonical path, all services but s3 require double encoding
+	path := r.URL.Path
+	if path == "" {
+		path = "/"
+	}
+	path = awsEscape(path, false)
+	if s.Service != "s3" {
+		path = awsEscape(path, false)
+	}
+
+	// canonical query
+	var query []string
+	for k, vv := range r.URL.Query() {
+		for _, v := range vv {
+			query = append(query, awsEscape(k, true)+"="+awsEscape(v, true))
+		}
+	}
+	sort.Strings(query)
+
+	canon := strings.Join([]string{
+		r.Method,
+		path,
+		strings.Join(query, "&"),
+		canonHeader,
+		signed,
+		payload,
+	}, "\n")
+
+	scope := date + "/" + s.Region + "/" + s.Service + "/aws4_request"
+	toSign := "AWS4-HMAC-SHA256\n" + amzdate + "\n" + scope + "\n" + sha256hex([]byte(canon))
+
+	key := []byte("AWS4" + s.SecretKey)
+	for _, v := range []string{date, s.Region, s.Service, "aws4_request"} {
+		key = hmacsha256(key, v)
+	}
+	sig := hex.EncodeToString(hmacsha256(key, toSign))
+
+	r.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKey, scope, signed, sig))
+	return nil
+}
+
+func sha256hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacsha256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// awsEscape percent-encodes every byte of s except the unreserved characters
+// as required by the AWS Signature Version 4 process. If slash is false the
+// "/" character is left as is.
+func awsEscape(s string, slash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !slash) {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"crypto/sha1"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHMACSign(t *testing.T) {
+	tests := []struct {
+		s    HMAC
+		name string
+		want string
+	}{
+		{HMAC{Key: []byte("secret")}, "X-Signature",
+			"c1fdc7146fe5b2c4998d87d739bc2e38ab948332f609555eb646b8b61b827aea"},
+		{HMAC{Key: []byte("secret"), Header: "X-Hub-Signature", Hash: sha1.New, Prefix: "sha1="}, "X-Hub-Signature",
+			"sha1=b910d7f05d1fc513b76517d981932361bf8eb0b9"},
+	}
+	for i, tt := range tests {
+		r, _ := http.NewRequest("POST", "http://localhost/foo?a=b", strings.NewReader("hello"))
+		if err := tt.s.Sign(r, []byte("hello")); err != nil {
+			t.Errorf("#%d: got err %v, want <nil>", i, err)
+		}
+		if got := r.Header.Get(tt.name); got != tt.want {
+			t.Errorf("#%d: got %q, want %q", i, got, tt.want)
+		}
+	}
+}
+
+func TestAWSV4Sign(t *testing.T) {
+	// test vectors from the AWS Signature Version 4 test suite
+	s := AWSV4{
+		AccessKey: "AKIDEXAMPLE",
+		SecretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		Region:    "us-east-1",
+		Service:   "service",
+		Time:      time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC),
+	}
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"http://example.amazonaws.com/", "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+			"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		{"http://example.amazonaws.com/?Param2=value2&Param1=value1", "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+			"SignedHeaders=host;x-amz-date, Signature=b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500"},
+	}
+	for i, tt := range tests {
+		r, _ := http.NewRequest("GET", tt.url, nil)
+		if err := s.Sign(r, nil); err != nil {
+			t.Errorf("#%d: got err %v, want <nil>", i, err)
+		}
+		if got := r.Header.Get("Authorization"); got != tt.want {
+			t.Errorf("#%d: got %q\nwant %q", i, got, tt.want)
+		}
+	}
+}
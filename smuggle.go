@@ -0,0 +1,64 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SmugglingProbes returns raw requests with ambiguous message framing, e.g.
+// conflicting Content-Length and Transfer-Encoding headers, that are the
+// basis of request smuggling attacks. Each of them expects the server to
+// reject the request. The specified host is sent as the Host header.
+func SmugglingProbes(host string) []RawRequest {
+	// smuggled is appended to the probes' bodies, a server that frames
+	// the request in one of the ambiguous ways would respond to it too.
+	smuggled := "GET /smuggled HTTP/1.1\r\nHost: " + host + "\r\n\r\n"
+	probe := func(name, header, body string) RawRequest {
+		return RawRequest{
+			Name: name,
+			Data: "POST / HTTP/1.1\r\nHost: " + host + "\r\n" + header + "\r\n" + body,
+			Want: RawResponse{Reject: true},
+		}
+	}
+	chunked := "0\r\n\r\n" + smuggled
+	probes := []RawRequest{
+		probe("CL.TE", fmt.Sprintf("Content-Length: %d\r\nTransfer-Encoding: chunked\r\n", len(chunked)), chunked),
+		probe("TE.CL", "Transfer-Encoding: chunked\r\nContent-Length: 4\r\n", "5c\r\n"+strings.Repeat("x", 0x5c)+"\r\n0\r\n\r\n"),
+		probe("CL.CL", "Content-Length: 0\r\nContent-Length: 5\r\n", "12345"+smuggled),
+		probe("TE.TE duplicate", "Transfer-Encoding: chunked\r\nTransfer-Encoding: identity\r\n", chunked),
+		probe("TE obfuscated value", "Transfer-Encoding: xchunked\r\n", chunked),
+		probe("TE space before colon", "Transfer-Encoding : chunked\r\n", chunked),
+		probe("TE line folding", "Transfer-Encoding:\r\n chunked\r\n", chunked),
+		probe("CL signed", "Content-Length: +5\r\n", "12345"+smuggled),
+		probe("CL list", "Content-Length: 5, 5\r\n", "12345"+smuggled),
+		probe("chunk size hex prefix", "Transfer-Encoding: chunked\r\n", "0x5\r\n12345\r\n0\r\n\r\n"+smuggled),
+	}
+	// chunked transfer encoding doesn't exist in HTTP/1.0
+	http10 := probe("HTTP/1.0 chunked", "Transfer-Encoding: chunked\r\n", chunked)
+	http10.Data = strings.Replace(http10.Data, "HTTP/1.1", "HTTP/1.0", 1)
+	return append(probes, http10)
+}
+
+// CheckSmuggling executes all of the SmugglingProbes against Addr and
+// returns an error describing every probe that wasn't rejected.
+func CheckSmuggling(host string) error {
+	return globalRunner().CheckSmuggling(host)
+}
+
+// CheckSmuggling executes all of the SmugglingProbes against the Runner's
+// Addr and returns an error describing every probe that wasn't rejected.
+func (rn *Runner) CheckSmuggling(host string) error {
+	var msg string
+	for _, r := range SmugglingProbes(host) {
+		if err := rn.RawRequest(r); err != nil {
+			msg += err.Error()
+		}
+	}
+	if msg != "" {
+		return fmt.Errorf("%s", msg)
+	}
+	return nil
+}
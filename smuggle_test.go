@@ -0,0 +1,34 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSmugglingProbes(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	// net/http resolves a Content-Length and Transfer-Encoding conflict
+	// in favor of the latter instead of rejecting the request, while it
+	// does reject conflicting Content-Length headers.
+	rejected := map[string]bool{"CL.CL": true, "CL signed": true, "TE obfuscated value": true}
+	for _, r := range SmugglingProbes("example.com") {
+		r.Timeout = 200 * time.Millisecond
+		err := rn.RawRequest(r)
+		if r.Name == "CL.TE" && (err == nil || !strings.Contains(err.Error(), "RAW CL.TE")) {
+			t.Errorf("%s: got err %v, want failure", r.Name, err)
+		}
+		if rejected[r.Name] && err != nil {
+			t.Errorf("%s: got err %v, want <nil>", r.Name, err)
+		}
+	}
+}
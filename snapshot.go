@@ -0,0 +1,256 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// SnapshotUpdateEnv is the environment variable that, if set to a non-empty
+// value, initializes UpdateSnapshots.
+const SnapshotUpdateEnv = "HIT_UPDATE_SNAPSHOTS"
+
+var (
+	// SnapshotDir is the directory in which the snapshot files are kept.
+	SnapshotDir = filepath.Join("testdata", "snapshots")
+
+	// UpdateSnapshots, if set, overwrites the snapshot files with the
+	// actual responses instead of comparing the responses to them.
+	UpdateSnapshots = os.Getenv(SnapshotUpdateEnv) != ""
+)
+
+// Snapshot compares a response to the one recorded in a snapshot file. If the
+// file doesn't exist yet the response is recorded and the comparison passes,
+// the file is then meant to be reviewed and committed along with the test.
+type Snapshot struct {
+	// Header lists the response headers that are recorded, the others are
+	// left out since they tend to differ between runs, e.g. Date.
+	Header []string
+
+	// Name, if set, is the name of the snapshot file, without its
+	// extension, in place of the one derived from the request.
+	Name string
+
+	// Scrub is applied, in order, to the response body before it's
+	// recorded or compared, e.g. to replace timestamps and generated ids
+	// that would otherwise churn the snapshot.
+	Scrub []Scrubber
+}
+
+// Scrubber replaces every match of Pattern in a response body with Replace,
+// which can refer to the submatches as in regexp.Regexp.ReplaceAll.
+type Scrubber struct {
+	Pattern *regexp.Regexp
+	Replace string
+}
+
+// usedSnapshots records the snapshot files compared or recorded by this process.
+var usedSnapshots = struct {
+	sync.Mutex
+	files map[string]bool
+}{files: make(map[string]bool)}
+
+// PruneSnapshots removes the files in SnapshotDir that weren't compared or
+// recorded by any Snapshot during this process and returns their paths. It's
+// meant to be called from TestMain after all of the tests have run, pruning
+// after a run filtered by -run would remove the snapshots of the skipped tests.
+func PruneSnapshots() ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(SnapshotDir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("hit: failed listing snapshots. %v", err)
+	}
+	usedSnapshots.Lock()
+	defer usedSnapshots.Unlock()
+	var removed []string
+	for _, f := range files {
+		if usedSnapshots.files[f] {
+			continue
+		}
+		if err := os.Remove(f); err != nil {
+			return removed, fmt.Errorf("hit: failed removing snapshot. %v", err)
+		}
+		removed = append(removed, f)
+	}
+	return removed, nil
+}
+
+// snapshot is the recorded form of a response.
+type snapshot struct {
+	Status int                 `json:"status"`
+	Header map[string][]string `json:"header,omitempty"`
+	// Body holds the decoded JSON body, or the body as a string if
+	// it's not JSON.
+	Body interface{} `json:"body"`
+}
+
+// compare compares the specified response, whose body was already read, to
+// the snapshot file of the request with the specified method and path, or
+// records it if there's none, and returns the failure message, if any.
+func (s *Snapshot) compare(r Request, method, path string, res *http.Response, body []byte) (string, error) {
+	for _, sc := range s.Scrub {
+		body = sc.Pattern.ReplaceAll(body, []byte(sc.Replace))
+	}
+	got, err := s.render(res, body)
+	if err != nil {
+		return "", err
+	}
+	name := s.Name
+	if name == "" {
+		if name, err = r.snapshotName(method, path); err != nil {
+			return "", err
+		}
+	}
+	file := filepath.Join(SnapshotDir, name+".json")
+	usedSnapshots.Lock()
+	usedSnapshots.files[file] = true
+	usedSnapshots.Unlock()
+
+	want, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) || UpdateSnapshots {
+		if err := os.MkdirAll(SnapshotDir, 0755); err != nil {
+			return "", fmt.Errorf("hit: failed creating snapshot directory. %v", err)
+		}
+		if err := ioutil.WriteFile(file, got, 0644); err != nil {
+			return "", fmt.Errorf("hit: failed writing snapshot. %v", err)
+		}
+		return "", nil
+	} else if err != nil {
+		return "", fmt.Errorf("hit: failed reading snapshot. %v", err)
+	}
+	if bytes.Equal(got, want) {
+		return "", nil
+	}
+	return fmt.Sprintf("Snapshot %q differs, set %s to update it:\n%s",
+		file, SnapshotUpdateEnv, lineDiff(string(want), string(got))), nil
+}
+
+// render returns the canonical, indented, JSON encoding of the response's
+// snapshot, object keys are sorted so that it's stable between runs.
+func (s *Snapshot) render(res *http.Response, body []byte) ([]byte, error) {
+	snap := snapshot{Status: res.StatusCode}
+	for _, k := range s.Header {
+		if vv := res.Header[http.CanonicalHeaderKey(k)]; vv != nil {
+			if snap.Header == nil {
+				snap.Header = make(map[string][]string)
+			}
+			snap.Header[http.CanonicalHeaderKey(k)] = vv
+		}
+	}
+	d := json.NewDecoder(bytes.NewReader(body))
+	d.UseNumber()
+	if len(bytes.TrimSpace(body)) == 0 || d.Decode(&snap.Body) != nil || d.More() {
+		snap.Body = string(body)
+	}
+
+	var b bytes.Buffer
+	e := json.NewEncoder(&b)
+	e.SetEscapeHTML(false)
+	e.SetIndent("", "  ")
+	if err := e.Encode(snap); err != nil {
+		return nil, fmt.Errorf("hit: failed encoding snapshot. %v", err)
+	}
+	return b.Bytes(), nil
+}
+
+var unsafeName = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// snapshotName returns the name of the request's snapshot file, without its
+// extension. It's made of the method and the path followed by a hash of the
+// request's header and body which tells apart the requests to the same
+// endpoint.
+func (r Request) snapshotName(method, path string) (string, error) {
+	h := sha1.New()
+	fmt.Fprintf(h, "%v\n", r.Header)
+	if r.Body != nil {
+		body, err := r.Body.Body()
+		if err != nil {
+			return "", fmt.Errorf("hit: failed reading request body. %v", err)
+		}
+		b, err := ioutil.ReadAll(body)
+		if err != nil {
+			return "", fmt.Errorf("hit: failed reading request body. %v", err)
+		}
+		h.Write(b)
+	}
+	name := strings.Trim(unsafeName.ReplaceAllString(method+"_"+path, "_"), "_")
+	return name + "_" + hex.EncodeToString(h.Sum(nil))[:8], nil
+}
+
+// maxDiffCells caps the size of the table used to compute a line diff, larger
+// differences are shown as a whole.
+const maxDiffCells = 1 << 22
+
+// lineDiff returns the lines of want that are missing from got, prefixed with
+// "-", and the lines of got that are missing from want, prefixed with "+".
+func lineDiff(want, got string) string {
+	a, b := strings.Split(want, "\n"), strings.Split(got, "\n")
+
+	// trim the common prefix and suffix
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+	a, b = a[pre:len(a)-suf], b[pre:len(b)-suf]
+
+	var out strings.Builder
+	del := func(i int) { fmt.Fprintf(&out, "%s%4d - %s%s\n", YellowColor, pre+i+1, a[i], StopColor) }
+	add := func(j int) { fmt.Fprintf(&out, "%s%4d + %s%s\n", RedColor, pre+j+1, b[j], StopColor) }
+
+	if (len(a)+1)*(len(b)+1) > maxDiffCells {
+		for i := range a {
+			del(i)
+		}
+		for j := range b {
+			add(j)
+		}
+		return out.String()
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i, j = i+1, j+1
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			del(i)
+			i++
+		default:
+			add(j)
+			j++
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,145 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequestSnapshot(t *testing.T) {
+	body := `{"id":1,"name":"foo"}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Volatile", r.URL.Query().Get("v"))
+		w.Write([]byte(body))
+	}))
+	defer ts.Close()
+
+	defer func(dir string, u bool) { SnapshotDir, UpdateSnapshots = dir, u }(SnapshotDir, UpdateSnapshots)
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+	SnapshotDir = t.TempDir()
+
+	r := Request{Want: Response{Status: 200}, Snapshot: &Snapshot{Header: []string{"content-type"}}}
+
+	// the first run records the snapshot
+	if err := rn.Execute(r, "GET", "/users/1?v=1"); err != nil {
+		t.Fatalf("first run: unexpected error %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(SnapshotDir, "*.json"))
+	if len(files) != 1 {
+		t.Fatalf("got %d snapshot files, want 1", len(files))
+	}
+	b, _ := ioutil.ReadFile(files[0])
+	for _, want := range []string{`"status": 200`, `"Content-Type"`, `"name": "foo"`} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("snapshot %s does not contain %s", b, want)
+		}
+	}
+	if strings.Contains(string(b), "X-Volatile") {
+		t.Errorf("snapshot %s contains an unlisted header", b)
+	}
+
+	// the same response, with the keys in a different order, passes
+	body = `{"name":"foo","id":1}`
+	if err := rn.Execute(r, "GET", "/users/1?v=1"); err != nil {
+		t.Errorf("second run: unexpected error %v", err)
+	}
+
+	// a different response fails with a diff
+	body = `{"id":1,"name":"bar"}`
+	err := rn.Execute(r, "GET", "/users/1?v=1")
+	if err == nil {
+		t.Fatal("changed response: want error, got nil")
+	}
+	for _, want := range []string{`- ` + `    "name": "foo"`, `+ ` + `    "name": "bar"`, SnapshotUpdateEnv} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err, want)
+		}
+	}
+
+	// updating overwrites the snapshot
+	UpdateSnapshots = true
+	if err := rn.Execute(r, "GET", "/users/1?v=1"); err != nil {
+		t.Errorf("update: unexpected error %v", err)
+	}
+	UpdateSnapshots = false
+	if err := rn.Execute(r, "GET", "/users/1?v=1"); err != nil {
+		t.Errorf("after update: unexpected error %v", err)
+	}
+
+	// a request with a different header gets a snapshot of its own
+	r.Header = Header{"Accept": {"text/plain"}}
+	if err := rn.Execute(r, "GET", "/users/1?v=1"); err != nil {
+		t.Errorf("other request: unexpected error %v", err)
+	}
+	if files, _ := filepath.Glob(filepath.Join(SnapshotDir, "*.json")); len(files) != 2 {
+		t.Errorf("got %d snapshot files, want 2", len(files))
+	}
+}
+
+func TestLineDiff(t *testing.T) {
+	plain := strings.NewReplacer(RedColor, "", YellowColor, "", StopColor, "")
+	got := plain.Replace(lineDiff("a\nb\nc\nd", "a\nc\nx\nd"))
+	want := "   2 - b\n   3 + x\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestSnapshotManagement(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"id":%d,"created":%q}`, time.Now().UnixNano(), time.Now().Format(time.RFC3339Nano))
+	}))
+	defer ts.Close()
+
+	defer func(dir string) { SnapshotDir = dir }(SnapshotDir)
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+	SnapshotDir = t.TempDir()
+
+	orphan := filepath.Join(SnapshotDir, "orphan.json")
+	if err := ioutil.WriteFile(orphan, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := Request{Want: Response{Status: 200}, Snapshot: &Snapshot{
+		Name: "create_user",
+		Scrub: []Scrubber{
+			{regexp.MustCompile(`"id":\d+`), `"id":0`},
+			{regexp.MustCompile(`"created":"[^"]*"`), `"created":"<time>"`},
+		},
+	}}
+	for i := 0; i < 2; i++ {
+		if err := rn.Execute(r, "POST", "/users"); err != nil {
+			t.Errorf("#%d: unexpected error %v", i, err)
+		}
+	}
+	named := filepath.Join(SnapshotDir, "create_user.json")
+	if b, err := ioutil.ReadFile(named); err != nil {
+		t.Errorf("named snapshot: %v", err)
+	} else if !strings.Contains(string(b), "<time>") {
+		t.Errorf("snapshot %s is not scrubbed", b)
+	}
+
+	removed, err := PruneSnapshots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 1 || removed[0] != orphan {
+		t.Errorf("got removed %v, want [%s]", removed, orphan)
+	}
+	if _, err := os.Stat(named); err != nil {
+		t.Errorf("used snapshot was pruned: %v", err)
+	}
+}
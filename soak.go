@@ -0,0 +1,153 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Soak executes a Scenario over and over for a wall-clock duration, e.g. 30
+// minutes, to catch leaks and gradual degradation. The iterations are grouped
+// into windows whose error counts, latencies and heap sizes are compared.
+type Soak struct {
+	Scenario Scenario
+	Duration time.Duration
+	// Pause, if greater than 0, is waited out between the iterations.
+	Pause time.Duration
+	// Window is the length of the windows, it defaults to a tenth of the
+	// Duration.
+	Window time.Duration
+
+	// MaxErrorRate is the fraction of iterations, between 0 and 1, that
+	// are allowed to fail.
+	MaxErrorRate float64
+	// MaxDrift, if greater than 0, is the maximum ratio of the 95th
+	// percentile iteration duration of the last window to that of the
+	// first window, e.g. 1.5.
+	MaxDrift float64
+	// MaxHeapGrowth, if greater than 0, is the maximum number of bytes by
+	// which the live heap of the test process may grow from the end of
+	// the first window to the end of the last window.
+	MaxHeapGrowth uint64
+}
+
+// SoakWindow holds the statistics of the iterations of one window.
+type SoakWindow struct {
+	Iterations int
+	Errors     int
+	Latency    Latency
+	// HeapAlloc is the size of the live heap at the end of the window.
+	HeapAlloc uint64
+}
+
+// SoakReport is the outcome of a Soak.
+type SoakReport struct {
+	Iterations int
+	Errors     int
+	Windows    []SoakWindow
+	// FirstErrors holds up to the first five errors.
+	FirstErrors []error
+}
+
+// Run executes the Soak and returns its report.
+func (s Soak) Run() *SoakReport {
+	return globalRunner().Soak(s)
+}
+
+// Soak executes the Soak against the Runner's target and returns its report.
+func (rn *Runner) Soak(s Soak) *SoakReport {
+	window := s.Window
+	if window <= 0 {
+		window = s.Duration / 10
+	}
+	rep := &SoakReport{}
+	start := time.Now()
+	for time.Since(start) < s.Duration {
+		end := time.Now().Add(window)
+		var w SoakWindow
+		var dd []time.Duration
+		for time.Now().Before(end) && time.Since(start) < s.Duration {
+			t := time.Now()
+			err := rn.Scenario(s.Scenario)
+			dd = append(dd, time.Since(t))
+			w.Iterations++
+			if err != nil {
+				w.Errors++
+				if len(rep.FirstErrors) < 5 {
+					rep.FirstErrors = append(rep.FirstErrors, err)
+				}
+			}
+			if s.Pause > 0 {
+				time.Sleep(s.Pause)
+			}
+		}
+		w.Latency = latencyOf(dd)
+		w.HeapAlloc = heapAlloc()
+		rep.Iterations += w.Iterations
+		rep.Errors += w.Errors
+		rep.Windows = append(rep.Windows, w)
+	}
+	return rep
+}
+
+// heapAlloc returns the size of the live heap after a garbage collection.
+func heapAlloc() uint64 {
+	runtime.GC()
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return ms.HeapAlloc
+}
+
+// Test executes the Soak and fails if its report exceeds the thresholds.
+func (s Soak) Test(t *testing.T) {
+	rep := s.Run()
+	t.Log(rep)
+	if err := s.check(rep); err != nil {
+		t.Error(err)
+	}
+}
+
+func (s Soak) check(rep *SoakReport) error {
+	var msg string
+	if rep.Iterations > 0 && float64(rep.Errors)/float64(rep.Iterations) > s.MaxErrorRate {
+		msg += fmt.Sprintf("Soak failed %s%d%s of %d iterations, want at most %s%.1f%%%s\n",
+			RedColor, rep.Errors, StopColor, rep.Iterations, RedColor, s.MaxErrorRate*100, StopColor)
+		for _, err := range rep.FirstErrors {
+			msg += fmt.Sprintf("%v\n", err)
+		}
+	}
+	if n := len(rep.Windows); n > 1 {
+		first, last := rep.Windows[0], rep.Windows[n-1]
+		if s.MaxDrift > 0 && first.Latency.P95 > 0 && last.Iterations > 0 {
+			if max := time.Duration(float64(first.Latency.P95) * s.MaxDrift); last.Latency.P95 > max {
+				msg += fmt.Sprintf("Soak p95 drifted from %s to %s%s%s, want at most %s%s%s\n",
+					first.Latency.P95, RedColor, last.Latency.P95, StopColor, RedColor, max, StopColor)
+			}
+		}
+		if s.MaxHeapGrowth > 0 && last.HeapAlloc > first.HeapAlloc && last.HeapAlloc-first.HeapAlloc > s.MaxHeapGrowth {
+			msg += fmt.Sprintf("Soak heap grew from %d to %s%d%s bytes, want growth of at most %s%d%s\n",
+				first.HeapAlloc, RedColor, last.HeapAlloc, StopColor, RedColor, s.MaxHeapGrowth, StopColor)
+		}
+	}
+	if msg != "" {
+		return fmt.Errorf("%sSoak %q:%s\n%s", PurpleColor, s.Scenario.Name, StopColor, msg)
+	}
+	return nil
+}
+
+// String returns the report formatted as a table of the windows.
+func (rep *SoakReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-6s %10s %8s %12s %12s %12s\n", "window", "iterations", "errors", "p50", "p95", "heap")
+	for i, w := range rep.Windows {
+		fmt.Fprintf(&b, "%-6d %10d %8d %12s %12s %12d\n",
+			i+1, w.Iterations, w.Errors, w.Latency.P50, w.Latency.P95, w.HeapAlloc)
+	}
+	fmt.Fprintf(&b, "failed %d of %d iterations\n", rep.Errors, rep.Iterations)
+	return b.String()
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSoak(t *testing.T) {
+	var calls int64
+	var failing int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if n := atomic.AddInt64(&calls, 1); atomic.LoadInt32(&failing) == 1 && n%2 == 0 {
+			w.WriteHeader(500)
+		}
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	s := Soak{
+		Scenario: Scenario{Name: "ping", Steps: []Step{
+			{Method: "GET", Path: "/", Request: Request{Want: Response{Status: 200}}},
+		}},
+		Duration:     150 * time.Millisecond,
+		Window:       50 * time.Millisecond,
+		MaxErrorRate: 0.01,
+	}
+	rep := rn.Soak(s)
+	if rep.Iterations == 0 || rep.Errors != 0 || len(rep.Windows) != 3 {
+		t.Errorf("got %d iterations, %d errors and %d windows, want some, 0 and 3",
+			rep.Iterations, rep.Errors, len(rep.Windows))
+	}
+	if err := s.check(rep); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	atomic.StoreInt32(&failing, 1)
+	rep = rn.Soak(s)
+	if rep.Errors == 0 || len(rep.FirstErrors) != 5 {
+		t.Errorf("failing: got %d errors and %d first errors, want some and 5", rep.Errors, len(rep.FirstErrors))
+	}
+	if err := s.check(rep); err == nil || !strings.Contains(err.Error(), "iterations, want at most") {
+		t.Errorf("failing: got error %v, want error rate failure", err)
+	}
+}
+
+func TestSoakCheck(t *testing.T) {
+	window := func(p95 time.Duration, heap uint64) SoakWindow {
+		return SoakWindow{Iterations: 10, Latency: Latency{P95: p95}, HeapAlloc: heap}
+	}
+	tests := []struct {
+		soak    Soak
+		windows []SoakWindow
+		want    string
+	}{
+		{Soak{MaxDrift: 2}, []SoakWindow{window(10, 0), window(20, 0)}, ""},
+		{Soak{MaxDrift: 2}, []SoakWindow{window(10, 0), window(21, 0)}, "p95 drifted"},
+		{Soak{MaxHeapGrowth: 100}, []SoakWindow{window(0, 1000), window(0, 1100)}, ""},
+		{Soak{MaxHeapGrowth: 100}, []SoakWindow{window(0, 1000), window(0, 1101)}, "heap grew"},
+		{Soak{MaxHeapGrowth: 100}, []SoakWindow{window(0, 1000), window(0, 500)}, ""},
+		// a single window has nothing to compare against
+		{Soak{MaxDrift: 1}, []SoakWindow{window(10, 0)}, ""},
+	}
+	for i, tt := range tests {
+		err := tt.soak.check(&SoakReport{Iterations: 20, Windows: tt.windows})
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("#%d: unexpected error %v", i, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("#%d: got error %v, want it to contain %q", i, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Here returns the Request with its Source set to the file and line of the
+// call, so that a failing row of a large table can be found at once, e.g.
+//
+//	Requests: hit.Requests{
+//		"GET": {hit.Here(hit.Request{Want: hit.Response{Status: 200}})},
+//	}
+func Here(r Request) Request {
+	if _, file, line, ok := runtime.Caller(1); ok {
+		r.Source = fmt.Sprintf("%s:%d", filepath.Base(file), line)
+	}
+	return r
+}
+
+// sourced returns err with its message prefixed by the Request's Source, if
+// any.
+func (r Request) sourced(err error) error {
+	if err == nil || r.Source == "" {
+		return err
+	}
+	msg := err.Error()
+	if !strings.HasPrefix(msg, " ") {
+		msg = " " + msg
+	}
+	return &messageError{r.Source + ":" + msg, err}
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHere(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	rows := []Request{
+		Here(Request{Want: Response{Status: 200}}),
+		Here(Request{Want: Response{Status: 404}}),
+		Here(Request{Want: Response{Status: 201}}),
+	}
+	for i, r := range rows {
+		if !strings.HasPrefix(r.Source, "source_test.go:") {
+			t.Errorf("#%d: got Source %q, want source_test.go:<line>", i, r.Source)
+		}
+		if i > 0 && r.Source == rows[i-1].Source {
+			t.Errorf("#%d: got the Source %q of the previous row", i, r.Source)
+		}
+	}
+
+	err := rn.Execute(rows[2], "GET", "/")
+	if err == nil || !strings.HasPrefix(err.Error(), rows[2].Source+":") {
+		t.Errorf("got err %v, want it prefixed by %q", err, rows[2].Source)
+	}
+	var se *StatusError
+	if !errors.As(err, &se) {
+		t.Errorf("got err %v, want it to wrap a *StatusError", err)
+	}
+	if err := rn.Execute(rows[1], "GET", "/"); err != nil {
+		t.Errorf("got err %v, want <nil>", err)
+	}
+}
@@ -0,0 +1,377 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// maxDump is the maximum number of bytes of a response body that are kept in
+// memory to be shown in a failure message. Bodies larger than that are still
+// compared in whole, they're just not shown.
+const maxDump = 1 << 20
+
+// compareJSONStream compares the JSON document read from r to the normalized
+// want value. The document is compared as it's being decoded so that only the
+// parts of it that need to be matched as a whole, e.g. by a Matcher, are held
+// in memory. The failure is printed with the values redacted by rd.
+func compareJSONStream(r io.Reader, want interface{}, mode Mode, rd Redaction) error {
+	dump := &capBuffer{max: maxDump}
+	d := json.NewDecoder(io.TeeReader(r, dump))
+	d.UseNumber()
+
+	err := matchStream(d, "", want, mode)
+	if err == io.EOF {
+		// an empty body is treated as an empty object, or null
+		var got interface{}
+		if _, ok := want.(map[string]interface{}); ok {
+			got = map[string]interface{}{}
+		}
+		if err = matchJSON("", got, want, mode); err != nil {
+			return bodyError(got, want, mode, rd, err)
+		}
+		return nil
+	}
+	if err == nil {
+		return nil
+	}
+	if e, ok := err.(*decodeError); ok {
+		return fmt.Errorf("hit: error decoding http.Response.Body. %v", e.err)
+	}
+
+	// read the rest of the body so it can be shown in the failure message
+	io.Copy(dump, r)
+	if dump.truncated {
+		return fmt.Errorf("Body got %s<%d bytes, too large to show>%s, want %s%#v%s\n%s\n",
+			RedColor, dump.n, StopColor, RedColor, want, StopColor, err)
+	}
+	var got interface{}
+	dd := json.NewDecoder(bytes.NewReader(dump.buf.Bytes()))
+	dd.UseNumber()
+	dd.Decode(&got)
+	return bodyError(got, want, mode, rd, err)
+}
+
+// decodeError wraps errors returned by the json.Decoder during a streaming
+// comparison to distinguish them from mismatches.
+type decodeError struct {
+	err error
+}
+
+func (e *decodeError) Error() string { return e.err.Error() }
+
+// matchStream is the streaming counterpart of matchJSON, it compares the next
+// value read from d to the normalized want value.
+func matchStream(d *json.Decoder, path string, want interface{}, mode Mode) error {
+	switch w := want.(type) {
+	case Modal:
+		return matchStream(d, path, w.v, w.apply(mode))
+	case map[string]interface{}:
+		tok, err := token(d)
+		if err != nil {
+			return err
+		}
+		if tok != json.Delim('{') {
+			return fmt.Errorf("%s: got %s, want object", pathOrRoot(path), kindOf(tok))
+		}
+		seen := make(map[string]bool, len(w))
+		var extra []string
+		for d.More() {
+			tok, err := token(d)
+			if err != nil {
+				return err
+			}
+			k := tok.(string)
+			wv, ok := w[k]
+			if !ok {
+				switch {
+				case mode&StrictObjects != 0:
+					extra = append(extra, pointer(path, k))
+				case mode&PartialObjects == 0:
+					return fmt.Errorf("%s: unexpected", pointer(path, k))
+				}
+				if err := skipValue(d); err != nil {
+					return err
+				}
+				continue
+			}
+			seen[k] = true
+			if err := matchStream(d, pointer(path, k), wv, mode); err != nil {
+				return err
+			}
+		}
+		if _, err := token(d); err != nil {
+			return err
+		}
+		for k, wv := range w {
+			if _, miss := wv.(missing); !seen[k] && !miss {
+				return fmt.Errorf("%s: missing", pointer(path, k))
+			}
+		}
+		if len(extra) > 0 {
+			sort.Strings(extra)
+			return &extraFieldsError{extra}
+		}
+		return nil
+	case []interface{}:
+		if mode&UnorderedArrays != 0 {
+			break // decoded in whole below
+		}
+		tok, err := token(d)
+		if err != nil {
+			return err
+		}
+		if tok != json.Delim('[') {
+			return fmt.Errorf("%s: got %s, want array", pathOrRoot(path), kindOf(tok))
+		}
+		i := 0
+		for ; d.More(); i++ {
+			if i >= len(w) {
+				return fmt.Errorf("%s: got more than %d elements", pathOrRoot(path), len(w))
+			}
+			if err := matchStream(d, pointer(path, strconv.Itoa(i)), w[i], mode); err != nil {
+				return err
+			}
+		}
+		if _, err := token(d); err != nil {
+			return err
+		}
+		if i != len(w) {
+			return fmt.Errorf("%s: got %d elements, want %d", pathOrRoot(path), i, len(w))
+		}
+		return nil
+	}
+
+	var got interface{}
+	if err := d.Decode(&got); err != nil {
+		if err == io.EOF && path == "" {
+			return err
+		}
+		return &decodeError{err}
+	}
+	return matchJSON(path, got, want, mode)
+}
+
+// token returns the next token from d, errors are wrapped in a decodeError
+// except for an io.EOF at the very beginning of the document.
+func token(d *json.Decoder) (json.Token, error) {
+	tok, err := d.Token()
+	if err == io.EOF && d.InputOffset() == 0 {
+		return nil, err
+	}
+	if err != nil {
+		return nil, &decodeError{err}
+	}
+	return tok, nil
+}
+
+// skipValue reads and discards the next value from d without holding it in
+// memory.
+func skipValue(d *json.Decoder) error {
+	depth := 0
+	for {
+		tok, err := token(d)
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// kindOf returns the kind of the JSON value that starts with the specified
+// token.
+func kindOf(tok json.Token) string {
+	switch tok {
+	case json.Delim('{'):
+		return "object"
+	case json.Delim('['):
+		return "array"
+	case nil:
+		return "null"
+	}
+	return reflect.TypeOf(tok).String()
+}
+
+// capBuffer is an io.Writer that keeps only the first max bytes written
+// to it, the rest is counted and discarded.
+type capBuffer struct {
+	buf       bytes.Buffer
+	max       int
+	n         int64
+	truncated bool
+}
+
+func (b *capBuffer) Write(p []byte) (int, error) {
+	b.n += int64(len(p))
+	if room := b.max - b.buf.Len(); room < len(p) {
+		b.truncated = true
+		if room > 0 {
+			b.buf.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+// ValidJSON returns a Comparer that only checks that the response body is
+// a single well-formed JSON value, its contents are not compared.
+func ValidJSON() Comparer { return validJSON("") }
+
+// ValidJSONObject returns a Comparer that only checks that the response body
+// is a well-formed JSON object.
+func ValidJSONObject() Comparer { return validJSON("object") }
+
+// ValidJSONArray returns a Comparer that only checks that the response body
+// is a well-formed JSON array.
+func ValidJSONArray() Comparer { return validJSON("array") }
+
+type validJSON string
+
+// Compare implements the Comparer interface.
+func (v validJSON) Compare(r io.Reader) error {
+	d := json.NewDecoder(r)
+	d.UseNumber()
+
+	tok, err := d.Token()
+	if err == io.EOF {
+		return fmt.Errorf("Body got %s<empty>%s, want %svalid JSON%s\n", RedColor, StopColor, RedColor, StopColor)
+	}
+	if err != nil {
+		return fmt.Errorf("Body is not valid JSON: %s%v%s\n", RedColor, err, StopColor)
+	}
+	if kind := kindOf(tok); v != "" && kind != string(v) {
+		return fmt.Errorf("Body got JSON %s%s%s, want JSON %s%s%s\n", RedColor, kind, StopColor, RedColor, v, StopColor)
+	}
+	// validate the rest of the value without holding it in memory
+	if tok == json.Delim('{') || tok == json.Delim('[') {
+		for depth := 1; depth > 0; {
+			if tok, err = d.Token(); err != nil {
+				return fmt.Errorf("Body is not valid JSON: %s%v%s\n", RedColor, err, StopColor)
+			}
+			switch tok {
+			case json.Delim('{'), json.Delim('['):
+				depth++
+			case json.Delim('}'), json.Delim(']'):
+				depth--
+			}
+		}
+	}
+	if _, err := d.Token(); err != io.EOF {
+		return fmt.Errorf("Body is not valid JSON: %sunexpected data after top-level value%s\n", RedColor, StopColor)
+	}
+	return nil
+}
+
+// GoString implements the fmt.GoStringer interface.
+func (v validJSON) GoString() string {
+	switch v {
+	case "object":
+		return "hit.ValidJSONObject()"
+	case "array":
+		return "hit.ValidJSONArray()"
+	}
+	return "hit.ValidJSON()"
+}
+
+// RawBody is a Comparer that expects the response body to be equal, byte for
+// byte, to its contents. The response body is compared in chunks as it's read
+// so it's never held in memory in whole.
+type RawBody []byte
+
+// Compare implements the Comparer interface.
+func (b RawBody) Compare(r io.Reader) error {
+	return compareStreams(r, bytes.NewReader(b))
+}
+
+// FileBody is a Comparer that expects the response body to be equal, byte for
+// byte, to the contents of the file at the named path. Both the file and the
+// response body are compared in chunks so neither is held in memory in whole,
+// which makes FileBody suitable for large downloads.
+type FileBody string
+
+// Compare implements the Comparer interface.
+func (f FileBody) Compare(r io.Reader) error {
+	file, err := os.Open(string(f))
+	if err != nil {
+		return fmt.Errorf("hit: FileBody error %v\n", err)
+	}
+	defer file.Close()
+	return compareStreams(r, file)
+}
+
+// compareStreams compares the got and want streams chunk by chunk and returns
+// an error describing the offset of the first difference.
+func compareStreams(got, want io.Reader) error {
+	const size = 32 << 10
+	gbuf, wbuf := make([]byte, size), make([]byte, size)
+	var off int64
+	for {
+		gn, gerr := io.ReadFull(got, gbuf)
+		wn, werr := io.ReadFull(want, wbuf)
+		if gerr != nil && gerr != io.EOF && gerr != io.ErrUnexpectedEOF {
+			return fmt.Errorf("hit: error reading http.Response.Body. %v\n", gerr)
+		}
+		if werr != nil && werr != io.EOF && werr != io.ErrUnexpectedEOF {
+			return fmt.Errorf("hit: error reading expected body. %v\n", werr)
+		}
+		n := gn
+		if wn < n {
+			n = wn
+		}
+		if i := firstDiff(gbuf[:n], wbuf[:n]); i >= 0 {
+			return fmt.Errorf("Body differs at byte %s%d%s, got %s%q%s, want %s%q%s\n",
+				RedColor, off+int64(i), StopColor,
+				RedColor, excerpt(gbuf[:gn], i), StopColor,
+				RedColor, excerpt(wbuf[:wn], i), StopColor)
+		}
+		off += int64(n)
+		if gn != wn {
+			grest, _ := io.Copy(ioutil.Discard, got)
+			wrest, _ := io.Copy(ioutil.Discard, want)
+			return fmt.Errorf("Body length got %s%d%s, want %s%d%s\n",
+				RedColor, off+int64(gn-n)+grest, StopColor,
+				RedColor, off+int64(wn-n)+wrest, StopColor)
+		}
+		if gerr != nil || werr != nil {
+			return nil
+		}
+	}
+}
+
+func firstDiff(a, b []byte) int {
+	for i := range a {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return -1
+}
+
+// excerpt returns up to 16 bytes of b starting at i.
+func excerpt(b []byte, i int) []byte {
+	if i >= len(b) {
+		return nil
+	}
+	b = b[i:]
+	if len(b) > 16 {
+		b = b[:16]
+	}
+	return b
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+var streamTests = []struct {
+	want Comparer
+	body string
+	err  string
+}{
+	{JSONBody{"a": 1, "b": []int{1, 2}}, `{"b":[1,2],"a":1}`, ""},
+	{JSONBody{"a": 1}, `{"a":1,"b":{"x":[1,{"y":2}]}}`, "Body /b"},
+	{Partial(JSONBody{"a": 1}), `{"a":1,"b":{"x":[1,{"y":2}]}}`, ""},
+	{JSONBody{"a": []int{1, 2}}, `{"a":[1,2,3]}`, "Body /a/2: " + RedColor + "got 3, want <missing>"},
+	{JSONBody{"a": []int{1, 2}}, `{"a":[1]}`, "Body /a/1: " + RedColor + "got <missing>, want 2"},
+	{JSONBody{"a": JSONBody{"b": 1}}, `{"a":[1]}`, "Body /a: " + RedColor + "got [1], want object"},
+	{JSONBody{}, ``, ""},
+	{JSONBody{"a": 1}, `{"a":`, "hit: error decoding"},
+	{Unordered(JSONBody{"a": []int{1, 2}}), `{"a":[2,1]}`, ""},
+	{RawBody("hello world"), "hello world", ""},
+	{RawBody("hello world"), "hello there", "Body differs at byte " + RedColor + "6"},
+	{RawBody("hello"), "hello world", "Body length got " + RedColor + "11" + StopColor + ", want " + RedColor + "5"},
+	{ValidJSON(), `{"a":[1,2,{"b":null}]}`, ""},
+	{ValidJSON(), `"str"`, ""},
+	{ValidJSON(), `{"a":[1,2}`, "Body is not valid JSON"},
+	{ValidJSON(), `{"a":1} x`, "unexpected data after top-level value"},
+	{ValidJSON(), ``, "want " + RedColor + "valid JSON"},
+	{ValidJSONObject(), `{"a":1}`, ""},
+	{ValidJSONObject(), `[1]`, "Body got JSON " + RedColor + "array" + StopColor + ", want JSON " + RedColor + "object"},
+	{ValidJSONArray(), `[1]`, ""},
+	{ValidJSONArray(), `<html></html>`, "Body is not valid JSON"},
+}
+
+func TestStreamCompare(t *testing.T) {
+	for i, tt := range streamTests {
+		err := tt.want.Compare(strings.NewReader(tt.body))
+		if tt.err == "" && err != nil {
+			t.Errorf("#%d: got err %v, want <nil>", i, err)
+		} else if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("#%d: got err %v, want err containing %q", i, err, tt.err)
+		}
+	}
+}
+
+// bigBody streams a JSON object with n array elements without holding it
+// in memory.
+func bigBody(n int, last string) io.Reader {
+	elems := make([]io.Reader, 0, n+2)
+	elems = append(elems, strings.NewReader(`{"id":1,"items":[`))
+	for i := 0; i < n; i++ {
+		elems = append(elems, strings.NewReader(`{"v":"xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx"},`))
+	}
+	elems = append(elems, strings.NewReader(last+`]}`))
+	return io.MultiReader(elems...)
+}
+
+func TestStreamCompareLarge(t *testing.T) {
+	want := Partial(JSONBody{"id": 1})
+	if err := want.Compare(bigBody(20000, `{}`)); err != nil {
+		t.Errorf("got err %v, want <nil>", err)
+	}
+	want = Partial(JSONBody{"id": 2})
+	err := want.Compare(bigBody(20000, `{}`))
+	if err == nil || !strings.Contains(err.Error(), "too large to show") || !strings.Contains(err.Error(), "/id") {
+		t.Errorf("got err %v, want truncated failure at /id", err)
+	}
+}
+
+func TestFileBody(t *testing.T) {
+	f, err := ioutil.TempFile("", "hit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	content := bytes.Repeat([]byte("0123456789"), 10000)
+	f.Write(content)
+	f.Close()
+
+	if err := FileBody(f.Name()).Compare(bytes.NewReader(content)); err != nil {
+		t.Errorf("got err %v, want <nil>", err)
+	}
+	content[54321] = 'x'
+	if err := FileBody(f.Name()).Compare(bytes.NewReader(content)); err == nil || !strings.Contains(err.Error(), "54321") {
+		t.Errorf("got err %v, want difference at 54321", err)
+	}
+}
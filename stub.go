@@ -0,0 +1,153 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Never can be used as a Route's Times to expect the route not to be called.
+const Never = -1
+
+// Route represents a programmed route of a Stub with its canned response.
+type Route struct {
+	// Match selects the requests handled by the route.
+	Match Inbound
+
+	// The canned response.
+	Status int // defaults to 200
+	Header Header
+	Body   Bodyer
+	// Delay, if set, is waited out before the response is written.
+	Delay time.Duration
+
+	// Times, if not 0, is the exact number of times the route is expected
+	// to be called, use Never for routes that must not be called at all.
+	Times int
+}
+
+// Stub is a fake upstream dependency of the system under test. It responds to
+// requests with the canned response of the first Route that matches them and
+// it records the number of calls of each Route.
+type Stub struct {
+	routes []Route
+
+	ln        net.Listener
+	srv       *http.Server
+	mu        sync.Mutex
+	counts    []int
+	unmatched []Called
+}
+
+// NewStub starts a Stub with the specified routes listening on a random port
+// of the loopback interface. It should be closed when it's no longer needed.
+func NewStub(routes ...Route) (*Stub, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("hit: failed starting stub listener. %v", err)
+	}
+	s := &Stub{routes: routes, ln: ln, counts: make([]int, len(routes))}
+	s.srv = &http.Server{Handler: http.HandlerFunc(s.serveHTTP)}
+	go s.srv.Serve(ln)
+	return s, nil
+}
+
+// URL returns the base URL of the Stub, e.g. "http://127.0.0.1:34567".
+func (s *Stub) URL() string { return "http://" + s.ln.Addr().String() }
+
+// Close stops the Stub.
+func (s *Stub) Close() error { return s.srv.Close() }
+
+// Count returns the number of times the i-th Route was called.
+func (s *Stub) Count(i int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.counts[i]
+}
+
+func (s *Stub) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	b, _ := ioutil.ReadAll(r.Body)
+	call := Called{Method: r.Method, Path: r.URL.RequestURI(), Header: r.Header, Body: b}
+
+	s.mu.Lock()
+	i := -1
+	for j, rt := range s.routes {
+		if rt.Match.match(call) == "" {
+			i = j
+			break
+		}
+	}
+	if i < 0 {
+		s.unmatched = append(s.unmatched, call)
+		s.mu.Unlock()
+		http.Error(w, "hit: no stub route matches "+r.Method+" "+call.Path, http.StatusNotImplemented)
+		return
+	}
+	s.counts[i]++
+	s.mu.Unlock()
+
+	rt := s.routes[i]
+	if rt.Delay > 0 {
+		time.Sleep(rt.Delay)
+	}
+	for k, vv := range rt.Header {
+		for _, v := range vv {
+			w.Header().Add(k, v)
+		}
+	}
+	var body io.Reader
+	if rt.Body != nil {
+		var err error
+		if body, err = rt.Body.Body(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", rt.Body.Type())
+		}
+	}
+	status := rt.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	if body != nil {
+		io.Copy(w, body)
+	}
+}
+
+// Verify checks that every Route was called the expected number of times and
+// that the Stub received no requests that didn't match any of its Routes.
+func (s *Stub) Verify() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var msg string
+	for i, rt := range s.routes {
+		want := rt.Times
+		if want == Never {
+			want = 0
+		} else if want == 0 {
+			continue
+		}
+		if s.counts[i] != want {
+			msg += fmt.Sprintf("Stub route #%d (%s) got = %s%d%s calls, want %s%d%s\n",
+				i+1, rt.Match, RedColor, s.counts[i], StopColor, RedColor, want, StopColor)
+		}
+	}
+	for _, c := range s.unmatched {
+		msg += fmt.Sprintf("Stub got = %s%s %s%s, want %s<no unmatched calls>%s\n",
+			RedColor, c.Method, c.Path, StopColor, RedColor, StopColor)
+	}
+	if msg != "" {
+		return fmt.Errorf("%s", msg)
+	}
+	return nil
+}
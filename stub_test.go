@@ -0,0 +1,69 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStub(t *testing.T) {
+	upstream, err := NewStub(
+		Route{
+			Match:  Inbound{Method: "GET", Path: "/rates/EUR"},
+			Body:   JSONBody{"rate": 1.1},
+			Header: Header{"X-Upstream": {"1"}},
+			Times:  2,
+		},
+		Route{Match: Inbound{Method: "GET", Path: "/rates/XXX"}, Status: 404},
+		Route{Match: Inbound{Method: "DELETE"}, Times: Never},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer upstream.Close()
+
+	// the service under test proxies to the upstream
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res, err := http.Get(upstream.URL() + "/rates/" + r.URL.Query().Get("c"))
+		if err != nil {
+			w.WriteHeader(502)
+			return
+		}
+		defer res.Body.Close()
+		b, _ := ioutil.ReadAll(res.Body)
+		w.Header().Set("X-Upstream", res.Header.Get("X-Upstream"))
+		w.WriteHeader(res.StatusCode)
+		w.Write(b)
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	h := Hit{Path: "/convert?c=EUR", Requests: Requests{"GET": {
+		{Want: Response{Status: 200, Header: Header{"X-Upstream": {"1"}}, Body: JSONBody{"rate": 1.1}}},
+		{Want: Response{Status: 200}},
+	}}}
+	rn.Test(t, h)
+	if err := rn.Execute(Request{Want: Response{Status: 404}}, "GET", "/convert?c=XXX"); err != nil {
+		t.Error(err)
+	}
+	if err := upstream.Verify(); err != nil {
+		t.Error(err)
+	}
+
+	if err := rn.Execute(Request{Want: Response{Status: 501}}, "GET", "/convert?c=USD"); err != nil {
+		t.Error(err)
+	}
+	err = upstream.Verify()
+	if err == nil || !strings.Contains(err.Error(), "/rates/USD") {
+		t.Errorf("got err %v, want unmatched call failure", err)
+	}
+	if n := upstream.Count(0); n != 2 {
+		t.Errorf("got %d calls, want 2", n)
+	}
+}
@@ -0,0 +1,247 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Target is the system under test started for the duration of a suite, as a
+// container or as a local command, which makes the suite a self-contained
+// end-to-end test, e.g.
+//
+//	var target = &hit.Target{Image: "example/api:latest", Port: 8080, Ready: "/healthz"}
+//
+//	func TestMain(m *testing.M) {
+//		os.Exit(target.Run(m))
+//	}
+//
+// with the tests executing their Hits by target.Runner().
+type Target struct {
+	// Image, if set, is the container image that's run with Docker, its
+	// Port is published on a random port of the loopback interface.
+	Image string
+	Port  int
+	// Docker is the docker command, it defaults to "docker", e.g. "podman"
+	// can be used in its place.
+	Docker string
+
+	// Command, if set and Image is not, is the command that's started,
+	// its first element being the program. The command is told the port
+	// to listen on in the PORT environment variable.
+	Command []string
+
+	// Env holds the environment variables of the container or command.
+	Env map[string]string
+	// Args are appended to the image's default arguments.
+	Args []string
+
+	// Ready is the path that's polled until it responds with a 2xx status,
+	// it defaults to "/". ReadyTimeout defaults to 30 seconds.
+	Ready        string
+	ReadyTimeout time.Duration
+
+	mu        sync.Mutex
+	container string
+	cmd       *exec.Cmd
+	output    *bytes.Buffer
+	addr      string
+}
+
+// Run starts the target, runs the tests and stops the target, it returns the
+// exit code to pass to os.Exit. It's meant to be called from TestMain.
+func (tg *Target) Run(m *testing.M) int {
+	if err := tg.Start(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	code := m.Run()
+	if err := tg.Stop(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		if code == 0 {
+			code = 1
+		}
+	}
+	return code
+}
+
+// Start starts the target and waits for it to be ready, its address is then
+// returned by Addr.
+func (tg *Target) Start() error {
+	tg.mu.Lock()
+	defer tg.mu.Unlock()
+
+	var err error
+	switch {
+	case tg.Image != "":
+		err = tg.startContainer()
+	case len(tg.Command) > 0:
+		err = tg.startCommand()
+	default:
+		return fmt.Errorf("hit: Target has neither an Image nor a Command")
+	}
+	if err != nil {
+		return err
+	}
+
+	ready, timeout := tg.Ready, tg.ReadyTimeout
+	if ready == "" {
+		ready = "/"
+	}
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	if err := tg.runner().WaitReady(ready, timeout, 100*time.Millisecond); err != nil {
+		var logs string
+		if tg.container != "" {
+			logs, _ = tg.docker("logs", tg.container)
+		}
+		tg.stop()
+		if tg.output != nil {
+			// the command has exited, stop waited for it, so its output
+			// is no longer being written
+			logs = tg.output.String()
+		}
+		tg.addr = ""
+		return fmt.Errorf("%v\n%s", err, logs)
+	}
+	return nil
+}
+
+// Stop stops the target.
+func (tg *Target) Stop() error {
+	tg.mu.Lock()
+	defer tg.mu.Unlock()
+	tg.addr = ""
+	return tg.stop()
+}
+
+// Addr returns the address of the started target, or "" if the target is
+// not running.
+func (tg *Target) Addr() string {
+	tg.mu.Lock()
+	defer tg.mu.Unlock()
+	return tg.addr
+}
+
+// Runner returns a new Runner, configured by DefaultConfig, whose requests
+// are sent to the started target.
+func (tg *Target) Runner() *Runner {
+	tg.mu.Lock()
+	defer tg.mu.Unlock()
+	return tg.runner()
+}
+
+func (tg *Target) runner() *Runner {
+	rn := NewRunner()
+	rn.Addr, rn.BaseURL, rn.Profile = tg.addr, "", nil
+	return rn
+}
+
+func (tg *Target) startContainer() error {
+	if tg.Port <= 0 {
+		return fmt.Errorf("hit: Target image %s has no Port", tg.Image)
+	}
+	args := []string{"run", "--detach", "--publish", "127.0.0.1::" + strconv.Itoa(tg.Port)}
+	for _, kv := range envList(tg.Env) {
+		args = append(args, "--env", kv)
+	}
+	args = append(append(args, tg.Image), tg.Args...)
+	out, err := tg.docker(args...)
+	if err != nil {
+		return fmt.Errorf("hit: failed starting Target image %s. %v", tg.Image, err)
+	}
+	tg.container = strings.TrimSpace(out)
+
+	out, err = tg.docker("port", tg.container, strconv.Itoa(tg.Port)+"/tcp")
+	if err != nil {
+		tg.stop()
+		return fmt.Errorf("hit: failed getting the port of Target image %s. %v", tg.Image, err)
+	}
+	// one line per host address, e.g. "127.0.0.1:49153"
+	s := bufio.NewScanner(strings.NewReader(out))
+	for s.Scan() {
+		if _, _, err := net.SplitHostPort(strings.TrimSpace(s.Text())); err == nil {
+			tg.addr = strings.TrimSpace(s.Text())
+			return nil
+		}
+	}
+	tg.stop()
+	return fmt.Errorf("hit: Target image %s has no published port in %q", tg.Image, out)
+}
+
+func (tg *Target) startCommand() error {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("hit: failed finding a free port for Target %s. %v", tg.Command[0], err)
+	}
+	tg.addr = l.Addr().String()
+	l.Close()
+	_, port, _ := net.SplitHostPort(tg.addr)
+
+	tg.cmd = exec.Command(tg.Command[0], append(tg.Command[1:], tg.Args...)...)
+	tg.cmd.Env = append(append(os.Environ(), envList(tg.Env)...), "PORT="+port)
+	tg.output = new(bytes.Buffer)
+	tg.cmd.Stdout, tg.cmd.Stderr = tg.output, tg.output
+	if err := tg.cmd.Start(); err != nil {
+		tg.cmd = nil
+		return fmt.Errorf("hit: failed starting Target %s. %v", tg.Command[0], err)
+	}
+	return nil
+}
+
+// stop removes the container or kills the command.
+func (tg *Target) stop() error {
+	if tg.container != "" {
+		id := tg.container
+		tg.container = ""
+		if _, err := tg.docker("rm", "--force", "--volumes", id); err != nil {
+			return fmt.Errorf("hit: failed removing Target container %s. %v", id, err)
+		}
+	}
+	if tg.cmd != nil {
+		cmd := tg.cmd
+		tg.cmd = nil
+		cmd.Process.Kill()
+		cmd.Wait()
+	}
+	return nil
+}
+
+// docker runs the docker command with the specified arguments and returns
+// its standard output.
+func (tg *Target) docker(args ...string) (string, error) {
+	docker := tg.Docker
+	if docker == "" {
+		docker = "docker"
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(docker, args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s %s: %v %s", docker, args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// envList returns the environment variables as sorted KEY=value pairs.
+func envList(env map[string]string) []string {
+	var kv []string
+	for k, v := range env {
+		kv = append(kv, k+"="+v)
+	}
+	sort.Strings(kv)
+	return kv
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestTargetHelper is the command started by TestTarget.
+func TestTargetHelper(t *testing.T) {
+	if os.Getenv("HIT_TARGET_HELPER") != "1" {
+		return
+	}
+	http.ListenAndServe("127.0.0.1:"+os.Getenv("PORT"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, os.Getenv("GREETING"))
+	}))
+	os.Exit(0)
+}
+
+func TestTarget(t *testing.T) {
+	defer func(a string) { Addr = a }(Addr)
+	Addr = "before"
+
+	tg := &Target{
+		Command: []string{os.Args[0], "-test.run=^TestTargetHelper$"},
+		Env:     map[string]string{"HIT_TARGET_HELPER": "1", "GREETING": "hello"},
+	}
+	if err := tg.Start(); err != nil {
+		t.Fatal(err)
+	}
+	addr := tg.Addr()
+	if addr == "" {
+		t.Errorf("got no Addr of the started Target")
+	}
+	if err := tg.Runner().Execute(Request{Want: Response{Status: 200, Body: RawBody("hello")}}, "GET", "/"); err != nil {
+		t.Errorf("got err %v, want <nil>", err)
+	}
+	if err := tg.Stop(); err != nil {
+		t.Errorf("stop: got err %v, want <nil>", err)
+	}
+	if Addr != "before" || tg.Addr() != "" {
+		t.Errorf("got Addr %q and Target Addr %q after Stop, want %q and none", Addr, tg.Addr(), "before")
+	}
+	if c, err := net.Dial("tcp", addr); err == nil {
+		c.Close()
+		t.Errorf("got the Target listening after Stop")
+	}
+
+	// a command that never gets ready
+	tg = &Target{Command: []string{os.Args[0], "-test.run=^$"}, ReadyTimeout: 200 * time.Millisecond}
+	if err := tg.Start(); err == nil || !strings.Contains(err.Error(), "not ready") {
+		t.Errorf("got err %v, want not ready", err)
+	}
+	if Addr != "before" || tg.Addr() != "" {
+		t.Errorf("got Addr %q and Target Addr %q after a failed Start, want %q and none", Addr, tg.Addr(), "before")
+	}
+}
+
+func TestTargetImage(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake docker command is a shell script")
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "hit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	calls := filepath.Join(dir, "calls")
+	docker := filepath.Join(dir, "docker")
+	script := fmt.Sprintf(`#!/bin/sh
+echo "$@" >> %s
+case "$1" in
+run) echo c0ffee ;;
+port) echo %s ;;
+esac
+`, calls, ts.URL[len("http://"):])
+	if err := ioutil.WriteFile(docker, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tg := &Target{
+		Image:  "example/api:latest",
+		Port:   8080,
+		Docker: docker,
+		Env:    map[string]string{"B": "2", "A": "1"},
+		Args:   []string{"--debug"},
+		Ready:  "/healthz",
+	}
+	if err := tg.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if want := ts.URL[len("http://"):]; tg.Addr() != want {
+		t.Errorf("got Addr %q, want %q", tg.Addr(), want)
+	}
+	if err := tg.Stop(); err != nil {
+		t.Errorf("stop: got err %v, want <nil>", err)
+	}
+
+	b, err := ioutil.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "run --detach --publish 127.0.0.1::8080 --env A=1 --env B=2 example/api:latest --debug\n" +
+		"port c0ffee 8080/tcp\n" +
+		"rm --force --volumes c0ffee\n"
+	if string(b) != want {
+		t.Errorf("got docker calls\n%s\nwant\n%s", b, want)
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// render executes s as a text/template template if the Request's Template is
+// set. The template's data is a snapshot of the values in Vars, e.g.
+// {{.id}}, a missing value fails the execution.
+func (r Request) render(s string) (string, error) {
+	if !r.Template || !strings.Contains(s, "{{") {
+		return s, nil
+	}
+	t, err := template.New("").Funcs(r.templateFuncs()).Option("missingkey=error").Parse(s)
+	if err != nil {
+		return "", fmt.Errorf("hit: failed parsing template %q. %v", s, err)
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, Vars.values()); err != nil {
+		return "", fmt.Errorf("hit: failed executing template %q. %v", s, err)
+	}
+	return b.String(), nil
+}
+
+// templateFuncs returns the functions available to the Request's templates:
+//
+//	iteration         the number, counting from 0, of the copy of a
+//	                  Concurrent request or of a request sent by a Load
+//	uuid              a new random UUID
+//	now LAYOUT        the current time formatted by the time layout, or as
+//	                  seconds since the Unix epoch if the layout is "unix"
+//	randInt A B       a random integer between A and B, inclusive
+//	b64 S...          the standard base64 encoding of the joined strings,
+//	                  e.g. {{b64 "user:" .password}}
+//	env NAME          the value of the environment variable, which must
+//	                  be set
+//	secret NAME       the value of the secret from the Runner's Secrets, it's
+//	                  redacted from the failure messages
+func (r Request) templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"iteration": func() int { return r.iter },
+		"uuid":      newUUID,
+		"now": func(layout string) string {
+			if layout == "unix" {
+				return strconv.FormatInt(time.Now().Unix(), 10)
+			}
+			return time.Now().Format(layout)
+		},
+		"randInt": func(a, b int) (int, error) {
+			if b < a {
+				return 0, fmt.Errorf("randInt %d %d, want the first not greater than the second", a, b)
+			}
+			return a + rand.Intn(b-a+1), nil
+		},
+		"b64": func(ss ...string) string {
+			return base64.StdEncoding.EncodeToString([]byte(strings.Join(ss, "")))
+		},
+		"secret": r.runner().Secret,
+		"env": func(name string) (string, error) {
+			v, ok := os.LookupEnv(name)
+			if !ok {
+				return "", fmt.Errorf("environment variable %s is not set", name)
+			}
+			return v, nil
+		},
+	}
+}
+
+// renderHeader returns a copy of the specified header with its values
+// rendered.
+func (r Request) renderHeader(h Header) (Header, error) {
+	if !r.Template || h == nil {
+		return h, nil
+	}
+	out := make(Header, len(h))
+	for k, vv := range h {
+		for _, v := range vv {
+			s, err := r.render(v)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = append(out[k], s)
+		}
+	}
+	return out, nil
+}
+
+// renderBody returns a copy of the specified body with its string values
+// rendered. Bodyers other than JSONBody, FormBody, MultipartBody and those
+// returned by Encoded are returned as they are.
+func (r Request) renderBody(b Bodyer) (Bodyer, error) {
+	if !r.Template {
+		return b, nil
+	}
+	switch b := b.(type) {
+	case JSONBody:
+		v, err := r.renderValue(map[string]interface{}(b))
+		if err != nil {
+			return nil, err
+		}
+		return JSONBody(v.(map[string]interface{})), nil
+	case FormBody:
+		h, err := r.renderHeader(Header(b))
+		if err != nil {
+			return nil, err
+		}
+		return FormBody(h), nil
+	case MultipartBody:
+		out := make(MultipartBody, len(b))
+		for k, vv := range b {
+			for _, v := range vv {
+				nv, err := r.renderValue(v)
+				if err != nil {
+					return nil, err
+				}
+				out[k] = append(out[k], nv)
+			}
+		}
+		return out, nil
+	case encodedBody:
+		v, err := r.renderValue(b.v)
+		if err != nil {
+			return nil, err
+		}
+		b.v = v
+		return b, nil
+	}
+	return b, nil
+}
+
+// renderValue returns a copy of the specified value with the strings in it,
+// at any depth of maps and slices of interface{}, rendered.
+func (r Request) renderValue(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		return r.render(v)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			ne, err := r.renderValue(e)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = ne
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, e := range v {
+			ne, err := r.renderValue(e)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = ne
+		}
+		return out, nil
+	}
+	return v, nil
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRequestTemplate(t *testing.T) {
+	type sent struct {
+		path, token string
+		body        map[string]interface{}
+	}
+	var mu sync.Mutex
+	var got []sent
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		var body map[string]interface{}
+		json.Unmarshal(b, &body)
+		mu.Lock()
+		got = append(got, sent{r.URL.Path, r.Header.Get("X-Token"), body})
+		mu.Unlock()
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+	defer Vars.Reset()
+	Vars.Set("id", 42)
+	Vars.Set("token", "t0k")
+
+	r := Request{
+		Template: true,
+		Header:   Header{"X-Token": {"{{.token}}"}},
+		Body:     JSONBody{"owner": "user-{{.id}}", "tags": []interface{}{"{{.token}}", 1}},
+		Want:     Response{Status: 200},
+	}
+	if err := rn.Execute(r, "PUT", "/users/{{.id}}"); err != nil {
+		t.Fatal(err)
+	}
+	want := sent{"/users/42", "t0k", map[string]interface{}{"owner": "user-42", "tags": []interface{}{"t0k", float64(1)}}}
+	if len(got) != 1 || got[0].path != want.path || got[0].token != want.token ||
+		got[0].body["owner"] != "user-42" || got[0].body["tags"].([]interface{})[0] != "t0k" {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	// the Request's own values are left as they were
+	if r.Body.(JSONBody)["owner"] != "user-{{.id}}" || r.Header["X-Token"][0] != "{{.token}}" {
+		t.Errorf("template modified in place: %+v", r)
+	}
+
+	// every concurrent copy has its own iteration
+	got = nil
+	c := Request{Template: true, Concurrent: 3, Want: Response{Status: 200}}
+	if err := rn.Execute(c, "POST", "/items/{{iteration}}"); err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, s := range got {
+		paths = append(paths, s.path)
+	}
+	sort.Strings(paths)
+	if strings.Join(paths, " ") != "/items/0 /items/1 /items/2" {
+		t.Errorf("got paths %v", paths)
+	}
+
+	// without Template the braces are sent as they are
+	got = nil
+	if err := rn.Execute(Request{Header: Header{"X-Token": {"{{.token}}"}}, Want: Response{Status: 200}}, "GET", "/"); err != nil {
+		t.Fatal(err)
+	}
+	if got[0].token != "{{.token}}" {
+		t.Errorf("got token %q, want it untouched", got[0].token)
+	}
+
+	for _, path := range []string{"/{{.missing}}", "/{{.id"} {
+		if err := rn.Execute(Request{Template: true}, "GET", path); err == nil {
+			t.Errorf("%s: want error, got nil", path)
+		}
+	}
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	os.Setenv("HIT_TEMPLATE_TEST", "secret")
+	defer os.Unsetenv("HIT_TEMPLATE_TEST")
+	defer Vars.Reset()
+	Vars.Set("pass", "p")
+
+	year := strconv.Itoa(time.Now().Year())
+	tests := []struct {
+		tmpl  string
+		match func(string) bool
+		err   string
+	}{
+		{tmpl: "{{uuid}}", match: func(s string) bool { return UUIDv4().Match(s) == nil }},
+		{tmpl: "{{now \"2006\"}}", match: func(s string) bool { return s == year }},
+		{tmpl: "{{now \"unix\"}}", match: func(s string) bool {
+			n, err := strconv.ParseInt(s, 10, 64)
+			return err == nil && n > 1e9
+		}},
+		{tmpl: "{{randInt 3 5}}", match: func(s string) bool { return s == "3" || s == "4" || s == "5" }},
+		{tmpl: "{{randInt 7 7}}", match: func(s string) bool { return s == "7" }},
+		{tmpl: "{{b64 \"user:\" .pass}}", match: func(s string) bool { return s == "dXNlcjpw" }},
+		{tmpl: "{{env \"HIT_TEMPLATE_TEST\"}}", match: func(s string) bool { return s == "secret" }},
+		{tmpl: "{{randInt 5 3}}", err: "randInt 5 3"},
+		{tmpl: "{{env \"HIT_TEMPLATE_UNSET\"}}", err: "HIT_TEMPLATE_UNSET is not set"},
+	}
+	r := Request{Template: true}
+	for i, tt := range tests {
+		got, err := r.render(tt.tmpl)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("#%d: got error %v, want it to contain %q", i, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("#%d: unexpected error %v", i, err)
+			continue
+		}
+		if !tt.match(got) {
+			t.Errorf("#%d: %s got %q", i, tt.tmpl, got)
+		}
+	}
+}
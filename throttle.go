@@ -0,0 +1,45 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"io"
+	"time"
+)
+
+// throttledBody is an io.ReadCloser that reads from the underlying body no
+// faster than rate bytes per second, simulating a slow client.
+type throttledBody struct {
+	rc    io.ReadCloser
+	rate  int
+	start time.Time
+	sent  int64
+}
+
+func throttle(rc io.ReadCloser, rate int) io.ReadCloser {
+	return &throttledBody{rc: rc, rate: rate}
+}
+
+func (b *throttledBody) Read(p []byte) (int, error) {
+	if b.start.IsZero() {
+		b.start = time.Now()
+	}
+	// send in chunks of a tenth of a second's worth of bytes
+	chunk := b.rate / 10
+	if chunk < 1 {
+		chunk = 1
+	}
+	if len(p) > chunk {
+		p = p[:chunk]
+	}
+	n, err := b.rc.Read(p)
+	b.sent += int64(n)
+	due := b.start.Add(time.Duration(b.sent) * time.Second / time.Duration(b.rate))
+	if d := time.Until(due); d > 0 {
+		time.Sleep(d)
+	}
+	return n, err
+}
+
+func (b *throttledBody) Close() error { return b.rc.Close() }
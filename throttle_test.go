@@ -0,0 +1,39 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRequestThrottle(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NewResponseController(w).SetReadDeadline(time.Now().Add(150 * time.Millisecond))
+		if _, err := io.Copy(ioutil.Discard, r.Body); err != nil {
+			w.WriteHeader(408)
+		}
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	start := time.Now()
+	r := Request{Body: BytesOfSize(100), Throttle: 1000, Want: Response{Status: 200}}
+	if err := rn.Execute(r, "POST", "/"); err != nil {
+		t.Error(err)
+	}
+	if d := time.Since(start); d < 90*time.Millisecond {
+		t.Errorf("got request duration %s, want at least 100ms", d)
+	}
+
+	r = Request{Body: BytesOfSize(300), Throttle: 1000, Want: Response{Status: 408}}
+	if err := rn.Execute(r, "POST", "/"); err != nil {
+		t.Error(err)
+	}
+}
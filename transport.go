@@ -0,0 +1,101 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"syscall"
+)
+
+// TransportError is a class of the errors with which a request can fail at
+// the transport level, i.e. before a response is received.
+type TransportError int
+
+const (
+	// AnyTransportError matches every transport error.
+	AnyTransportError TransportError = iota + 1
+	// ConnRefused is the failure to connect to a port nobody listens on.
+	ConnRefused
+	// ConnReset is a connection closed, or reset, by the server before it
+	// sent a response.
+	ConnReset
+	// Timeout is a request that timed out, e.g. because of the Config's
+	// Timeout.
+	Timeout
+	// TLSError is a failed TLS handshake, e.g. because of an untrusted
+	// certificate or of a protocol mismatch.
+	TLSError
+	// DNSError is the failure to resolve the host's name.
+	DNSError
+)
+
+var transportErrorNames = map[TransportError]string{
+	AnyTransportError: "any transport error",
+	ConnRefused:       "connection refused",
+	ConnReset:         "connection reset",
+	Timeout:           "timeout",
+	TLSError:          "TLS error",
+	DNSError:          "DNS error",
+}
+
+func (c TransportError) String() string {
+	if s, ok := transportErrorNames[c]; ok {
+		return s
+	}
+	return fmt.Sprintf("TransportError(%d)", int(c))
+}
+
+// classifyTransportError returns the class of err, AnyTransportError if it
+// belongs to none of the others.
+func classifyTransportError(err error) TransportError {
+	var (
+		dnsErr  *net.DNSError
+		netErr  net.Error
+		recErr  tls.RecordHeaderError
+		alert   tls.AlertError
+		verify  *tls.CertificateVerificationError
+		unknown x509.UnknownAuthorityError
+		host    x509.HostnameError
+		invalid x509.CertificateInvalidError
+	)
+	switch {
+	case errors.As(err, &dnsErr):
+		return DNSError
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ConnRefused
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return Timeout
+	case errors.As(err, &recErr), errors.As(err, &alert), errors.As(err, &verify),
+		errors.As(err, &unknown), errors.As(err, &host), errors.As(err, &invalid):
+		return TLSError
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return ConnReset
+	}
+	return AnyTransportError
+}
+
+// check returns the failure message of a request that was expected to fail
+// with a transport error of the class c but got the specified error and
+// response instead, if any.
+func (c TransportError) check(err error, res *http.Response) string {
+	if err == nil {
+		ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		return fmt.Sprintf("Transport error got = %sa %d response%s, want = %s%s%s\n",
+			RedColor, res.StatusCode, StopColor, RedColor, c, StopColor)
+	}
+	if got := classifyTransportError(err); c != AnyTransportError && got != c {
+		return fmt.Sprintf("Transport error got = %s%s%s, want = %s%s%s\n%v\n",
+			RedColor, got, StopColor, RedColor, c, StopColor, err)
+	}
+	return ""
+}
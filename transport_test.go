@@ -0,0 +1,68 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTransportError(t *testing.T) {
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ok.Close()
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer slow.Close()
+	reset := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			conn.Close()
+		}
+	}))
+	defer reset.Close()
+	secure := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer secure.Close()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := "http://" + l.Addr().String()
+	l.Close()
+
+	// the timeout is short for the slow server only, the TLS handshake
+	// takes longer than that under the race detector
+	tests := []struct {
+		url     string
+		want    TransportError
+		err     string
+		timeout time.Duration
+	}{
+		{closed, ConnRefused, "", 5 * time.Second},
+		{closed, AnyTransportError, "", 5 * time.Second},
+		{closed, Timeout, "Transport error got = " + RedColor + "connection refused", 5 * time.Second},
+		{closed, 0, "hit: GET / failed.", 5 * time.Second},
+		{slow.URL, Timeout, "", 100 * time.Millisecond},
+		{reset.URL, ConnReset, "", 5 * time.Second},
+		{secure.URL, TLSError, "", 5 * time.Second},
+		{ok.URL, ConnRefused, "Transport error got = " + RedColor + "a 200 response", 5 * time.Second},
+	}
+	for i, tt := range tests {
+		rn, err := Config{BaseURL: tt.url, Timeout: tt.timeout}.NewRunner()
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = rn.Execute(Request{TransportError: tt.want, Want: Response{Status: 200}}, "GET", "/")
+		if tt.err == "" && err != nil {
+			t.Errorf("#%d: got err %v, want <nil>", i, err)
+		} else if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("#%d: got err %v, want err containing %q", i, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+// ResumableUpload tests a resumable upload protocol. The upload is created,
+// its data is sent in chunks, each at its offset, and the assembled resource
+// is then downloaded and compared to the data. The header names and the
+// chunk method default to those of the tus 1.0 protocol.
+type ResumableUpload struct {
+	Name string
+
+	// Create is the path to which a POST, with the data's length in the
+	// LengthHeader, creates the upload. The response's Location is the URL
+	// of the upload to which the chunks are sent.
+	Create string
+	// Header is sent with every request, e.g. {"Tus-Resumable": {"1.0.0"}}.
+	Header Header
+
+	Data      []byte
+	ChunkSize int
+
+	// DropChunk, if greater than 0, is the number of the chunk, counting
+	// from 1, whose connection is closed midway. The upload must then be
+	// resumed from the offset reported by a HEAD request to the upload,
+	// which must lie within the dropped chunk's sent bytes.
+	DropChunk int
+
+	// Download, if set, is the path from which the assembled resource is
+	// downloaded, it defaults to the upload's URL.
+	Download string
+
+	// OffsetHeader defaults to "Upload-Offset", LengthHeader defaults to
+	// "Upload-Length", ChunkMethod defaults to "PATCH" and ChunkType to
+	// "application/offset+octet-stream".
+	OffsetHeader string
+	LengthHeader string
+	ChunkMethod  string
+	ChunkType    string
+}
+
+// Test executes the upload.
+func (u ResumableUpload) Test(t *testing.T) {
+	if err := u.Execute(); err != nil {
+		t.Error(err)
+	}
+}
+
+// Execute executes the upload, it stops at the first failing request.
+func (u ResumableUpload) Execute() error {
+	return globalRunner().ResumableUpload(u)
+}
+
+// ResumableUpload executes the upload against the Runner's target, it stops
+// at the first failing request.
+func (rn *Runner) ResumableUpload(u ResumableUpload) error {
+	if u.OffsetHeader == "" {
+		u.OffsetHeader = "Upload-Offset"
+	}
+	if u.LengthHeader == "" {
+		u.LengthHeader = "Upload-Length"
+	}
+	if u.ChunkMethod == "" {
+		u.ChunkMethod = "PATCH"
+	}
+	if u.ChunkType == "" {
+		u.ChunkType = "application/offset+octet-stream"
+	}
+	if u.ChunkSize <= 0 {
+		return u.failure(fmt.Errorf("hit: ChunkSize must be greater than 0, got %d", u.ChunkSize))
+	}
+
+	base, err := url.Parse(rn.baseURL() + u.Create)
+	if err != nil {
+		return u.failure(err)
+	}
+	res, _, err := u.send(rn, "POST", base.String(), nil, map[string]string{
+		u.LengthHeader: strconv.Itoa(len(u.Data)),
+	})
+	if err != nil {
+		return u.failure(err)
+	}
+	if res.StatusCode != http.StatusCreated && res.StatusCode != http.StatusOK {
+		return u.failure(fmt.Errorf("POST %s StatusCode got = %s%d%s, want %s%d%s\n",
+			u.Create, RedColor, res.StatusCode, StopColor, RedColor, http.StatusCreated, StopColor))
+	}
+	loc, err := base.Parse(res.Header.Get("Location"))
+	if err != nil || res.Header.Get("Location") == "" {
+		return u.failure(fmt.Errorf("POST %s Header[%q] got = %s%q%s, want = %sthe upload URL%s\n",
+			u.Create, "Location", RedColor, res.Header.Get("Location"), StopColor, RedColor, StopColor))
+	}
+	upload := loc.String()
+
+	off, chunk := 0, 0
+	for off < len(u.Data) {
+		chunk++
+		end := off + u.ChunkSize
+		if end > len(u.Data) {
+			end = len(u.Data)
+		}
+		hdr := map[string]string{u.OffsetHeader: strconv.Itoa(off), "Content-Type": u.ChunkType}
+
+		if chunk == u.DropChunk {
+			sent := (end - off) / 2
+			body := &abortBody{rc: ioutil.NopCloser(bytes.NewReader(u.Data[off:end])), left: int64(sent)}
+			// the error is expected, the transport fails once the body aborts
+			if res, _, err := u.send(rn, u.ChunkMethod, upload, body, hdr); err == nil {
+				res.Body.Close()
+			}
+
+			res, _, err := u.send(rn, "HEAD", upload, nil, nil)
+			if err != nil {
+				return u.failure(err)
+			}
+			got, err := strconv.Atoi(res.Header.Get(u.OffsetHeader))
+			if err != nil || got < off || got > off+sent {
+				return u.failure(fmt.Errorf("HEAD after dropped chunk #%d Header[%q] got = %s%q%s, want = %sbetween %d and %d%s\n",
+					chunk, u.OffsetHeader, RedColor, res.Header.Get(u.OffsetHeader), StopColor, RedColor, off, off+sent, StopColor))
+			}
+			off = got
+			continue
+		}
+
+		res, _, err := u.send(rn, u.ChunkMethod, upload, bytes.NewReader(u.Data[off:end]), hdr)
+		if err != nil {
+			return u.failure(err)
+		}
+		if res.StatusCode/100 != 2 {
+			return u.failure(fmt.Errorf("%s chunk #%d at offset %d StatusCode got = %s%d%s, want %s2xx%s\n",
+				u.ChunkMethod, chunk, off, RedColor, res.StatusCode, StopColor, RedColor, StopColor))
+		}
+		if got := res.Header.Get(u.OffsetHeader); got != "" && got != strconv.Itoa(end) {
+			return u.failure(fmt.Errorf("%s chunk #%d Header[%q] got = %s%q%s, want = %s%q%s\n",
+				u.ChunkMethod, chunk, u.OffsetHeader, RedColor, got, StopColor, RedColor, strconv.Itoa(end), StopColor))
+		}
+		off = end
+	}
+
+	download := upload
+	if u.Download != "" {
+		download = rn.baseURL() + u.Download
+	}
+	res, body, err := u.send(rn, "GET", download, nil, nil)
+	if err != nil {
+		return u.failure(err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return u.failure(fmt.Errorf("GET %s StatusCode got = %s%d%s, want %s%d%s\n",
+			download, RedColor, res.StatusCode, StopColor, RedColor, http.StatusOK, StopColor))
+	}
+	if err := RawBody(u.Data).Compare(bytes.NewReader(body)); err != nil {
+		return u.failure(fmt.Errorf("GET %s %v", download, err))
+	}
+	return nil
+}
+
+// send sends a request with the receiver's Header and the specified header
+// and returns the response with its body read in whole.
+func (u ResumableUpload) send(rn *Runner, method, urlStr string, body io.Reader, header map[string]string) (*http.Response, []byte, error) {
+	req, err := http.NewRequest(method, urlStr, body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if u.Header != nil {
+		u.Header.AddTo(req)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	if _, ok := body.(*abortBody); ok {
+		req.ContentLength = -1
+	}
+	res, err := rn.httpClient().Do(req)
+	if err != nil && !isRedirectError(err) {
+		return nil, nil, fmt.Errorf("hit: %s %s failed. %v", method, urlStr, err)
+	}
+	defer res.Body.Close()
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("hit: error reading http.Response.Body. %v", err)
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(b))
+	return res, b, nil
+}
+
+// failure returns the specified error prefixed with the name of the upload.
+func (u ResumableUpload) failure(err error) error {
+	return fmt.Errorf("%sUpload %q:%s\n%v", PurpleColor, u.Name, StopColor, err)
+}
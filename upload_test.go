@@ -0,0 +1,92 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// tusServer is a minimal in-memory tus server, if corrupt is set it drops
+// every chunk's last byte.
+type tusServer struct {
+	mu      sync.Mutex
+	data    []byte
+	length  int
+	corrupt bool
+}
+
+func (s *tusServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case r.Method == "POST" && r.URL.Path == "/files":
+		s.length, _ = strconv.Atoi(r.Header.Get("Upload-Length"))
+		s.data = nil
+		w.Header().Set("Location", "/files/1")
+		w.WriteHeader(201)
+	case r.Method == "HEAD":
+		w.Header().Set("Upload-Offset", strconv.Itoa(len(s.data)))
+	case r.Method == "PATCH":
+		if off, _ := strconv.Atoi(r.Header.Get("Upload-Offset")); off != len(s.data) {
+			w.WriteHeader(409)
+			return
+		}
+		// keep whatever arrived, even if the connection was dropped
+		var b bytes.Buffer
+		io.Copy(&b, r.Body)
+		chunk := b.Bytes()
+		if s.corrupt && len(chunk) > 0 {
+			chunk = chunk[:len(chunk)-1]
+		}
+		s.data = append(s.data, chunk...)
+		w.Header().Set("Upload-Offset", strconv.Itoa(len(s.data)))
+		w.WriteHeader(204)
+	case r.Method == "GET":
+		w.Write(s.data)
+	}
+}
+
+func TestResumableUpload(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789abcdef"), 64)
+	tests := []struct {
+		srv  *tusServer
+		drop int
+		want string
+	}{
+		{srv: &tusServer{}},
+		{srv: &tusServer{}, drop: 3},
+		{srv: &tusServer{corrupt: true}, want: "Upload-Offset"},
+	}
+	for i, tt := range tests {
+		ts := httptest.NewServer(tt.srv)
+		rn := NewRunner()
+		rn.Addr = ts.URL[len("http://"):]
+		err := rn.ResumableUpload(ResumableUpload{
+			Name:      "avatar",
+			Create:    "/files",
+			Header:    Header{"Tus-Resumable": {"1.0.0"}},
+			Data:      data,
+			ChunkSize: 100,
+			DropChunk: tt.drop,
+		})
+		ts.Close()
+
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("#%d: unexpected error %v", i, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("#%d: got error %v, want it to contain %q", i, err, tt.want)
+		}
+	}
+}
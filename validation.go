@@ -0,0 +1,255 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// ValidationStatus is the status of the responses expected by Invalid.
+var ValidationStatus = 422
+
+// Invalid returns the expected response of a request that fails validation,
+// i.e. one with the ValidationStatus whose body has errors for the specified
+// fields, e.g.
+//
+//	Want: Invalid(Fields{"email": "required", "age": GT(0)})
+//
+// Errors of other fields are ignored, use a ValidationErrors with Only set
+// as the response's Body to fail on them.
+func Invalid(fields Fields) Response {
+	return Response{Status: ValidationStatus, Body: ValidationErrors{Fields: fields}}
+}
+
+// Fields maps the names of fields to their expected validation error messages.
+// A string matches a message that contains it, a Matcher matches the messages
+// it matches and an empty string or nil matches any message. A field with
+// multiple messages matches if any one of them matches.
+type Fields map[string]interface{}
+
+// ValidationErrors represents the expected validation errors of a response
+// body. The errors are looked up in the common layouts, i.e. an object that
+// maps fields to a message or to a list of messages, e.g.
+//
+//	{"errors": {"email": ["is required"]}}
+//
+// or a list of objects with a field and a message, e.g.
+//
+//	{"errors": [{"field": "email", "message": "is required"}]}
+//	{"invalid-params": [{"name": "email", "reason": "is required"}]}
+//	{"errors": [{"source": {"pointer": "/data/attributes/email"}, "detail": "is required"}]}
+//
+// under one of the members "errors", "invalid-params", "invalidParams",
+// "violations" or "details", or at the top level of the body. A field given
+// as the JSON pointer of a JSON:API error's source matches by its last
+// reference token.
+type ValidationErrors struct {
+	Fields Fields
+	// Only, if set, fails the comparison if the body has errors for
+	// fields other than those in Fields.
+	Only bool
+	// Path, if set, is the JSON pointer of the errors in the body, e.g.
+	// "/error/details", in place of the common members.
+	Path string
+}
+
+var (
+	validationMembers = []string{"errors", "invalid-params", "invalidParams", "violations", "details"}
+	validationFields  = []string{"field", "name", "param", "property", "propertyPath", "path"}
+	validationMsgs    = []string{"message", "msg", "reason", "detail", "title", "error", "description"}
+)
+
+// fieldError is the validation error of a single field found in a body.
+type fieldError struct {
+	path string
+	msgs []string
+}
+
+// Compare implements the Comparer interface.
+func (v ValidationErrors) Compare(r io.Reader) error {
+	var doc interface{}
+	d := json.NewDecoder(r)
+	d.UseNumber()
+	if err := d.Decode(&doc); err != nil {
+		return fmt.Errorf("hit: error decoding http.Response.Body. %v", err)
+	}
+
+	base, errs := v.Path, interface{}(nil)
+	if base != "" {
+		errs, _ = lookupPointer(doc, base)
+	} else if obj, ok := doc.(map[string]interface{}); ok {
+		errs = obj
+		for _, k := range validationMembers {
+			if x, ok := obj[k]; ok {
+				base, errs = pointer("", k), x
+				break
+			}
+		}
+	}
+	got := collectFieldErrors(base, errs)
+
+	e := &BodyDiffError{Got: doc, Want: v.Fields}
+	var names []string
+	for f := range v.Fields {
+		names = append(names, f)
+	}
+	sort.Strings(names)
+	seen := make(map[string]bool)
+	for _, f := range names {
+		key, fe := lookupFieldError(got, f)
+		if fe == nil {
+			e.Diffs = append(e.Diffs, BodyDiff{pointer(base, f), fmt.Sprintf("got <missing>, want %s", describeMessage(v.Fields[f]))})
+			continue
+		}
+		seen[key] = true
+		if err := matchMessages(fe.msgs, v.Fields[f]); err != nil {
+			e.Diffs = append(e.Diffs, BodyDiff{fe.path, err.Error()})
+		}
+	}
+	if v.Only {
+		for key, fe := range got {
+			if !seen[key] {
+				e.Extra = append(e.Extra, fe.path)
+			}
+		}
+		sort.Strings(e.Extra)
+	}
+	if len(e.Diffs) > 0 || len(e.Extra) > 0 {
+		return e
+	}
+	return nil
+}
+
+// collectFieldErrors returns the field errors of the value found at the JSON
+// pointer path, keyed by field.
+func collectFieldErrors(path string, v interface{}) map[string]*fieldError {
+	got := make(map[string]*fieldError)
+	add := func(field, path string, msgs ...string) {
+		if fe, ok := got[field]; ok {
+			fe.msgs = append(fe.msgs, msgs...)
+			return
+		}
+		got[field] = &fieldError{path: path, msgs: msgs}
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, x := range v {
+			switch x := x.(type) {
+			case string:
+				add(k, pointer(path, k), x)
+			case []interface{}:
+				var msgs []string
+				for _, m := range x {
+					if s, ok := errorMessage(m); ok {
+						msgs = append(msgs, s)
+					}
+				}
+				add(k, pointer(path, k), msgs...)
+			case map[string]interface{}:
+				if s, ok := errorMessage(x); ok {
+					add(k, pointer(path, k), s)
+				}
+			}
+		}
+	case []interface{}:
+		for i, x := range v {
+			obj, ok := x.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			field := firstString(obj, validationFields)
+			if src, ok := obj["source"].(map[string]interface{}); ok && field == "" {
+				field = firstString(src, []string{"pointer", "parameter"})
+			}
+			if field == "" {
+				continue
+			}
+			msg, _ := errorMessage(obj)
+			add(field, pointer(path, fmt.Sprint(i)), msg)
+		}
+	}
+	return got
+}
+
+// lookupFieldError returns the error of the specified field and its key in
+// got, matching a JSON pointer key by its last reference token.
+func lookupFieldError(got map[string]*fieldError, field string) (string, *fieldError) {
+	if fe, ok := got[field]; ok {
+		return field, fe
+	}
+	for k, fe := range got {
+		if strings.HasPrefix(k, "/") && strings.HasSuffix(k, pointer("", field)) {
+			return k, fe
+		}
+	}
+	return "", nil
+}
+
+// errorMessage returns the message of a validation error, either a string or
+// an object with one of the common message members.
+func errorMessage(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case map[string]interface{}:
+		s := firstString(v, validationMsgs)
+		return s, s != ""
+	}
+	return "", false
+}
+
+// firstString returns the first non-empty string value of the object's
+// specified members.
+func firstString(obj map[string]interface{}, keys []string) string {
+	for _, k := range keys {
+		if s, ok := obj[k].(string); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// matchMessages checks that any one of the messages matches the expected one.
+func matchMessages(msgs []string, want interface{}) error {
+	if want == nil || want == "" {
+		return nil
+	}
+	for _, m := range msgs {
+		switch w := want.(type) {
+		case string:
+			if strings.Contains(m, w) {
+				return nil
+			}
+		case Matcher:
+			if w.Match(m) == nil {
+				return nil
+			}
+		default:
+			return fmt.Errorf("hit: Fields value %#v is neither a string nor a Matcher", want)
+		}
+	}
+	if len(msgs) == 1 {
+		return fmt.Errorf("got %q, want %s", msgs[0], describeMessage(want))
+	}
+	return fmt.Errorf("got %q, want %s", msgs, describeMessage(want))
+}
+
+// describeMessage describes the expected message in a failure message.
+func describeMessage(want interface{}) string {
+	switch w := want.(type) {
+	case nil:
+		return "any message"
+	case string:
+		if w == "" {
+			return "any message"
+		}
+		return fmt.Sprintf("message containing %q", w)
+	}
+	return fmt.Sprintf("message matching %#v", want)
+}
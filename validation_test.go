@@ -0,0 +1,91 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidationErrors(t *testing.T) {
+	tests := []struct {
+		body string
+		want ValidationErrors
+		errs []string
+	}{{
+		body: `{"errors": {"email": ["is required"], "age": "must be positive"}}`,
+		want: ValidationErrors{Fields: Fields{"email": "required", "age": "positive"}},
+	}, {
+		body: `{"message": "invalid", "errors": [{"field": "email", "message": "is required"}, {"field": "name", "message": "too long"}]}`,
+		want: ValidationErrors{Fields: Fields{"email": "required"}},
+	}, {
+		body: `{"type": "about:blank", "invalid-params": [{"name": "age", "reason": "must be a number"}]}`,
+		want: ValidationErrors{Fields: Fields{"age": nil}},
+	}, {
+		body: `{"errors": [{"source": {"pointer": "/data/attributes/email"}, "detail": "has already been taken"}]}`,
+		want: ValidationErrors{Fields: Fields{"email": "taken"}},
+	}, {
+		body: `{"email": "is invalid"}`,
+		want: ValidationErrors{Fields: Fields{"email": OneOf("is invalid", "is required")}},
+	}, {
+		body: `{"error": {"details": {"email": "is invalid"}}}`,
+		want: ValidationErrors{Fields: Fields{"email": "invalid"}, Path: "/error/details"},
+	}, {
+		body: `{"errors": {"email": ["is required", "is invalid"]}}`,
+		want: ValidationErrors{Fields: Fields{"email": "invalid"}, Only: true},
+	}, {
+		body: `{"errors": {"email": "is required"}}`,
+		want: ValidationErrors{Fields: Fields{"email": "invalid", "age": ""}},
+		errs: []string{
+			`Body /errors/age: ` + RedColor + `got <missing>, want any message` + StopColor,
+			`Body /errors/email: ` + RedColor + `got "is required", want message containing "invalid"` + StopColor,
+		},
+	}, {
+		body: `{"errors": [{"field": "email", "message": "is required"}, {"field": "name", "message": "too long"}]}`,
+		want: ValidationErrors{Fields: Fields{"email": "required"}, Only: true},
+		errs: []string{`Body has unexpected fields ` + RedColor + `/errors/1` + StopColor},
+	}}
+
+	for i, tt := range tests {
+		err := tt.want.Compare(strings.NewReader(tt.body))
+		if len(tt.errs) == 0 {
+			if err != nil {
+				t.Errorf("#%d: got err %v, want <nil>", i, err)
+			}
+			continue
+		}
+		if !errors.Is(err, &BodyDiffError{}) {
+			t.Errorf("#%d: got err %v, want a *BodyDiffError", i, err)
+			continue
+		}
+		for _, want := range tt.errs {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("#%d: got err %q, want it to contain %q", i, err, want)
+			}
+		}
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(422)
+		w.Write([]byte(`{"errors": {"email": ["is required"], "name": ["is too long"]}}`))
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	r := Request{Body: JSONBody{"name": "foo"}, Want: Invalid(Fields{"email": "required"})}
+	if err := rn.Execute(r, "POST", "/users"); err != nil {
+		t.Errorf("got err %v, want <nil>", err)
+	}
+	r.Want = Invalid(Fields{"age": "required"})
+	if err := rn.Execute(r, "POST", "/users"); err == nil || !strings.Contains(err.Error(), "/errors/age") {
+		t.Errorf("got err %v, want a missing /errors/age", err)
+	}
+}
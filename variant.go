@@ -0,0 +1,75 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"net/http"
+	"sort"
+)
+
+// Variant represents a variation of a Request, it's useful for testing
+// content negotiation where the same request is sent with different Accept
+// headers and is expected to produce different responses.
+type Variant struct {
+	Header Header
+	Want   Response
+}
+
+// Negotiate returns a Variant for every combination of the specified header
+// values, e.g. every Accept value combined with every Accept-Language value.
+// The want function is called with the header of each combination and should
+// return the response expected for it.
+func Negotiate(axes map[string][]string, want func(h Header) Response) []Variant {
+	var names []string
+	canon := make(map[string][]string)
+	for k, v := range axes {
+		k = http.CanonicalHeaderKey(k)
+		if _, ok := canon[k]; !ok {
+			names = append(names, k)
+		}
+		canon[k] = append(canon[k], v...)
+	}
+	sort.Strings(names)
+
+	vv := []Variant{{Header: Header{}}}
+	for _, k := range names {
+		var next []Variant
+		for _, v := range vv {
+			for _, val := range canon[k] {
+				h := make(Header, len(v.Header)+1)
+				for hk, hv := range v.Header {
+					h[hk] = hv
+				}
+				h[k] = []string{val}
+				next = append(next, Variant{Header: h})
+			}
+		}
+		vv = next
+	}
+	for i := range vv {
+		vv[i].Want = want(vv[i].Header)
+	}
+	return vv
+}
+
+// variants executes a copy of the receiver for each of its Variants, it returns
+// an ErrorList of the failures of the Variants.
+func (r Request) variants(method, path string) error {
+	var ll ErrorList
+	for _, v := range r.Variants {
+		cp := r
+		cp.Variants = nil
+		cp.Want = v.Want
+		cp.Want.Body = r.runner().withMode(v.Want.Body)
+		cp.Header = make(Header, len(r.Header)+len(v.Header))
+		for k, vv := range r.Header {
+			cp.Header[k] = vv
+		}
+		for k, vv := range v.Header {
+			cp.Header[http.CanonicalHeaderKey(k)] = vv
+		}
+		ll.add(cp.execute(method, path))
+	}
+	return ll.err()
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	got := Negotiate(map[string][]string{
+		"accept":          {"a", "b"},
+		"Accept-Language": {"en", "de"},
+	}, func(h Header) Response {
+		return Response{Status: 200, Header: Header{"Content-Type": h["Accept"]}}
+	})
+	want := []Variant{
+		{Header{"Accept": {"a"}, "Accept-Language": {"en"}}, Response{Status: 200, Header: Header{"Content-Type": {"a"}}}},
+		{Header{"Accept": {"a"}, "Accept-Language": {"de"}}, Response{Status: 200, Header: Header{"Content-Type": {"a"}}}},
+		{Header{"Accept": {"b"}, "Accept-Language": {"en"}}, Response{Status: 200, Header: Header{"Content-Type": {"b"}}}},
+		{Header{"Accept": {"b"}, "Accept-Language": {"de"}}, Response{Status: 200, Header: Header{"Content-Type": {"b"}}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestRequestVariants(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") == "text/plain" {
+			w.Header().Set("Content-Type", "text/plain")
+			fmt.Fprint(w, "hello")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"hello":%q}`, r.Header.Get("Accept-Language"))
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+
+	r := Request{Header: Header{"Accept-Language": {"en"}}, Variants: []Variant{
+		{Header{"Accept": {"application/json"}}, Response{Status: 200, Body: JSONBody{"hello": "en"}}},
+		{Header{"Accept": {"application/json"}, "Accept-Language": {"de"}}, Response{Status: 200, Body: JSONBody{"hello": "de"}}},
+		{Header{"Accept": {"text/plain"}}, Response{Status: 200, Header: Header{"Content-Type": {"text/plain"}}}},
+	}}
+	if err := rn.Execute(r, "GET", "/"); err != nil {
+		t.Error(err)
+	}
+
+	r.Variants[2].Want.Header = Header{"Content-Type": {"text/html"}}
+	if err := rn.Execute(r, "GET", "/"); err == nil || !strings.Contains(err.Error(), "text/html") {
+		t.Errorf("got err %v, want Content-Type error", err)
+	}
+
+	// the failures of the variants are typed, sourced and reported once
+	var buf bytes.Buffer
+	rn.Events = &buf
+	r.Variants[0].Want.Status = 201
+	r = Here(r)
+	err := rn.Execute(r, "GET", "/")
+	var se *StatusError
+	if !errors.As(err, &se) || se.Got != 200 {
+		t.Errorf("got err %v, want a *StatusError", err)
+	}
+	if !errors.Is(err, &HeaderError{Name: "Content-Type"}) {
+		t.Errorf("got err %v, want a Content-Type *HeaderError", err)
+	}
+	if n := strings.Count(err.Error(), r.Source); n != 1 {
+		t.Errorf("got source %q %d times in %q, want once", r.Source, n, err)
+	}
+	if n := strings.Count(buf.String(), "\n"); n != 2 {
+		t.Errorf("got %d events, want 2:\n%s", n, buf.String())
+	}
+}
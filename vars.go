@@ -0,0 +1,110 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Vars is the default variable store, it's used by the package level
+// CaptureAs and SameAs matchers.
+var Vars = NewStore()
+
+// Store is a concurrency safe variable store used to share values between
+// requests, e.g. to capture an id returned by a POST request and assert that
+// it's returned by a subsequent GET request.
+type Store struct {
+	mu sync.RWMutex
+	m  map[string]interface{}
+}
+
+// NewStore returns a new, empty, Store.
+func NewStore() *Store {
+	return &Store{m: make(map[string]interface{})}
+}
+
+// Get returns the value stored under the specified name and a boolean
+// reporting whether the value was present.
+func (s *Store) Get(name string) (interface{}, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.m[name]
+	return v, ok
+}
+
+// Set stores the value under the specified name.
+func (s *Store) Set(name string, v interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.m[name] = v
+}
+
+// values returns a copy of the values in the store.
+func (s *Store) values() map[string]interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	m := make(map[string]interface{}, len(s.m))
+	for k, v := range s.m {
+		m[k] = v
+	}
+	return m
+}
+
+// Reset removes all the values from the store.
+func (s *Store) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.m = make(map[string]interface{})
+}
+
+// CaptureAs returns a Matcher that matches any value and stores it in the
+// receiver under the specified name.
+func (s *Store) CaptureAs(name string) Matcher {
+	return capture{s, name}
+}
+
+type capture struct {
+	s    *Store
+	name string
+}
+
+func (c capture) Match(got interface{}) error {
+	c.s.Set(c.name, got)
+	return nil
+}
+
+// matchMode stores the value unless the matching elements are still being
+// searched for, e.g. in an Unordered array.
+func (c capture) matchMode(got interface{}, mode Mode) error {
+	if mode&dryCapture != 0 {
+		return nil
+	}
+	return c.Match(got)
+}
+
+func (c capture) GoString() string { return fmt.Sprintf("hit.CaptureAs(%q)", c.name) }
+
+// SameAs returns a Matcher that matches a value equal to the one stored in the
+// receiver under the specified name. It fails if no value has been stored.
+func (s *Store) SameAs(name string) Matcher {
+	return MatcherFunc(fmt.Sprintf("SameAs(%q)", name), func(got interface{}) error {
+		want, ok := s.Get(name)
+		if !ok {
+			return fmt.Errorf("no value captured as %q", name)
+		}
+		if err := matchJSON("", got, want, DefaultMode); err != nil {
+			return fmt.Errorf("got %#v, want %#v captured as %q", got, want, name)
+		}
+		return nil
+	})
+}
+
+// CaptureAs returns a Matcher that matches any value and stores it in Vars
+// under the specified name.
+func CaptureAs(name string) Matcher { return Vars.CaptureAs(name) }
+
+// SameAs returns a Matcher that matches a value equal to the one stored in
+// Vars under the specified name.
+func SameAs(name string) Matcher { return Vars.SameAs(name) }
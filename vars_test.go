@@ -0,0 +1,84 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCaptureAsSameAs(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			w.Header().Set("Location", "/orders/42")
+			w.WriteHeader(201)
+			fmt.Fprint(w, `{"id":42}`)
+			return
+		}
+		fmt.Fprintf(w, `{"id":%s,"self":"/orders/%[1]s"}`, r.URL.Query().Get("id"))
+	}))
+	defer ts.Close()
+	rn := NewRunner()
+	rn.Addr = ts.URL[len("http://"):]
+	defer Vars.Reset()
+
+	post := Request{Want: Response{
+		Status:      201,
+		HeaderMatch: map[string]Matcher{"Location": CaptureAs("location")},
+		Body:        JSONBody{"id": CaptureAs("orderID")},
+	}}
+	if err := rn.Execute(post, "POST", "/orders"); err != nil {
+		t.Fatal(err)
+	}
+
+	get := Request{Want: Response{Status: 200, Body: JSONBody{"id": SameAs("orderID"), "self": SameAs("location")}}}
+	if err := rn.Execute(get, "GET", "/orders?id=42"); err != nil {
+		t.Error(err)
+	}
+	if err := rn.Execute(get, "GET", "/orders?id=43"); err == nil || !strings.Contains(err.Error(), `hit.SameAs("orderID")`) {
+		t.Errorf("got err %v, want SameAs mismatch", err)
+	}
+
+	s := NewStore()
+	if err := s.SameAs("x").Match(1); err == nil {
+		t.Error("got err <nil>, want err for missing value")
+	}
+}
+
+func TestCaptureAsUnordered(t *testing.T) {
+	body := `[{"id":2,"name":"b"},{"id":1,"name":"a"},{"id":3,"name":"c"}]`
+	// the elements are tried against every candidate, repeat to cover
+	// the random order in which the fields of an object are compared
+	for n := 0; n < 20; n++ {
+		s := NewStore()
+		want := Unordered([]interface{}{
+			JSONBody{"id": s.CaptureAs("b"), "name": "b"},
+			JSONBody{"id": s.CaptureAs("a"), "name": "a"},
+			JSONBody{"id": Any(), "name": "c", "tags": ContainsItem(s.CaptureAs("tag"))},
+		})
+		if err := want.Compare(strings.NewReader(body)); err == nil {
+			t.Fatalf("got <nil>, want err for the missing tags")
+		}
+		if v, ok := s.Get("a"); ok {
+			t.Fatalf("got %v captured by a failed comparison, want nothing", v)
+		}
+
+		want = Unordered([]interface{}{
+			JSONBody{"id": s.CaptureAs("b"), "name": "b"},
+			JSONBody{"id": s.CaptureAs("a"), "name": "a"},
+			JSONBody{"id": Any(), "name": "c"},
+		})
+		if err := want.Compare(strings.NewReader(body)); err != nil {
+			t.Fatal(err)
+		}
+		for name, id := range map[string]string{"a": "1", "b": "2"} {
+			if v, _ := s.Get(name); fmt.Sprint(v) != id {
+				t.Fatalf("got %q captured as %v, want %s", name, v, id)
+			}
+		}
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// Wire checks the bytes exchanged with the server on the connection of a
+// Request, e.g. the casing of a header's name or the absence of a default
+// header, for protocol conformance tests. The Request is sent over HTTP/1.1
+// on a new connection of its own, the bytes of a TLS connection are those
+// before encryption. The bytes received are those read by the time the
+// Response has been compared, a Response without a Body may leave the body
+// unread. A Runner with a custom Transport can't capture the bytes.
+type Wire struct {
+	// Sent and Received list byte sequences that must appear in the bytes
+	// written to and read from the connection, e.g. "\r\nX-API-Key: ".
+	Sent, Received []string
+	// NotSent and NotReceived list byte sequences that must not appear,
+	// e.g. "\r\nServer: ".
+	NotSent, NotReceived []string
+
+	// Check, if set, is called with the captured bytes.
+	Check func(sent, received []byte) error
+}
+
+// wireRecorder records the bytes exchanged on the connections of a request.
+type wireRecorder struct {
+	mu             sync.Mutex
+	sent, received bytes.Buffer
+}
+
+type wireKey struct{}
+
+// withWire returns a copy of the specified request whose connections are
+// recorded by the returned recorder.
+func withWire(req *http.Request) (*http.Request, *wireRecorder) {
+	rec := new(wireRecorder)
+	return req.WithContext(context.WithValue(req.Context(), wireKey{}, rec)), rec
+}
+
+// wireOf returns the recorder of the specified request, if any.
+func wireOf(req *http.Request) *wireRecorder {
+	rec, _ := req.Context().Value(wireKey{}).(*wireRecorder)
+	return rec
+}
+
+func (rec *wireRecorder) reset() {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.sent.Reset()
+	rec.received.Reset()
+}
+
+// bytes returns copies of the bytes recorded so far.
+func (rec *wireRecorder) bytes() (sent, received []byte) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return append([]byte(nil), rec.sent.Bytes()...), append([]byte(nil), rec.received.Bytes()...)
+}
+
+// wireConn is a net.Conn whose reads and writes are recorded.
+type wireConn struct {
+	net.Conn
+	rec *wireRecorder
+}
+
+func (c wireConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.rec.mu.Lock()
+	c.rec.received.Write(p[:n])
+	c.rec.mu.Unlock()
+	return n, err
+}
+
+func (c wireConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.rec.mu.Lock()
+	c.rec.sent.Write(p[:n])
+	c.rec.mu.Unlock()
+	return n, err
+}
+
+// wireClient returns a client whose connections are recorded by rec and are
+// not reused.
+func (rn *Runner) wireClient(rec *wireRecorder) *http.Client {
+	tr := defaultTransport.Clone()
+	tr.TLSClientConfig = rn.TLS
+	if rn.Profile != nil && rn.Profile.TLS != nil {
+		tr.TLSClientConfig = rn.Profile.TLS
+	}
+	tr.DisableKeepAlives = true
+	tr.ForceAttemptHTTP2 = false
+	dial := tr.DialContext
+	tr.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		c, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return wireConn{c, rec}, nil
+	}
+	// the TLS connection is wrapped, rather than the TCP one beneath it,
+	// so that the recorded bytes are readable
+	tr.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		c, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		cfg := &tls.Config{}
+		if tr.TLSClientConfig != nil {
+			cfg = tr.TLSClientConfig.Clone()
+		}
+		if cfg.ServerName == "" {
+			cfg.ServerName, _, _ = net.SplitHostPort(addr)
+		}
+		tc := tls.Client(c, cfg)
+		if err := tc.HandshakeContext(ctx); err != nil {
+			c.Close()
+			return nil, err
+		}
+		return wireConn{tc, rec}, nil
+	}
+	return &http.Client{Transport: tr, CheckRedirect: client.CheckRedirect, Timeout: rn.Timeout}
+}
+
+// check compares the recorded bytes to the Wire and returns the failure
+// message, if any.
+func (w *Wire) check(rec *wireRecorder) string {
+	sent, received := rec.bytes()
+	var fail string
+	for _, c := range []struct {
+		name      string
+		b         []byte
+		want, not []string
+	}{
+		{"sent", sent, w.Sent, w.NotSent},
+		{"received", received, w.Received, w.NotReceived},
+	} {
+		for _, s := range c.want {
+			if !bytes.Contains(c.b, []byte(s)) {
+				fail += fmt.Sprintf("Wire %s got = %s%q%s, want = %scontaining %q%s\n",
+					c.name, RedColor, wireHead(c.b), StopColor, RedColor, s, StopColor)
+			}
+		}
+		for _, s := range c.not {
+			if bytes.Contains(c.b, []byte(s)) {
+				fail += fmt.Sprintf("Wire %s got = %s%q%s, want = %snot containing %q%s\n",
+					c.name, RedColor, wireHead(c.b), StopColor, RedColor, s, StopColor)
+			}
+		}
+	}
+	if w.Check != nil {
+		if err := w.Check(sent, received); err != nil {
+			fail += fmt.Sprintf("Wire %v\n", err)
+		}
+	}
+	return fail
+}
+
+// wireHead returns the head of an HTTP message, i.e. the part before the
+// blank line that ends the header.
+func wireHead(b []byte) []byte {
+	if i := bytes.Index(b, []byte("\r\n\r\n")); i >= 0 {
+		return b[:i+2]
+	}
+	return b
+}
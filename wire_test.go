@@ -0,0 +1,85 @@
+// Copyright (c) 2015, Marian Kopriva
+// All rights reserved.
+// Licensed under BSD, see LICENSE for details.
+package hit
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWire(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// bypass the canonicalization of the header's name
+		w.Header()["x-lower"] = []string{"1"}
+		w.Header().Set("X-Powered-By", "hit")
+		w.Write([]byte("ok"))
+	})
+	ts, tls1 := httptest.NewServer(h), httptest.NewTLSServer(h)
+	defer ts.Close()
+	defer tls1.Close()
+
+	tests := []struct {
+		wire Wire
+		want []string
+	}{
+		{wire: Wire{
+			Sent:     []string{"GET /wire HTTP/1.1\r\n", "\r\nX-Api-Key: secret\r\n"},
+			Received: []string{"\r\nx-lower: 1\r\n", "\r\n\r\nok"},
+		}},
+		{wire: Wire{
+			NotSent:     []string{"\r\nAccept-Encoding: "},
+			NotReceived: []string{"\r\nX-Powered-By: "},
+			Received:    []string{"\r\nX-Lower: "},
+		}, want: []string{
+			`Wire sent got = `, `not containing "\r\nAccept-Encoding: "`,
+			`not containing "\r\nX-Powered-By: "`, `containing "\r\nX-Lower: "`,
+		}},
+		{wire: Wire{Check: func(sent, received []byte) error {
+			if !bytes.HasPrefix(received, []byte("HTTP/1.1 200 OK\r\n")) {
+				return errors.New("not a 200")
+			}
+			return errors.New("custom")
+		}}, want: []string{"Wire custom"}},
+	}
+	for _, srv := range []*httptest.Server{ts, tls1} {
+		rn := NewRunner()
+		rn.BaseURL = srv.URL
+		rn.TLS = &tls.Config{InsecureSkipVerify: true}
+		for i, tt := range tests {
+			w := tt.wire
+			err := rn.Execute(Request{
+				Header: Header{"X-Api-Key": {"secret"}},
+				Wire:   &w,
+				Want:   Response{Status: 200, Body: RawBody("ok")},
+			}, "GET", "/wire")
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Errorf("%s #%d: unexpected error %v", srv.URL, i, err)
+				}
+				continue
+			}
+			if err == nil {
+				t.Errorf("%s #%d: want error, got nil", srv.URL, i)
+				continue
+			}
+			for _, s := range tt.want {
+				if !strings.Contains(err.Error(), s) {
+					t.Errorf("%s #%d: error %q does not contain %q", srv.URL, i, err, s)
+				}
+			}
+		}
+	}
+
+	rn := NewRunner()
+	rn.BaseURL = ts.URL
+	rn.Transport = http.DefaultTransport
+	if err := rn.Execute(Request{Wire: &Wire{}}, "GET", "/"); err == nil {
+		t.Error("custom Transport: want error, got nil")
+	}
+}